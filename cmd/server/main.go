@@ -10,26 +10,36 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/redis/go-redis/v9"
 
 	// Adapters
+	"immortal-chat/internal/adapters/gateway"
 	"immortal-chat/internal/adapters/handler"
 	"immortal-chat/internal/adapters/repository"
+	"immortal-chat/internal/adapters/storage"
 	logws "immortal-chat/internal/adapters/websocket"
 
 	// Core
 	"immortal-chat/internal/config"
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
 	"immortal-chat/internal/core/services"
 )
 
 func main() {
 	fmt.Println("=== Immortal Chat OS - System Initialization (Merged Phase 2+3) ===")
 
+	// Root context cancelled on SIGINT/SIGTERM (docker stop) for graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// 1. Load Configuration
 	fmt.Println("[1/6] Loading configuration...")
 	cfg, err := config.LoadConfig()
@@ -74,25 +84,167 @@ func main() {
 	// A. Repositories
 	mariadbRepo := repository.NewMariaDBRepository(db)
 	redisRepo := repository.NewRedisRepository(rdb)
+	webhookQueue, err := repository.NewRedisWebhookQueue(ctx, rdb)
+	if err != nil {
+		log.Fatalf("❌ Failed to init webhook queue: %v", err)
+	}
+
+	// B. Platform Adapters (one per channel, keyed by pages.platform)
+	platforms := services.NewPlatformRegistry(
+		gateway.NewFacebookAdapter(gateway.NewFacebookClient(), cfg.Facebook.AppSecret),
+		gateway.NewInstagramAdapter(gateway.NewFacebookClient(), cfg.Facebook.AppSecret),
+	)
+	if cfg.Zalo.Enabled() {
+		platforms.Register(gateway.NewZaloAdapter(
+			gateway.NewZaloClient(cfg.Zalo.APIBaseURL),
+			cfg.Zalo.AppID,
+			cfg.Zalo.OASecretKey,
+		))
+		fmt.Println("✓ Zalo OA channel enabled (Webhook: /webhook/zalo)")
+	}
+	if cfg.Telegram.Enabled() {
+		platforms.Register(gateway.NewTelegramAdapter(
+			gateway.NewTelegramClient(cfg.Telegram.APIBaseURL),
+			cfg.Telegram.BotToken,
+			cfg.Telegram.WebhookSecret,
+		))
+		fmt.Println("✓ Telegram channel enabled (Webhook: /webhook/telegram)")
+	}
+
+	// C. Services
+	// Live dashboard updates, each staff only receives events of conversations in their scope
+	eventHub := logws.NewEventHub(mariadbRepo)
+	go eventHub.Run()
+	var eventPublisher ports.EventPublisher = eventHub
+
+	// Conversation assignment; new inbound conversations are routed to online agents
+	assignmentService := services.NewAssignmentService(mariadbRepo, mariadbRepo, redisRepo, eventPublisher, services.RoutingConfig{
+		Strategy:    cfg.Routing.Strategy,
+		PresenceTTL: time.Duration(cfg.Routing.PresenceTTLSec) * time.Second,
+	})
+
+	// Media files on local disk: dashboard uploads and copies of inbound attachments
+	mediaStore, err := storage.NewLocalMediaStore(cfg.Media.Dir)
+	if err != nil {
+		log.Fatalf("❌ Failed to init media storage: %v", err)
+	}
+	mediaMirror := services.NewMediaMirrorService(mariadbRepo, mediaStore, gateway.NewMediaDownloader(2*time.Minute), services.MediaMirrorConfig{
+		Interval:    time.Duration(cfg.Media.MirrorIntervalSec) * time.Second,
+		MaxSize:     int64(cfg.Media.MirrorMaxMB) << 20,
+		MaxAttempts: cfg.Media.MirrorMaxAttempts,
+		MaxBackoff:  time.Duration(cfg.Media.MirrorMaxBackoffMin) * time.Minute,
+	})
+	go mediaMirror.Run(ctx)
+
+	// Page -> tenant routing of inbound events (Redis cache in front of the pages table)
+	tenantResolver := services.NewTenantResolver(mariadbRepo, redisRepo)
 
-	// B. Services (Gateway is instantiated inside handlers as needed)
 	dispatcher := services.NewDispatcher(
 		mariadbRepo,
 		mariadbRepo,
 		mariadbRepo,
 		redisRepo,
+		webhookQueue,
+		platforms,
+		tenantResolver,
+		mariadbRepo,
+		eventPublisher,
+		assignmentService,
+		mediaMirror,
 	)
 
-	// D. Handlers
+	// D. Webhook Worker Pool (drains the durable queue, at-least-once)
+	webhookWorkers := services.NewWebhookWorkerPool(dispatcher, webhookQueue, services.WebhookWorkerConfig{
+		Workers:           cfg.WebhookQueue.Workers,
+		VisibilityTimeout: time.Duration(cfg.WebhookQueue.VisibilityTimeoutSec) * time.Second,
+		MaxDeliveries:     cfg.WebhookQueue.MaxDeliveries,
+	})
+	webhookWorkers.Start(ctx)
+
+	// Replay engine for failed / stale pending webhook_logs (exponential backoff)
+	replayService := services.NewReplayService(dispatcher, mariadbRepo, webhookQueue, services.ReplayConfig{
+		Interval:    time.Duration(cfg.WebhookReplay.IntervalSec) * time.Second,
+		MaxRetries:  cfg.WebhookReplay.MaxRetries,
+		BaseBackoff: time.Duration(cfg.WebhookReplay.BaseBackoffSec) * time.Second,
+		MaxBackoff:  time.Duration(cfg.WebhookReplay.MaxBackoffSec) * time.Second,
+		StaleAfter:  time.Duration(cfg.WebhookReplay.StalePendingMin) * time.Minute,
+	})
+	go replayService.Run(ctx)
+
+	// Plan limits / expiry (outbound) and message retention per tenant
+	quotaService := services.NewQuotaService(mariadbRepo)
+	go quotaService.RunRetention(ctx, time.Duration(cfg.Retention.IntervalMin)*time.Minute)
+
+	// Archive / resolve / snooze; snoozed conversations are reopened when due
+	lifecycleService := services.NewLifecycleService(mariadbRepo, eventPublisher)
+	go lifecycleService.RunSnoozeWaker(ctx, time.Duration(cfg.Lifecycle.SnoozeCheckIntervalSec)*time.Second)
+
+	// Media uploads (local disk) for attachment replies
+	mediaService := services.NewMediaService(mariadbRepo, mediaStore, services.MediaConfig{
+		PublicURL: cfg.Media.PublicURL,
+		MaxSize:   int64(cfg.Media.MaxUploadMB) << 20,
+	})
+
+	// Outbound queue: replies are sent by workers within each page's rate limit, with retries
+	outboundService := services.NewOutboundService(mariadbRepo, platforms, mediaService, tenantResolver, eventPublisher, services.OutboundConfig{
+		Workers:       cfg.Outbound.Workers,
+		RatePerSecond: float64(cfg.Outbound.RatePerSecond),
+		Burst:         cfg.Outbound.Burst,
+		MaxAttempts:   cfg.Outbound.MaxAttempts,
+		MaxBackoff:    time.Duration(cfg.Outbound.MaxBackoffSec) * time.Second,
+		PollInterval:  time.Duration(cfg.Outbound.PollIntervalMsec) * time.Millisecond,
+	})
+	go outboundService.Run(ctx)
+
+	// Staff login and dashboard tokens
+	authService := services.NewAuthService(mariadbRepo, redisRepo, quotaService, services.AuthConfig{
+		Secret:     []byte(cfg.Auth.JWTSecret),
+		AccessTTL:  time.Duration(cfg.Auth.AccessTTLMin) * time.Minute,
+		RefreshTTL: time.Duration(cfg.Auth.RefreshTTLHours) * time.Hour,
+	})
+	if cfg.Auth.BootstrapEmail != "" && cfg.Auth.BootstrapPassword != "" {
+		if err := authService.EnsureBootstrapAdmin(ctx, cfg.Auth.BootstrapTenantID, cfg.Auth.BootstrapEmail, cfg.Auth.BootstrapName, cfg.Auth.BootstrapPassword); err != nil {
+			log.Fatalf("❌ Failed to create bootstrap staff account: %v", err)
+		}
+	}
+
+	// E. Handlers
 	webhookHandler := handler.NewWebhookHandler(
 		dispatcher,
-		cfg.Facebook.AppSecret,
+		platforms,
 		cfg.Facebook.VerifyToken,
 	)
 
 	// Dashboard Handler (Phase 3 Upgrade)
 	// Lưu ý: DashboardHandler cần hỗ trợ cả method cũ (Metrics) và mới (Chat)
-	dashboardHandler := handler.NewDashboardHandler(db, rdb)
+	dashboardHandler := handler.NewDashboardHandler(db, rdb, platforms, quotaService, mediaService, outboundService)
+
+	// Tenant Handler (plan usage)
+	tenantHandler := handler.NewTenantHandler(quotaService)
+
+	// Auth Handler (login / tokens / staff accounts, RequireAuth / Require middleware)
+	// Require(permission, ...) also checks the staff role (owner, admin, agent, viewer)
+	authHandler := handler.NewAuthHandler(authService)
+	requireAuth := authHandler.RequireAuth
+	require := authHandler.Require
+
+	// Assignment Handler (assign / transfer / unassign, agent presence)
+	assignmentHandler := handler.NewAssignmentHandler(assignmentService)
+
+	// Lifecycle Handler (archive / resolve / snooze / reopen)
+	lifecycleHandler := handler.NewLifecycleHandler(lifecycleService)
+
+	// Tag Handler (tag definitions, conversation tags)
+	tagHandler := handler.NewTagHandler(services.NewTagService(mariadbRepo, eventPublisher))
+
+	// Media Handler (uploads for attachment replies)
+	mediaHandler := handler.NewMediaHandler(mediaService, int64(cfg.Media.MaxUploadMB)<<20)
+
+	// Search Handler (full-text search over messages and customers)
+	searchHandler := handler.NewSearchHandler(services.NewSearchService(mariadbRepo))
+
+	// Admin Handler (internal ops, protected by X-Mesh-Secret)
+	adminHandler := handler.NewAdminHandler(replayService, cfg.MeshSecret)
 
 	// ==================================================================
 	// ROUTING SETUP (FIX LỖI STATIC FILES & 404)
@@ -109,25 +261,107 @@ func main() {
 	fs := http.FileServer(http.Dir(staticDir))
 	mux.Handle("/static/", http.StripPrefix("/static/", fs))
 
-	// 2. PHASE 2 API (GIỮ NGUYÊN TÍNH NĂNG CŨ)
-	mux.HandleFunc("/api/status", dashboardHandler.GetStatus)
-	mux.HandleFunc("/api/system/metrics", dashboardHandler.GetSystemMetrics)
-	mux.HandleFunc("/api/platforms", dashboardHandler.GetPlatforms)     // <-- Đã khôi phục
-	mux.HandleFunc("/api/sync/status", dashboardHandler.GetSyncStatus) // <-- Đã khôi phục
+	// Uploaded media (public: platforms fetch attachments by URL, IDs are random)
+	mux.Handle("/media/", handler.MediaFiles(mediaStore.Dir()))
+
+	// 1a. AUTH API (public: login / refresh / logout)
+	mux.HandleFunc("/api/auth/login", authHandler.Login)
+	mux.HandleFunc("/api/auth/refresh", authHandler.Refresh)
+	mux.HandleFunc("/api/auth/logout", authHandler.Logout)
+	mux.HandleFunc("/api/auth/me", requireAuth(authHandler.Me))
 
-	// 3. PHASE 3 API (TÍNH NĂNG CHAT MỚI)
-	mux.HandleFunc("/api/conversations", dashboardHandler.GetConversations)
+	// 1b. STAFF & TEAMS (GET: staff:read, POST: staff:manage)
+	listStaff := require(domain.PermissionViewStaff, authHandler.ListStaff)
+	createStaff := require(domain.PermissionManageStaff, authHandler.CreateStaff)
+	mux.HandleFunc("/api/staff", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			listStaff(w, r)
+		} else {
+			createStaff(w, r)
+		}
+	})
+	listTeams := require(domain.PermissionViewStaff, authHandler.ListTeams)
+	createTeam := require(domain.PermissionManageStaff, authHandler.CreateTeam)
+	mux.HandleFunc("/api/teams", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			listTeams(w, r)
+		case http.MethodPost:
+			createTeam(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// 2. PHASE 2 API (GIỮ NGUYÊN TÍNH NĂNG CŨ) - requires staff login
+	mux.HandleFunc("/api/status", requireAuth(dashboardHandler.GetStatus))
+	mux.HandleFunc("/api/system/metrics", require(domain.PermissionViewSystem, dashboardHandler.GetSystemMetrics))
+	mux.HandleFunc("/api/platforms", require(domain.PermissionViewDashboard, dashboardHandler.GetPlatforms))    // <-- Đã khôi phục
+	mux.HandleFunc("/api/sync/status", require(domain.PermissionViewDashboard, dashboardHandler.GetSyncStatus)) // <-- Đã khôi phục
+
+	// 3. PHASE 3 API (TÍNH NĂNG CHAT MỚI) - requires staff login, filtered by data scope
+	mux.HandleFunc("/api/conversations", require(domain.PermissionViewConversations, dashboardHandler.GetConversations))
 	
-	// Route con cho messages (VD: /api/conversations/123/messages)
+	// Route con cho messages / khung 24h / phân công / nhãn / trạng thái (VD: /api/conversations/123/messages, /api/conversations/123/assign)
+	conversationMessages := require(domain.PermissionViewConversations, dashboardHandler.GetConversationMessages)
+	messagingWindow := require(domain.PermissionViewConversations, dashboardHandler.GetMessagingWindow)
+	assignConversation := require(domain.PermissionAssign, assignmentHandler.Assign)
+	transferConversation := require(domain.PermissionAssign, assignmentHandler.Transfer)
+	unassignConversation := require(domain.PermissionAssign, assignmentHandler.Unassign)
+	tagConversation := require(domain.PermissionTag, tagHandler.ConversationTags)
+	changeStatus := require(domain.PermissionChangeStatus, lifecycleHandler.ChangeStatus)
 	mux.HandleFunc("/api/conversations/", func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasSuffix(r.URL.Path, "/messages") {
-			dashboardHandler.GetConversationMessages(w, r)
-		} else {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/messages"):
+			conversationMessages(w, r)
+		case strings.HasSuffix(r.URL.Path, "/window"):
+			messagingWindow(w, r)
+		case strings.HasSuffix(r.URL.Path, "/assign"):
+			assignConversation(w, r)
+		case strings.HasSuffix(r.URL.Path, "/transfer"):
+			transferConversation(w, r)
+		case strings.HasSuffix(r.URL.Path, "/unassign"):
+			unassignConversation(w, r)
+		case strings.Contains(r.URL.Path, "/tags"):
+			tagConversation(w, r)
+		case strings.HasSuffix(r.URL.Path, "/archive"), strings.HasSuffix(r.URL.Path, "/resolve"),
+			strings.HasSuffix(r.URL.Path, "/snooze"), strings.HasSuffix(r.URL.Path, "/reopen"):
+			changeStatus(w, r)
+		default:
 			http.NotFound(w, r)
 		}
 	})
 	
-	mux.HandleFunc("/api/messages/reply", dashboardHandler.SendReply)
+	// Tags (GET: conversations:read with counts per data scope, POST / DELETE: tags:manage)
+	listTags := require(domain.PermissionViewConversations, tagHandler.ListTags)
+	createTag := require(domain.PermissionManageTags, tagHandler.CreateTag)
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			listTags(w, r)
+		case http.MethodPost:
+			createTag(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/tags/", require(domain.PermissionManageTags, tagHandler.DeleteTag))
+	
+	// Full-text search (messages + customer names, within the data scope)
+	mux.HandleFunc("/api/search", require(domain.PermissionViewConversations, searchHandler.Search))
+	
+	// Agent presence (dashboard heartbeat), used by automatic routing
+	mux.HandleFunc("/api/presence", requireAuth(assignmentHandler.Presence))
+	
+	mux.HandleFunc("/api/messages/reply", require(domain.PermissionReply, dashboardHandler.SendReply))
+	mux.HandleFunc("/api/messages/structured", require(domain.PermissionReply, dashboardHandler.SendStructured))
+	mux.HandleFunc("/api/media", require(domain.PermissionReply, mediaHandler.Upload))
+	mux.HandleFunc("/api/tenant/usage", require(domain.PermissionViewUsage, tenantHandler.GetUsage))
+
+	// Admin API (X-Mesh-Secret)
+	mux.HandleFunc("/api/admin/webhooks/replay", adminHandler.ReplayWebhooks)
+	mux.HandleFunc("/api/admin/webhooks/status", adminHandler.WebhookStatus)
+	mux.HandleFunc("/api/admin/quarantine/replay", adminHandler.ReplayQuarantined)
 
 	// 4. FACEBOOK WEBHOOK
 	mux.HandleFunc("/webhook/facebook", func(w http.ResponseWriter, r *http.Request) {
@@ -140,6 +374,35 @@ func main() {
 		}
 	})
 
+	// 4a. INSTAGRAM WEBHOOK (same Graph webhook format & verification as Facebook)
+	mux.HandleFunc("/webhook/instagram", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			webhookHandler.HandleFacebookVerify(w, r)
+		} else if r.Method == http.MethodPost {
+			webhookHandler.HandleInstagramEvent(w, r)
+		} else {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// 4b. ZALO OA WEBHOOK (POST only, no verification handshake)
+	mux.HandleFunc("/webhook/zalo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		webhookHandler.HandleZaloEvent(w, r)
+	})
+
+	// 4c. TELEGRAM BOT WEBHOOK (POST only, secret token header)
+	mux.HandleFunc("/webhook/telegram", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		webhookHandler.HandleTelegramEvent(w, r)
+	})
+
 	// 5. SYSTEM LIVE MONITOR (WebSocket)
 	// Route: /ws/logs?secret_key=YOUR_MESH_SECRET
 	if logHub != nil {
@@ -147,6 +410,16 @@ func main() {
 		log.Println("✓ WebSocket route /ws/logs registered")
 	}
 
+	// 5a. LIVE DASHBOARD EVENTS (WebSocket)
+	// Route: /ws/events, subprotocols ["events", "bearer.<access token>"]
+	mux.HandleFunc("/ws/events", eventHub.Handler(authService))
+	log.Println("✓ WebSocket route /ws/events registered")
+
+	// 5b. LOGIN PAGE (public)
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join(staticDir, "login.html"))
+	})
+
 	// 6. ROOT HANDLER (SPA Fallback)
 	// Tất cả request không khớp API hay Static sẽ trả về index.html (để React/JS xử lý)
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -170,9 +443,29 @@ func main() {
 	// Start Watchdog Service (Phase 2 Resilience)
 	services.RunWatchdog(db)
 
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		log.Fatalf("❌ HTTP server failed: %v", err)
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("❌ HTTP server failed: %v", err)
+		}
+	}()
+
+	// ==================================================================
+	// GRACEFUL SHUTDOWN
+	// Stop accepting webhooks first, then let workers finish in-flight jobs.
+	// Anything not Acked stays in the queue and is picked up after restart
+	// ==================================================================
+	<-ctx.Done()
+	fmt.Println("\n⏳ Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
 	}
+
+	webhookWorkers.Wait()
+	fmt.Println("✅ Shutdown complete")
 }
 
 // --- Helper Functions (Logic Retry không đổi) ---
@@ -1,5 +1,6 @@
 // Simple HTTP Server to serve the dashboard
 // This is a minimal server for development purposes
+// Run from the repository root: go run ./cmd/simpleserver
 package main
 
 import (
@@ -5,8 +5,20 @@ package dto
 // FacebookWebhookRequest is the top-level webhook payload from Facebook
 // Ref: https://developers.facebook.com/docs/messenger-platform/webhooks
 type FacebookWebhookRequest struct {
-	Object string          `json:"object"` // Always "page" for Messenger
-	Entry  []FacebookEntry `json:"entry"`  // Array of page entries
+	Object string          `json:"object"` // "page" for Messenger, "instagram" for Instagram Direct
+	Entry  []FacebookEntry `json:"entry"`  // Array of page (or IG business account) entries
+}
+
+// Webhook object values
+const (
+	FacebookObjectPage      = "page"
+	FacebookObjectInstagram = "instagram"
+)
+
+// IsInstagram reports whether the webhook carries Instagram Direct events
+// Instagram uses the same entry/messaging format; IDs are IG-scoped (IGSID)
+func (r *FacebookWebhookRequest) IsInstagram() bool {
+	return r.Object == FacebookObjectInstagram
 }
 
 // FacebookEntry represents a single page's webhook events
@@ -34,6 +46,73 @@ type FacebookMessaging struct {
 	
 	// Read confirmation (message was read)
 	Read      *FacebookRead     `json:"read,omitempty"`      // Read receipt
+	
+	// Postback (user tapped a button / Get Started / persistent menu item)
+	Postback  *FacebookPostback `json:"postback,omitempty"`
+	
+	// Referral (user entered an existing thread via m.me link, ad or plugin)
+	Referral  *FacebookReferral `json:"referral,omitempty"`
+	
+	// Opt-in (checkbox plugin, Send to Messenger, notification messages)
+	Optin     *FacebookOptin    `json:"optin,omitempty"`
+	
+	// Edit of an earlier message (new text replaces the old one)
+	MessageEdit *FacebookMessageEdit `json:"message_edit,omitempty"`
+	
+	// Reaction added to / removed from a message
+	Reaction  *FacebookReaction `json:"reaction,omitempty"`
+}
+
+// FacebookPostback represents a button click
+// Ref: https://developers.facebook.com/docs/messenger-platform/reference/webhook-events/messaging_postbacks
+type FacebookPostback struct {
+	MID      string            `json:"mid,omitempty"`      // Present on newer API versions
+	Title    string            `json:"title"`              // Button title shown to the user
+	Payload  string            `json:"payload"`            // Developer-defined payload
+	Referral *FacebookReferral `json:"referral,omitempty"` // Get Started tapped from an m.me link / ad
+}
+
+// FacebookReferral describes where the user came from
+// Ref: https://developers.facebook.com/docs/messenger-platform/reference/webhook-events/messaging_referrals
+type FacebookReferral struct {
+	Ref    string `json:"ref,omitempty"`   // ?ref= parameter of the m.me link
+	Source string `json:"source"`          // "SHORTLINK", "ADS", "CUSTOMER_CHAT_PLUGIN", ...
+	Type   string `json:"type"`            // "OPEN_THREAD"
+	AdID   string `json:"ad_id,omitempty"` // Click-to-Messenger ads
+}
+
+// FacebookOptin represents an opt-in event
+// Ref: https://developers.facebook.com/docs/messenger-platform/reference/webhook-events/messaging_optins
+type FacebookOptin struct {
+	Type                       string `json:"type,omitempty"`     // "notification_messages" or empty (plugin)
+	Ref                        string `json:"ref,omitempty"`      // data-ref of the plugin
+	UserRef                    string `json:"user_ref,omitempty"` // Checkbox plugin: no PSID yet
+	Payload                    string `json:"payload,omitempty"`
+	Title                      string `json:"title,omitempty"`
+	NotificationMessagesToken  string `json:"notification_messages_token,omitempty"`
+	NotificationMessagesStatus string `json:"notification_messages_status,omitempty"`
+}
+
+// FacebookMessageEdit represents a message_edits event
+// Ref: https://developers.facebook.com/docs/messenger-platform/reference/webhook-events/message-edits
+type FacebookMessageEdit struct {
+	MID     string `json:"mid"`      // Edited message
+	Text    string `json:"text"`     // New text
+	NumEdit int    `json:"num_edit"` // Number of edits so far
+}
+
+// FacebookReaction represents a message_reactions event
+// Ref: https://developers.facebook.com/docs/messenger-platform/reference/webhook-events/message-reactions
+type FacebookReaction struct {
+	MID      string `json:"mid"`                // Reacted message
+	Action   string `json:"action"`             // "react" or "unreact"
+	Reaction string `json:"reaction,omitempty"` // "love", "like", "smile", ... ("other" for custom emoji)
+	Emoji    string `json:"emoji,omitempty"`
+}
+
+// FacebookQuickReply carries the payload of a tapped quick reply
+type FacebookQuickReply struct {
+	Payload string `json:"payload"`
 }
 
 // FacebookUser represents a sender or recipient (PSID)
@@ -50,19 +129,44 @@ type FacebookMessage struct {
 	Attachments []FacebookAttachment `json:"attachments,omitempty"`
 	
 	// IsEcho indicates this message was sent BY the page (not TO the page)
-	// CRITICAL: We must filter these out per user requirement
+	// Echoes are not customer messages: they are stored as agent messages instead
 	IsEcho bool `json:"is_echo,omitempty"`
+	
+	// ReplyTo is set when the message replies to a message or (Instagram) a story
+	ReplyTo *FacebookReplyTo `json:"reply_to,omitempty"`
+	
+	// QuickReply is set when the user tapped a quick reply button
+	QuickReply *FacebookQuickReply `json:"quick_reply,omitempty"`
+	
+	// Referral is set on the first message sent from an ad / shop entry point
+	Referral *FacebookReferral `json:"referral,omitempty"`
+	
+	// IsDeleted is set when the user unsent the message (only mid is present)
+	IsDeleted bool `json:"is_deleted,omitempty"`
+}
+
+// FacebookReplyTo references what a message replies to
+type FacebookReplyTo struct {
+	MID   string            `json:"mid,omitempty"`   // Replied message
+	Story *FacebookStoryRef `json:"story,omitempty"` // Instagram story reply
+}
+
+// FacebookStoryRef identifies an Instagram story (URL expires after 24h)
+type FacebookStoryRef struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
 }
 
 // FacebookAttachment represents media attachments
 type FacebookAttachment struct {
-	Type    string                `json:"type"`    // "image", "video", "audio", "file"
+	Type    string                `json:"type"`    // "image", "video", "audio", "file", IG: "share", "story_mention"
 	Payload FacebookAttachmentPayload `json:"payload"` // Attachment details
 }
 
 // FacebookAttachmentPayload contains attachment URL and metadata
 type FacebookAttachmentPayload struct {
-	URL string `json:"url"` // Download URL for the attachment
+	URL string `json:"url"`          // Download URL for the attachment
+	ID  string `json:"id,omitempty"` // Instagram story ID (story replies)
 }
 
 // FacebookDelivery represents a delivery confirmation
@@ -73,7 +177,8 @@ type FacebookDelivery struct {
 
 // FacebookRead represents a read confirmation
 type FacebookRead struct {
-	Watermark int64 `json:"watermark"` // All messages before this timestamp were read
+	Watermark int64  `json:"watermark"`     // All messages before this timestamp were read
+	MID       string `json:"mid,omitempty"` // Instagram: last message seen (no watermark)
 }
 
 // IsUserMessage determines if this messaging event is an actual user message
@@ -90,6 +195,11 @@ func (m *FacebookMessaging) IsUserMessage() bool {
 		return false
 	}
 	
+	// Filter out unsends (they reference an existing message)
+	if m.Message.IsDeleted {
+		return false
+	}
+	
 	// Filter out delivery receipts
 	if m.Delivery != nil {
 		return false
@@ -118,9 +228,14 @@ func (m *FacebookMessaging) GetMessageType() string {
 		return ""
 	}
 	
+	// Instagram story reply (text + reference to the story)
+	if m.Message.ReplyTo != nil && m.Message.ReplyTo.Story != nil {
+		return "story_reply"
+	}
+	
 	// Check for attachments first
 	if len(m.Message.Attachments) > 0 {
-		return m.Message.Attachments[0].Type // "image", "video", "audio", "file"
+		return m.Message.Attachments[0].Type // "image", "video", "audio", "file", "share", "story_mention"
 	}
 	
 	// Default to text if no attachments
@@ -0,0 +1,203 @@
+package dto
+
+import "strings"
+
+// TelegramUpdate is the webhook payload from the Telegram Bot API
+// Telegram sends one Update per request
+// Ref: https://core.telegram.org/bots/api#update
+type TelegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *TelegramMessage `json:"message,omitempty"` // New incoming message
+}
+
+// TelegramMessage represents a message sent to the bot
+// Ref: https://core.telegram.org/bots/api#message
+type TelegramMessage struct {
+	MessageID int64         `json:"message_id"` // Unique only inside a chat
+	From      *TelegramUser `json:"from,omitempty"`
+	Chat      TelegramChat  `json:"chat"`
+	Date      int64         `json:"date"` // Unix seconds
+
+	Text     string              `json:"text,omitempty"`
+	Caption  string              `json:"caption,omitempty"` // For photo / document / voice
+	Photo    []TelegramPhotoSize `json:"photo,omitempty"`   // Available sizes, smallest first
+	Document *TelegramDocument   `json:"document,omitempty"`
+	Voice    *TelegramVoice      `json:"voice,omitempty"`
+	Sticker  *TelegramSticker    `json:"sticker,omitempty"`
+}
+
+// TelegramUser represents the sender of a message
+type TelegramUser struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+// TelegramChat represents the chat a message belongs to
+// For private chats Chat.ID equals the user ID; replies are sent to Chat.ID
+type TelegramChat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"` // "private", "group", "supergroup", "channel"
+}
+
+// TelegramPhotoSize is one resolution of a photo
+type TelegramPhotoSize struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// TelegramDocument represents a general file
+type TelegramDocument struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// TelegramVoice represents a voice note
+type TelegramVoice struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Duration     int    `json:"duration"` // Seconds
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// TelegramSticker represents a sticker
+type TelegramSticker struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Emoji        string `json:"emoji,omitempty"`
+	SetName      string `json:"set_name,omitempty"`
+}
+
+// TelegramAttachment is what we store in messages.attachments
+// Only file IDs are kept: download URLs embed the bot token and must not be persisted
+type TelegramAttachment struct {
+	Type    string                    `json:"type"` // "image", "file", "voice", "sticker"
+	Payload TelegramAttachmentPayload `json:"payload"`
+}
+
+// TelegramAttachmentPayload contains the file reference and metadata
+type TelegramAttachmentPayload struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+	Duration     int    `json:"duration,omitempty"`
+	Emoji        string `json:"emoji,omitempty"`
+}
+
+// IsUserMessage reports whether the update is a supported customer message
+// Edits, channel posts, callback queries and messages from other bots are ignored
+func (u *TelegramUpdate) IsUserMessage() bool {
+	m := u.Message
+	if m == nil || (m.From != nil && m.From.IsBot) {
+		return false
+	}
+	return m.Text != "" || len(m.Photo) > 0 || m.Document != nil || m.Voice != nil || m.Sticker != nil
+}
+
+// GetMessageType maps the message content to messages.type
+func (m *TelegramMessage) GetMessageType() string {
+	switch {
+	case len(m.Photo) > 0:
+		return "image"
+	case m.Document != nil:
+		return "file"
+	case m.Voice != nil:
+		return "voice"
+	case m.Sticker != nil:
+		return "sticker"
+	default:
+		return "text"
+	}
+}
+
+// GetContent extracts a human-readable content (text, caption, emoji or file name)
+func (m *TelegramMessage) GetContent() string {
+	switch {
+	case m.Text != "":
+		return m.Text
+	case m.Caption != "":
+		return m.Caption
+	case m.Sticker != nil:
+		return m.Sticker.Emoji
+	case m.Document != nil:
+		return m.Document.FileName
+	}
+	return ""
+}
+
+// GetAttachments converts the media of the message into stored attachments
+// For photos only the largest size is kept
+func (m *TelegramMessage) GetAttachments() []TelegramAttachment {
+	var attachments []TelegramAttachment
+
+	if len(m.Photo) > 0 {
+		largest := m.Photo[len(m.Photo)-1]
+		attachments = append(attachments, TelegramAttachment{
+			Type: "image",
+			Payload: TelegramAttachmentPayload{
+				FileID:       largest.FileID,
+				FileUniqueID: largest.FileUniqueID,
+				FileSize:     largest.FileSize,
+			},
+		})
+	}
+	if m.Document != nil {
+		attachments = append(attachments, TelegramAttachment{
+			Type: "file",
+			Payload: TelegramAttachmentPayload{
+				FileID:       m.Document.FileID,
+				FileUniqueID: m.Document.FileUniqueID,
+				FileName:     m.Document.FileName,
+				MimeType:     m.Document.MimeType,
+				FileSize:     m.Document.FileSize,
+			},
+		})
+	}
+	if m.Voice != nil {
+		attachments = append(attachments, TelegramAttachment{
+			Type: "voice",
+			Payload: TelegramAttachmentPayload{
+				FileID:       m.Voice.FileID,
+				FileUniqueID: m.Voice.FileUniqueID,
+				MimeType:     m.Voice.MimeType,
+				FileSize:     m.Voice.FileSize,
+				Duration:     m.Voice.Duration,
+			},
+		})
+	}
+	if m.Sticker != nil {
+		attachments = append(attachments, TelegramAttachment{
+			Type: "sticker",
+			Payload: TelegramAttachmentPayload{
+				FileID:       m.Sticker.FileID,
+				FileUniqueID: m.Sticker.FileUniqueID,
+				Emoji:        m.Sticker.Emoji,
+			},
+		})
+	}
+
+	return attachments
+}
+
+// SenderName builds a display name for conversations.customer_name
+func (m *TelegramMessage) SenderName() string {
+	if m.From == nil {
+		return ""
+	}
+	name := strings.TrimSpace(m.From.FirstName + " " + m.From.LastName)
+	if name == "" && m.From.Username != "" {
+		name = "@" + m.From.Username
+	}
+	return name
+}
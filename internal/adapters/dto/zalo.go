@@ -0,0 +1,107 @@
+package dto
+
+import "strings"
+
+// ZaloWebhookEvent is the webhook payload from a Zalo Official Account
+// Zalo sends exactly one event per request (no batching like Facebook)
+// Ref: https://developers.zalo.me/docs/official-account/webhook/tin-nhan/su-kien-nguoi-dung-gui-tin-nhan
+type ZaloWebhookEvent struct {
+	AppID       string       `json:"app_id"`
+	EventName   string       `json:"event_name"` // "user_send_text", "user_send_image", ...
+	Sender      ZaloUser     `json:"sender"`     // Customer (user_send_*) or OA (oa_send_*)
+	Recipient   ZaloUser     `json:"recipient"`  // OA (user_send_*) or customer (oa_send_*)
+	UserIDByApp string       `json:"user_id_by_app,omitempty"`
+	Message     *ZaloMessage `json:"message,omitempty"`
+	Timestamp   string       `json:"timestamp"` // Unix milliseconds, sent as a string
+}
+
+// ZaloUser represents a sender or recipient (Zalo user ID or OA ID)
+type ZaloUser struct {
+	ID string `json:"id"`
+}
+
+// ZaloMessage represents the message content of a user_send_* event
+type ZaloMessage struct {
+	MsgID       string           `json:"msg_id"`            // Used for deduplication
+	MsgIDs      []string         `json:"msg_ids,omitempty"` // user_received_message / user_seen_message
+	Text        string           `json:"text,omitempty"`
+	Attachments []ZaloAttachment `json:"attachments,omitempty"`
+}
+
+// ZaloAttachment represents media attachments
+type ZaloAttachment struct {
+	Type    string                `json:"type"` // "image", "file", "sticker", "audio", "gif", "video"
+	Payload ZaloAttachmentPayload `json:"payload"`
+}
+
+// ZaloAttachmentPayload contains attachment URL and metadata
+type ZaloAttachmentPayload struct {
+	URL       string `json:"url,omitempty"`
+	Thumbnail string `json:"thumbnail,omitempty"` // image
+	ID        string `json:"id,omitempty"`        // sticker
+	Name      string `json:"name,omitempty"`      // file
+	Size      string `json:"size,omitempty"`      // file (bytes, as string)
+	Checksum  string `json:"checksum,omitempty"`  // file
+	Type      string `json:"type,omitempty"`      // file extension, e.g. "pdf"
+}
+
+// Zalo event names we store as customer messages
+const (
+	ZaloEventUserSendText    = "user_send_text"
+	ZaloEventUserSendImage   = "user_send_image"
+	ZaloEventUserSendFile    = "user_send_file"
+	ZaloEventUserSendSticker = "user_send_sticker"
+
+	// Receipts for OA messages (msg_ids of the acknowledged messages)
+	ZaloEventUserReceivedMessage = "user_received_message"
+	ZaloEventUserSeenMessage     = "user_seen_message"
+)
+
+// IsUserMessage reports whether the event is a supported customer message
+// Other events (follow, oa_send_*, user_seen_message, ...) are ignored
+func (e *ZaloWebhookEvent) IsUserMessage() bool {
+	if e.Message == nil {
+		return false
+	}
+
+	switch e.EventName {
+	case ZaloEventUserSendText, ZaloEventUserSendImage, ZaloEventUserSendFile, ZaloEventUserSendSticker:
+		return true
+	}
+	return false
+}
+
+// IsEcho reports whether the event is a message sent by the OA itself
+func (e *ZaloWebhookEvent) IsEcho() bool {
+	return strings.HasPrefix(e.EventName, "oa_send_")
+}
+
+// GetMessageType maps the event name to messages.type
+// Works for both user_send_* and oa_send_* (echo) events
+func (e *ZaloWebhookEvent) GetMessageType() string {
+	kind := strings.TrimPrefix(strings.TrimPrefix(e.EventName, "oa_send_"), "user_send_")
+	switch kind {
+	case "image", "file", "sticker":
+		return kind
+	default:
+		return "text"
+	}
+}
+
+// GetContent extracts the message content (text or attachment URL)
+// Image messages may carry a caption in Text; the caption wins like on Facebook
+func (e *ZaloWebhookEvent) GetContent() string {
+	if e.Message == nil {
+		return ""
+	}
+
+	if e.Message.Text != "" {
+		return e.Message.Text
+	}
+
+	if len(e.Message.Attachments) > 0 {
+		return e.Message.Attachments[0].Payload.URL
+	}
+
+	return ""
+}
@@ -0,0 +1,376 @@
+// Package gateway implements external API adapters
+package gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/adapters/dto"
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// Ensure FacebookAdapter implements PlatformAdapter, AttachmentSender and StructuredSender
+var (
+	_ ports.PlatformAdapter  = (*FacebookAdapter)(nil)
+	_ ports.AttachmentSender = (*FacebookAdapter)(nil)
+	_ ports.StructuredSender = (*FacebookAdapter)(nil)
+)
+
+// ErrInvalidSignature is returned by VerifySignature implementations
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ErrUnsupportedAttachment is returned when a channel cannot send a media file the way it is stored
+// (e.g. Instagram needs a public URL, see MEDIA_PUBLIC_URL)
+var ErrUnsupportedAttachment = fmt.Errorf("attachment cannot be sent on this channel: %w", ports.ErrMessageRejected)
+
+// FacebookAdapter plugs Facebook Messenger into the platform registry
+// Inbound: dto.FacebookWebhookRequest -> domain.InboundEvent
+// Outbound: FacebookClient (Graph Send API)
+type FacebookAdapter struct {
+	client    *FacebookClient
+	appSecret string // For X-Hub-Signature-256 validation
+}
+
+// NewFacebookAdapter creates the Facebook platform adapter
+func NewFacebookAdapter(client *FacebookClient, appSecret string) *FacebookAdapter {
+	return &FacebookAdapter{
+		client:    client,
+		appSecret: appSecret,
+	}
+}
+
+// Platform returns the registry key (matches pages.platform)
+func (a *FacebookAdapter) Platform() string {
+	return domain.PlatformFacebook
+}
+
+// VerifySignature validates the HMAC SHA256 signature from Facebook
+// Per .rulesgemini: Security requirement - reject invalid signatures
+// Ref: https://developers.facebook.com/docs/messenger-platform/webhooks#security
+func (a *FacebookAdapter) VerifySignature(header http.Header, body []byte) error {
+	signatureHeader := header.Get("X-Hub-Signature-256")
+	if signatureHeader == "" {
+		return fmt.Errorf("%w: missing X-Hub-Signature-256 header", ErrInvalidSignature)
+	}
+
+	// Facebook sends signature in format: "sha256=<hex_signature>"
+	const prefix = "sha256="
+	if !strings.HasPrefix(signatureHeader, prefix) {
+		return fmt.Errorf("%w: missing sha256= prefix", ErrInvalidSignature)
+	}
+	expectedSignature := strings.TrimPrefix(signatureHeader, prefix)
+
+	// Compute HMAC SHA256
+	mac := hmac.New(sha256.New, []byte(a.appSecret))
+	mac.Write(body)
+	computedSignature := hex.EncodeToString(mac.Sum(nil))
+
+	// Compare signatures (constant-time comparison to prevent timing attacks)
+	if !hmac.Equal([]byte(computedSignature), []byte(expectedSignature)) {
+		return fmt.Errorf("%w: HMAC mismatch", ErrInvalidSignature)
+	}
+
+	return nil
+}
+
+// ParseEvents converts a Messenger webhook into normalized events
+// Facebook can send multiple entries, each with multiple messaging events
+func (a *FacebookAdapter) ParseEvents(payload []byte) ([]domain.InboundEvent, error) {
+	var fbPayload dto.FacebookWebhookRequest
+	if err := json.Unmarshal(payload, &fbPayload); err != nil {
+		return nil, fmt.Errorf("parse facebook webhook: %w", err)
+	}
+
+	// Instagram Direct shares the Messenger format; events are stored as their own platform
+	platform := domain.PlatformFacebook
+	if fbPayload.IsInstagram() {
+		platform = domain.PlatformInstagram
+	}
+
+	var events []domain.InboundEvent
+	for _, entry := range fbPayload.Entry {
+		for i := range entry.Messaging {
+			events = append(events, a.toEvent(platform, &entry.Messaging[i]))
+		}
+	}
+
+	return events, nil
+}
+
+// toEvent maps a single messaging event
+func (a *FacebookAdapter) toEvent(platform string, messaging *dto.FacebookMessaging) domain.InboundEvent {
+	event := domain.InboundEvent{
+		Platform:  platform,
+		PageID:    messaging.Recipient.ID, // Facebook Page ID / IG business account ID
+		SenderID:  messaging.Sender.ID,    // Facebook PSID / Instagram IGSID
+		Timestamp: time.UnixMilli(messaging.Timestamp),
+	}
+
+	switch {
+	case messaging.Delivery != nil:
+		event.Type = domain.EventTypeDelivery
+		event.MessageIDs = messaging.Delivery.MIDs
+		if messaging.Delivery.Watermark > 0 {
+			event.Watermark = time.UnixMilli(messaging.Delivery.Watermark)
+		}
+	case messaging.Read != nil:
+		event.Type = domain.EventTypeRead
+		event.Watermark = time.UnixMilli(messaging.Read.Watermark)
+		if messaging.Read.Watermark == 0 {
+			// Instagram sends the seen mid instead: everything before the event was read
+			event.Watermark = event.Timestamp
+		}
+		if messaging.Read.MID != "" {
+			event.MessageIDs = []string{messaging.Read.MID}
+		}
+	case messaging.MessageEdit != nil:
+		event.Type = domain.EventTypeEdit
+		event.ExternalMsgID = messaging.MessageEdit.MID
+		event.Content = messaging.MessageEdit.Text
+	case messaging.Reaction != nil:
+		event.Type = domain.EventTypeReaction
+		event.ExternalMsgID = messaging.Reaction.MID
+		event.Reaction = &domain.Reaction{
+			Action:   messaging.Reaction.Action,
+			Reaction: messaging.Reaction.Reaction,
+			Emoji:    messaging.Reaction.Emoji,
+		}
+	case messaging.Message != nil && messaging.Message.IsDeleted:
+		// Unsend: only the mid of the removed message is sent
+		event.Type = domain.EventTypeUnsend
+		event.ExternalMsgID = messaging.GetMessageID()
+	case messaging.Message != nil && messaging.Message.IsEcho:
+		// Echo: the page is the sender, the customer is the recipient
+		event.Type = domain.EventTypeEcho
+		event.PageID = messaging.Sender.ID
+		event.SenderID = messaging.Recipient.ID
+		event.ExternalMsgID = messaging.GetMessageID()
+		event.MessageType = messaging.GetMessageType()
+		event.Content = messaging.GetContent()
+		event.Attachments = json.RawMessage("[]")
+		if len(messaging.Message.Attachments) > 0 {
+			event.Attachments, _ = json.Marshal(messaging.Message.Attachments)
+		}
+	case messaging.Postback != nil:
+		// Button click: stored as a "postback" message so bot flows can read the payload
+		event.Type = domain.EventTypePostback
+		event.ExternalMsgID = messaging.Postback.MID
+		if event.ExternalMsgID == "" {
+			// Older API versions send no mid: sender + timestamp is unique per click
+			event.ExternalMsgID = fmt.Sprintf("postback:%s:%d", messaging.Sender.ID, messaging.Timestamp)
+		}
+		event.MessageType = domain.MessageTypePostback
+		event.Content = messaging.Postback.Title
+		event.Payload = messaging.Postback.Payload
+		event.Attachments = json.RawMessage("[]")
+		event.Referral = toReferral(messaging.Postback.Referral)
+	case messaging.Optin != nil:
+		if messaging.Sender.ID == "" {
+			// Checkbox plugin: only a user_ref, no PSID / conversation yet
+			slog.Debug("Ignoring opt-in without PSID", "user_ref", messaging.Optin.UserRef)
+			break
+		}
+		event.Type = domain.EventTypeOptin
+		event.ExternalMsgID = fmt.Sprintf("optin:%s:%d", messaging.Sender.ID, messaging.Timestamp)
+		event.MessageType = domain.MessageTypeOptin
+		event.Content = messaging.Optin.Title
+		if event.Content == "" {
+			event.Content = messaging.Optin.Ref
+		}
+		event.Payload = messaging.Optin.Payload
+		if event.Payload == "" {
+			event.Payload = messaging.Optin.Ref
+		}
+		// Keep the whole opt-in (incl. notification_messages_token) for later sends
+		optinJSON, _ := json.Marshal(messaging.Optin)
+		event.Attachments = json.RawMessage("[" + string(optinJSON) + "]")
+	case messaging.Referral != nil:
+		// Existing thread re-opened from an m.me link / ad: no message, only attribution
+		event.Type = domain.EventTypeReferral
+		event.Referral = toReferral(messaging.Referral)
+	case messaging.IsUserMessage():
+		event.Type = domain.EventTypeMessage
+		event.ExternalMsgID = messaging.GetMessageID()
+		event.MessageType = messaging.GetMessageType()
+		event.Content = messaging.GetContent()
+		event.Referral = toReferral(messaging.Message.Referral)
+		if messaging.Message.QuickReply != nil {
+			event.Payload = messaging.Message.QuickReply.Payload
+		}
+
+		// Create empty JSON array for attachments
+		attachments := messaging.Message.Attachments
+		if replyTo := messaging.Message.ReplyTo; replyTo != nil && replyTo.Story != nil {
+			// Keep the replied-to story next to the text (story URLs expire after 24h)
+			attachments = append(attachments, dto.FacebookAttachment{
+				Type: domain.AttachmentTypeStory,
+				Payload: dto.FacebookAttachmentPayload{
+					URL: replyTo.Story.URL,
+					ID:  replyTo.Story.ID,
+				},
+			})
+		}
+		event.Attachments = json.RawMessage("[]")
+		if len(attachments) > 0 {
+			event.Attachments, _ = json.Marshal(attachments)
+		}
+	}
+
+	return event
+}
+
+// toReferral maps a Messenger referral (nil-safe)
+func toReferral(ref *dto.FacebookReferral) *domain.Referral {
+	if ref == nil {
+		return nil
+	}
+	return &domain.Referral{
+		Source: ref.Source,
+		Type:   ref.Type,
+		Ref:    ref.Ref,
+		AdID:   ref.AdID,
+	}
+}
+
+// SendText sends a text reply via the Send API and returns the message ID (mid)
+func (a *FacebookAdapter) SendText(ctx context.Context, target domain.OutboundTarget, text string) (string, error) {
+	messageID, err := a.client.SendReply(ctx, target.RecipientID, target.AccessToken, text, target.MessageTag)
+	if err != nil {
+		return "", err
+	}
+
+	slog.Debug("Facebook reply sent",
+		"page_id", target.PageID,
+		"message_id", messageID,
+	)
+
+	return messageID, nil
+}
+
+// SendAttachment sends a media message via the Send API
+// Returns the mid and the reusable attachment ID for the page (when Facebook issued one)
+func (a *FacebookAdapter) SendAttachment(ctx context.Context, target domain.OutboundTarget, attachment domain.OutboundAttachment) (string, string, error) {
+	messageID, attachmentID, err := a.client.SendAttachment(ctx, target.RecipientID, target.AccessToken, attachment, target.MessageTag)
+	if err != nil {
+		return "", "", err
+	}
+
+	slog.Debug("Facebook attachment sent",
+		"page_id", target.PageID,
+		"message_id", messageID,
+		"type", attachment.Type,
+	)
+
+	return messageID, attachmentID, nil
+}
+
+// SendStructured sends quick replies or a button / generic / media template via the Send API
+func (a *FacebookAdapter) SendStructured(ctx context.Context, target domain.OutboundTarget, message domain.StructuredMessage) (string, error) {
+	var (
+		messageID string
+		err       error
+	)
+	switch message.Type {
+	case domain.StructuredTypeQuickReplies:
+		messageID, err = a.client.SendQuickReplies(ctx, target.RecipientID, target.AccessToken, message.Text, message.QuickReplies, target.MessageTag)
+	case domain.StructuredTypeButton:
+		messageID, err = a.client.SendButtonTemplate(ctx, target.RecipientID, target.AccessToken, message.Text, message.Buttons, message.QuickReplies, target.MessageTag)
+	case domain.StructuredTypeGeneric:
+		messageID, err = a.client.SendGenericTemplate(ctx, target.RecipientID, target.AccessToken, message.Elements, message.QuickReplies, target.MessageTag)
+	case domain.StructuredTypeMedia:
+		if message.Media == nil {
+			return "", fmt.Errorf("media template without media")
+		}
+		messageID, err = a.client.SendMediaTemplate(ctx, target.RecipientID, target.AccessToken, *message.Media, message.QuickReplies, target.MessageTag)
+	default:
+		return "", fmt.Errorf("unknown structured message type %q", message.Type)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	slog.Debug("Facebook structured message sent",
+		"page_id", target.PageID,
+		"message_id", messageID,
+		"type", message.Type,
+	)
+
+	return messageID, nil
+}
+
+// ============================================================================
+// Instagram Direct (Messenger API for Instagram)
+// ============================================================================
+
+// Ensure InstagramAdapter implements PlatformAdapter and AttachmentSender
+var (
+	_ ports.PlatformAdapter  = (*InstagramAdapter)(nil)
+	_ ports.AttachmentSender = (*InstagramAdapter)(nil)
+)
+
+// InstagramAdapter handles Instagram business accounts connected to a Facebook page
+// Webhooks are signed with the same app secret and use the Messenger format
+// (object "instagram"), so verification and parsing are shared with FacebookAdapter
+//
+// IG accounts are registered in pages with platform = 'instagram',
+// page_id = IG business account ID and access_token = linked page token
+type InstagramAdapter struct {
+	*FacebookAdapter
+}
+
+// NewInstagramAdapter creates the Instagram platform adapter
+func NewInstagramAdapter(client *FacebookClient, appSecret string) *InstagramAdapter {
+	return &InstagramAdapter{
+		FacebookAdapter: NewFacebookAdapter(client, appSecret),
+	}
+}
+
+// Platform returns the registry key (matches pages.platform)
+func (a *InstagramAdapter) Platform() string {
+	return domain.PlatformInstagram
+}
+
+// SendText sends a text reply via the IG messaging endpoint and returns the mid
+func (a *InstagramAdapter) SendText(ctx context.Context, target domain.OutboundTarget, text string) (string, error) {
+	messageID, err := a.client.SendInstagramReply(ctx, target.PageID, target.RecipientID, target.AccessToken, text, target.MessageTag)
+	if err != nil {
+		return "", err
+	}
+
+	slog.Debug("Instagram reply sent",
+		"ig_account_id", target.PageID,
+		"message_id", messageID,
+	)
+
+	return messageID, nil
+}
+
+// SendAttachment sends a media message by URL (Instagram accepts no uploads)
+// Returns ErrUnsupportedAttachment when the media has no public URL
+func (a *InstagramAdapter) SendAttachment(ctx context.Context, target domain.OutboundTarget, attachment domain.OutboundAttachment) (string, string, error) {
+	if attachment.URL == "" {
+		return "", "", ErrUnsupportedAttachment
+	}
+	messageID, err := a.client.SendInstagramAttachment(ctx, target.PageID, target.RecipientID, target.AccessToken, attachment, target.MessageTag)
+	if err != nil {
+		return "", "", err
+	}
+
+	slog.Debug("Instagram attachment sent",
+		"ig_account_id", target.PageID,
+		"message_id", messageID,
+		"type", attachment.Type,
+	)
+
+	return messageID, "", nil
+}
@@ -0,0 +1,222 @@
+package gateway
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+// parseMessaging parses a webhook of the given object with one messaging event
+func parseMessaging(t *testing.T, object, messaging string) []domain.InboundEvent {
+	t.Helper()
+	payload := `{"object": "` + object + `", "entry": [{"id": "PAGE", "time": 1716200000000, "messaging": [` + messaging + `]}]}`
+	events, err := NewFacebookAdapter(nil, "app-secret").ParseEvents([]byte(payload))
+	if err != nil {
+		t.Fatalf("parse %s: %v", messaging, err)
+	}
+	return events
+}
+
+// parseOne parses a Messenger webhook that must yield exactly one event
+func parseOne(t *testing.T, messaging string) domain.InboundEvent {
+	t.Helper()
+	events := parseMessaging(t, "page", messaging)
+	if len(events) != 1 {
+		t.Fatalf("parse %s: %d events", messaging, len(events))
+	}
+	return events[0]
+}
+
+func TestFacebookParseMessages(t *testing.T) {
+	at := time.UnixMilli(1716200000123)
+
+	for name, tc := range map[string]struct {
+		messaging   string
+		messageType string
+		content     string
+	}{
+		"text": {`{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+			"message": {"mid": "m.1", "text": "Áo này còn size M không?"}}`, "text", "Áo này còn size M không?"},
+		"image": {`{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+			"message": {"mid": "m.1", "attachments": [{"type": "image", "payload": {"url": "https://cdn.fb/1.jpg"}}]}}`, "image", "https://cdn.fb/1.jpg"},
+	} {
+		event := parseOne(t, tc.messaging)
+		if event.Type != domain.EventTypeMessage || event.Platform != domain.PlatformFacebook {
+			t.Errorf("%s: type %q, platform %q", name, event.Type, event.Platform)
+		}
+		if event.PageID != "PAGE" || event.SenderID != "PSID" || event.ExternalMsgID != "m.1" || !event.Timestamp.Equal(at) {
+			t.Errorf("%s: event = %+v", name, event)
+		}
+		if event.MessageType != tc.messageType || event.Content != tc.content {
+			t.Errorf("%s: message type %q, content %q", name, event.MessageType, event.Content)
+		}
+	}
+
+	// Several entries and messaging events in one delivery
+	payload := `{"object": "page", "entry": [
+		{"id": "P1", "messaging": [{"sender": {"id": "A"}, "recipient": {"id": "P1"}, "message": {"mid": "m.a", "text": "1"}},
+			{"sender": {"id": "B"}, "recipient": {"id": "P1"}, "message": {"mid": "m.b", "text": "2"}}]},
+		{"id": "P2", "messaging": [{"sender": {"id": "C"}, "recipient": {"id": "P2"}, "message": {"mid": "m.c", "text": "3"}}]}]}`
+	events, err := NewFacebookAdapter(nil, "app-secret").ParseEvents([]byte(payload))
+	if err != nil || len(events) != 3 || events[2].PageID != "P2" || events[2].ExternalMsgID != "m.c" {
+		t.Errorf("batched events = %+v, err = %v", events, err)
+	}
+
+	if _, err := NewFacebookAdapter(nil, "app-secret").ParseEvents([]byte(`{"object": `)); err == nil {
+		t.Error("malformed payload parsed")
+	}
+}
+
+func TestInstagramParseEvents(t *testing.T) {
+	events := parseMessaging(t, "instagram", `{"sender": {"id": "IGSID"}, "recipient": {"id": "IGACCOUNT"}, "timestamp": 1716200000123,
+		"message": {"mid": "ig.1", "text": "đẹp quá", "reply_to": {"story": {"id": "st.1", "url": "https://lookaside.fbsbx.com/story.mp4"}}}}`)
+	if len(events) != 1 {
+		t.Fatalf("%d events", len(events))
+	}
+	event := events[0]
+	if event.Platform != domain.PlatformInstagram || event.PageID != "IGACCOUNT" || event.SenderID != "IGSID" {
+		t.Errorf("event = %+v", event)
+	}
+	if event.Type != domain.EventTypeMessage || event.MessageType != "story_reply" || event.Content != "đẹp quá" {
+		t.Errorf("story reply: type %q, message type %q, content %q", event.Type, event.MessageType, event.Content)
+	}
+
+	// The story is kept next to the text: its URL expires after 24h
+	var attachments []struct {
+		Type    string `json:"type"`
+		Payload struct {
+			URL string `json:"url"`
+			ID  string `json:"id"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(event.Attachments, &attachments); err != nil || len(attachments) != 1 ||
+		attachments[0].Type != "story" || attachments[0].Payload.ID != "st.1" {
+		t.Errorf("attachments = %s", event.Attachments)
+	}
+
+	mention := parseMessaging(t, "instagram", `{"sender": {"id": "IGSID"}, "recipient": {"id": "IGACCOUNT"}, "timestamp": 1716200000123,
+		"message": {"mid": "ig.2", "attachments": [{"type": "story_mention", "payload": {"url": "https://lookaside.fbsbx.com/m.jpg"}}]}}`)[0]
+	if mention.MessageType != "story_mention" || mention.Content != "https://lookaside.fbsbx.com/m.jpg" {
+		t.Errorf("story mention: message type %q, content %q", mention.MessageType, mention.Content)
+	}
+
+	// Messenger pages keep their platform
+	if event := parseOne(t, `{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "message": {"mid": "m.1", "text": "hi"}}`); event.Platform != domain.PlatformFacebook {
+		t.Errorf("page object parsed as %q", event.Platform)
+	}
+}
+
+func TestFacebookParseCustomerActions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		messaging string
+		want      domain.InboundEvent
+	}{
+		"postback": {`{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+			"postback": {"mid": "m.pb", "title": "Mua ngay", "payload": "BUY_SKU_1"}}`,
+			domain.InboundEvent{Type: domain.EventTypePostback, ExternalMsgID: "m.pb", MessageType: domain.MessageTypePostback,
+				Content: "Mua ngay", Payload: "BUY_SKU_1"}},
+		"postback without mid": {`{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+			"postback": {"title": "Bắt đầu", "payload": "GET_STARTED", "referral": {"source": "SHORTLINK", "type": "OPEN_THREAD", "ref": "summer"}}}`,
+			domain.InboundEvent{Type: domain.EventTypePostback, ExternalMsgID: "postback:PSID:1716200000123", MessageType: domain.MessageTypePostback,
+				Content: "Bắt đầu", Payload: "GET_STARTED", Referral: &domain.Referral{Source: "SHORTLINK", Type: "OPEN_THREAD", Ref: "summer"}}},
+		"quick reply": {`{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+			"message": {"mid": "m.qr", "text": "Size M", "quick_reply": {"payload": "SIZE_M"}}}`,
+			domain.InboundEvent{Type: domain.EventTypeMessage, ExternalMsgID: "m.qr", MessageType: "text", Content: "Size M", Payload: "SIZE_M"}},
+		"message from an ad": {`{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+			"message": {"mid": "m.ad", "text": "Tư vấn", "referral": {"source": "ADS", "type": "OPEN_THREAD", "ad_id": "ad.9"}}}`,
+			domain.InboundEvent{Type: domain.EventTypeMessage, ExternalMsgID: "m.ad", MessageType: "text", Content: "Tư vấn",
+				Referral: &domain.Referral{Source: "ADS", Type: "OPEN_THREAD", AdID: "ad.9"}}},
+		"referral": {`{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+			"referral": {"source": "SHORTLINK", "type": "OPEN_THREAD", "ref": "flash-sale"}}`,
+			domain.InboundEvent{Type: domain.EventTypeReferral, Referral: &domain.Referral{Source: "SHORTLINK", Type: "OPEN_THREAD", Ref: "flash-sale"}}},
+		"opt-in": {`{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+			"optin": {"type": "notification_messages", "title": "Báo giá", "payload": "PRICE_ALERT", "notification_messages_token": "tok"}}`,
+			domain.InboundEvent{Type: domain.EventTypeOptin, ExternalMsgID: "optin:PSID:1716200000123", MessageType: domain.MessageTypeOptin,
+				Content: "Báo giá", Payload: "PRICE_ALERT"}},
+		"opt-in ref only": {`{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+			"optin": {"ref": "checkout"}}`,
+			domain.InboundEvent{Type: domain.EventTypeOptin, ExternalMsgID: "optin:PSID:1716200000123", MessageType: domain.MessageTypeOptin,
+				Content: "checkout", Payload: "checkout"}},
+		"checkbox opt-in without PSID": {`{"sender": {}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+			"optin": {"ref": "checkout", "user_ref": "u-ref"}}`,
+			domain.InboundEvent{}},
+	} {
+		event := parseOne(t, tc.messaging)
+		got := domain.InboundEvent{Type: event.Type, ExternalMsgID: event.ExternalMsgID, MessageType: event.MessageType,
+			Content: event.Content, Payload: event.Payload, Referral: event.Referral}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: event = %+v, want %+v", name, got, tc.want)
+		}
+	}
+}
+
+func TestFacebookParseReceipts(t *testing.T) {
+	delivery := parseOne(t, `{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200009000,
+		"delivery": {"mids": ["m.1", "m.2"], "watermark": 1716200005000}}`)
+	if delivery.Type != domain.EventTypeDelivery || !reflect.DeepEqual(delivery.MessageIDs, []string{"m.1", "m.2"}) ||
+		!delivery.Watermark.Equal(time.UnixMilli(1716200005000)) {
+		t.Errorf("delivery = %+v", delivery)
+	}
+
+	read := parseOne(t, `{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200009000,
+		"read": {"watermark": 1716200007000}}`)
+	if read.Type != domain.EventTypeRead || len(read.MessageIDs) != 0 || !read.Watermark.Equal(time.UnixMilli(1716200007000)) {
+		t.Errorf("read = %+v", read)
+	}
+
+	// Instagram sends the seen mid instead of a watermark
+	seen := parseMessaging(t, "instagram", `{"sender": {"id": "IGSID"}, "recipient": {"id": "IGACCOUNT"}, "timestamp": 1716200009000,
+		"read": {"mid": "ig.7"}}`)[0]
+	if seen.Type != domain.EventTypeRead || !reflect.DeepEqual(seen.MessageIDs, []string{"ig.7"}) ||
+		!seen.Watermark.Equal(time.UnixMilli(1716200009000)) {
+		t.Errorf("instagram read = %+v", seen)
+	}
+}
+
+func TestFacebookParseEchoes(t *testing.T) {
+	// Echoes of our own Send API replies carry our app_id, replies typed in the
+	// Page inbox carry Meta's: both are parsed alike, the dispatcher dedups by mid
+	for name, messaging := range map[string]string{
+		"with app_id":    `{"sender": {"id": "PAGE"}, "recipient": {"id": "PSID"}, "timestamp": 1716200000123, "message": {"mid": "m.echo", "is_echo": true, "app_id": 263902037430900, "text": "Dạ còn ạ"}}`,
+		"without app_id": `{"sender": {"id": "PAGE"}, "recipient": {"id": "PSID"}, "timestamp": 1716200000123, "message": {"mid": "m.echo", "is_echo": true, "text": "Dạ còn ạ"}}`,
+	} {
+		event := parseOne(t, messaging)
+		if event.Type != domain.EventTypeEcho || event.ExternalMsgID != "m.echo" || event.Content != "Dạ còn ạ" {
+			t.Errorf("%s: event = %+v", name, event)
+		}
+		// The page is the sender: the conversation is still keyed by the customer
+		if event.PageID != "PAGE" || event.SenderID != "PSID" {
+			t.Errorf("%s: page %q, sender %q", name, event.PageID, event.SenderID)
+		}
+	}
+}
+
+func TestFacebookParseMessageChanges(t *testing.T) {
+	edit := parseOne(t, `{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+		"message_edit": {"mid": "m.1", "text": "Size L nhé", "num_edit": 1}}`)
+	if edit.Type != domain.EventTypeEdit || edit.ExternalMsgID != "m.1" || edit.Content != "Size L nhé" {
+		t.Errorf("edit = %+v", edit)
+	}
+
+	unsend := parseOne(t, `{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123,
+		"message": {"mid": "m.1", "is_deleted": true}}`)
+	if unsend.Type != domain.EventTypeUnsend || unsend.ExternalMsgID != "m.1" {
+		t.Errorf("unsend = %+v", unsend)
+	}
+
+	for name, tc := range map[string]struct {
+		messaging string
+		want      domain.Reaction
+	}{
+		"react":   {`{"reaction": {"mid": "m.1", "action": "react", "reaction": "love", "emoji": "❤"}}`, domain.Reaction{Action: "react", Reaction: "love", Emoji: "❤"}},
+		"unreact": {`{"reaction": {"mid": "m.1", "action": "unreact"}}`, domain.Reaction{Action: "unreact"}},
+	} {
+		event := parseOne(t, `{"sender": {"id": "PSID"}, "recipient": {"id": "PAGE"}, "timestamp": 1716200000123, `+tc.messaging[1:])
+		if event.Type != domain.EventTypeReaction || event.ExternalMsgID != "m.1" || event.Reaction == nil || *event.Reaction != tc.want {
+			t.Errorf("%s: event = %+v", name, event)
+		}
+	}
+}
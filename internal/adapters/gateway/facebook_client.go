@@ -4,32 +4,46 @@ package gateway
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
 )
 
-// Custom errors for specific Facebook API failures
+// Custom errors for specific platform API failures (shared by all gateway clients)
+// Aliases of the ports send errors, so the core can tell them apart without the gateway
 var (
-	// ErrTokenExpired indicates the page access token is expired or invalid (code 190)
+	// ErrTokenExpired indicates the page access token is expired or invalid
+	// (Facebook code 190, Zalo -216/-124)
 	// Handler should call DeactivatePage() when this error is received
-	ErrTokenExpired = errors.New("facebook access token expired or invalid")
+	ErrTokenExpired = ports.ErrTokenExpired
+	
+	// ErrRateLimited indicates the platform rate limit was exceeded
+	// (Facebook code 4, 17, 32, 613, Zalo -32)
+	ErrRateLimited = ports.ErrRateLimited
 	
-	// ErrRateLimited indicates Facebook rate limit exceeded (code 4, 17, 32, 613)
-	ErrRateLimited = errors.New("facebook rate limit exceeded")
+	// ErrPermissionDenied indicates missing permissions
+	// (Facebook code 10, 200, 299, Zalo -213/-230)
+	ErrPermissionDenied = ports.ErrPermissionDenied
 	
-	// ErrPermissionDenied indicates missing permissions (code 10, 200, 299)
-	ErrPermissionDenied = errors.New("facebook permission denied")
+	// ErrOutsideMessagingWindow indicates a standard reply after the 24-hour window
+	// or a message tag the page may not use (Facebook code 10 subcodes 2018278, 2534022)
+	ErrOutsideMessagingWindow = ports.ErrOutsideMessagingWindow
 )
 
 // FacebookClient handles communication with Facebook Graph API
 // Phase 3: Send messages back to customers
 type FacebookClient struct {
 	httpClient *http.Client
+	baseURL    string // Graph API host (overridable for local stubs)
 	apiVersion string
 }
 
@@ -39,6 +53,7 @@ func NewFacebookClient() *FacebookClient {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		baseURL:    "https://graph.facebook.com",
 		apiVersion: "v19.0", // Facebook Graph API version
 	}
 }
@@ -51,13 +66,99 @@ type SendMessageRequest struct {
 	Message struct {
 		Text string `json:"text"`
 	} `json:"message"`
-	MessagingType string `json:"messaging_type"` // "RESPONSE" for replies
+	MessagingType string `json:"messaging_type"` // "RESPONSE" for replies, "MESSAGE_TAG" outside the 24-hour window
+	Tag           string `json:"tag,omitempty"`  // With MESSAGE_TAG, e.g. "HUMAN_AGENT"
+}
+
+// SendAttachmentRequest represents a Send API attachment message (by attachment ID or URL)
+type SendAttachmentRequest struct {
+	Recipient struct {
+		ID string `json:"id"` // PSID / IGSID
+	} `json:"recipient"`
+	Message struct {
+		Attachment SendAttachment `json:"attachment"`
+	} `json:"message"`
+	MessagingType string `json:"messaging_type"` // "RESPONSE" for replies, "MESSAGE_TAG" outside the 24-hour window
+	Tag           string `json:"tag,omitempty"`  // With MESSAGE_TAG
+}
+
+// SendAttachment is the attachment of a Send API message
+type SendAttachment struct {
+	Type    string                `json:"type"` // "image", "video", "audio", "file"
+	Payload SendAttachmentPayload `json:"payload"`
+}
+
+// SendAttachmentPayload points at the media: a reusable attachment ID, a URL, or the uploaded file
+type SendAttachmentPayload struct {
+	AttachmentID string `json:"attachment_id,omitempty"`
+	URL          string `json:"url,omitempty"`
+	IsReusable   bool   `json:"is_reusable,omitempty"` // Ask for an attachment_id to send the media again
+}
+
+// SendStructuredRequest represents a Send API message with quick replies and/or a template
+type SendStructuredRequest struct {
+	Recipient struct {
+		ID string `json:"id"` // PSID
+	} `json:"recipient"`
+	Message       StructuredMessagePayload `json:"message"`
+	MessagingType string                   `json:"messaging_type"` // "RESPONSE" for replies, "MESSAGE_TAG" outside the 24-hour window
+	Tag           string                   `json:"tag,omitempty"`  // With MESSAGE_TAG
+}
+
+// StructuredMessagePayload is the message of a structured send: text or a template attachment
+type StructuredMessagePayload struct {
+	Text         string              `json:"text,omitempty"`
+	Attachment   *TemplateAttachment `json:"attachment,omitempty"`
+	QuickReplies []QuickReplyPayload `json:"quick_replies,omitempty"`
+}
+
+// TemplateAttachment wraps a template ({"type": "template", "payload": {...}})
+type TemplateAttachment struct {
+	Type    string          `json:"type"` // Always "template"
+	Payload TemplatePayload `json:"payload"`
+}
+
+// TemplatePayload is a button, generic or media template
+type TemplatePayload struct {
+	TemplateType string                   `json:"template_type"`      // "button", "generic", "media"
+	Text         string                   `json:"text,omitempty"`     // button
+	Buttons      []ButtonPayload          `json:"buttons,omitempty"`  // button
+	Elements     []TemplateElementPayload `json:"elements,omitempty"` // generic, media (one element)
+}
+
+// TemplateElementPayload is a generic template card or the media of a media template
+type TemplateElementPayload struct {
+	Title         string          `json:"title,omitempty"`
+	Subtitle      string          `json:"subtitle,omitempty"`
+	ImageURL      string          `json:"image_url,omitempty"`
+	DefaultAction *ButtonPayload  `json:"default_action,omitempty"` // web_url without title
+	MediaType     string          `json:"media_type,omitempty"`     // media: "image", "video"
+	URL           string          `json:"url,omitempty"`            // media
+	AttachmentID  string          `json:"attachment_id,omitempty"`  // media
+	Buttons       []ButtonPayload `json:"buttons,omitempty"`
+}
+
+// ButtonPayload is a template button
+type ButtonPayload struct {
+	Type    string `json:"type"` // "web_url", "postback", "phone_number"
+	Title   string `json:"title,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Payload string `json:"payload,omitempty"`
+}
+
+// QuickReplyPayload is a quick reply chip
+type QuickReplyPayload struct {
+	ContentType string `json:"content_type"` // "text", "user_phone_number", "user_email"
+	Title       string `json:"title,omitempty"`
+	Payload     string `json:"payload,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
 }
 
 // SendMessageResponse represents Facebook's response
 type SendMessageResponse struct {
-	RecipientID string `json:"recipient_id"`
-	MessageID   string `json:"message_id"`
+	RecipientID  string `json:"recipient_id"`
+	MessageID    string `json:"message_id"`
+	AttachmentID string `json:"attachment_id,omitempty"` // Reusable attachments only
 }
 
 // FacebookError represents an error from Facebook API
@@ -69,56 +170,164 @@ type FacebookError struct {
 	FBTraceID    string `json:"fbtrace_id"`
 }
 
-// SendReply sends a text message to a Facebook user with retry mechanism
+// SendReply sends a text message to a Facebook user (one attempt, the outbound queue retries)
 // recipientPSID: Page-Scoped User ID (from conversations.platform_id)
 // pageAccessToken: From database (pages.access_token)
 // text: The message content to send
+// messageTag: "" for a standard reply, else the message tag (outside the 24-hour window)
 // 
-// Returns specific errors:
+// Returns the Facebook message ID (mid) on success, or specific errors:
 // - ErrTokenExpired: Token invalid/expired (code 190) → Caller should deactivate page
-// - ErrRateLimited: Rate limit exceeded → Caller should retry later
+// - ErrRateLimited: Rate limit exceeded → Caller should retry later (slower)
 // - ErrPermissionDenied: Missing permissions
-func (c *FacebookClient) SendReply(recipientPSID, pageAccessToken, text string) error {
-	const maxRetries = 3
-	
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		err := c.sendReplyAttempt(recipientPSID, pageAccessToken, text, attempt)
-		
-		if err == nil {
-			return nil // Success
-		}
-		
-		// Don't retry on these specific errors
-		if errors.Is(err, ErrTokenExpired) ||
-			errors.Is(err, ErrPermissionDenied) ||
-			errors.Is(err, ErrRateLimited) {
-			return err
+// - ErrOutsideMessagingWindow: Window closed / tag refused → Caller should pick a tag
+func (c *FacebookClient) SendReply(ctx context.Context, recipientPSID, pageAccessToken, text, messageTag string) (string, error) {
+	return c.sendText(ctx, "me", recipientPSID, pageAccessToken, text, messageTag)
+}
+
+// SendInstagramReply sends a text message to an Instagram user (Messenger API for Instagram)
+// igAccountID: Instagram business account ID (pages.page_id for platform 'instagram')
+// recipientIGSID: Instagram-scoped user ID (from conversations.platform_id)
+// accessToken: Token of the Facebook page linked to the IG account
+//
+// Same error semantics as SendReply
+func (c *FacebookClient) SendInstagramReply(ctx context.Context, igAccountID, recipientIGSID, accessToken, text, messageTag string) (string, error) {
+	return c.sendText(ctx, igAccountID, recipientIGSID, accessToken, text, messageTag)
+}
+
+// sendText posts to /{node}/messages
+// node is "me" (page resolved from the token) or an IG business account ID
+func (c *FacebookClient) sendText(ctx context.Context, node, recipientID, accessToken, text, messageTag string) (string, error) {
+	resp, err := c.sendReplyAttempt(ctx, node, recipientID, accessToken, text, messageTag)
+	if err != nil {
+		return "", err
+	}
+	return resp.MessageID, nil
+}
+
+// SendAttachment sends a media message to a Facebook user
+// The media is referenced by its reusable attachment ID if known, else by URL, else the
+// file is uploaded (multipart); URL and upload sends ask for a reusable attachment ID
+//
+// Returns the message ID and the attachment ID issued by Facebook ("" if none)
+// Same error semantics as SendReply
+func (c *FacebookClient) SendAttachment(ctx context.Context, recipientPSID, pageAccessToken string, attachment domain.OutboundAttachment, messageTag string) (string, string, error) {
+	return c.sendAttachmentMessage(ctx, "me", recipientPSID, pageAccessToken, attachment, true, messageTag)
+}
+
+// SendInstagramAttachment sends a media message to an Instagram user, by URL only
+// (the Instagram messaging API has no multipart upload and no reusable attachments)
+func (c *FacebookClient) SendInstagramAttachment(ctx context.Context, igAccountID, recipientIGSID, accessToken string, attachment domain.OutboundAttachment, messageTag string) (string, error) {
+	attachment.AttachmentID, attachment.Open = "", nil
+	messageID, _, err := c.sendAttachmentMessage(ctx, igAccountID, recipientIGSID, accessToken, attachment, false, messageTag)
+	return messageID, err
+}
+
+// sendAttachmentMessage posts a media message to /{node}/messages
+func (c *FacebookClient) sendAttachmentMessage(ctx context.Context, node, recipientID, accessToken string, attachment domain.OutboundAttachment, reusable bool, messageTag string) (string, string, error) {
+	if attachment.AttachmentID == "" && attachment.URL == "" && attachment.Open == nil {
+		return "", "", fmt.Errorf("attachment has no attachment ID, URL or file")
+	}
+	resp, err := c.sendAttachmentAttempt(ctx, node, recipientID, accessToken, attachment, reusable, messageTag)
+	if err != nil {
+		return "", "", err
+	}
+	return resp.MessageID, resp.AttachmentID, nil
+}
+
+// SendQuickReplies sends text with quick reply chips to a Facebook user
+// Same error semantics as SendReply
+func (c *FacebookClient) SendQuickReplies(ctx context.Context, recipientPSID, pageAccessToken, text string, replies []domain.QuickReply, messageTag string) (string, error) {
+	return c.sendStructured(ctx, "me", recipientPSID, pageAccessToken, StructuredMessagePayload{
+		Text:         text,
+		QuickReplies: toQuickReplyPayloads(replies),
+	}, messageTag)
+}
+
+// SendButtonTemplate sends text with up to 3 buttons to a Facebook user
+// Same error semantics as SendReply
+func (c *FacebookClient) SendButtonTemplate(ctx context.Context, recipientPSID, pageAccessToken, text string, buttons []domain.MessageButton, replies []domain.QuickReply, messageTag string) (string, error) {
+	return c.sendStructured(ctx, "me", recipientPSID, pageAccessToken, StructuredMessagePayload{
+		Attachment: &TemplateAttachment{
+			Type: "template",
+			Payload: TemplatePayload{
+				TemplateType: "button",
+				Text:         text,
+				Buttons:      toButtonPayloads(buttons),
+			},
+		},
+		QuickReplies: toQuickReplyPayloads(replies),
+	}, messageTag)
+}
+
+// SendGenericTemplate sends a carousel of up to 10 cards to a Facebook user
+// Same error semantics as SendReply
+func (c *FacebookClient) SendGenericTemplate(ctx context.Context, recipientPSID, pageAccessToken string, elements []domain.TemplateElement, replies []domain.QuickReply, messageTag string) (string, error) {
+	payloads := make([]TemplateElementPayload, 0, len(elements))
+	for _, element := range elements {
+		payload := TemplateElementPayload{
+			Title:    element.Title,
+			Subtitle: element.Subtitle,
+			ImageURL: element.ImageURL,
+			Buttons:  toButtonPayloads(element.Buttons),
 		}
-		
-		// Retry on network errors with exponential backoff
-		if attempt < maxRetries {
-			backoff := time.Duration(attempt) * 500 * time.Millisecond
-			slog.Warn("Retrying Facebook API call",
-				"attempt", attempt,
-				"max_retries", maxRetries,
-				"backoff_ms", backoff.Milliseconds(),
-				"error", err,
-			)
-			time.Sleep(backoff)
+		if element.DefaultURL != "" {
+			payload.DefaultAction = &ButtonPayload{Type: domain.ButtonWebURL, URL: element.DefaultURL}
 		}
+		payloads = append(payloads, payload)
 	}
-	
-	return fmt.Errorf("failed after %d attempts", maxRetries)
+
+	return c.sendStructured(ctx, "me", recipientPSID, pageAccessToken, StructuredMessagePayload{
+		Attachment: &TemplateAttachment{
+			Type: "template",
+			Payload: TemplatePayload{
+				TemplateType: "generic",
+				Elements:     payloads,
+			},
+		},
+		QuickReplies: toQuickReplyPayloads(replies),
+	}, messageTag)
+}
+
+// SendMediaTemplate sends an image / video with a button to a Facebook user
+// The media is an attachment ID of the page or a facebook.com URL of a photo / video
+// Same error semantics as SendReply
+func (c *FacebookClient) SendMediaTemplate(ctx context.Context, recipientPSID, pageAccessToken string, media domain.MediaElement, replies []domain.QuickReply, messageTag string) (string, error) {
+	return c.sendStructured(ctx, "me", recipientPSID, pageAccessToken, StructuredMessagePayload{
+		Attachment: &TemplateAttachment{
+			Type: "template",
+			Payload: TemplatePayload{
+				TemplateType: "media",
+				Elements: []TemplateElementPayload{{
+					MediaType:    media.MediaType,
+					URL:          media.URL,
+					AttachmentID: media.AttachmentID,
+					Buttons:      toButtonPayloads(media.Buttons),
+				}},
+			},
+		},
+		QuickReplies: toQuickReplyPayloads(replies),
+	}, messageTag)
+}
+
+// sendStructured posts a quick reply / template message to /{node}/messages
+func (c *FacebookClient) sendStructured(ctx context.Context, node, recipientID, accessToken string, message StructuredMessagePayload, messageTag string) (string, error) {
+	resp, err := c.sendStructuredAttempt(ctx, node, recipientID, accessToken, message, messageTag)
+	if err != nil {
+		return "", err
+	}
+	return resp.MessageID, nil
 }
 
 // sendReplyAttempt performs a single attempt to send message
-func (c *FacebookClient) sendReplyAttempt(recipientPSID, pageAccessToken, text string, attempt int) error {
+func (c *FacebookClient) sendReplyAttempt(ctx context.Context, node, recipientPSID, pageAccessToken, text, messageTag string) (*SendMessageResponse, error) {
 	// Construct the API URL
-	url := fmt.Sprintf("https://graph.facebook.com/%s/me/messages", c.apiVersion)
+	url := fmt.Sprintf("%s/%s/%s/messages", c.baseURL, c.apiVersion, node)
 	
 	// Build request payload
 	payload := SendMessageRequest{
-		MessagingType: "RESPONSE",
+		MessagingType: messagingType(messageTag),
+		Tag:           messageTag,
 	}
 	payload.Recipient.ID = recipientPSID
 	payload.Message.Text = text
@@ -126,13 +335,13 @@ func (c *FacebookClient) sendReplyAttempt(recipientPSID, pageAccessToken, text s
 	// Marshal to JSON
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 	
 	// Create HTTP request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	
 	// Set headers
@@ -143,24 +352,200 @@ func (c *FacebookClient) sendReplyAttempt(recipientPSID, pageAccessToken, text s
 	slog.Info("Sending message to Facebook",
 		"recipient_psid", recipientPSID,
 		"text_length", len(text),
-		"attempt", attempt,
+		"message_tag", messageTag,
+	)
+	
+	return c.send(req, recipientPSID)
+}
+
+// sendStructuredAttempt performs a single attempt to send a quick reply / template message
+func (c *FacebookClient) sendStructuredAttempt(ctx context.Context, node, recipientID, accessToken string, message StructuredMessagePayload, messageTag string) (*SendMessageResponse, error) {
+	url := fmt.Sprintf("%s/%s/%s/messages", c.baseURL, c.apiVersion, node)
+	
+	payload := SendStructuredRequest{
+		Message:       message,
+		MessagingType: messagingType(messageTag),
+		Tag:           messageTag,
+	}
+	payload.Recipient.ID = recipientID
+	
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.URL.RawQuery = fmt.Sprintf("access_token=%s", accessToken)
+	
+	templateType := ""
+	if message.Attachment != nil {
+		templateType = message.Attachment.Payload.TemplateType
+	}
+	slog.Info("Sending structured message to Facebook",
+		"recipient_psid", recipientID,
+		"template_type", templateType,
+		"quick_replies", len(message.QuickReplies),
+		"message_tag", messageTag,
+	)
+	
+	return c.send(req, recipientID)
+}
+
+// sendAttachmentAttempt performs a single attempt to send a media message
+func (c *FacebookClient) sendAttachmentAttempt(ctx context.Context, node, recipientID, accessToken string, attachment domain.OutboundAttachment, reusable bool, messageTag string) (*SendMessageResponse, error) {
+	url := fmt.Sprintf("%s/%s/%s/messages", c.baseURL, c.apiVersion, node)
+	
+	var (
+		req *http.Request
+		err error
+	)
+	if attachment.AttachmentID != "" || attachment.URL != "" {
+		payload := SendAttachmentRequest{
+			MessagingType: messagingType(messageTag),
+			Tag:           messageTag,
+		}
+		payload.Recipient.ID = recipientID
+		payload.Message.Attachment = SendAttachment{
+			Type: attachment.Type,
+			Payload: SendAttachmentPayload{
+				AttachmentID: attachment.AttachmentID,
+			},
+		}
+		if attachment.AttachmentID == "" {
+			payload.Message.Attachment.Payload.URL = attachment.URL
+			payload.Message.Attachment.Payload.IsReusable = reusable
+		}
+		
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		if req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData)); err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		if req, err = newAttachmentUpload(ctx, url, recipientID, attachment, messageTag); err != nil {
+			return nil, err
+		}
+	}
+	req.URL.RawQuery = fmt.Sprintf("access_token=%s", accessToken)
+	
+	slog.Info("Sending attachment to Facebook",
+		"recipient_psid", recipientID,
+		"type", attachment.Type,
+		"by_attachment_id", attachment.AttachmentID != "",
+		"by_url", attachment.AttachmentID == "" && attachment.URL != "",
+		"message_tag", messageTag,
 	)
 	
+	return c.send(req, recipientID)
+}
+
+// messagingType returns the Send API messaging_type: RESPONSE for a standard reply,
+// MESSAGE_TAG when the message is sent with a tag
+func messagingType(messageTag string) string {
+	if messageTag != "" {
+		return "MESSAGE_TAG"
+	}
+	return "RESPONSE"
+}
+
+// toQuickReplyPayloads maps quick replies to the Send API format (nil for none)
+func toQuickReplyPayloads(replies []domain.QuickReply) []QuickReplyPayload {
+	var payloads []QuickReplyPayload
+	for _, reply := range replies {
+		payloads = append(payloads, QuickReplyPayload{
+			ContentType: reply.ContentType,
+			Title:       reply.Title,
+			Payload:     reply.Payload,
+			ImageURL:    reply.ImageURL,
+		})
+	}
+	return payloads
+}
+
+// toButtonPayloads maps template buttons to the Send API format (nil for none)
+func toButtonPayloads(buttons []domain.MessageButton) []ButtonPayload {
+	var payloads []ButtonPayload
+	for _, button := range buttons {
+		payloads = append(payloads, ButtonPayload{
+			Type:    button.Type,
+			Title:   button.Title,
+			URL:     button.URL,
+			Payload: button.Payload,
+		})
+	}
+	return payloads
+}
+
+// newAttachmentUpload builds a multipart Send API request carrying the file (filedata)
+func newAttachmentUpload(ctx context.Context, url, recipientID string, attachment domain.OutboundAttachment, messageTag string) (*http.Request, error) {
+	file, err := attachment.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open attachment: %w", err)
+	}
+	defer file.Close()
+	
+	recipient, _ := json.Marshal(map[string]string{"id": recipientID})
+	message, _ := json.Marshal(map[string]interface{}{
+		"attachment": SendAttachment{
+			Type:    attachment.Type,
+			Payload: SendAttachmentPayload{IsReusable: true},
+		},
+	})
+	
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("recipient", string(recipient))
+	form.WriteField("message", string(message))
+	form.WriteField("messaging_type", messagingType(messageTag))
+	if messageTag != "" {
+		form.WriteField("tag", messageTag)
+	}
+	
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="filedata"; filename=%q`, attachment.FileName))
+	header.Set("Content-Type", attachment.ContentType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return nil, fmt.Errorf("create upload part: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("read attachment: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("close upload form: %w", err)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req, nil
+}
+
+// send performs a Send API request and maps Graph API errors
+func (c *FacebookClient) send(req *http.Request, recipientPSID string) (*SendMessageResponse, error) {
 	// Send request
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		slog.Error("Failed to send request to Facebook",
 			"error", err,
-			"attempt", attempt,
 		)
-		return fmt.Errorf("facebook api request failed: %w", err)
+		return nil, fmt.Errorf("facebook api request failed: %w", err)
 	}
 	defer resp.Body.Close()
 	
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 	
 	// Check HTTP status
@@ -175,7 +560,7 @@ func (c *FacebookClient) sendReplyAttempt(recipientPSID, pageAccessToken, text s
 				"status_code", resp.StatusCode,
 				"body", string(body),
 			)
-			return fmt.Errorf("facebook api error %d: %s", resp.StatusCode, string(body))
+			return nil, fmt.Errorf("facebook api error %d: %s", resp.StatusCode, string(body))
 		}
 		
 		slog.Error("Facebook API error",
@@ -189,15 +574,18 @@ func (c *FacebookClient) sendReplyAttempt(recipientPSID, pageAccessToken, text s
 		// Return specific errors based on code
 		switch fbError.Error.Code {
 		case 190: // Token expired/invalid
-			return ErrTokenExpired
+			return nil, ErrTokenExpired
 		case 4, 17, 32, 613: // Rate limiting
-			return ErrRateLimited
+			return nil, ErrRateLimited
 		case 10, 200, 299: // Permission errors
-			return ErrPermissionDenied
+			if fbError.Error.ErrorSubcode == 2018278 || fbError.Error.ErrorSubcode == 2534022 {
+				return nil, ErrOutsideMessagingWindow // Sent outside the allowed window
+			}
+			return nil, ErrPermissionDenied
 		case 100: // Invalid parameter
-			return fmt.Errorf("invalid parameter: %s", fbError.Error.Message)
+			return nil, fmt.Errorf("%w: invalid parameter: %s", ports.ErrMessageRejected, fbError.Error.Message)
 		default:
-			return fmt.Errorf("facebook api error (code %d): %s", fbError.Error.Code, fbError.Error.Message)
+			return nil, fmt.Errorf("facebook api error (code %d): %s", fbError.Error.Code, fbError.Error.Message)
 		}
 	}
 	
@@ -208,23 +596,22 @@ func (c *FacebookClient) sendReplyAttempt(recipientPSID, pageAccessToken, text s
 			"error", err,
 			"body", string(body),
 		)
-		// Still return nil since HTTP 200 means it worked
-		return nil
+		// HTTP 200 means it worked, only the message ID is unknown
+		return &SendMessageResponse{}, nil
 	}
 	
 	slog.Info("Message sent successfully",
 		"recipient_psid", recipientPSID,
 		"message_id", sendResp.MessageID,
-		"attempt", attempt,
 	)
 	
-	return nil
+	return &sendResp, nil
 }
 
 // SendTypingIndicator sends a typing indicator (optional enhancement)
 // Shows "..." bubbles in customer's Messenger
-func (c *FacebookClient) SendTypingIndicator(recipientPSID, pageAccessToken string, action string) error {
-	url := fmt.Sprintf("https://graph.facebook.com/%s/me/messages", c.apiVersion)
+func (c *FacebookClient) SendTypingIndicator(ctx context.Context, recipientPSID, pageAccessToken string, action string) error {
+	url := fmt.Sprintf("%s/%s/me/messages", c.baseURL, c.apiVersion)
 	
 	payload := map[string]interface{}{
 		"recipient": map[string]string{
@@ -238,7 +625,7 @@ func (c *FacebookClient) SendTypingIndicator(recipientPSID, pageAccessToken stri
 		return err
 	}
 	
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,91 @@
+// Package gateway implements the download of remote media (platform CDN URLs)
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"immortal-chat/internal/core/ports"
+)
+
+// Ensure MediaDownloader implements ports.MediaDownloader
+var _ ports.MediaDownloader = (*MediaDownloader)(nil)
+
+// errBlockedAddress is returned when a download would connect to a non-public address
+var errBlockedAddress = errors.New("media address is not public")
+
+// MediaDownloader fetches attachment URLs received in webhooks
+// Only public addresses are dialled (also after redirects): the URLs come from
+// platform payloads and must not reach services inside our network
+type MediaDownloader struct {
+	httpClient *http.Client
+}
+
+// NewMediaDownloader creates a downloader; timeout bounds one whole download
+func NewMediaDownloader(timeout time.Duration) *MediaDownloader {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+				return fmt.Errorf("%w: %s", errBlockedAddress, host)
+			}
+			return nil
+		},
+	}
+	return &MediaDownloader{
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+// Download opens an http(s) URL; 4xx answers other than 408 / 429 and blocked
+// addresses are reported as ports.ErrMediaUnavailable (CDN URLs expire with 403 / 404)
+func (d *MediaDownloader) Download(ctx context.Context, rawURL string) (io.ReadCloser, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, "", fmt.Errorf("%w: unsupported url", ports.ErrMediaUnavailable)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, errBlockedAddress) {
+			return nil, "", fmt.Errorf("%w: %v", ports.ErrMediaUnavailable, err)
+		}
+		return nil, "", fmt.Errorf("download media: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+			resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, "", fmt.Errorf("%w: HTTP %d", ports.ErrMediaUnavailable, resp.StatusCode)
+		}
+		return nil, "", fmt.Errorf("download media: HTTP %d", resp.StatusCode)
+	}
+
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/ports"
+)
+
+func TestMediaDownloaderRefusesNonPublicAddresses(t *testing.T) {
+	var reached bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	downloader := NewMediaDownloader(5 * time.Second)
+	for _, url := range []string{
+		server.URL + "/media.png",                  // Loopback
+		"http://localhost:1/",                      // Resolves to loopback
+		"http://10.1.2.3/media.png",                // Private
+		"http://192.168.0.10/media.png",            // Private
+		"http://169.254.169.254/latest/meta-data/", // Link-local (cloud metadata)
+		"http://[::1]:1/",                          // IPv6 loopback
+		"http://[fd00::1]/",                        // IPv6 unique local
+		"ftp://cdn.example.com/media.png",          // Unsupported scheme
+		"file:///etc/passwd",                       // Unsupported scheme
+		"https:///no-host",                         // Missing host
+	} {
+		body, _, err := downloader.Download(context.Background(), url)
+		if body != nil {
+			body.Close()
+		}
+		if !errors.Is(err, ports.ErrMediaUnavailable) {
+			t.Errorf("%s: err = %v, want ErrMediaUnavailable (permanent)", url, err)
+		}
+	}
+	if reached {
+		t.Error("downloader connected to the loopback server")
+	}
+}
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/adapters/dto"
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// Ensure TelegramAdapter implements PlatformAdapter and AttachmentSender
+var (
+	_ ports.PlatformAdapter  = (*TelegramAdapter)(nil)
+	_ ports.AttachmentSender = (*TelegramAdapter)(nil)
+)
+
+// TelegramAdapter plugs a Telegram bot into the platform registry
+// Inbound: dto.TelegramUpdate -> domain.InboundEvent
+// Outbound: TelegramClient (sendMessage / sendPhoto / sendDocument)
+//
+// The bot is registered in pages with page_id = bot ID (the numeric prefix of
+// the bot token) and access_token = bot token
+type TelegramAdapter struct {
+	client        *TelegramClient
+	botID         string // pages.page_id of the bot
+	webhookSecret string // secret_token passed to setWebhook
+}
+
+// NewTelegramAdapter creates the Telegram platform adapter
+// botToken is only used to derive the bot ID, sends use pages.access_token
+func NewTelegramAdapter(client *TelegramClient, botToken, webhookSecret string) *TelegramAdapter {
+	return &TelegramAdapter{
+		client:        client,
+		botID:         TelegramBotID(botToken),
+		webhookSecret: webhookSecret,
+	}
+}
+
+// TelegramBotID returns the bot ID part of a bot token ("123456:ABC-DEF" -> "123456")
+func TelegramBotID(botToken string) string {
+	id, _, _ := strings.Cut(botToken, ":")
+	return id
+}
+
+// Platform returns the registry key (matches pages.platform)
+func (a *TelegramAdapter) Platform() string {
+	return domain.PlatformTelegram
+}
+
+// VerifySignature checks the X-Telegram-Bot-Api-Secret-Token header
+// Telegram does not sign bodies; it echoes the secret_token given to setWebhook
+// Ref: https://core.telegram.org/bots/api#setwebhook
+func (a *TelegramAdapter) VerifySignature(header http.Header, body []byte) error {
+	provided := header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if provided == "" {
+		return fmt.Errorf("%w: missing X-Telegram-Bot-Api-Secret-Token header", ErrInvalidSignature)
+	}
+
+	// Constant-time comparison to prevent timing attacks
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(a.webhookSecret)) != 1 {
+		return fmt.Errorf("%w: secret token mismatch", ErrInvalidSignature)
+	}
+
+	return nil
+}
+
+// ParseEvents converts a Telegram Update into normalized events
+// Unsupported updates (edits, callback queries, ...) yield no events
+func (a *TelegramAdapter) ParseEvents(payload []byte) ([]domain.InboundEvent, error) {
+	var update dto.TelegramUpdate
+	if err := json.Unmarshal(payload, &update); err != nil {
+		return nil, fmt.Errorf("parse telegram update: %w", err)
+	}
+
+	if !update.IsUserMessage() {
+		slog.Debug("Ignoring unsupported Telegram update", "update_id", update.UpdateID)
+		return nil, nil
+	}
+
+	msg := update.Message
+	chatID := strconv.FormatInt(msg.Chat.ID, 10)
+
+	event := domain.InboundEvent{
+		Type:     domain.EventTypeMessage,
+		Platform: a.Platform(),
+		PageID:   a.botID,
+		SenderID: chatID, // Replies go to the chat, not to the user
+		// message_id is only unique per chat
+		ExternalMsgID: chatID + ":" + strconv.FormatInt(msg.MessageID, 10),
+		SenderName:    msg.SenderName(),
+		MessageType:   msg.GetMessageType(),
+		Content:       msg.GetContent(),
+		Attachments:   json.RawMessage("[]"),
+		Timestamp:     time.Unix(msg.Date, 0),
+	}
+
+	if attachments := msg.GetAttachments(); len(attachments) > 0 {
+		event.Attachments, _ = json.Marshal(attachments)
+	}
+
+	return []domain.InboundEvent{event}, nil
+}
+
+// SendText sends a text message and returns "chatID:message_id"
+// (same format as inbound IDs so dedup/echo lookups line up)
+func (a *TelegramAdapter) SendText(ctx context.Context, target domain.OutboundTarget, text string) (string, error) {
+	messageID, err := a.client.SendMessage(ctx, target.AccessToken, target.RecipientID, text)
+	if err != nil {
+		return "", err
+	}
+	return target.RecipientID + ":" + strconv.FormatInt(messageID, 10), nil
+}
+
+// SendAttachment sends an image as a photo and any other file as a document
+// The returned attachment ID is the Telegram file_id, reusable by the same bot
+func (a *TelegramAdapter) SendAttachment(ctx context.Context, target domain.OutboundTarget, attachment domain.OutboundAttachment) (string, string, error) {
+	messageID, fileID, err := a.client.SendMedia(ctx, target.AccessToken, target.RecipientID, attachment)
+	if err != nil {
+		return "", "", err
+	}
+	return target.RecipientID + ":" + strconv.FormatInt(messageID, 10), fileID, nil
+}
@@ -0,0 +1,225 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/adapters/dto"
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// DefaultTelegramAPIBaseURL is the production Telegram Bot API host
+const DefaultTelegramAPIBaseURL = "https://api.telegram.org"
+
+// TelegramClient handles communication with the Telegram Bot API
+// The base URL is configurable so the client can be pointed at a local stub
+type TelegramClient struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewTelegramClient creates a new Telegram Bot API client
+// baseURL: empty means DefaultTelegramAPIBaseURL
+func NewTelegramClient(baseURL string) *TelegramClient {
+	if baseURL == "" {
+		baseURL = DefaultTelegramAPIBaseURL
+	}
+
+	return &TelegramClient{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// TelegramResponse is the envelope of every Bot API response
+type TelegramResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	ErrorCode   int             `json:"error_code,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Parameters  *struct {
+		RetryAfter int `json:"retry_after,omitempty"`
+	} `json:"parameters,omitempty"`
+}
+
+// SendMessage sends a text message to a chat and returns the Telegram message_id
+// botToken: From database (pages.access_token)
+// chatID: conversations.platform_id
+//
+// Errors map to the shared gateway errors:
+// - ErrTokenExpired: Bot token revoked (401/404) → Caller should deactivate page
+// - ErrRateLimited: Flood control (429)
+// - ErrPermissionDenied: Bot was blocked by the user / kicked from the chat (403)
+func (c *TelegramClient) SendMessage(ctx context.Context, botToken, chatID, text string) (int64, error) {
+	message, err := c.send(ctx, botToken, "sendMessage", map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return message.MessageID, nil
+}
+
+// SendMedia sends an image with sendPhoto and any other file with sendDocument
+// The file is referenced by file_id (attachment.AttachmentID) or URL, else uploaded
+// Returns the message_id and the file_id Telegram assigned, reusable by this bot
+// Same error semantics as SendMessage
+func (c *TelegramClient) SendMedia(ctx context.Context, botToken, chatID string, attachment domain.OutboundAttachment) (int64, string, error) {
+	method, field := "sendDocument", "document"
+	if attachment.Type == domain.AttachmentTypeImage {
+		method, field = "sendPhoto", "photo"
+	}
+
+	var (
+		message *dto.TelegramMessage
+		err     error
+	)
+	switch {
+	case attachment.AttachmentID != "":
+		message, err = c.send(ctx, botToken, method, map[string]interface{}{"chat_id": chatID, field: attachment.AttachmentID})
+	case attachment.URL != "":
+		message, err = c.send(ctx, botToken, method, map[string]interface{}{"chat_id": chatID, field: attachment.URL})
+	case attachment.Open != nil:
+		message, err = c.upload(ctx, botToken, method, chatID, field, attachment)
+	default:
+		return 0, "", fmt.Errorf("attachment has no file_id, URL or file")
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	var fileID string
+	switch {
+	case len(message.Photo) > 0:
+		fileID = message.Photo[len(message.Photo)-1].FileID // Largest size
+	case message.Document != nil:
+		fileID = message.Document.FileID
+	}
+	return message.MessageID, fileID, nil
+}
+
+// send calls a Bot API method with a JSON body
+func (c *TelegramClient) send(ctx context.Context, botToken, method string, payload map[string]interface{}) (*dto.TelegramMessage, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return c.call(ctx, botToken, method, fmt.Sprint(payload["chat_id"]), "application/json", bytes.NewBuffer(jsonData))
+}
+
+// upload calls a Bot API method with the file as multipart/form-data
+func (c *TelegramClient) upload(ctx context.Context, botToken, method, chatID, field string, attachment domain.OutboundAttachment) (*dto.TelegramMessage, error) {
+	file, err := attachment.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open attachment: %w", err)
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("chat_id", chatID)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, field, attachment.FileName))
+	header.Set("Content-Type", attachment.ContentType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return nil, fmt.Errorf("create upload part: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("read attachment: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("close upload form: %w", err)
+	}
+
+	return c.call(ctx, botToken, method, chatID, form.FormDataContentType(), &body)
+}
+
+// call performs a Bot API request for a method that returns a Message
+func (c *TelegramClient) call(ctx context.Context, botToken, method, chatID, contentType string, body io.Reader) (*dto.TelegramMessage, error) {
+	// The token is part of the path: never log the URL
+	url := fmt.Sprintf("%s/bot%s/%s", c.baseURL, botToken, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	slog.Info("Sending message to Telegram",
+		"method", method,
+		"chat_id", chatID,
+	)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		slog.Error("Failed to send request to Telegram", "method", method, "error", redactToken(err, botToken))
+		return nil, fmt.Errorf("telegram api request failed: %s", redactToken(err, botToken))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var tgResp TelegramResponse
+	if err := json.Unmarshal(respBody, &tgResp); err != nil {
+		return nil, fmt.Errorf("telegram api error %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if !tgResp.OK {
+		slog.Error("Telegram API error",
+			"method", method,
+			"error_code", tgResp.ErrorCode,
+			"description", tgResp.Description,
+		)
+
+		switch tgResp.ErrorCode {
+		case http.StatusUnauthorized, http.StatusNotFound: // Token revoked / unknown bot
+			return nil, ErrTokenExpired
+		case http.StatusTooManyRequests:
+			return nil, ErrRateLimited
+		case http.StatusForbidden: // Bot blocked by user
+			return nil, ErrPermissionDenied
+		case http.StatusBadRequest: // Bad message (empty text, unknown chat, ...)
+			return nil, fmt.Errorf("%w: telegram api error (code %d): %s", ports.ErrMessageRejected, tgResp.ErrorCode, tgResp.Description)
+		default:
+			return nil, fmt.Errorf("telegram api error (code %d): %s", tgResp.ErrorCode, tgResp.Description)
+		}
+	}
+
+	var message dto.TelegramMessage
+	if err := json.Unmarshal(tgResp.Result, &message); err != nil {
+		slog.Warn("Failed to parse Telegram result", "error", err)
+		return &message, nil // ok=true means it was sent
+	}
+
+	slog.Info("Telegram message sent successfully",
+		"method", method,
+		"message_id", message.MessageID,
+	)
+
+	return &message, nil
+}
+
+// redactToken removes the bot token from transport errors (they include the URL)
+func redactToken(err error, botToken string) string {
+	if botToken == "" {
+		return err.Error()
+	}
+	return strings.ReplaceAll(err.Error(), botToken, "<redacted>")
+}
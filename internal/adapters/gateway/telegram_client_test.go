@@ -0,0 +1,168 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+const testBotToken = "123456:ABC-secret"
+
+func TestTelegramSendMessage(t *testing.T) {
+	var (
+		path    string
+		payload map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("request body %s: %v", body, err)
+		}
+		io.WriteString(w, `{"ok": true, "result": {"message_id": 42, "chat": {"id": 777}}}`)
+	}))
+	defer server.Close()
+
+	adapter := NewTelegramAdapter(NewTelegramClient(server.URL), testBotToken, "hook-secret")
+	messageID, err := adapter.SendText(context.Background(), domain.OutboundTarget{
+		PageID:      "123456",
+		RecipientID: "777",
+		AccessToken: testBotToken,
+	}, "xin chào")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageID != "777:42" {
+		t.Errorf("message ID = %q, want chatID:message_id", messageID)
+	}
+	if path != "/bot"+testBotToken+"/sendMessage" {
+		t.Errorf("path = %q", path)
+	}
+	if payload["chat_id"] != "777" || payload["text"] != "xin chào" {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestTelegramSendMessageErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		response string
+		want     error
+	}{
+		"token revoked": {`{"ok": false, "error_code": 401, "description": "Unauthorized"}`, ports.ErrTokenExpired},
+		"flood control": {`{"ok": false, "error_code": 429, "description": "Too Many Requests", "parameters": {"retry_after": 5}}`, ports.ErrRateLimited},
+		"bot blocked":   {`{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}`, ports.ErrPermissionDenied},
+		"bad request":   {`{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"}`, ports.ErrMessageRejected},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, tc.response)
+		}))
+		_, err := NewTelegramClient(server.URL).SendMessage(context.Background(), testBotToken, "777", "hi")
+		server.Close()
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+
+	// Transport errors include the URL: the token must not leak into them
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	_, err := NewTelegramClient(server.URL).SendMessage(context.Background(), testBotToken, "777", "hi")
+	if err == nil || strings.Contains(err.Error(), testBotToken) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestTelegramVerifySecretToken(t *testing.T) {
+	adapter := NewTelegramAdapter(nil, testBotToken, "hook-secret")
+
+	for name, tc := range map[string]struct {
+		token string
+		valid bool
+	}{
+		"valid":          {"hook-secret", true},
+		"wrong token":    {"hook-secreT", false},
+		"prefix only":    {"hook", false},
+		"missing header": {"", false},
+	} {
+		header := http.Header{}
+		if tc.token != "" {
+			header.Set("X-Telegram-Bot-Api-Secret-Token", tc.token)
+		}
+		err := adapter.VerifySignature(header, []byte(`{"update_id": 1}`))
+		if tc.valid && err != nil {
+			t.Errorf("%s: err = %v", name, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: err = %v, want ErrInvalidSignature", name, err)
+		}
+	}
+}
+
+func TestTelegramSendAttachment(t *testing.T) {
+	type request struct {
+		path, chatID, file, fileName string
+	}
+	var got request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = request{path: r.URL.Path}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			file, header, err := r.FormFile("document")
+			if err != nil {
+				t.Errorf("upload: %v", err)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			got.chatID, got.file, got.fileName = r.FormValue("chat_id"), string(data), header.Filename
+			io.WriteString(w, `{"ok": true, "result": {"message_id": 8, "document": {"file_id": "doc-file"}}}`)
+			return
+		}
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		got.chatID, got.file = payload["chat_id"], payload["photo"]
+		io.WriteString(w, `{"ok": true, "result": {"message_id": 7, "photo": [{"file_id": "small"}, {"file_id": "large"}]}}`)
+	}))
+	defer server.Close()
+
+	adapter := NewTelegramAdapter(NewTelegramClient(server.URL), testBotToken, "hook-secret")
+	target := domain.OutboundTarget{PageID: "123456", RecipientID: "777", AccessToken: testBotToken}
+
+	// Image by URL: sendPhoto, the largest size's file_id is kept for reuse
+	messageID, fileID, err := adapter.SendAttachment(context.Background(), target, domain.OutboundAttachment{
+		Type: domain.AttachmentTypeImage,
+		URL:  "https://chat.example.com/media/abc.jpg",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageID != "777:7" || fileID != "large" {
+		t.Errorf("photo: message ID %q, file ID %q", messageID, fileID)
+	}
+	if want := (request{path: "/bot" + testBotToken + "/sendPhoto", chatID: "777", file: "https://chat.example.com/media/abc.jpg"}); got != want {
+		t.Errorf("photo request = %+v, want %+v", got, want)
+	}
+
+	// Other files without URL or file_id: uploaded with sendDocument
+	messageID, fileID, err = adapter.SendAttachment(context.Background(), target, domain.OutboundAttachment{
+		Type:        domain.AttachmentTypeFile,
+		FileName:    "bao-gia.pdf",
+		ContentType: "application/pdf",
+		Open:        func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("%PDF-1.4")), nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageID != "777:8" || fileID != "doc-file" {
+		t.Errorf("document: message ID %q, file ID %q", messageID, fileID)
+	}
+	if want := (request{path: "/bot" + testBotToken + "/sendDocument", chatID: "777", file: "%PDF-1.4", fileName: "bao-gia.pdf"}); got != want {
+		t.Errorf("document request = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/adapters/dto"
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// Ensure ZaloAdapter implements PlatformAdapter
+var _ ports.PlatformAdapter = (*ZaloAdapter)(nil)
+
+// ZaloAdapter plugs Zalo Official Account into the platform registry
+// Inbound: dto.ZaloWebhookEvent -> domain.InboundEvent
+// Outbound: ZaloClient (OA customer-service message API)
+type ZaloAdapter struct {
+	client      *ZaloClient
+	appID       string // Zalo app linked to the OA (part of the MAC)
+	oaSecretKey string // OA secret key from the webhook settings (part of the MAC)
+}
+
+// NewZaloAdapter creates the Zalo platform adapter
+func NewZaloAdapter(client *ZaloClient, appID, oaSecretKey string) *ZaloAdapter {
+	return &ZaloAdapter{
+		client:      client,
+		appID:       appID,
+		oaSecretKey: oaSecretKey,
+	}
+}
+
+// Platform returns the registry key (matches pages.platform)
+func (a *ZaloAdapter) Platform() string {
+	return domain.PlatformZalo
+}
+
+// VerifySignature validates the X-ZEvent-Signature MAC from Zalo
+// Format: "mac=<hex>" where mac = sha256(appId + body + timestamp + OASecretKey)
+// and timestamp is the "timestamp" field of the JSON body
+// Ref: https://developers.zalo.me/docs/official-account/webhook/xac-thuc-webhook
+func (a *ZaloAdapter) VerifySignature(header http.Header, body []byte) error {
+	signatureHeader := header.Get("X-ZEvent-Signature")
+	if signatureHeader == "" {
+		return fmt.Errorf("%w: missing X-ZEvent-Signature header", ErrInvalidSignature)
+	}
+	expectedSignature := strings.TrimPrefix(signatureHeader, "mac=")
+
+	var envelope struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("%w: unparseable body", ErrInvalidSignature)
+	}
+
+	sum := sha256.Sum256([]byte(a.appID + string(body) + envelope.Timestamp + a.oaSecretKey))
+	computedSignature := hex.EncodeToString(sum[:])
+
+	// Constant-time comparison to prevent timing attacks
+	if subtle.ConstantTimeCompare([]byte(computedSignature), []byte(strings.ToLower(expectedSignature))) != 1 {
+		return fmt.Errorf("%w: MAC mismatch", ErrInvalidSignature)
+	}
+
+	return nil
+}
+
+// ParseEvents converts a Zalo OA webhook into normalized events
+// Unsupported events (follow, user_submit_info, ...) yield no events
+func (a *ZaloAdapter) ParseEvents(payload []byte) ([]domain.InboundEvent, error) {
+	var zaloEvent dto.ZaloWebhookEvent
+	if err := json.Unmarshal(payload, &zaloEvent); err != nil {
+		return nil, fmt.Errorf("parse zalo webhook: %w", err)
+	}
+
+	event := domain.InboundEvent{
+		Platform: a.Platform(),
+		PageID:   zaloEvent.Recipient.ID, // OA ID
+		SenderID: zaloEvent.Sender.ID,    // Zalo user ID
+	}
+	if ms, err := strconv.ParseInt(zaloEvent.Timestamp, 10, 64); err == nil {
+		event.Timestamp = time.UnixMilli(ms)
+	}
+
+	switch {
+	case zaloEvent.EventName == dto.ZaloEventUserReceivedMessage && zaloEvent.Message != nil:
+		event.Type = domain.EventTypeDelivery
+		event.MessageIDs = zaloEvent.Message.MsgIDs
+	case zaloEvent.EventName == dto.ZaloEventUserSeenMessage && zaloEvent.Message != nil:
+		event.Type = domain.EventTypeRead
+		event.MessageIDs = zaloEvent.Message.MsgIDs
+	case zaloEvent.IsEcho():
+		// Echo: the OA is the sender, the customer is the recipient
+		event.Type = domain.EventTypeEcho
+		event.PageID = zaloEvent.Sender.ID
+		event.SenderID = zaloEvent.Recipient.ID
+		if zaloEvent.Message != nil {
+			event.ExternalMsgID = zaloEvent.Message.MsgID
+			event.MessageType = zaloEvent.GetMessageType()
+			event.Content = zaloEvent.GetContent()
+			event.Attachments = json.RawMessage("[]")
+			if len(zaloEvent.Message.Attachments) > 0 {
+				event.Attachments, _ = json.Marshal(zaloEvent.Message.Attachments)
+			}
+		}
+	case zaloEvent.IsUserMessage():
+		event.Type = domain.EventTypeMessage
+		event.ExternalMsgID = zaloEvent.Message.MsgID
+		event.MessageType = zaloEvent.GetMessageType()
+		event.Content = zaloEvent.GetContent()
+
+		event.Attachments = json.RawMessage("[]")
+		if len(zaloEvent.Message.Attachments) > 0 {
+			event.Attachments, _ = json.Marshal(zaloEvent.Message.Attachments)
+		}
+	default:
+		slog.Debug("Ignoring unsupported Zalo event", "event_name", zaloEvent.EventName)
+		return nil, nil
+	}
+
+	return []domain.InboundEvent{event}, nil
+}
+
+// SendText sends a customer-service text message and returns the Zalo message ID
+func (a *ZaloAdapter) SendText(ctx context.Context, target domain.OutboundTarget, text string) (string, error) {
+	messageID, err := a.client.SendReply(ctx, target.RecipientID, target.AccessToken, text)
+	if err != nil {
+		return "", err
+	}
+
+	slog.Debug("Zalo reply sent",
+		"oa_id", target.PageID,
+		"message_id", messageID,
+	)
+
+	return messageID, nil
+}
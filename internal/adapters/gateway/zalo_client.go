@@ -0,0 +1,168 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/core/ports"
+)
+
+// DefaultZaloAPIBaseURL is the production Zalo OpenAPI host
+const DefaultZaloAPIBaseURL = "https://openapi.zalo.me"
+
+// ZaloClient handles communication with the Zalo Official Account OpenAPI
+// The base URL is configurable so the client can be pointed at a local fake server
+type ZaloClient struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewZaloClient creates a new Zalo OA API client
+// baseURL: empty means DefaultZaloAPIBaseURL
+func NewZaloClient(baseURL string) *ZaloClient {
+	if baseURL == "" {
+		baseURL = DefaultZaloAPIBaseURL
+	}
+
+	return &ZaloClient{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// ZaloSendMessageRequest represents the Zalo customer-service message payload
+type ZaloSendMessageRequest struct {
+	Recipient struct {
+		UserID string `json:"user_id"`
+	} `json:"recipient"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// ZaloSendMessageResponse represents Zalo's response
+// Zalo answers HTTP 200 even on failure; Error != 0 signals an API error
+type ZaloSendMessageResponse struct {
+	Error   int    `json:"error"`
+	Message string `json:"message"`
+	Data    struct {
+		MessageID string `json:"message_id"`
+		UserID    string `json:"user_id"`
+	} `json:"data"`
+}
+
+// ZaloAPIError is a non-zero "error" code returned by the Zalo OpenAPI
+type ZaloAPIError struct {
+	Code    int
+	Message string
+}
+
+func (e *ZaloAPIError) Error() string {
+	return fmt.Sprintf("zalo api error (code %d): %s", e.Code, e.Message)
+}
+
+// Unwrap classifies API errors as rejected messages (transport failures are retried
+// by the outbound queue)
+func (e *ZaloAPIError) Unwrap() error {
+	return ports.ErrMessageRejected
+}
+
+// SendReply sends a customer-service text message to a Zalo user (one attempt, the
+// outbound queue retries)
+// recipientUserID: Zalo user ID (from conversations.platform_id)
+// oaAccessToken: From database (pages.access_token)
+//
+// Returns the Zalo message ID on success, or the shared gateway errors:
+// - ErrTokenExpired: OA access token invalid/expired → Caller should deactivate page
+// - ErrRateLimited: Quota exceeded → Caller should retry later
+// - ErrPermissionDenied: User has not followed / interacted with the OA recently
+// Transport failures are returned wrapped (errors.Is works on the cause)
+func (c *ZaloClient) SendReply(ctx context.Context, recipientUserID, oaAccessToken, text string) (string, error) {
+	url := c.baseURL + "/v3.0/oa/message/cs"
+
+	payload := ZaloSendMessageRequest{}
+	payload.Recipient.UserID = recipientUserID
+	payload.Message.Text = text
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Zalo takes the OA token in a header, not in the query string
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("access_token", oaAccessToken)
+
+	slog.Info("Sending message to Zalo",
+		"recipient_user_id", recipientUserID,
+		"text_length", len(text),
+	)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		slog.Error("Failed to send request to Zalo",
+			"error", err,
+		)
+		return "", fmt.Errorf("zalo api request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		slog.Error("Zalo API HTTP error",
+			"status_code", resp.StatusCode,
+			"body", string(body),
+		)
+		return "", fmt.Errorf("zalo api error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var sendResp ZaloSendMessageResponse
+	if err := json.Unmarshal(body, &sendResp); err != nil {
+		return "", fmt.Errorf("zalo api returned unparseable body: %s", string(body))
+	}
+
+	if sendResp.Error != 0 {
+		slog.Error("Zalo API error",
+			"error_code", sendResp.Error,
+			"error_message", sendResp.Message,
+		)
+
+		// Return specific errors based on code
+		// Ref: https://developers.zalo.me/docs/official-account/phu-luc/ma-loi
+		switch sendResp.Error {
+		case -216, -124: // Access token invalid / expired
+			return "", ErrTokenExpired
+		case -32: // Request quota exceeded
+			return "", ErrRateLimited
+		case -213, -230: // User not following OA / no interaction in the last 7 days
+			return "", ErrPermissionDenied
+		default:
+			return "", &ZaloAPIError{Code: sendResp.Error, Message: sendResp.Message}
+		}
+	}
+
+	slog.Info("Zalo message sent successfully",
+		"recipient_user_id", recipientUserID,
+		"message_id", sendResp.Data.MessageID,
+	)
+
+	return sendResp.Data.MessageID, nil
+}
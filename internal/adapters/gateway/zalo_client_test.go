@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"immortal-chat/internal/core/ports"
+)
+
+// zaloServer answers /v3.0/oa/message/cs with the given JSON body and records the request
+func zaloServer(t *testing.T, status int, response string) (*httptest.Server, *http.Request, *ZaloSendMessageRequest) {
+	t.Helper()
+	var (
+		received http.Request
+		payload  ZaloSendMessageRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = *r
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("request body %s: %v", body, err)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &received, &payload
+}
+
+func TestZaloSendReply(t *testing.T) {
+	server, received, payload := zaloServer(t, http.StatusOK,
+		`{"error": 0, "message": "Success", "data": {"message_id": "zmid.1", "user_id": "u1"}}`)
+
+	messageID, err := NewZaloClient(server.URL).SendReply(context.Background(), "u1", "oa-token", "xin chào")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageID != "zmid.1" {
+		t.Errorf("message ID = %q", messageID)
+	}
+	if received.URL.Path != "/v3.0/oa/message/cs" || received.Method != http.MethodPost {
+		t.Errorf("request = %s %s", received.Method, received.URL.Path)
+	}
+	if got := received.Header.Get("access_token"); got != "oa-token" {
+		t.Errorf("access_token header = %q", got)
+	}
+	if received.URL.Query().Has("access_token") {
+		t.Error("token leaked into the query string")
+	}
+	if payload.Recipient.UserID != "u1" || payload.Message.Text != "xin chào" {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestZaloSendReplyErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		status   int
+		response string
+		want     error
+	}{
+		"token expired":   {http.StatusOK, `{"error": -216, "message": "Access token is invalid"}`, ports.ErrTokenExpired},
+		"quota exceeded":  {http.StatusOK, `{"error": -32, "message": "Quota exceeded"}`, ports.ErrRateLimited},
+		"not following":   {http.StatusOK, `{"error": -213, "message": "User has not followed OA"}`, ports.ErrPermissionDenied},
+		"other api error": {http.StatusOK, `{"error": -201, "message": "Parameters are invalid"}`, ports.ErrMessageRejected},
+	} {
+		server, _, _ := zaloServer(t, tc.status, tc.response)
+		_, err := NewZaloClient(server.URL).SendReply(context.Background(), "u1", "oa-token", "hi")
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+
+	// Transport and HTTP failures are retryable: not classified as rejected
+	server, _, _ := zaloServer(t, http.StatusBadGateway, `bad gateway`)
+	_, err := NewZaloClient(server.URL).SendReply(context.Background(), "u1", "oa-token", "hi")
+	if err == nil || errors.Is(err, ports.ErrMessageRejected) {
+		t.Errorf("HTTP 502: err = %v", err)
+	}
+
+	var apiErr *ZaloAPIError
+	server, _, _ = zaloServer(t, http.StatusOK, `{"error": -201, "message": "Parameters are invalid"}`)
+	_, err = NewZaloClient(server.URL).SendReply(context.Background(), "u1", "oa-token", "hi")
+	if !errors.As(err, &apiErr) || apiErr.Code != -201 {
+		t.Errorf("err = %v, want ZaloAPIError -201", err)
+	}
+}
+
+func TestZaloVerifySignature(t *testing.T) {
+	adapter := NewZaloAdapter(nil, "app-1", "oa-secret")
+	body := []byte(`{"app_id": "app-1", "event_name": "user_send_text", "timestamp": "1700000000000"}`)
+	sum := sha256.Sum256([]byte("app-1" + string(body) + "1700000000000" + "oa-secret"))
+	mac := hex.EncodeToString(sum[:])
+
+	for name, tc := range map[string]struct {
+		signature string
+		body      []byte
+		valid     bool
+	}{
+		"valid":             {"mac=" + mac, body, true},
+		"tampered body":     {"mac=" + mac, []byte(`{"app_id": "app-1", "event_name": "user_send_text", "timestamp": "1700000000001"}`), false},
+		"wrong mac":         {"mac=" + hex.EncodeToString(make([]byte, 32)), body, false},
+		"missing header":    {"", body, false},
+		"unparseable body":  {"mac=" + mac, []byte(`not json`), false},
+		"uppercase hex mac": {"mac=" + strings.ToUpper(mac), body, true},
+	} {
+		header := http.Header{}
+		if tc.signature != "" {
+			header.Set("X-ZEvent-Signature", tc.signature)
+		}
+		err := adapter.VerifySignature(header, tc.body)
+		if tc.valid && err != nil {
+			t.Errorf("%s: err = %v", name, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: err = %v, want ErrInvalidSignature", name, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package handler implements HTTP request handlers for internal admin operations
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/services"
+)
+
+// AdminHandler exposes operational endpoints (webhook replay, quarantine replay, ...)
+// Protected by the mesh secret (X-Mesh-Secret header), not by dashboard login
+type AdminHandler struct {
+	replay     *services.ReplayService
+	meshSecret string
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(replay *services.ReplayService, meshSecret string) *AdminHandler {
+	return &AdminHandler{
+		replay:     replay,
+		meshSecret: meshSecret,
+	}
+}
+
+// ReplayRequest represents the JSON payload for POST /api/admin/webhooks/replay
+// Either ID, or any combination of From/To/Platform/Statuses
+type ReplayRequest struct {
+	ID       int64      `json:"id,omitempty"`
+	From     *time.Time `json:"from,omitempty"` // RFC3339
+	To       *time.Time `json:"to,omitempty"`   // RFC3339
+	Platform string     `json:"platform,omitempty"`
+	Statuses []string   `json:"statuses,omitempty"` // Default: failed + pending
+	Limit    int        `json:"limit,omitempty"`    // Default 100, max 1000
+}
+
+// ReplayWebhooks re-runs logged webhooks through the dispatcher
+// POST /api/admin/webhooks/replay
+// Body: {"id": 42} | {"from": "2024-01-01T00:00:00Z", "to": "...", "platform": "facebook"}
+func (h *AdminHandler) ReplayWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, NewErrorResponse(http.StatusMethodNotAllowed, "Method Not Allowed"))
+		return
+	}
+
+	if !h.authorized(r) {
+		slog.Warn("Unauthorized admin replay attempt", "remote_addr", r.RemoteAddr)
+		writeJSON(w, http.StatusUnauthorized, NewErrorResponse(http.StatusUnauthorized, "Unauthorized"))
+		return
+	}
+
+	var req ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid JSON body"))
+		return
+	}
+
+	for _, status := range req.Statuses {
+		if status != domain.WebhookStatusFailed && status != domain.WebhookStatusPending && status != domain.WebhookStatusProcessed {
+			writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid status: "+status))
+			return
+		}
+	}
+
+	var (
+		result *services.ReplayResult
+		err    error
+	)
+
+	if req.ID > 0 {
+		result, err = h.replay.ReplayByID(r.Context(), req.ID)
+	} else {
+		// Refuse an unbounded replay of the whole table by accident
+		if req.From == nil && req.To == nil && req.Platform == "" {
+			writeJSON(w, http.StatusBadRequest, BadRequestResponse("Provide id, or from/to, or platform"))
+			return
+		}
+
+		result, err = h.replay.ReplayMatching(r.Context(), domain.WebhookReplayFilter{
+			Platform: strings.ToLower(req.Platform),
+			From:     req.From,
+			To:       req.To,
+			Statuses: req.Statuses,
+			Limit:    req.Limit,
+		})
+	}
+
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		writeJSON(w, http.StatusNotFound, NotFoundResponse("Webhook log not found"))
+		return
+	}
+
+	if errors.Is(err, services.ErrWebhookQueued) {
+		writeJSON(w, http.StatusConflict, NewErrorResponse(http.StatusConflict, "Webhook is still pending in the queue, it will be retried automatically"))
+		return
+	}
+
+	if err != nil {
+		slog.Error("Webhook replay request failed",
+			"error", err,
+			"webhook_id", req.ID,
+			"platform", req.Platform,
+		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse(err.Error()))
+		return
+	}
+
+	slog.Info("Webhook replay request completed",
+		"attempted", result.Attempted,
+		"succeeded", result.Succeeded,
+		"failed", result.Failed,
+	)
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(result))
+}
+
+// WebhookStatus reports the system-wide webhook backlog (pending / failed webhook_logs)
+// GET /api/admin/webhooks/status
+func (h *AdminHandler) WebhookStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, NewErrorResponse(http.StatusMethodNotAllowed, "Method Not Allowed"))
+		return
+	}
+
+	if !h.authorized(r) {
+		slog.Warn("Unauthorized admin webhook status request", "remote_addr", r.RemoteAddr)
+		writeJSON(w, http.StatusUnauthorized, NewErrorResponse(http.StatusUnauthorized, "Unauthorized"))
+		return
+	}
+
+	backlog, err := h.replay.Backlog(r.Context())
+	if err != nil {
+		slog.Error("Failed to count webhook logs", "error", err)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse(err.Error()))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(backlog))
+}
+
+// QuarantineReplayRequest represents the JSON payload for POST /api/admin/quarantine/replay
+type QuarantineReplayRequest struct {
+	Platform string `json:"platform"`
+	PageID   string `json:"page_id"`
+}
+
+// ReplayQuarantined processes the events quarantined for a page after it was connected
+// or reactivated in the pages table
+// POST /api/admin/quarantine/replay
+// Body: {"platform": "facebook", "page_id": "123456789"}
+func (h *AdminHandler) ReplayQuarantined(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, NewErrorResponse(http.StatusMethodNotAllowed, "Method Not Allowed"))
+		return
+	}
+
+	if !h.authorized(r) {
+		slog.Warn("Unauthorized admin quarantine replay attempt", "remote_addr", r.RemoteAddr)
+		writeJSON(w, http.StatusUnauthorized, NewErrorResponse(http.StatusUnauthorized, "Unauthorized"))
+		return
+	}
+
+	var req QuarantineReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid JSON body"))
+		return
+	}
+	if req.Platform == "" || req.PageID == "" {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Provide platform and page_id"))
+		return
+	}
+
+	result, err := h.replay.ReplayQuarantined(r.Context(), strings.ToLower(req.Platform), req.PageID)
+	if errors.Is(err, services.ErrUnknownPage) || errors.Is(err, services.ErrInactivePage) {
+		writeJSON(w, http.StatusConflict, NewErrorResponse(http.StatusConflict, "Page is not connected or inactive, fix it in the pages table first"))
+		return
+	}
+	if err != nil {
+		slog.Error("Quarantine replay request failed",
+			"error", err,
+			"platform", req.Platform,
+			"page_id", req.PageID,
+		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse(err.Error()))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(result))
+}
+
+// authorized checks the X-Mesh-Secret header (constant-time)
+// Admin endpoints are disabled entirely when MESH_SECRET is not configured
+func (h *AdminHandler) authorized(r *http.Request) bool {
+	if h.meshSecret == "" {
+		return false
+	}
+	provided := r.Header.Get("X-Mesh-Secret")
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(h.meshSecret)) == 1
+}
@@ -0,0 +1,178 @@
+// Package handler implements HTTP request handlers for conversation assignment
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/services"
+)
+
+// AssignmentHandler handles manual assignment and agent presence
+type AssignmentHandler struct {
+	assignments *services.AssignmentService
+}
+
+// NewAssignmentHandler creates a new assignment handler
+func NewAssignmentHandler(assignments *services.AssignmentService) *AssignmentHandler {
+	return &AssignmentHandler{
+		assignments: assignments,
+	}
+}
+
+// AssignRequest represents the JSON payload for assign / transfer (exactly one field)
+type AssignRequest struct {
+	StaffID *int `json:"staff_id,omitempty"`
+	TeamID  *int `json:"team_id,omitempty"`
+}
+
+// assignAction is one of the AssignmentService operations, bound to its target
+type assignAction func(ctx context.Context, actor *domain.AuthClaims, conversationID int64) (*domain.ConversationAssignment, error)
+
+// Assign gives a conversation to a staff or team (agents: only to themselves)
+// POST /api/conversations/{id}/assign
+// Body: {"staff_id": 5} or {"team_id": 2}
+func (h *AssignmentHandler) Assign(w http.ResponseWriter, r *http.Request) {
+	target, ok := decodeAssignTarget(w, r)
+	if !ok {
+		return
+	}
+	h.apply(w, r, func(ctx context.Context, actor *domain.AuthClaims, conversationID int64) (*domain.ConversationAssignment, error) {
+		return h.assignments.Assign(ctx, actor, conversationID, target)
+	})
+}
+
+// Transfer hands a conversation over to another staff or team (agents: only their own)
+// POST /api/conversations/{id}/transfer
+// Body: {"staff_id": 5} or {"team_id": 2}
+func (h *AssignmentHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	target, ok := decodeAssignTarget(w, r)
+	if !ok {
+		return
+	}
+	h.apply(w, r, func(ctx context.Context, actor *domain.AuthClaims, conversationID int64) (*domain.ConversationAssignment, error) {
+		return h.assignments.Transfer(ctx, actor, conversationID, target)
+	})
+}
+
+// Unassign removes the staff assignee, the conversation stays in its team queue (agents: only their own)
+// POST /api/conversations/{id}/unassign
+func (h *AssignmentHandler) Unassign(w http.ResponseWriter, r *http.Request) {
+	h.apply(w, r, h.assignments.Unassign)
+}
+
+// decodeAssignTarget reads the assign / transfer body, writing a 400 when it is invalid
+func decodeAssignTarget(w http.ResponseWriter, r *http.Request) (services.AssignTarget, bool) {
+	var req AssignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu không hợp lệ"))
+		return services.AssignTarget{}, false
+	}
+	if (req.StaffID == nil) == (req.TeamID == nil) {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Cần chọn đúng một nhân viên hoặc một nhóm"))
+		return services.AssignTarget{}, false
+	}
+	return services.AssignTarget{StaffID: req.StaffID, TeamID: req.TeamID}, true
+}
+
+// apply runs an assignment operation on the conversation of the URL and writes the result
+func (h *AssignmentHandler) apply(w http.ResponseWriter, r *http.Request, action assignAction) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// URL format: /api/conversations/123/assign
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid URL format"))
+		return
+	}
+	conversationID, err := strconv.ParseInt(pathParts[3], 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid conversation ID"))
+		return
+	}
+
+	actor := AuthFromContext(r.Context())
+	assignment, err := action(r.Context(), actor, conversationID)
+	switch {
+	case errors.Is(err, services.ErrConversationNotFound):
+		writeJSON(w, http.StatusNotFound, NotFoundResponse("Không tìm thấy hội thoại"))
+		return
+	case errors.Is(err, services.ErrAssignNotAllowed):
+		writeJSON(w, http.StatusForbidden, NewErrorResponse(http.StatusForbidden, "Bạn chỉ có thể nhận hội thoại cho mình hoặc chuyển hội thoại đang được giao cho mình"))
+		return
+	case errors.Is(err, services.ErrInvalidAssignee):
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Nhân viên không tồn tại hoặc không thể nhận hội thoại"))
+		return
+	case errors.Is(err, services.ErrTeamNotFound):
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Không tìm thấy nhóm"))
+		return
+	case err != nil:
+		slog.Error("Failed to change conversation assignment",
+			"error", err,
+			"conversation_id", conversationID,
+			"staff_id", actor.StaffID,
+		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi phân công hội thoại"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(assignment))
+}
+
+// PresenceRequest represents the JSON payload for POST /api/presence
+type PresenceRequest struct {
+	Online *bool `json:"online,omitempty"` // Default true; false when the dashboard is closed
+}
+
+// Presence records the dashboard heartbeat (POST) or lists online staff IDs (GET)
+// Only online agents receive automatically routed conversations
+// POST /api/presence  Body: {"online": true} (optional)
+// GET  /api/presence
+func (h *AssignmentHandler) Presence(w http.ResponseWriter, r *http.Request) {
+	claims := AuthFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		online, err := h.assignments.OnlineStaff(r.Context(), claims.TenantID)
+		if err != nil {
+			slog.Error("Failed to get online staff", "error", err, "tenant_id", claims.TenantID)
+			writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tải trạng thái trực tuyến"))
+			return
+		}
+		if online == nil {
+			online = []int{}
+		}
+		writeJSON(w, http.StatusOK, NewSuccessResponse(map[string]interface{}{
+			"staff_ids": online,
+		}))
+
+	case http.MethodPost:
+		var req PresenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu không hợp lệ"))
+			return
+		}
+		online := req.Online == nil || *req.Online
+		if err := h.assignments.Heartbeat(r.Context(), claims.TenantID, claims.StaffID, online); err != nil {
+			slog.Error("Failed to record presence", "error", err, "staff_id", claims.StaffID)
+			writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi cập nhật trạng thái trực tuyến"))
+			return
+		}
+		writeJSON(w, http.StatusOK, NewSuccessResponse(map[string]interface{}{
+			"online": online,
+		}))
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,371 @@
+// Package handler implements HTTP request handlers for dashboard authentication
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/services"
+)
+
+// authContextKey is the request context key of the authenticated identity
+type authContextKey struct{}
+
+// AuthHandler handles staff login, token refresh and staff accounts
+type AuthHandler struct {
+	auth *services.AuthService
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(auth *services.AuthService) *AuthHandler {
+	return &AuthHandler{
+		auth: auth,
+	}
+}
+
+// RequireAuth rejects requests without a valid access token (Authorization: Bearer ...)
+// and puts the staff ID / tenant ID of the token into the request context
+func (h *AuthHandler) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, NewErrorResponse(http.StatusUnauthorized, "Vui lòng đăng nhập"))
+			return
+		}
+
+		claims, err := h.auth.VerifyAccessToken(token)
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, NewErrorResponse(http.StatusUnauthorized, "Phiên đăng nhập đã hết hạn. Vui lòng đăng nhập lại"))
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), authContextKey{}, claims)))
+	}
+}
+
+// Require is RequireAuth plus a role permission check (domain.Permission*)
+func (h *AuthHandler) Require(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return h.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		claims := AuthFromContext(r.Context())
+		if !domain.RoleHasPermission(claims.Role, permission) {
+			slog.Warn("Permission denied",
+				"staff_id", claims.StaffID,
+				"role", claims.Role,
+				"permission", permission,
+				"path", r.URL.Path,
+			)
+			writeJSON(w, http.StatusForbidden, NewErrorResponse(http.StatusForbidden, "Bạn không có quyền thực hiện thao tác này"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+// AuthFromContext returns the authenticated identity (empty claims outside RequireAuth)
+func AuthFromContext(ctx context.Context) *domain.AuthClaims {
+	if claims, ok := ctx.Value(authContextKey{}).(*domain.AuthClaims); ok {
+		return claims
+	}
+	return &domain.AuthClaims{}
+}
+
+// StaffIDFromContext returns the authenticated staff ID (0 outside RequireAuth)
+func StaffIDFromContext(ctx context.Context) int {
+	return AuthFromContext(ctx).StaffID
+}
+
+// TenantIDFromContext returns the tenant of the authenticated staff (0 outside RequireAuth)
+func TenantIDFromContext(ctx context.Context) int {
+	return AuthFromContext(ctx).TenantID
+}
+
+// ScopeFromContext returns the conversation data scope of the authenticated staff
+func ScopeFromContext(ctx context.Context) domain.ConversationScope {
+	return domain.ScopeForClaims(AuthFromContext(ctx))
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+// LoginRequest represents the JSON payload for POST /api/auth/login
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginResponse is returned by login: the token pair plus the logged-in staff
+type LoginResponse struct {
+	*domain.TokenPair
+	Staff *domain.Staff `json:"staff"`
+}
+
+// Login checks email / password and returns access + refresh tokens
+// POST /api/auth/login
+// Body: {"email": "agent@shop.vn", "password": "..."}
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, NewErrorResponse(http.StatusMethodNotAllowed, "Method Not Allowed"))
+		return
+	}
+
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu không hợp lệ"))
+		return
+	}
+	if strings.TrimSpace(req.Email) == "" || req.Password == "" {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Vui lòng nhập email và mật khẩu"))
+		return
+	}
+
+	pair, staff, err := h.auth.Login(r.Context(), req.Email, req.Password)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		slog.Warn("Failed login attempt", "remote_addr", r.RemoteAddr)
+		writeJSON(w, http.StatusUnauthorized, NewErrorResponse(http.StatusUnauthorized, "Email hoặc mật khẩu không đúng"))
+		return
+	}
+	if err != nil {
+		slog.Error("Login failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi đăng nhập"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(LoginResponse{TokenPair: pair, Staff: staff}))
+}
+
+// RefreshRequest represents the JSON payload for POST /api/auth/refresh and /api/auth/logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh exchanges a refresh token for a new token pair (the old refresh token is revoked)
+// POST /api/auth/refresh
+// Body: {"refresh_token": "..."}
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, NewErrorResponse(http.StatusMethodNotAllowed, "Method Not Allowed"))
+		return
+	}
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Thiếu refresh_token"))
+		return
+	}
+
+	pair, err := h.auth.Refresh(r.Context(), req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidToken) {
+		writeJSON(w, http.StatusUnauthorized, NewErrorResponse(http.StatusUnauthorized, "Phiên đăng nhập đã hết hạn. Vui lòng đăng nhập lại"))
+		return
+	}
+	if err != nil {
+		slog.Error("Token refresh failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi gia hạn phiên đăng nhập"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(pair))
+}
+
+// Logout revokes the refresh token
+// POST /api/auth/logout
+// Body: {"refresh_token": "..."}
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, NewErrorResponse(http.StatusMethodNotAllowed, "Method Not Allowed"))
+		return
+	}
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu không hợp lệ"))
+		return
+	}
+
+	if err := h.auth.Logout(r.Context(), req.RefreshToken); err != nil {
+		slog.Error("Logout failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi đăng xuất"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(nil))
+}
+
+// Me returns the logged-in staff account
+// GET /api/auth/me (requires auth)
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, NewErrorResponse(http.StatusMethodNotAllowed, "Method Not Allowed"))
+		return
+	}
+
+	staffID := StaffIDFromContext(r.Context())
+	staff, err := h.auth.GetStaff(r.Context(), staffID)
+	if err != nil {
+		slog.Error("Failed to get staff", "error", err, "staff_id", staffID)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tải tài khoản"))
+		return
+	}
+	if staff == nil || !staff.IsActive {
+		writeJSON(w, http.StatusUnauthorized, NewErrorResponse(http.StatusUnauthorized, "Tài khoản không còn hoạt động"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(staff))
+}
+
+// ListStaff returns the staff accounts of the caller's tenant
+// GET /api/staff (staff:read)
+func (h *AuthHandler) ListStaff(w http.ResponseWriter, r *http.Request) {
+	tenantID := TenantIDFromContext(r.Context())
+	staff, err := h.auth.ListStaff(r.Context(), tenantID)
+	if err != nil {
+		slog.Error("Failed to list staff", "error", err, "tenant_id", tenantID)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tải danh sách nhân viên"))
+		return
+	}
+	if staff == nil {
+		staff = []*domain.Staff{}
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(staff))
+}
+
+// CreateStaffRequest represents the JSON payload for POST /api/staff
+type CreateStaffRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Role     string `json:"role,omitempty"` // owner | admin | agent (default) | viewer
+	TeamID   *int   `json:"team_id,omitempty"`
+}
+
+// CreateStaff adds a staff account to the caller's tenant (counts against the plan's agent limit)
+// POST /api/staff (staff:manage)
+// Body: {"email": "agent@shop.vn", "name": "Lan", "password": "...", "role": "agent", "team_id": 2}
+func (h *AuthHandler) CreateStaff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, NewErrorResponse(http.StatusMethodNotAllowed, "Method Not Allowed"))
+		return
+	}
+
+	var req CreateStaffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu không hợp lệ"))
+		return
+	}
+	if !strings.Contains(req.Email, "@") {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Email không hợp lệ"))
+		return
+	}
+
+	claims := AuthFromContext(r.Context())
+	tenantID := claims.TenantID
+	staff, err := h.auth.CreateStaff(r.Context(), tenantID, claims.Role, services.NewStaff{
+		Email:    req.Email,
+		Name:     req.Name,
+		Password: req.Password,
+		Role:     req.Role,
+		TeamID:   req.TeamID,
+	})
+	switch {
+	case errors.Is(err, services.ErrInvalidRole):
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Vai trò không hợp lệ (owner, admin, agent, viewer)"))
+		return
+	case errors.Is(err, services.ErrRoleNotAllowed):
+		writeJSON(w, http.StatusForbidden, NewErrorResponse(http.StatusForbidden, "Chỉ chủ tài khoản mới có thể tạo chủ tài khoản khác"))
+		return
+	case errors.Is(err, services.ErrTeamNotFound):
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Không tìm thấy nhóm"))
+		return
+	case errors.Is(err, services.ErrWeakPassword):
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Mật khẩu phải có từ 8 đến 72 ký tự"))
+		return
+	case errors.Is(err, services.ErrEmailTaken):
+		writeJSON(w, http.StatusConflict, NewErrorResponse(http.StatusConflict, "Email đã được sử dụng"))
+		return
+	case errors.Is(err, services.ErrQuotaExceeded):
+		writeJSON(w, http.StatusPaymentRequired, NewErrorResponse(http.StatusPaymentRequired,
+			"Đã đạt số nhân viên tối đa của gói dịch vụ. Vui lòng nâng cấp gói để thêm nhân viên"))
+		return
+	case err != nil:
+		if status, resp, ok := quotaErrorResponse(err); ok {
+			writeJSON(w, status, resp)
+			return
+		}
+		slog.Error("Failed to create staff", "error", err, "tenant_id", tenantID)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tạo tài khoản"))
+		return
+	}
+
+	slog.Info("Staff account created",
+		"staff_id", staff.ID,
+		"tenant_id", tenantID,
+		"role", staff.Role,
+		"created_by", claims.StaffID,
+	)
+	writeJSON(w, http.StatusCreated, APIResponse{Code: http.StatusCreated, Message: "Success", Data: staff})
+}
+
+// ListTeams returns the teams of the caller's tenant
+// GET /api/teams (staff:read)
+func (h *AuthHandler) ListTeams(w http.ResponseWriter, r *http.Request) {
+	tenantID := TenantIDFromContext(r.Context())
+	teams, err := h.auth.ListTeams(r.Context(), tenantID)
+	if err != nil {
+		slog.Error("Failed to list teams", "error", err, "tenant_id", tenantID)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tải danh sách nhóm"))
+		return
+	}
+	if teams == nil {
+		teams = []*domain.Team{}
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(teams))
+}
+
+// CreateTeamRequest represents the JSON payload for POST /api/teams
+type CreateTeamRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateTeam adds a team to the caller's tenant
+// POST /api/teams (staff:manage)
+// Body: {"name": "CSKH miền Nam"}
+func (h *AuthHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	var req CreateTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu không hợp lệ"))
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Tên nhóm không được để trống"))
+		return
+	}
+
+	tenantID := TenantIDFromContext(r.Context())
+	team, err := h.auth.CreateTeam(r.Context(), tenantID, req.Name)
+	if errors.Is(err, services.ErrTeamExists) {
+		writeJSON(w, http.StatusConflict, NewErrorResponse(http.StatusConflict, "Tên nhóm đã tồn tại"))
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to create team", "error", err, "tenant_id", tenantID)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tạo nhóm"))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, APIResponse{Code: http.StatusCreated, Message: "Success", Data: team})
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+	"immortal-chat/internal/core/services"
+)
+
+// loginStaff serves a single staff account
+type loginStaff struct {
+	ports.StaffRepository
+	staff *domain.Staff
+}
+
+func (s loginStaff) GetStaffByEmail(ctx context.Context, email string) (*domain.Staff, error) {
+	return s.staff, nil
+}
+
+func (s loginStaff) UpdateLastLogin(ctx context.Context, staffID int) error {
+	return nil
+}
+
+// noopTokens accepts refresh tokens without keeping them
+type noopTokens struct{ ports.TokenStore }
+
+func (noopTokens) SaveRefreshToken(ctx context.Context, tokenID string, staffID int, ttl time.Duration) error {
+	return nil
+}
+
+// loginAs returns an access token of a staff with the given role
+func loginAs(t *testing.T, auth func(*domain.Staff) *services.AuthService, role string) string {
+	t.Helper()
+	hash, err := services.HashPassword("mat-khau-dai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	staff := &domain.Staff{ID: 5, TenantID: 1, Email: "agent@example.com", Role: role, PasswordHash: hash, IsActive: true}
+	pair, _, err := auth(staff).Login(context.Background(), staff.Email, "mat-khau-dai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair.AccessToken
+}
+
+func TestRequireChecksTokenAndPermission(t *testing.T) {
+	config := services.AuthConfig{Secret: []byte("test-secret")}
+	newAuth := func(staff *domain.Staff) *services.AuthService {
+		return services.NewAuthService(loginStaff{staff: staff}, noopTokens{}, nil, config)
+	}
+	agentToken := loginAs(t, newAuth, domain.RoleAgent)
+	viewerToken := loginAs(t, newAuth, domain.RoleViewer)
+
+	h := NewAuthHandler(newAuth(nil))
+	for name, tc := range map[string]struct {
+		authorization string
+		permission    string
+		want          int
+	}{
+		"no header":             {"", domain.PermissionReply, http.StatusUnauthorized},
+		"not a bearer token":    {"Basic " + agentToken, domain.PermissionReply, http.StatusUnauthorized},
+		"tampered token":        {"Bearer " + agentToken + "x", domain.PermissionReply, http.StatusUnauthorized},
+		"agent replies":         {"Bearer " + agentToken, domain.PermissionReply, http.StatusOK},
+		"agent manages staff":   {"Bearer " + agentToken, domain.PermissionManageStaff, http.StatusForbidden},
+		"viewer replies":        {"Bearer " + viewerToken, domain.PermissionReply, http.StatusForbidden},
+		"viewer reads":          {"bearer " + viewerToken, domain.PermissionViewConversations, http.StatusOK},
+		"viewer changes status": {"Bearer " + viewerToken, domain.PermissionChangeStatus, http.StatusForbidden},
+	} {
+		var scope domain.ConversationScope
+		next := func(w http.ResponseWriter, r *http.Request) {
+			scope = ScopeFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/api/conversations", nil)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		rec := httptest.NewRecorder()
+		h.Require(tc.permission, next)(rec, req)
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", name, rec.Code, tc.want)
+			continue
+		}
+		if rec.Code == http.StatusOK && (scope.TenantID != 1 || scope.StaffID != 5) {
+			t.Errorf("%s: scope = %+v", name, scope)
+		}
+	}
+}
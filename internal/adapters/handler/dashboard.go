@@ -2,13 +2,15 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"immortal-chat/internal/adapters/gateway"
 	"immortal-chat/internal/adapters/repository"
 	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+	"immortal-chat/internal/core/services"
 	"log/slog"
 	"net/http"
 	"runtime"
@@ -24,15 +26,23 @@ import (
 
 // DashboardHandler handles dashboard API requests
 type DashboardHandler struct {
-	db    *sql.DB
-	redis *redis.Client
+	db        *sql.DB
+	redis     *redis.Client
+	platforms *services.PlatformRegistry // Outbound adapters keyed by pages.platform
+	quota     *services.QuotaService     // Plan limits / expiry for outbound replies
+	media     *services.MediaService     // Uploaded attachments for outbound replies
+	outbound  *services.OutboundService  // Queue that sends the replies
 }
 
 // NewDashboardHandler creates a new dashboard handler instance
-func NewDashboardHandler(db *sql.DB, rdb *redis.Client) *DashboardHandler {
+func NewDashboardHandler(db *sql.DB, rdb *redis.Client, platforms *services.PlatformRegistry, quota *services.QuotaService, media *services.MediaService, outbound *services.OutboundService) *DashboardHandler {
 	return &DashboardHandler{
-		db:    db,
-		redis: rdb,
+		db:        db,
+		redis:     rdb,
+		platforms: platforms,
+		quota:     quota,
+		media:     media,
+		outbound:  outbound,
 	}
 }
 
@@ -137,6 +147,7 @@ type SystemStatusResponse struct {
 	ActiveConnections  int    `json:"active_connections"`
 	Version            string `json:"version"`
 	TenantID           int    `json:"tenant_id"`
+	StaffID            int    `json:"staff_id"`
 	StaffRole          string `json:"staff_role"`
 	DataScope          string `json:"data_scope"`
 }
@@ -152,14 +163,16 @@ func (h *DashboardHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 	// Count active goroutines as a proxy for connections
 	activeConnections := runtime.NumGoroutine()
 	
+	claims := AuthFromContext(r.Context())
 	response := SystemStatusResponse{
 		Online:            true,
 		Uptime:            uptimeStr,
 		ActiveConnections: activeConnections,
 		Version:           "2.0.0",
-		TenantID:          1, // TODO: Get from auth context
-		StaffRole:         "admin",
-		DataScope:         "global",
+		TenantID:          claims.TenantID,
+		StaffID:           claims.StaffID,
+		StaffRole:         claims.Role,
+		DataScope:         domain.DataScopeForRole(claims.Role),
 	}
 	
 	writeJSON(w, http.StatusOK, response)
@@ -183,57 +196,88 @@ type PlatformResponse struct {
 	PendingSync      int       `json:"pending_sync"`
 }
 
+// platformCatalog lists the channels shown on the dashboard (stable IDs for the UI)
+var platformCatalog = []struct {
+	ID       int
+	Name     string
+	Platform string
+}{
+	{1, "Facebook Messenger", domain.PlatformFacebook},
+	{2, "Zalo", domain.PlatformZalo},
+	{3, "Telegram", domain.PlatformTelegram},
+	{4, "Instagram Direct", domain.PlatformInstagram},
+}
+
+// platformLabel returns the human-readable channel name for user-facing messages
+func platformLabel(platform string) string {
+	switch platform {
+	case domain.PlatformZalo:
+		return "Zalo"
+	case domain.PlatformTelegram:
+		return "Telegram"
+	case domain.PlatformInstagram:
+		return "Instagram"
+	default:
+		return "Facebook"
+	}
+}
+
 // GetPlatforms returns list of all platforms
 // GET /api/platforms
+// A platform is "offline" unless its adapter is registered and it has an active page;
+// otherwise status is derived from the last inbound activity on that platform
 func (h *DashboardHandler) GetPlatforms(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	
-	// Query messages for activity
-	query := `
-		SELECT 
-			COUNT(*) as total_today,
-			MAX(created_at) as  last_activity
-		FROM messages
-		WHERE DATE(created_at) = CURDATE()
-	`
-	
-	var totalToday int
-	var lastActivity sql.NullTime
-	err := h.db.QueryRowContext(ctx, query).Scan(&totalToday, &lastActivity)
-	if err != nil && err != sql.ErrNoRows {
-		slog.Error("Failed to query message stats", "error", err)
-	}
-	
-	// Count pending sync
-	var pendingSync int
-	h.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM messages WHERE is_synced = FALSE").Scan(&pendingSync)
-	
-	// Hardcoded platforms for now (TODO: Get from pages table)
-	platforms := []PlatformResponse{
-		{
-			ID:                1,
-			Name:              "Facebook Messenger",
-			Platform:          "facebook",
-			Status:            determineStatus(lastActivity),
-			Icon:              "facebook",
-			LastActivity:      getTimeOrNow(lastActivity),
-			MessageCountToday: totalToday,
-			PendingSync:       pendingSync,
-		},
-		{
-			ID:       2,
-			Name:     "Zalo",
-			Platform: "zalo",
-			Status:   "offline",
-			Icon:     "zalo",
-		},
-		{
-			ID:       3,
-			Name:     "Telegram",
-			Platform: "telegram",
+	tenantID := TenantIDFromContext(ctx)
+	
+	platforms := make([]PlatformResponse, 0, len(platformCatalog))
+	for _, p := range platformCatalog {
+		resp := PlatformResponse{
+			ID:       p.ID,
+			Name:     p.Name,
+			Platform: p.Platform,
 			Status:   "offline",
-			Icon:     "telegram",
-		},
+			Icon:     p.Platform,
+		}
+		
+		// Query messages for activity
+		query := `
+			SELECT 
+				COUNT(*) as total_today,
+				MAX(m.created_at) as last_activity
+			FROM messages m
+			JOIN conversations c ON c.id = m.conversation_id
+			WHERE c.tenant_id = ? AND c.platform = ? AND DATE(m.created_at) = CURDATE()
+		`
+		
+		var totalToday int
+		var lastActivity sql.NullTime
+		err := h.db.QueryRowContext(ctx, query, tenantID, p.Platform).Scan(&totalToday, &lastActivity)
+		if err != nil && err != sql.ErrNoRows {
+			slog.Error("Failed to query message stats", "error", err, "platform", p.Platform)
+		}
+		
+		// Count pending sync
+		var pendingSync int
+		h.db.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM messages m
+			JOIN conversations c ON c.id = m.conversation_id
+			WHERE c.tenant_id = ? AND c.platform = ? AND m.is_synced = FALSE
+		`, tenantID, p.Platform).Scan(&pendingSync)
+		
+		// Connected pages / OAs of the tenant
+		var activePages int
+		h.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pages WHERE tenant_id = ? AND platform = ? AND is_active = TRUE", tenantID, p.Platform).Scan(&activePages)
+		
+		resp.LastActivity = getTimeOrNow(lastActivity)
+		resp.MessageCountToday = totalToday
+		resp.PendingSync = pendingSync
+		
+		if _, err := h.platforms.Get(p.Platform); err == nil && activePages > 0 {
+			resp.Status = determineStatus(lastActivity)
+		}
+		
+		platforms = append(platforms, resp)
 	}
 	
 	writeJSON(w, http.StatusOK, platforms)
@@ -246,7 +290,6 @@ func (h *DashboardHandler) GetPlatforms(w http.ResponseWriter, r *http.Request)
 // SyncStatusResponse represents federated sync health
 type SyncStatusResponse struct {
 	PendingMessages     int       `json:"pending_messages"`
-	PendingWebhooks     int       `json:"pending_webhooks"`
 	LastSyncAt          time.Time `json:"last_sync_at"`
 	SyncLagSeconds      int       `json:"sync_lag_seconds"`
 	HomeServerReachable bool      `json:"home_server_reachable"`
@@ -255,16 +298,18 @@ type SyncStatusResponse struct {
 
 // GetSyncStatus returns sync status
 // GET /api/sync/status
+// Tenant-scoped: webhook_logs rows carry no tenant, so webhook queue health is
+// not reported here (GET /api/admin/webhooks/status)
 func (h *DashboardHandler) GetSyncStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	
 	// Count pending messages
 	var pendingMessages int
-	h.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM messages WHERE is_synced = FALSE").Scan(&pendingMessages)
-	
-	// Count pending webhooks
-	var pendingWebhooks int
-	h.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_logs WHERE status = 'pending'").Scan(&pendingWebhooks)
+	h.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE c.tenant_id = ? AND m.is_synced = FALSE
+	`, TenantIDFromContext(ctx)).Scan(&pendingMessages)
 	
 	// For now, simulate last sync (TODO: Implement actual federated sync)
 	lastSyncAt := time.Now().Add(-2 * time.Minute)
@@ -283,7 +328,6 @@ func (h *DashboardHandler) GetSyncStatus(w http.ResponseWriter, r *http.Request)
 	
 	response := SyncStatusResponse{
 		PendingMessages:     pendingMessages,
-		PendingWebhooks:     pendingWebhooks,
 		LastSyncAt:          lastSyncAt,
 		SyncLagSeconds:      syncLagSeconds,
 		HomeServerReachable: true, // TODO: Implement ping check
@@ -346,39 +390,71 @@ func getTimeOrNow(t sql.NullTime) time.Time {
 // Phase 3: Conversation Management & Reply APIs
 // ============================================================================
 
-// GetConversations returns list of conversations for a specific page
-// GET /api/conversations?page_id=xxx
+// GetConversations returns the conversations the logged-in staff may see
+// (whole tenant, or only assigned ones for agents)
+// GET /api/conversations?page_id=xxx&assigned=me|unassigned&tag=VIP&status=open (all filters optional)
+// status: open (unread + read), unread, read, snoozed, resolved or archived; default: all
+// Pagination: ?limit=50 (max 100); paging.next -> ?before=<cursor> (older), paging.prev -> ?after=<cursor> (newer)
 func (h *DashboardHandler) GetConversations(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	scope := ScopeFromContext(ctx)
 	
-	// Get page_id from query params
-	pageID := r.URL.Query().Get("page_id")
-	if pageID == "" {
-		// If no page_id provided, use default (first active page)
-		// In production, this should come from auth context
-		pageID = "770225079500025" // TODO: Get from logged-in user's context
+	// Optional filters
+	filter := domain.ConversationFilter{
+		PageID: r.URL.Query().Get("page_id"),
+		Tag:    r.URL.Query().Get("tag"),
+	}
+	switch r.URL.Query().Get("assigned") {
+	case "":
+	case "me":
+		filter.AssigneeID = &scope.StaffID
+	case "unassigned":
+		filter.Unassigned = true
+	default:
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("assigned chỉ nhận me hoặc unassigned"))
+		return
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		statuses, ok := domain.ConversationStatusesFor(status)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, BadRequestResponse("status chỉ nhận open, unread, read, snoozed, resolved hoặc archived"))
+			return
+		}
+		filter.Statuses = statuses
+	}
+	page, err := parsePageQuery(r, repository.MaxConversationPageSize)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Tham số phân trang không hợp lệ"))
+		return
 	}
 	
 	// Call repository
 	repo := h.db
 	mariadbRepo := repository.NewMariaDBRepository(repo)
-	conversations, err := mariadbRepo.GetConversations(ctx, pageID)
+	conversations, cursors, err := mariadbRepo.GetConversations(ctx, scope, filter, page)
 	
 	if err != nil {
 		slog.Error("Failed to get conversations",
 			"error", err,
-			"page_id", pageID,
+			"tenant_id", scope.TenantID,
+			"page_id", filter.PageID,
 		)
 		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Failed to load conversations"))
 		return
 	}
 	
 	// Return with Response Envelope
-	writeJSON(w, http.StatusOK, NewSuccessResponse(conversations))
+	if conversations == nil {
+		conversations = []repository.ConversationWithSnippet{}
+	}
+	writeJSON(w, http.StatusOK, NewPagedResponse(conversations, newPaging(cursors)))
 }
 
 // GetConversationMessages returns message history for a conversation
-// GET /api/conversations/{id}/messages
+// GET /api/conversations/{id}/messages?limit=100 (max 500), most recent page first
+// Pagination: paging.prev -> ?before=<cursor> (older), paging.next -> ?after=<cursor> (newer)
+// Outbound messages carry delivery_status: "queued" | "sent" | "delivered" | "read" | "failed"
+// (failed ones with delivery_error: "token_expired", "outside_window", "send_failed", ...)
 // Enhancement: Auto-marks conversation as read when Admin opens chat
 func (h *DashboardHandler) GetConversationMessages(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -398,11 +474,32 @@ func (h *DashboardHandler) GetConversationMessages(w http.ResponseWriter, r *htt
 		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid conversation ID"))
 		return
 	}
+	page, err := parsePageQuery(r, repository.MaxMessagePageSize)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Tham số phân trang không hợp lệ"))
+		return
+	}
 	
 	// Call repository
 	repo := h.db
 	mariadbRepo := repository.NewMariaDBRepository(repo)
-	messages, err := mariadbRepo.GetMessages(ctx, conversationID)
+	
+	// Conversations outside the staff's data scope (other tenant, not assigned) look like missing ones
+	allowed, err := mariadbRepo.CanAccessConversation(ctx, conversationID, ScopeFromContext(ctx))
+	if err != nil {
+		slog.Error("Failed to check conversation access",
+			"error", err,
+			"conversation_id", conversationID,
+		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Failed to load messages"))
+		return
+	}
+	if !allowed {
+		writeJSON(w, http.StatusNotFound, NotFoundResponse("Không tìm thấy hội thoại"))
+		return
+	}
+	
+	messages, cursors, err := mariadbRepo.GetMessages(ctx, conversationID, page)
 	
 	if err != nil {
 		slog.Error("Failed to get messages",
@@ -424,23 +521,86 @@ func (h *DashboardHandler) GetConversationMessages(w http.ResponseWriter, r *htt
 	}
 	
 	// Return with Response Envelope
-	writeJSON(w, http.StatusOK, NewSuccessResponse(messages))
+	if messages == nil {
+		messages = []*domain.Message{}
+	}
+	writeJSON(w, http.StatusOK, NewPagedResponse(messages, newPaging(cursors)))
+}
+
+// GetMessagingWindow tells whether a standard reply can still be sent to the customer
+// GET /api/conversations/{id}/window
+// Messenger / Instagram: open until 24 hours after the customer's last message, then
+// allowed_tags lists the message tags usable for POST /api/messages/reply
+func (h *DashboardHandler) GetMessagingWindow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	
+	// URL format: /api/conversations/123/window
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 4 {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid URL format"))
+		return
+	}
+	conversationID, err := strconv.ParseInt(pathParts[3], 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid conversation ID"))
+		return
+	}
+	
+	mariadbRepo := repository.NewMariaDBRepository(h.db)
+	var platform string
+	err = h.db.QueryRowContext(ctx, `SELECT platform FROM conversations WHERE id = ?`, conversationID).Scan(&platform)
+	if err == nil {
+		allowed, accessErr := mariadbRepo.CanAccessConversation(ctx, conversationID, ScopeFromContext(ctx))
+		if accessErr != nil {
+			err = accessErr
+		} else if !allowed {
+			err = sql.ErrNoRows
+		}
+	}
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, NotFoundResponse("Không tìm thấy hội thoại"))
+		return
+	}
+	
+	var lastInboundAt *time.Time
+	if err == nil {
+		lastInboundAt, err = mariadbRepo.GetLastInboundAt(ctx, conversationID)
+	}
+	if err != nil {
+		slog.Error("Failed to get messaging window",
+			"error", err,
+			"conversation_id", conversationID,
+		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi kiểm tra khung 24 giờ"))
+		return
+	}
+	
+	writeJSON(w, http.StatusOK, NewSuccessResponse(services.MessagingWindowAt(platform, lastInboundAt, time.Now())))
 }
 
 // ReplyRequest represents the JSON payload for POST /api/messages/reply
 type ReplyRequest struct {
 	ConversationID int64  `json:"conversation_id"`
 	Text           string `json:"text"`
+	MediaID        string `json:"media_id,omitempty"`    // From POST /api/media, sent before the text
+	MessageTag     string `json:"message_tag,omitempty"` // Messenger tag, required after the 24-hour window
 }
 
-// SendReply handles admin replies to customers via Facebook
+// SendReply handles admin replies to customers via the conversation's platform
 // POST /api/messages/reply
 // Body: {"conversation_id": 123, "text": "Hello!"}
+// or:   {"conversation_id": 123, "media_id": "9f86d0...", "text": "Caption (optional)"}
+// A media reply is sent as an attachment message, followed by the text as a second message
+// Messenger / Instagram: 24 hours after the customer's last message a standard reply is
+// refused with 409 (data = messaging window); resend with "message_tag": "HUMAN_AGENT", ...
 // 
-// ENHANCEMENTS:
-// - Auto-deactivates page on token expiry (ErrTokenExpired)
-// - User-friendly error messages (not technical details)
-// - Proper error recovery per "Core hệ thống lỗi"
+// The messages are stored as "queued" and returned right away; the outbound queue sends
+// them within the page's rate limit and pushes "sent" / "failed" (with delivery_error)
+// as message_updated events. A page whose token expired is deactivated there
 func (h *DashboardHandler) SendReply(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	
@@ -457,135 +617,370 @@ func (h *DashboardHandler) SendReply(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	if strings.TrimSpace(req.Text) == "" {
+	if strings.TrimSpace(req.Text) == "" && req.MediaID == "" {
 		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Nội dung tin nhắn không được để trống"))
 		return
 	}
 	
-	// Step 1: Get conversation details to find page_id and platform_id
+	// Steps 1-4: Conversation, tenant plan, page token, adapter and messaging window
 	mariadbRepo := repository.NewMariaDBRepository(h.db)
+	channel, ok := h.openReplyChannel(ctx, w, mariadbRepo, req.ConversationID, req.MessageTag)
+	if !ok {
+		return
+	}
+	platform, tenantID := channel.platform, channel.tenantID
+	
+	// Replies are attributed to the logged-in staff (messages.sender_id)
+	staffID := strconv.Itoa(StaffIDFromContext(ctx))
+	
+	// Step 5a: Queue the media first (its own message), then the text
+	// The outbound queue sends them in order and pushes the result as message_updated
+	lastMessage := req.Text
+	var queued []*domain.Message
+	if req.MediaID != "" {
+		media, err := h.media.GetMedia(ctx, tenantID, req.MediaID)
+		if errors.Is(err, services.ErrMediaNotFound) {
+			writeJSON(w, http.StatusBadRequest, BadRequestResponse("Không tìm thấy tệp đính kèm"))
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to get media", "error", err, "media_id", req.MediaID)
+			writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tra cứu tệp đính kèm"))
+			return
+		}
+		if _, ok := channel.adapter.(ports.AttachmentSender); !ok {
+			writeJSON(w, http.StatusBadRequest, BadRequestResponse(
+				fmt.Sprintf("%s chưa hỗ trợ gửi tệp đính kèm", platformLabel(platform)),
+			))
+			return
+		}
+		
+		attachments, _ := json.Marshal([]map[string]interface{}{{
+			"type": media.Type,
+			"payload": map[string]string{
+				"url":       media.URL,
+				"media_id":  media.ID,
+				"file_name": media.FileName,
+			},
+		}})
+		mediaMsg := &domain.Message{
+			ConversationID: req.ConversationID,
+			SenderID:       &staffID,
+			SenderType:     domain.SenderTypeAgent,
+			Content:        &media.URL, // Like inbound attachments: content = attachment URL
+			Attachments:    attachments,
+			Type:           &media.Type,
+			MessageTag:     channel.messageTag(),
+		}
+		job := channel.job()
+		job.MediaID = media.ID
+		if !h.enqueue(ctx, w, mediaMsg, job) {
+			return
+		}
+		queued = append(queued, mediaMsg)
+		if strings.TrimSpace(req.Text) == "" {
+			lastMessage = "📎 " + media.FileName
+		}
+	}
 	
-	var platformID, pageID string
-	query := `SELECT platform_id, page_id FROM conversations WHERE id = ?`
-	err := h.db.QueryRowContext(ctx, query, req.ConversationID).Scan(&platformID, &pageID)
+	// Step 5b: Text
+	if strings.TrimSpace(req.Text) != "" {
+		textMsg := &domain.Message{
+			ConversationID: req.ConversationID,
+			SenderID:       &staffID,
+			SenderType:     domain.SenderTypeAgent,
+			Content:        &req.Text,
+			MessageTag:     channel.messageTag(),
+		}
+		job := channel.job()
+		job.Text = req.Text
+		if !h.enqueue(ctx, w, textMsg, job) {
+			return
+		}
+		queued = append(queued, textMsg)
+	}
+	
+	// Step 6: Update conversation's last message
+	if err := mariadbRepo.UpdateConversationLastMessage(ctx, req.ConversationID, lastMessage); err != nil {
+		slog.Warn("Failed to update conversation last message",
+			"error", err,
+		)
+	}
+	
+	// Return the queued messages; their final status arrives over the WebSocket
+	writeJSON(w, http.StatusOK, NewSuccessResponse(map[string]interface{}{
+		"status":          domain.DeliveryStatusQueued,
+		"conversation_id": req.ConversationID,
+		"message":         "Tin nhắn đang được gửi",
+		"messages":        queued,
+	}))
+}
+
+// StructuredReplyRequest represents the JSON payload for POST /api/messages/structured
+type StructuredReplyRequest struct {
+	ConversationID int64                    `json:"conversation_id"`
+	Message        domain.StructuredMessage `json:"message"`
+	MessageTag     string                   `json:"message_tag,omitempty"` // As for POST /api/messages/reply
+}
+
+// SendStructured sends quick reply chips or a button / generic / media template (Messenger)
+// POST /api/messages/structured
+// Body: {"conversation_id": 123, "message": {"type": "quick_replies", "text": "Chọn size?", "quick_replies": [{"content_type": "text", "title": "M", "payload": "SIZE_M"}]}}
+// or:   {"conversation_id": 123, "message": {"type": "button", "text": "Đơn #1234", "buttons": [{"type": "web_url", "title": "Xem đơn", "url": "https://..."}]}}
+// or:   {"conversation_id": 123, "message": {"type": "generic", "elements": [{"title": "Áo thun", "subtitle": "199.000đ", "image_url": "https://...", "buttons": [...]}]}}
+// or:   {"conversation_id": 123, "message": {"type": "media", "media": {"media_type": "image", "attachment_id": "...", "buttons": [...]}}}
+// Messenger limits apply (13 quick replies, 3 buttons, 10 cards, 20 character titles, ...);
+// taps come back as messages carrying the payload (quick replies) or as postbacks (buttons)
+// The 24-hour window applies as for POST /api/messages/reply
+// (messages:reply)
+func (h *DashboardHandler) SendStructured(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	
+	var req StructuredReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu không hợp lệ"))
+		return
+	}
+	if req.ConversationID == 0 {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Thiếu ID hội thoại"))
+		return
+	}
+	if err := services.ValidateStructuredMessage(req.Message); err != nil {
+		reason := strings.TrimPrefix(err.Error(), services.ErrInvalidStructuredMessage.Error()+": ")
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Tin nhắn không hợp lệ: "+reason))
+		return
+	}
+	
+	mariadbRepo := repository.NewMariaDBRepository(h.db)
+	channel, ok := h.openReplyChannel(ctx, w, mariadbRepo, req.ConversationID, req.MessageTag)
+	if !ok {
+		return
+	}
+	if _, ok := channel.adapter.(ports.StructuredSender); !ok {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse(
+			fmt.Sprintf("%s chưa hỗ trợ tin nhắn có nút bấm / mẫu", platformLabel(channel.platform)),
+		))
+		return
+	}
+	
+	// Stored like an attachment so the dashboard can draw the chips / cards
+	staffID := strconv.Itoa(StaffIDFromContext(ctx))
+	preview := services.StructuredPreview(req.Message)
+	messageType := domain.MessageTypeTemplate
+	if req.Message.Type == domain.StructuredTypeQuickReplies {
+		messageType = domain.MessageTypeText
+	}
+	attachments, _ := json.Marshal([]map[string]interface{}{{
+		"type":    "structured",
+		"payload": req.Message,
+	}})
+	msg := &domain.Message{
+		ConversationID: req.ConversationID,
+		SenderID:       &staffID,
+		SenderType:     domain.SenderTypeAgent,
+		Content:        &preview,
+		Attachments:    attachments,
+		Type:           &messageType,
+		MessageTag:     channel.messageTag(),
+	}
+	job := channel.job()
+	job.Structured = &req.Message
+	if !h.enqueue(ctx, w, msg, job) {
+		return
+	}
+	
+	if err := mariadbRepo.UpdateConversationLastMessage(ctx, req.ConversationID, preview); err != nil {
+		slog.Warn("Failed to update conversation last message",
+			"error", err,
+		)
+	}
+	
+	writeJSON(w, http.StatusOK, NewSuccessResponse(map[string]interface{}{
+		"status":          domain.DeliveryStatusQueued,
+		"conversation_id": req.ConversationID,
+		"message":         "Tin nhắn đang được gửi",
+		"messages":        []*domain.Message{msg},
+	}))
+}
+
+// replyChannel is where replies to a conversation go
+type replyChannel struct {
+	tenantID int
+	platform string
+	pageID   string
+	target   domain.OutboundTarget // target.MessageTag is set outside the 24-hour window
+	adapter  ports.PlatformAdapter
+}
+
+// messageTag returns the tag stored with the sent messages (nil for a standard reply)
+func (c *replyChannel) messageTag() *string {
+	if c.target.MessageTag == "" {
+		return nil
+	}
+	return &c.target.MessageTag
+}
+
+// job returns the send job of a reply through this channel (content still to be set)
+func (c *replyChannel) job() *domain.OutboundJob {
+	return &domain.OutboundJob{
+		TenantID:    c.tenantID,
+		Platform:    c.platform,
+		PageID:      c.pageID,
+		RecipientID: c.target.RecipientID,
+		MessageTag:  c.target.MessageTag,
+	}
+}
+
+// openReplyChannel looks up a conversation within the staff's data scope, checks the
+// tenant's plan and the page token, picks the platform adapter and checks the
+// messaging window (messageTag is the tag requested for sending outside it)
+// Returns false after writing the error response
+func (h *DashboardHandler) openReplyChannel(ctx context.Context, w http.ResponseWriter, mariadbRepo *repository.MariaDBRepository, conversationID int64, messageTag string) (*replyChannel, bool) {
+	// Step 1: Get conversation details to find page_id, platform_id and platform
+	var platformID, pageID, platform string
+	var tenantID int
+	query := `
+		SELECT c.platform_id, c.page_id, c.platform, c.tenant_id
+		FROM conversations c
+		WHERE c.id = ?
+		LIMIT 1
+	`
+	err := h.db.QueryRowContext(ctx, query, conversationID).Scan(&platformID, &pageID, &platform, &tenantID)
+	
+	// Conversations outside the staff's data scope (other tenant, not assigned) look like missing ones
+	if err == nil {
+		allowed, accessErr := mariadbRepo.CanAccessConversation(ctx, conversationID, ScopeFromContext(ctx))
+		if accessErr != nil {
+			err = accessErr
+		} else if !allowed {
+			err = sql.ErrNoRows
+		}
+	}
 	
 	if err == sql.ErrNoRows {
 		writeJSON(w, http.StatusNotFound, NotFoundResponse("Không tìm thấy hội thoại"))
-		return
+		return nil, false
 	}
 	
 	if err != nil {
 		slog.Error("Failed to get conversation details",
 			"error", err,
-			"conversation_id", req.ConversationID,
+			"conversation_id", conversationID,
 		)
 		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tra cứu hội thoại"))
-		return
+		return nil, false
+	}
+	
+	// Step 1b: Expired tenants / exhausted plans cannot reply (inbound is still stored)
+	if err := h.quota.CheckOutbound(ctx, tenantID); err != nil {
+		if status, resp, ok := quotaErrorResponse(err); ok {
+			slog.Warn("Reply blocked by tenant plan",
+				"error", err,
+				"tenant_id", tenantID,
+				"conversation_id", conversationID,
+			)
+			writeJSON(w, status, resp)
+			return nil, false
+		}
+		slog.Error("Failed to check tenant quota",
+			"error", err,
+			"tenant_id", tenantID,
+		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi kiểm tra gói dịch vụ"))
+		return nil, false
 	}
 	
 	// Step 2: Get page access token from database
-	accessToken, err := mariadbRepo.GetPageAccessToken(ctx, pageID)
+	accessToken, err := mariadbRepo.GetPageAccessToken(ctx, platform, pageID)
 	if err != nil {
 		slog.Error("Failed to get page access token",
 			"error", err,
+			"platform", platform,
 			"page_id", pageID,
 		)
 		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi cấu hình Fanpage. Vui lòng liên hệ quản trị viên"))
-		return
+		return nil, false
 	}
 	
-	// Step 3: Send message via Facebook API
-	fbClient := gateway.NewFacebookClient()
-	err = fbClient.SendReply(platformID, accessToken, req.Text)
-	
+	// Step 3: Platform adapter that sends the reply
+	adapter, err := h.platforms.Get(platform)
 	if err != nil {
-		// CRITICAL: Handle token death per "Core hệ thống lỗi"
-		if errors.Is(err, gateway.ErrTokenExpired) {
-			// Auto-deactivate page to prevent futile retries
-			if deactivateErr := mariadbRepo.DeactivatePage(ctx, pageID); deactivateErr != nil {
-				slog.Error("Failed to deactivate page after token expiry",
-					"error", deactivateErr,
-					"page_id", pageID,
-				)
-			}
-			
-			slog.Warn("🔴 PAGE AUTO-DEACTIVATED",
-				"page_id", pageID,
-				"conversation_id", req.ConversationID,
-				"reason", "Token expired",
-			)
-			
-			// Return user-friendly message
-			writeJSON(w, http.StatusBadRequest, BadRequestResponse(
-				"Fanpage đã mất kết nối với Facebook. Vui lòng kết nối lại trong phần Cài đặt",
-			))
-			return
-		}
-		
-		// Handle rate limiting
-		if errors.Is(err, gateway.ErrRateLimited) {
-			writeJSON(w, http.StatusTooManyRequests, APIResponse{
-				Code:    429,
-				Message: "Bạn đang gửi tin quá nhanh. Vui lòng chờ vài giây rồi thử lại",
-				Data:    nil,
-			})
-			return
-		}
-		
-		// Handle permission errors
-		if errors.Is(err, gateway.ErrPermissionDenied) {
-			writeJSON(w, http.StatusForbidden, APIResponse{
-				Code:    403,
-				Message: "Fanpage không có quyền gửi tin nhắn. Vui lòng kiểm tra cài đặt Facebook",
-				Data:    nil,
-			})
-			return
-		}
-		
-		// Generic Facebook error (network, timeout, etc.)
-		slog.Error("Failed to send message via Facebook",
-			"error", err,
-			"conversation_id", req.ConversationID,
+		slog.Error("No adapter for conversation platform",
+			"platform", platform,
+			"conversation_id", conversationID,
 		)
-		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse(
-			"Không thể gửi tin nhắn. Vui lòng thử lại sau",
-		))
-		return
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Nền tảng của hội thoại chưa được hỗ trợ gửi tin"))
+		return nil, false
 	}
 	
-	// Step 4: Save outbound message to database
-	// TODO: Get staff_id from JWT context instead of hardcoding
-	outboundMsg := &domain.Message{
-		ConversationID: req.ConversationID,
-		SenderID:       ptr("admin"), // TODO: Replace with actual staff_id from auth
-		SenderType:     domain.SenderTypeAgent,
-		Content:        &req.Text,
-	}
-	
-	if err := mariadbRepo.SaveOutboundMessage(ctx, outboundMsg); err != nil {
-		// Log error but don't fail the request (message was already sent to Facebook)
-		slog.Warn("Failed to save outbound message to DB",
+	// Step 4: Messenger / Instagram 24-hour window, outside it only with an allowed tag
+	lastInboundAt, err := mariadbRepo.GetLastInboundAt(ctx, conversationID)
+	if err != nil {
+		slog.Error("Failed to get last inbound message time",
 			"error", err,
-			"conversation_id", req.ConversationID,
+			"conversation_id", conversationID,
 		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi kiểm tra khung 24 giờ"))
+		return nil, false
+	}
+	window := services.MessagingWindowAt(platform, lastInboundAt, time.Now())
+	tag, err := services.ResolveMessageTag(window, messageTag)
+	if err != nil {
+		writeJSON(w, http.StatusConflict, messagingWindowResponse(err, window))
+		return nil, false
 	}
 	
-	// Step 5: Update conversation's last message
-	if err := mariadbRepo.UpdateConversationLastMessage(ctx, req.ConversationID, req.Text); err != nil {
-		slog.Warn("Failed to update conversation last message",
-			"error", err,
-		)
+	target := domain.OutboundTarget{
+		PageID:      pageID,
+		RecipientID: platformID,
+		AccessToken: accessToken,
+		MessageTag:  tag,
 	}
 	
-	// Return success with user-friendly message
-	writeJSON(w, http.StatusOK, NewSuccessResponse(map[string]interface{}{
-		"status":          "sent",
-		"conversation_id": req.ConversationID,
-		"message":         "Tin nhắn đã được gửi thành công",
-	}))
+	return &replyChannel{
+		tenantID: tenantID,
+		platform: platform,
+		pageID:   pageID,
+		target:   target,
+		adapter:  adapter,
+	}, true
+}
+
+// messagingWindowResponse explains a reply refused by the messaging window; data is
+// the window so the dashboard can offer the allowed tags
+func messagingWindowResponse(err error, window domain.MessagingWindow) APIResponse {
+	message := "Đã quá 24 giờ kể từ tin nhắn cuối của khách. Vui lòng chọn thẻ tin nhắn (MESSAGE_TAG) phù hợp để gửi"
+	switch {
+	case len(window.AllowedTags) == 0:
+		message = "Đã hết thời hạn nhắn tin với khách. Vui lòng chờ khách nhắn lại"
+	case errors.Is(err, services.ErrMessageTagNotAllowed):
+		message = "Thẻ tin nhắn không hợp lệ hoặc không dùng được cho hội thoại này"
+	}
+	return APIResponse{
+		Code:    http.StatusConflict,
+		Message: message,
+		Data:    window,
+	}
 }
 
-// Helper to create string pointer
-func ptr(s string) *string {
-	return &s
+// enqueue stores msg as queued with its send job; returns false after writing the
+// error response
+// Platform errors (token expired, rate limits, ...) no longer reach the request: the
+// outbound queue retries them and marks the message failed with a delivery_error
+func (h *DashboardHandler) enqueue(ctx context.Context, w http.ResponseWriter, msg *domain.Message, job *domain.OutboundJob) bool {
+	if err := h.outbound.Enqueue(ctx, msg, job); err != nil {
+		slog.Error("Failed to queue outbound message",
+			"error", err,
+			"conversation_id", msg.ConversationID,
+		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse(
+			"Không thể gửi tin nhắn. Vui lòng thử lại sau",
+		))
+		return false
+	}
+	return true
 }
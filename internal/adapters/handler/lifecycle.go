@@ -0,0 +1,100 @@
+// Package handler implements HTTP request handlers for the conversation lifecycle
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/services"
+)
+
+// LifecycleHandler handles archive / resolve / snooze / reopen of conversations
+type LifecycleHandler struct {
+	lifecycle *services.LifecycleService
+}
+
+// NewLifecycleHandler creates a new lifecycle handler
+func NewLifecycleHandler(lifecycle *services.LifecycleService) *LifecycleHandler {
+	return &LifecycleHandler{
+		lifecycle: lifecycle,
+	}
+}
+
+// SnoozeRequest represents the JSON payload for POST /api/conversations/{id}/snooze
+type SnoozeRequest struct {
+	Until time.Time `json:"until"` // RFC 3339, e.g. "2026-10-17T08:00:00+07:00"
+}
+
+// ChangeStatus archives, resolves, snoozes or reopens a conversation (within the staff's data scope)
+// The action is the last path segment; a new customer message reopens any closed conversation
+// POST /api/conversations/{id}/archive
+// POST /api/conversations/{id}/resolve
+// POST /api/conversations/{id}/snooze   Body: {"until": "2026-10-17T08:00:00+07:00"}
+// POST /api/conversations/{id}/reopen
+// (conversations:status)
+func (h *LifecycleHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// URL format: /api/conversations/123/snooze
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) != 5 {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid URL format"))
+		return
+	}
+	conversationID, err := strconv.ParseInt(pathParts[3], 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid conversation ID"))
+		return
+	}
+
+	ctx := r.Context()
+	scope := ScopeFromContext(ctx)
+
+	var change *domain.ConversationStatusChange
+	switch action := pathParts[4]; action {
+	case "archive":
+		change, err = h.lifecycle.Archive(ctx, scope, conversationID)
+	case "resolve":
+		change, err = h.lifecycle.Resolve(ctx, scope, conversationID)
+	case "reopen":
+		change, err = h.lifecycle.Reopen(ctx, scope, conversationID)
+	case "snooze":
+		var req SnoozeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, BadRequestResponse("Thời gian tạm ẩn không hợp lệ (định dạng RFC 3339)"))
+			return
+		}
+		change, err = h.lifecycle.Snooze(ctx, scope, conversationID, req.Until)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case errors.Is(err, services.ErrInvalidSnooze):
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Thời gian tạm ẩn phải ở tương lai"))
+		return
+	case errors.Is(err, services.ErrConversationNotFound):
+		writeJSON(w, http.StatusNotFound, NotFoundResponse("Không tìm thấy hội thoại"))
+		return
+	case err != nil:
+		slog.Error("Failed to change conversation status",
+			"error", err,
+			"conversation_id", conversationID,
+			"action", pathParts[4],
+		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi cập nhật trạng thái hội thoại"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(change))
+}
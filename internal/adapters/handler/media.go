@@ -0,0 +1,122 @@
+// Package handler implements HTTP request handlers for media uploads
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"immortal-chat/internal/core/services"
+)
+
+// sniffLength is how much of an upload is read to detect its content type
+const sniffLength = 512
+
+// MediaHandler handles media uploads from the dashboard
+type MediaHandler struct {
+	media   *services.MediaService
+	maxSize int64
+}
+
+// NewMediaHandler creates a new media handler; maxSize bounds one upload (bytes)
+func NewMediaHandler(media *services.MediaService, maxSize int64) *MediaHandler {
+	return &MediaHandler{
+		media:   media,
+		maxSize: maxSize,
+	}
+}
+
+// Upload stores a file to send later with POST /api/messages/reply {"media_id": ...}
+// POST /api/media (multipart/form-data, field "file") (messages:reply)
+// The content type decides the attachment type: image/*, video/*, audio/*, anything else is a file
+func (h *MediaHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Cần gửi tệp dạng multipart/form-data (trường file)"))
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu tải lên không hợp lệ"))
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		h.store(w, r, part.FileName(), part.Header.Get("Content-Type"), part)
+		part.Close()
+		return
+	}
+
+	writeJSON(w, http.StatusBadRequest, BadRequestResponse("Thiếu tệp (trường file)"))
+}
+
+// store saves one uploaded file, detecting the content type when the browser sent none
+func (h *MediaHandler) store(w http.ResponseWriter, r *http.Request, fileName, contentType string, file io.Reader) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu tải lên không hợp lệ"))
+		return
+	}
+	head = head[:n]
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType == "application/octet-stream" {
+		contentType = http.DetectContentType(head)
+	} else {
+		contentType = mediaType
+	}
+
+	tenantID := TenantIDFromContext(r.Context())
+	media, err := h.media.Upload(r.Context(), tenantID, fileName, contentType, io.MultiReader(bytes.NewReader(head), file))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, services.ErrMediaTooLarge), errors.As(err, &tooLarge):
+		writeJSON(w, http.StatusRequestEntityTooLarge, NewErrorResponse(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Tệp vượt quá dung lượng cho phép (%d MB)", h.maxSize>>20)))
+		return
+	case errors.Is(err, services.ErrEmptyMedia):
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Tệp rỗng"))
+		return
+	case err != nil:
+		slog.Error("Failed to upload media", "error", err, "tenant_id", tenantID)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tải tệp lên"))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, APIResponse{Code: http.StatusCreated, Message: "Success", Data: media})
+}
+
+// MediaFiles serves stored media under /media/{id}, without directory listings
+// Public on purpose: platforms fetch media by URL (IDs are random, so unguessable);
+// uploads are never rendered as active content on this origin
+func MediaFiles(dir string) http.Handler {
+	files := http.StripPrefix("/media/", http.FileServer(http.Dir(dir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaID := strings.TrimPrefix(r.URL.Path, "/media/")
+		if mediaID == "" || strings.ContainsAny(mediaID, "/\\") || strings.HasPrefix(mediaID, ".") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+		files.ServeHTTP(w, r)
+	})
+}
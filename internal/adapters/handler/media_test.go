@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"immortal-chat/internal/adapters/storage"
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+	"immortal-chat/internal/core/services"
+)
+
+// savedMedia records the media rows of successful uploads
+type savedMedia struct {
+	ports.MediaRepository
+	media []*domain.Media
+}
+
+func (s *savedMedia) SaveMedia(ctx context.Context, media *domain.Media) error {
+	s.media = append(s.media, media)
+	return nil
+}
+
+// uploadRequest builds a multipart upload of content in the "file" field
+func uploadRequest(t *testing.T, fileName string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/media", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestUploadEnforcesMaxSize(t *testing.T) {
+	const maxSize = 4 << 10
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+
+	for name, tc := range map[string]struct {
+		content []byte
+		want    int
+	}{
+		"within the limit":           {png, http.StatusCreated},
+		"exactly the limit":          {bytes.Repeat([]byte("a"), maxSize), http.StatusCreated},
+		"one byte over":              {bytes.Repeat([]byte("a"), maxSize+1), http.StatusRequestEntityTooLarge},
+		"over the multipart framing": {bytes.Repeat([]byte("a"), maxSize+2<<20), http.StatusRequestEntityTooLarge},
+		"empty file":                 {nil, http.StatusBadRequest},
+	} {
+		dir := t.TempDir()
+		store, err := storage.NewLocalMediaStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo := &savedMedia{}
+		h := NewMediaHandler(services.NewMediaService(repo, store, services.MediaConfig{MaxSize: maxSize}), maxSize)
+
+		rec := httptest.NewRecorder()
+		h.Upload(rec, uploadRequest(t, "../../ảnh.png", tc.content))
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d (%s)", name, rec.Code, tc.want, rec.Body)
+			continue
+		}
+		files, _ := os.ReadDir(dir)
+		if tc.want != http.StatusCreated {
+			if len(files) != 0 || len(repo.media) != 0 {
+				t.Errorf("%s: rejected upload left %d files, %d rows", name, len(files), len(repo.media))
+			}
+			continue
+		}
+
+		var resp struct{ Data domain.Media }
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if len(files) != 1 || files[0].Name() != resp.Data.ID || resp.Data.FileName != "ảnh.png" || resp.Data.Size != int64(len(tc.content)) {
+			t.Errorf("%s: stored %v, media %+v", name, files, resp.Data)
+		}
+	}
+}
+
+func TestMediaFilesServesPlainIDsOnly(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "media")
+	os.Mkdir(dir, 0o755)
+	os.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0o600)
+	os.WriteFile(filepath.Join(dir, ".upload-1"), []byte("partial"), 0o600)
+	os.WriteFile(filepath.Join(dir, "0a1b2c"), []byte("<html>hi</html>"), 0o600)
+	files := MediaFiles(dir)
+
+	for _, path := range []string{"/media/../secret", "/media/..%2Fsecret", "/media/.upload-1", "/media/", "/media/sub/0a1b2c"} {
+		rec := httptest.NewRecorder()
+		files.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "secret") {
+			t.Errorf("%s: status = %d, body %q", path, rec.Code, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	files.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/media/0a1b2c", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Content-Type-Options") != "nosniff" ||
+		!strings.Contains(rec.Header().Get("Content-Security-Policy"), "sandbox") {
+		t.Errorf("plain id: status = %d, headers %v", rec.Code, rec.Header())
+	}
+}
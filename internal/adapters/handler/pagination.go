@@ -0,0 +1,89 @@
+// Package handler implements keyset pagination parameters for list endpoints
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+// errInvalidPageQuery is returned for malformed limit / before / after parameters
+var errInvalidPageQuery = errors.New("invalid page query")
+
+// parsePageQuery reads ?limit=&before=&after= (before and after are opaque cursors from paging)
+// Limits above max are capped; 0 or missing uses the repository default
+func parsePageQuery(r *http.Request, max int) (domain.PageQuery, error) {
+	var page domain.PageQuery
+	query := r.URL.Query()
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return page, errInvalidPageQuery
+		}
+		if n > max {
+			n = max
+		}
+		page.Limit = n
+	}
+
+	before, after := query.Get("before"), query.Get("after")
+	if before != "" && after != "" {
+		return page, errInvalidPageQuery
+	}
+	var err error
+	if before != "" {
+		if page.Before, err = decodeCursor(before); err != nil {
+			return page, err
+		}
+	}
+	if after != "" {
+		if page.After, err = decodeCursor(after); err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+// newPaging encodes the neighbouring page cursors for the response envelope
+func newPaging(cursors domain.PageCursors) Paging {
+	return Paging{
+		Next: encodeCursor(cursors.Next),
+		Prev: encodeCursor(cursors.Prev),
+	}
+}
+
+// encodeCursor returns the opaque form of a cursor: base64url("<unix nanos>_<id>")
+func encodeCursor(cursor *domain.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+	raw := strconv.FormatInt(cursor.At.UnixNano(), 10) + "_" + strconv.FormatInt(cursor.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(s string) (*domain.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidPageQuery
+	}
+	at, id, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return nil, errInvalidPageQuery
+	}
+	nanos, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return nil, errInvalidPageQuery
+	}
+	rowID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, errInvalidPageQuery
+	}
+	return &domain.Cursor{At: time.Unix(0, nanos).UTC(), ID: rowID}, nil
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := &domain.Cursor{At: time.Date(2024, 5, 20, 10, 30, 0, 123456789, time.UTC), ID: 9876}
+
+	encoded := encodeCursor(cursor)
+	decoded, err := decodeCursor(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.At.Equal(cursor.At) || decoded.ID != cursor.ID {
+		t.Errorf("decoded = %+v, want %+v", decoded, cursor)
+	}
+	if encodeCursor(nil) != "" {
+		t.Error("nil cursor must encode to an empty string")
+	}
+}
+
+func TestDecodeCursorRejectsMalformedInput(t *testing.T) {
+	for name, s := range map[string]string{
+		"not base64":   "%%%",
+		"no separator": base64.RawURLEncoding.EncodeToString([]byte("1716200000000000000")),
+		"bad time":     base64.RawURLEncoding.EncodeToString([]byte("yesterday_5")),
+		"bad id":       base64.RawURLEncoding.EncodeToString([]byte("1716200000000000000_x")),
+	} {
+		if _, err := decodeCursor(s); !errors.Is(err, errInvalidPageQuery) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestParsePageQuery(t *testing.T) {
+	cursor := encodeCursor(&domain.Cursor{At: time.Unix(1716200000, 0), ID: 5})
+
+	page, err := parsePageQuery(httptest.NewRequest("GET", "/?limit=500&before="+cursor, nil), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Limit != 100 || page.Before == nil || page.Before.ID != 5 || page.After != nil {
+		t.Errorf("page = %+v", page)
+	}
+
+	for name, query := range map[string]string{
+		"negative limit":    "?limit=-1",
+		"non-numeric limit": "?limit=ten",
+		"both directions":   "?before=" + cursor + "&after=" + cursor,
+		"bad cursor":        "?after=nope",
+	} {
+		if _, err := parsePageQuery(httptest.NewRequest("GET", "/"+query, nil), 100); !errors.Is(err, errInvalidPageQuery) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
@@ -4,9 +4,17 @@ package handler
 // APIResponse represents the standard response envelope
 // Per .rules_immortal_chat: ALL API responses must use this format
 type APIResponse struct {
-	Code    int         `json:"code"`    // HTTP status code (200, 400, 500, etc.)
-	Message string      `json:"message"` // Human-readable message ("Success", error description)
-	Data    interface{} `json:"data"`    // Actual payload (can be null)
+	Code    int         `json:"code"`             // HTTP status code (200, 400, 500, etc.)
+	Message string      `json:"message"`          // Human-readable message ("Success", error description)
+	Data    interface{} `json:"data"`             // Actual payload (can be null)
+	Paging  *Paging     `json:"paging,omitempty"` // Cursors of paginated lists
+}
+
+// Paging holds the opaque cursors of the neighbouring pages ("" = no such page)
+// Next continues after the last item in list order, Prev before the first one
+type Paging struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
 }
 
 // NewSuccessResponse creates a successful response (code 200)
@@ -18,6 +26,16 @@ func NewSuccessResponse(data interface{}) APIResponse {
 	}
 }
 
+// NewPagedResponse creates a successful response (code 200) for one page of a list
+func NewPagedResponse(data interface{}, paging Paging) APIResponse {
+	return APIResponse{
+		Code:    200,
+		Message: "Success",
+		Data:    data,
+		Paging:  &paging,
+	}
+}
+
 // NewErrorResponse creates an error response
 func NewErrorResponse(code int, message string) APIResponse {
 	return APIResponse{
@@ -0,0 +1,96 @@
+// Package handler implements HTTP request handlers for full-text search
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/services"
+)
+
+// SearchHandler handles search across messages and customers
+type SearchHandler struct {
+	search *services.SearchService
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(search *services.SearchService) *SearchHandler {
+	return &SearchHandler{
+		search: search,
+	}
+}
+
+// Search finds messages and customers matching every word of q (within the staff's data scope)
+// GET /api/search?q=order 1234&page_id=xxx&from=2026-10-01&to=2026-10-15&sender_type=user&type=text
+// from / to: RFC 3339 or YYYY-MM-DD (to is inclusive for a date); all filters but q optional
+// Words shorter than 3 characters are ignored; highlight fields are HTML with <mark> around matches
+// Messages are newest first, ?limit=20 (max 100); paging.next -> ?before=<cursor> (older),
+// paging.prev -> ?after=<cursor> (newer); customers are only returned on the first page
+// (conversations:read)
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query := domain.SearchQuery{
+		PageID:      params.Get("page_id"),
+		SenderType:  params.Get("sender_type"),
+		MessageType: params.Get("type"),
+	}
+	var err error
+	if query.From, err = parseSearchTime(params.Get("from"), false); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("from không hợp lệ (RFC 3339 hoặc YYYY-MM-DD)"))
+		return
+	}
+	if query.To, err = parseSearchTime(params.Get("to"), true); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("to không hợp lệ (RFC 3339 hoặc YYYY-MM-DD)"))
+		return
+	}
+	page, err := parsePageQuery(r, services.MaxSearchPageSize)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Tham số phân trang không hợp lệ"))
+		return
+	}
+
+	scope := ScopeFromContext(r.Context())
+	results, cursors, err := h.search.Search(r.Context(), scope, params.Get("q"), query, page)
+	switch {
+	case errors.Is(err, services.ErrInvalidSearch):
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Cần ít nhất một từ 2 ký tự trở lên; sender_type, type hoặc khoảng thời gian không hợp lệ"))
+		return
+	case err != nil:
+		slog.Error("Failed to search",
+			"error", err,
+			"tenant_id", scope.TenantID,
+			"staff_id", scope.StaffID,
+		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tìm kiếm"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewPagedResponse(results, newPaging(cursors)))
+}
+
+// parseSearchTime reads an RFC 3339 time or a YYYY-MM-DD date (nil if empty)
+// A date used as upper bound (endOfDay) covers the whole day
+func parseSearchTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,173 @@
+// Package handler implements HTTP request handlers for conversation tags
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/services"
+)
+
+// TagHandler handles tag definitions and the tags of conversations
+type TagHandler struct {
+	tags *services.TagService
+}
+
+// NewTagHandler creates a new tag handler
+func NewTagHandler(tags *services.TagService) *TagHandler {
+	return &TagHandler{
+		tags: tags,
+	}
+}
+
+// ListTags returns the tenant's tags with conversation counts (within the staff's data scope)
+// GET /api/tags (conversations:read)
+func (h *TagHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	scope := ScopeFromContext(r.Context())
+	tags, err := h.tags.ListTags(r.Context(), scope)
+	if err != nil {
+		slog.Error("Failed to list tags", "error", err, "tenant_id", scope.TenantID)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tải danh sách nhãn"))
+		return
+	}
+	if tags == nil {
+		tags = []*domain.Tag{}
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(tags))
+}
+
+// CreateTagRequest represents the JSON payload for POST /api/tags
+type CreateTagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"` // "#RRGGBB", optional
+}
+
+// CreateTag adds a tag definition to the caller's tenant
+// POST /api/tags (tags:manage)
+// Body: {"name": "VIP", "color": "#F59E0B"}
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var req CreateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu không hợp lệ"))
+		return
+	}
+
+	tenantID := TenantIDFromContext(r.Context())
+	tag, err := h.tags.CreateTag(r.Context(), tenantID, req.Name, req.Color)
+	switch {
+	case errors.Is(err, services.ErrInvalidTag):
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Tên nhãn (tối đa 50 ký tự) hoặc màu (#RRGGBB) không hợp lệ"))
+		return
+	case errors.Is(err, services.ErrTagExists):
+		writeJSON(w, http.StatusConflict, NewErrorResponse(http.StatusConflict, "Tên nhãn đã tồn tại"))
+		return
+	case err != nil:
+		slog.Error("Failed to create tag", "error", err, "tenant_id", tenantID)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tạo nhãn"))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, APIResponse{Code: http.StatusCreated, Message: "Success", Data: tag})
+}
+
+// DeleteTag removes a tag definition; conversations carrying it lose the tag
+// DELETE /api/tags/{id} (tags:manage)
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// URL format: /api/tags/3
+	tagID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/tags/"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid tag ID"))
+		return
+	}
+
+	tenantID := TenantIDFromContext(r.Context())
+	err = h.tags.DeleteTag(r.Context(), tenantID, tagID)
+	if errors.Is(err, services.ErrTagNotFound) {
+		writeJSON(w, http.StatusNotFound, NotFoundResponse("Không tìm thấy nhãn"))
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to delete tag", "error", err, "tenant_id", tenantID, "tag_id", tagID)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi xóa nhãn"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(nil))
+}
+
+// ConversationTagRequest represents the JSON payload for POST /api/conversations/{id}/tags
+type ConversationTagRequest struct {
+	TagID int `json:"tag_id"`
+}
+
+// ConversationTags adds or removes a tag on a conversation (within the staff's data scope)
+// POST   /api/conversations/{id}/tags           Body: {"tag_id": 3}
+// DELETE /api/conversations/{id}/tags/{tag_id}
+// (conversations:tag)
+func (h *TagHandler) ConversationTags(w http.ResponseWriter, r *http.Request) {
+	// URL format: /api/conversations/123/tags[/3]
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid URL format"))
+		return
+	}
+	conversationID, err := strconv.ParseInt(pathParts[3], 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid conversation ID"))
+		return
+	}
+
+	var (
+		tagID   int
+		present bool
+	)
+	switch {
+	case r.Method == http.MethodPost && len(pathParts) == 5:
+		var req ConversationTagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TagID <= 0 {
+			writeJSON(w, http.StatusBadRequest, BadRequestResponse("Dữ liệu không hợp lệ"))
+			return
+		}
+		tagID, present = req.TagID, true
+	case r.Method == http.MethodDelete && len(pathParts) == 6:
+		if tagID, err = strconv.Atoi(pathParts[5]); err != nil {
+			writeJSON(w, http.StatusBadRequest, BadRequestResponse("Invalid tag ID"))
+			return
+		}
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	scope := ScopeFromContext(r.Context())
+	result, err := h.tags.TagConversation(r.Context(), scope, conversationID, tagID, present)
+	switch {
+	case errors.Is(err, services.ErrTagNotFound):
+		writeJSON(w, http.StatusBadRequest, BadRequestResponse("Không tìm thấy nhãn"))
+		return
+	case errors.Is(err, services.ErrConversationNotFound):
+		writeJSON(w, http.StatusNotFound, NotFoundResponse("Không tìm thấy hội thoại"))
+		return
+	case err != nil:
+		slog.Error("Failed to tag conversation",
+			"error", err,
+			"conversation_id", conversationID,
+			"tag_id", tagID,
+		)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi gắn nhãn hội thoại"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(result))
+}
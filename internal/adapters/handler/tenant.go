@@ -0,0 +1,63 @@
+// Package handler implements HTTP request handlers for tenant self-service
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"immortal-chat/internal/core/services"
+)
+
+// TenantHandler exposes plan / usage information to the dashboard
+type TenantHandler struct {
+	quota *services.QuotaService
+}
+
+// NewTenantHandler creates a new tenant handler
+func NewTenantHandler(quota *services.QuotaService) *TenantHandler {
+	return &TenantHandler{
+		quota: quota,
+	}
+}
+
+// GetUsage returns the plan limits and current usage of the logged-in staff's tenant
+// GET /api/tenant/usage
+func (h *TenantHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, NewErrorResponse(http.StatusMethodNotAllowed, "Method Not Allowed"))
+		return
+	}
+
+	tenantID := TenantIDFromContext(r.Context())
+
+	usage, err := h.quota.Usage(r.Context(), tenantID)
+	if errors.Is(err, services.ErrTenantNotFound) {
+		writeJSON(w, http.StatusNotFound, NotFoundResponse("Không tìm thấy khách hàng"))
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to get tenant usage", "error", err, "tenant_id", tenantID)
+		writeJSON(w, http.StatusInternalServerError, InternalErrorResponse("Lỗi hệ thống khi tải thông tin sử dụng"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewSuccessResponse(usage))
+}
+
+// quotaErrorResponse maps a QuotaService error to a user-facing response
+// Returns ok=false for errors that are not plan related
+func quotaErrorResponse(err error) (int, APIResponse, bool) {
+	switch {
+	case errors.Is(err, services.ErrTenantExpired):
+		return http.StatusPaymentRequired, NewErrorResponse(http.StatusPaymentRequired,
+			"Gói dịch vụ đã hết hạn. Tin nhắn của khách vẫn được lưu, vui lòng gia hạn để tiếp tục trả lời"), true
+	case errors.Is(err, services.ErrTenantInactive):
+		return http.StatusForbidden, NewErrorResponse(http.StatusForbidden,
+			"Tài khoản đã bị tạm khóa. Vui lòng liên hệ quản trị viên"), true
+	case errors.Is(err, services.ErrQuotaExceeded):
+		return http.StatusPaymentRequired, NewErrorResponse(http.StatusPaymentRequired,
+			"Đã vượt giới hạn của gói dịch vụ. Vui lòng nâng cấp gói để tiếp tục trả lời"), true
+	}
+	return 0, APIResponse{}, false
+}
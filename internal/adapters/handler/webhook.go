@@ -3,31 +3,31 @@
 package handler
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"strings"
+	"time"
 
+	"immortal-chat/internal/core/domain"
 	"immortal-chat/internal/core/services"
 )
 
-// WebhookHandler handles Facebook webhook verification and events
+// WebhookHandler handles webhook verification and events for all platforms
+// Signature checks are delegated to the platform adapter from the registry
 // Per .rulesgemini Section 4: Must respond < 3 seconds
 type WebhookHandler struct {
 	dispatcher  *services.Dispatcher
-	appSecret   string // For HMAC signature validation
-	verifyToken string // For webhook verification
+	platforms   *services.PlatformRegistry
+	verifyToken string // For Facebook webhook verification
 }
 
 // NewWebhookHandler creates a new webhook handler
-func NewWebhookHandler(dispatcher *services.Dispatcher, appSecret, verifyToken string) *WebhookHandler {
+func NewWebhookHandler(dispatcher *services.Dispatcher, platforms *services.PlatformRegistry, verifyToken string) *WebhookHandler {
 	return &WebhookHandler{
 		dispatcher:  dispatcher,
-		appSecret:   appSecret,
+		platforms:   platforms,
 		verifyToken: verifyToken,
 	}
 }
@@ -77,113 +77,115 @@ func (h *WebhookHandler) HandleFacebookVerify(w http.ResponseWriter, r *http.Req
 // ============================================================================
 
 // HandleFacebookEvent handles incoming Facebook webhook events
-// Per .rulesgemini Section 4: Fire & Forget - return 200 OK immediately
+// Per .rulesgemini Section 4: Enqueue durably, then return 200 OK immediately
 // Per user requirement: Validate HMAC signature before processing
 func (h *WebhookHandler) HandleFacebookEvent(w http.ResponseWriter, r *http.Request) {
+	h.handleEvent(w, r, domain.PlatformFacebook)
+}
+
+// ============================================================================
+// POST /webhook/instagram - Instagram Direct Events
+// ============================================================================
+
+// HandleInstagramEvent handles Instagram webhooks (object "instagram")
+// Verification (GET) is identical to Facebook: reuse HandleFacebookVerify.
+// Instagram events posted to /webhook/facebook are also handled; this route
+// only makes webhook_logs.platform = 'instagram' for separate replay/filtering
+func (h *WebhookHandler) HandleInstagramEvent(w http.ResponseWriter, r *http.Request) {
+	h.handleEvent(w, r, domain.PlatformInstagram)
+}
+
+// ============================================================================
+// POST /webhook/zalo - Zalo Official Account Events
+// ============================================================================
+
+// HandleZaloEvent handles incoming Zalo OA webhook events
+// Zalo has no GET verification handshake: the domain is verified in the
+// Zalo developer console and every event carries X-ZEvent-Signature
+func (h *WebhookHandler) HandleZaloEvent(w http.ResponseWriter, r *http.Request) {
+	h.handleEvent(w, r, domain.PlatformZalo)
+}
+
+// ============================================================================
+// POST /webhook/telegram - Telegram Bot Updates
+// ============================================================================
+
+// HandleTelegramEvent handles incoming Telegram Bot API updates
+// The webhook is registered via setWebhook with a secret_token that Telegram
+// echoes in X-Telegram-Bot-Api-Secret-Token
+func (h *WebhookHandler) HandleTelegramEvent(w http.ResponseWriter, r *http.Request) {
+	h.handleEvent(w, r, domain.PlatformTelegram)
+}
+
+// handleEvent is the platform-agnostic webhook intake:
+// read body -> verify signature via adapter -> enqueue durably -> 200 OK
+func (h *WebhookHandler) handleEvent(w http.ResponseWriter, r *http.Request, platform string) {
 	// ========================================================================
 	// Step 1: Read request body
 	// ========================================================================
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		slog.Error("Failed to read webhook body", "error", err)
+		slog.Error("Failed to read webhook body", "error", err, "platform", platform)
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
 
+	adapter, err := h.platforms.Get(platform)
+	if err != nil {
+		slog.Error("No adapter registered for webhook platform", "platform", platform)
+		http.NotFound(w, r)
+		return
+	}
+
 	// ========================================================================
-	// Step 2: CRITICAL - Validate HMAC Signature
+	// Step 2: CRITICAL - Validate Signature
 	// Per .rulesgemini Section 4: Do NOT process without valid signature
 	// Per user requirement: Security is mandatory
 	// ========================================================================
-	signature := r.Header.Get("X-Hub-Signature-256")
-	if signature == "" {
-		slog.Warn("Webhook received without signature header")
-		http.Error(w, "Forbidden - No signature", http.StatusForbidden)
-		return
-	}
-
-	if !h.validateSignature(body, signature) {
+	if err := adapter.VerifySignature(r.Header, body); err != nil {
 		slog.Warn("Webhook signature validation failed",
-			"signature", signature,
+			"platform", platform,
+			"error", err,
 		)
 		http.Error(w, "Forbidden - Invalid signature", http.StatusForbidden)
 		return
 	}
 
-	slog.Debug("Webhook signature validated successfully")
+	slog.Debug("Webhook signature validated successfully", "platform", platform)
 
 	// ========================================================================
-	// Step 3: Return HTTP 200 OK IMMEDIATELY (Fire & Forget)
-	// Per .rulesgemini Section 4: Must respond < 3 seconds
+	// Step 3: Persist the event in the durable queue BEFORE acknowledging
+	// A crash/restart after this point cannot lose the event: workers pick it
+	// up from the queue. If the queue is down we return 5xx so the platform retries
+	// Use a short detached timeout: the event must be stored even if the client hangs up
 	// ========================================================================
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("EVENT_RECEIVED"))
+	enqueueCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := h.dispatcher.Enqueue(enqueueCtx, platform, body); err != nil {
+		slog.Error("Failed to enqueue webhook, asking platform to retry",
+			"error", err,
+			"platform", platform,
+			"content_length", len(body),
+		)
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
 
 	// ========================================================================
-	// Step 4: Process webhook asynchronously in goroutine
-	// Per user requirement: Use defer recover() to prevent container crash
+	// Step 4: Return HTTP 200 OK (event is safely queued)
+	// Per .rulesgemini Section 4: Must respond < 3 seconds
 	// ========================================================================
-	go func() {
-		// Panic recovery per user requirement
-		defer func() {
-			if r := recover(); r != nil {
-				slog.Error("PANIC in webhook processing goroutine",
-					"panic", r,
-				)
-			}
-		}()
-
-		// Process webhook with dispatcher
-		// Platform is hardcoded as "facebook" for this handler
-		h.dispatcher.ProcessWebhook(r.Context(), "facebook", body)
-	}()
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("EVENT_RECEIVED"))
 
 	slog.Info("Webhook received and queued for processing",
+		"platform", platform,
 		"content_length", len(body),
 	)
 }
 
-// ============================================================================
-// HMAC Signature Validation
-// ============================================================================
-
-// validateSignature validates the HMAC SHA256 signature from Facebook
-// Per .rulesgemini: Security requirement - reject invalid signatures
-// Ref: https://developers.facebook.com/docs/messenger-platform/webhooks#security
-func (h *WebhookHandler) validateSignature(payload []byte, signatureHeader string) bool {
-	// Facebook sends signature in format: "sha256=<hex_signature>"
-	const prefix = "sha256="
-	if !strings.HasPrefix(signatureHeader, prefix) {
-		slog.Warn("Invalid signature format - missing sha256= prefix")
-		return false
-	}
-
-	// Extract hex signature
-	expectedSignature := strings.TrimPrefix(signatureHeader, prefix)
-
-	// Compute HMAC SHA256
-	mac := hmac.New(sha256.New, []byte(h.appSecret))
-	mac.Write(payload)
-	computedSignatureBytes := mac.Sum(nil)
-	computedSignature := hex.EncodeToString(computedSignatureBytes)
-
-	// Compare signatures (constant-time comparison to prevent timing attacks)
-	isValid := hmac.Equal(
-		[]byte(computedSignature),
-		[]byte(expectedSignature),
-	)
-
-	if !isValid {
-		slog.Warn("HMAC signature mismatch",
-			"expected", expectedSignature,
-			"computed", computedSignature,
-		)
-	}
-
-	return isValid
-}
-
 // ============================================================================
 // Response Helper (Per .rulesgemini Section 7: Standard JSON envelope)
 // ============================================================================
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"immortal-chat/internal/core/domain"
+)
+
+func TestConversationScopeFilter(t *testing.T) {
+	team := 3
+	for name, tc := range map[string]struct {
+		scope     domain.ConversationScope
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		"tenant wide": {
+			domain.ConversationScope{TenantID: 1, StaffID: 5, TeamID: &team},
+			"c.tenant_id = ?", []interface{}{1},
+		},
+		"assigned only": {
+			domain.ConversationScope{TenantID: 1, StaffID: 5, AssignedOnly: true},
+			"c.tenant_id = ? AND c.assignee_id = ?", []interface{}{1, 5},
+		},
+		"assigned or team": {
+			domain.ConversationScope{TenantID: 1, StaffID: 5, TeamID: &team, AssignedOnly: true},
+			"c.tenant_id = ? AND (c.assignee_id = ? OR c.assigned_team_id = ?)", []interface{}{1, 5, 3},
+		},
+	} {
+		where, args := conversationScopeFilter(tc.scope)
+		if where != tc.wantWhere || !reflect.DeepEqual(args, tc.wantArgs) {
+			t.Errorf("%s: got %q %v, want %q %v", name, where, args, tc.wantWhere, tc.wantArgs)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	at := time.Unix(1716200000, 0)
+
+	cond, args, desc := keysetCondition("m.created_at", "m.id", domain.PageQuery{Before: &domain.Cursor{At: at, ID: 7}})
+	if cond != "(m.created_at < ? OR (m.created_at = ? AND m.id < ?))" || len(args) != 3 || !desc {
+		t.Errorf("before: %q %v desc=%v", cond, args, desc)
+	}
+
+	cond, args, desc = keysetCondition("m.created_at", "m.id", domain.PageQuery{After: &domain.Cursor{At: at, ID: 7}})
+	if cond != "(m.created_at > ? OR (m.created_at = ? AND m.id > ?))" || len(args) != 3 || desc {
+		t.Errorf("after: %q %v desc=%v", cond, args, desc)
+	}
+
+	if cond, args, desc = keysetCondition("m.created_at", "m.id", domain.PageQuery{}); cond != "" || args != nil || !desc {
+		t.Errorf("first page: %q %v desc=%v", cond, args, desc)
+	}
+}
+
+func TestKeysetCursors(t *testing.T) {
+	keys := []domain.Cursor{{ID: 3}, {ID: 2}, {ID: 1}} // Newest first
+	cursor := &domain.Cursor{ID: 4}
+
+	for name, tc := range map[string]struct {
+		page                  domain.PageQuery
+		fetchedDesc, listDesc bool
+		more                  bool
+		next, prev            int64 // 0 = no such page
+	}{
+		"first page, more":          {domain.PageQuery{}, true, true, true, 1, 0},
+		"only page":                 {domain.PageQuery{}, true, true, false, 0, 0},
+		"older page, more":          {domain.PageQuery{Before: cursor}, true, true, true, 1, 3},
+		"oldest page":               {domain.PageQuery{Before: cursor}, true, true, false, 0, 3},
+		"newer page fetched upward": {domain.PageQuery{After: cursor}, false, true, true, 1, 3},
+		"newest page fetched up":    {domain.PageQuery{After: cursor}, false, true, false, 1, 0},
+	} {
+		cursors := keysetCursors(tc.page, keys, tc.fetchedDesc, tc.listDesc, tc.more)
+		if id := cursorID(cursors.Next); id != tc.next {
+			t.Errorf("%s: next = %d, want %d", name, id, tc.next)
+		}
+		if id := cursorID(cursors.Prev); id != tc.prev {
+			t.Errorf("%s: prev = %d, want %d", name, id, tc.prev)
+		}
+	}
+
+	if cursors := keysetCursors(domain.PageQuery{Before: cursor}, nil, true, true, false); cursors.Next != nil || cursors.Prev != nil {
+		t.Errorf("empty page cursors = %+v", cursors)
+	}
+}
+
+func cursorID(cursor *domain.Cursor) int64 {
+	if cursor == nil {
+		return 0
+	}
+	return cursor.ID
+}
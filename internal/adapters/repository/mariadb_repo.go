@@ -8,6 +8,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
+	"time"
 
 	"immortal-chat/internal/core/domain"
 	"immortal-chat/internal/core/ports"
@@ -18,6 +21,17 @@ var (
 	_ ports.WebhookRepository      = (*MariaDBRepository)(nil)
 	_ ports.MessageRepository      = (*MariaDBRepository)(nil)
 	_ ports.ConversationRepository = (*MariaDBRepository)(nil)
+	_ ports.PageRepository         = (*MariaDBRepository)(nil)
+	_ ports.QuarantineRepository   = (*MariaDBRepository)(nil)
+	_ ports.TenantRepository       = (*MariaDBRepository)(nil)
+	_ ports.StaffRepository        = (*MariaDBRepository)(nil)
+	_ ports.AssignmentRepository   = (*MariaDBRepository)(nil)
+	_ ports.TagRepository          = (*MariaDBRepository)(nil)
+	_ ports.LifecycleRepository    = (*MariaDBRepository)(nil)
+	_ ports.SearchRepository       = (*MariaDBRepository)(nil)
+	_ ports.MediaRepository        = (*MariaDBRepository)(nil)
+	_ ports.MediaMirrorRepository  = (*MariaDBRepository)(nil)
+	_ ports.OutboundRepository     = (*MariaDBRepository)(nil)
 )
 
 // MariaDBRepository implements persistence operations for MariaDB
@@ -39,13 +53,14 @@ func NewMariaDBRepository(db *sql.DB) *MariaDBRepository {
 
 // SaveLog persists a webhook event to the audit log
 // Updated for new schema: payload_json (JSON type)
+// Sets log.ID so the caller can track the row through its lifecycle
 func (r *MariaDBRepository) SaveLog(ctx context.Context, log *domain.WebhookLog) error {
 	query := `
 		INSERT INTO webhook_logs (platform, payload_json, status, retry_count, created_at)
 		VALUES (?, ?, ?, ?, ?)
 	`
 	
-	_, err := r.db.ExecContext(ctx, query,
+	result, err := r.db.ExecContext(ctx, query,
 		log.Platform,
 		log.PayloadJSON,
 		log.Status,
@@ -61,7 +76,13 @@ func (r *MariaDBRepository) SaveLog(ctx context.Context, log *domain.WebhookLog)
 		return fmt.Errorf("save webhook log: %w", err)
 	}
 	
+	log.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("get webhook log id: %w", err)
+	}
+	
 	slog.Debug("Webhook log saved",
+		"webhook_id", log.ID,
 		"platform", log.Platform,
 		"status", log.Status,
 	)
@@ -70,14 +91,18 @@ func (r *MariaDBRepository) SaveLog(ctx context.Context, log *domain.WebhookLog)
 }
 
 // UpdateStatus updates the processing status of a webhook log
-func (r *MariaDBRepository) UpdateStatus(ctx context.Context, id string, status string) error {
+// Failed attempts bump retry_count and keep the latest error text in error_log
+func (r *MariaDBRepository) UpdateStatus(ctx context.Context, id int64, status string, errorLog *string) error {
 	query := `
 		UPDATE webhook_logs
-		SET status = ?
+		SET status = ?,
+			retry_count = retry_count + IF(? = 'failed', 1, 0),
+			error_log = COALESCE(?, error_log),
+			last_attempt_at = NOW()
 		WHERE id = ?
 	`
 	
-	result, err := r.db.ExecContext(ctx, query, status, id)
+	result, err := r.db.ExecContext(ctx, query, status, status, errorLog, id)
 	if err != nil {
 		slog.Error("Failed to update webhook status",
 			"error", err,
@@ -97,24 +122,187 @@ func (r *MariaDBRepository) UpdateStatus(ctx context.Context, id string, status
 	return nil
 }
 
+// GetLog retrieves a single webhook log by ID (nil if not found)
+func (r *MariaDBRepository) GetLog(ctx context.Context, id int64) (*domain.WebhookLog, error) {
+	query := `
+		SELECT id, platform, payload_json, status, retry_count, error_log, last_attempt_at, created_at
+		FROM webhook_logs
+		WHERE id = ?
+	`
+	
+	log, err := scanWebhookLog(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	
+	if err != nil {
+		slog.Error("Failed to get webhook log",
+			"error", err,
+			"webhook_id", id,
+		)
+		return nil, fmt.Errorf("get webhook log: %w", err)
+	}
+	
+	return log, nil
+}
+
+// CountByStatus returns the number of webhook logs per status
+// webhook_logs rows carry no tenant: the counts cover the whole system
+func (r *MariaDBRepository) CountByStatus(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT status, COUNT(*) FROM webhook_logs GROUP BY status")
+	if err != nil {
+		return nil, fmt.Errorf("count webhook logs: %w", err)
+	}
+	defer rows.Close()
+	
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			status string
+			count  int
+		)
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("scan webhook log count: %w", err)
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// FindForReplay lists webhook logs eligible for replay, oldest first
+// Backoff is evaluated in SQL so a batch is never filled with rows that are not due yet
+func (r *MariaDBRepository) FindForReplay(ctx context.Context, filter domain.WebhookReplayFilter) ([]*domain.WebhookLog, error) {
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{domain.WebhookStatusFailed, domain.WebhookStatusPending}
+	}
+	
+	limit := filter.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	
+	query := `
+		SELECT id, platform, payload_json, status, retry_count, error_log, last_attempt_at, created_at
+		FROM webhook_logs
+		WHERE status IN (?` + strings.Repeat(", ?", len(statuses)-1) + `)
+	`
+	args := make([]interface{}, 0, len(statuses)+8)
+	for _, status := range statuses {
+		args = append(args, status)
+	}
+	
+	if filter.ID > 0 {
+		query += " AND id = ?"
+		args = append(args, filter.ID)
+	}
+	if filter.Platform != "" {
+		query += " AND platform = ?"
+		args = append(args, filter.Platform)
+	}
+	if filter.From != nil {
+		query += " AND created_at >= ?"
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		query += " AND created_at < ?"
+		args = append(args, *filter.To)
+	}
+	if filter.OlderThan > 0 {
+		query += " AND created_at < NOW() - INTERVAL ? SECOND"
+		args = append(args, int64(filter.OlderThan.Seconds()))
+	}
+	if filter.MaxRetries > 0 {
+		query += " AND retry_count < ?"
+		args = append(args, filter.MaxRetries)
+	}
+	if filter.BaseBackoff > 0 {
+		// Exponential backoff: wait base * 2^retry_count (capped) since the last attempt
+		query += ` AND (last_attempt_at IS NULL
+			OR last_attempt_at <= NOW() - INTERVAL LEAST(? * POW(2, retry_count), ?) SECOND)`
+		args = append(args, int64(filter.BaseBackoff.Seconds()), int64(filter.MaxBackoff.Seconds()))
+	}
+	
+	query += " ORDER BY created_at ASC, id ASC LIMIT ?"
+	args = append(args, limit)
+	
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.Error("Failed to find webhook logs for replay",
+			"error", err,
+			"platform", filter.Platform,
+		)
+		return nil, fmt.Errorf("find webhook logs for replay: %w", err)
+	}
+	defer rows.Close()
+	
+	var logs []*domain.WebhookLog
+	for rows.Next() {
+		log, err := scanWebhookLog(rows)
+		if err != nil {
+			slog.Error("Failed to scan webhook log row", "error", err)
+			continue
+		}
+		logs = append(logs, log)
+	}
+	
+	return logs, rows.Err()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanWebhookLog maps a webhook_logs row (column order as in GetLog)
+func scanWebhookLog(row rowScanner) (*domain.WebhookLog, error) {
+	var log domain.WebhookLog
+	var payload []byte
+	var lastAttemptAt sql.NullTime
+	
+	err := row.Scan(
+		&log.ID,
+		&log.Platform,
+		&payload,
+		&log.Status,
+		&log.RetryCount,
+		&log.ErrorLog,
+		&lastAttemptAt,
+		&log.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	
+	log.PayloadJSON = json.RawMessage(payload)
+	if lastAttemptAt.Valid {
+		log.LastAttemptAt = &lastAttemptAt.Time
+	}
+	
+	return &log, nil
+}
+
 // ============================================================================
 // MessageRepository Implementation
 // ============================================================================
 
 // SaveMessage persists a parsed message to the database
 // Updated for new schema: sender_type, type, external_msg_id, attachments (JSON)
+// uniq_conversation_external_msg makes this the atomic dedup: the no-op update affects
+// no row when the message is already stored
 func (r *MariaDBRepository) SaveMessage(ctx context.Context, msg *domain.Message) error {
 	query := `
 		INSERT INTO messages (
 			conversation_id, sender_id, sender_type, content, 
-			attachments, type, is_synced, external_msg_id, created_at
+			attachments, type, is_synced, external_msg_id, payload,
+			delivery_status, is_external, created_at
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
-			content = VALUES(content)
+			id = id
 	`
 	
-	_, err := r.db.ExecContext(ctx, query,
+	result, err := r.db.ExecContext(ctx, query,
 		msg.ConversationID,
 		msg.SenderID,
 		msg.SenderType,
@@ -123,6 +311,9 @@ func (r *MariaDBRepository) SaveMessage(ctx context.Context, msg *domain.Message
 		msg.Type,
 		msg.IsSynced,
 		msg.ExternalMsgID,
+		msg.Payload,
+		msg.DeliveryStatus,
+		msg.IsExternal,
 		msg.CreatedAt,
 	)
 	
@@ -133,6 +324,12 @@ func (r *MariaDBRepository) SaveMessage(ctx context.Context, msg *domain.Message
 		)
 		return fmt.Errorf("save message: %w", err)
 	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ports.ErrDuplicateMessage
+	}
+	if id, err := result.LastInsertId(); err == nil && id > 0 {
+		msg.ID = id
+	}
 	
 	slog.Info("Message saved successfully",
 		"conversation_id", msg.ConversationID,
@@ -146,7 +343,8 @@ func (r *MariaDBRepository) SaveMessage(ctx context.Context, msg *domain.Message
 func (r *MariaDBRepository) GetByID(ctx context.Context, id string) (*domain.Message, error) {
 	query := `
 		SELECT id, conversation_id, sender_id, sender_type, content, 
-			   attachments, type, is_synced, external_msg_id, created_at
+			   attachments, type, is_synced, external_msg_id, payload, delivery_status, delivery_error, is_external,
+			   edit_history, edited_at, is_deleted, deleted_at, reactions, message_tag, created_at
 		FROM messages
 		WHERE id = ?
 	`
@@ -162,6 +360,16 @@ func (r *MariaDBRepository) GetByID(ctx context.Context, id string) (*domain.Mes
 		&msg.Type,
 		&msg.IsSynced,
 		&msg.ExternalMsgID,
+		&msg.Payload,
+		&msg.DeliveryStatus,
+		&msg.DeliveryError,
+		&msg.IsExternal,
+		(*[]byte)(&msg.EditHistory),
+		&msg.EditedAt,
+		&msg.IsDeleted,
+		&msg.DeletedAt,
+		(*[]byte)(&msg.Reactions),
+		&msg.MessageTag,
 		&msg.CreatedAt,
 	)
 	
@@ -202,17 +410,35 @@ func (r *MariaDBRepository) Exists(ctx context.Context, id string) (bool, error)
 	return true, nil
 }
 
+// GetLastInboundAt returns the time of the customer's last message (nil if none)
+func (r *MariaDBRepository) GetLastInboundAt(ctx context.Context, conversationID int64) (*time.Time, error) {
+	query := `
+		SELECT MAX(created_at)
+		FROM messages
+		WHERE conversation_id = ? AND sender_type = 'user'
+	`
+	
+	var lastInboundAt sql.NullTime
+	if err := r.db.QueryRowContext(ctx, query, conversationID).Scan(&lastInboundAt); err != nil {
+		return nil, fmt.Errorf("get last inbound message time: %w", err)
+	}
+	if !lastInboundAt.Valid {
+		return nil, nil
+	}
+	return &lastInboundAt.Time, nil
+}
+
 // ============================================================================
 // ConversationRepository Implementation
 // ============================================================================
 
 // GetOrCreateByPlatformID retrieves an existing conversation or creates a new one
-// Updated for new schema: tenant_id, platform_id, page_id
-func (r *MariaDBRepository) GetOrCreateByPlatformID(ctx context.Context, tenantID int, platformID, pageID string) (int64, error) {
+// Updated for new schema: tenant_id, platform, platform_id, page_id
+func (r *MariaDBRepository) GetOrCreateByPlatformID(ctx context.Context, tenantID int, platform, platformID, pageID string) (int64, error) {
 	// Try to get existing conversation
 	var id int64
-	query := `SELECT id FROM conversations WHERE tenant_id = ? AND platform_id = ? AND page_id = ?`
-	err := r.db.QueryRowContext(ctx, query, tenantID, platformID, pageID).Scan(&id)
+	query := `SELECT id FROM conversations WHERE tenant_id = ? AND platform = ? AND platform_id = ? AND page_id = ?`
+	err := r.db.QueryRowContext(ctx, query, tenantID, platform, platformID, pageID).Scan(&id)
 	
 	if err == nil {
 		// Conversation exists
@@ -235,13 +461,14 @@ func (r *MariaDBRepository) GetOrCreateByPlatformID(ctx context.Context, tenantI
 	
 	insertQuery := `
 		INSERT INTO conversations (
-			tenant_id, platform_id, page_id, tags, status, created_at
+			tenant_id, platform, platform_id, page_id, tags, status, created_at
 		)
-		VALUES (?, ?, ?, ?, ?, NOW())
+		VALUES (?, ?, ?, ?, ?, ?, NOW())
 	`
 	
 	result, err := r.db.ExecContext(ctx, insertQuery, 
 		tenantID, 
+		platform,
 		platformID, 
 		pageID,
 		tagsJSON,
@@ -264,105 +491,339 @@ func (r *MariaDBRepository) GetOrCreateByPlatformID(ctx context.Context, tenantI
 	slog.Info("New conversation created",
 		"conversation_id", id,
 		"tenant_id", tenantID,
+		"platform", platform,
 		"platform_id", platformID,
 	)
 	
 	return id, nil
 }
 
+// UpdateCustomerName stores the display name sent by the platform
+// No-op when unchanged to avoid bumping updated_at on every message
+func (r *MariaDBRepository) UpdateCustomerName(ctx context.Context, conversationID int64, name string) error {
+	query := `
+		UPDATE conversations
+		SET customer_name = ?
+		WHERE id = ? AND (customer_name IS NULL OR customer_name <> ?)
+	`
+	
+	if _, err := r.db.ExecContext(ctx, query, name, conversationID, name); err != nil {
+		return fmt.Errorf("update customer name: %w", err)
+	}
+	
+	return nil
+}
+
+// UpdateReferral stores the latest entry point (last-touch attribution)
+// Out-of-order webhooks cannot overwrite a newer referral
+func (r *MariaDBRepository) UpdateReferral(ctx context.Context, conversationID int64, referral *domain.Referral, referredAt time.Time) error {
+	query := `
+		UPDATE conversations
+		SET referral_source = ?,
+			referral_ref = NULLIF(?, ''),
+			referral_ad_id = NULLIF(?, ''),
+			referred_at = ?
+		WHERE id = ? AND (referred_at IS NULL OR referred_at <= ?)
+	`
+	
+	_, err := r.db.ExecContext(ctx, query,
+		referral.Source,
+		referral.Ref,
+		referral.AdID,
+		referredAt,
+		conversationID,
+		referredAt,
+	)
+	if err != nil {
+		slog.Error("Failed to update conversation referral",
+			"error", err,
+			"conversation_id", conversationID,
+		)
+		return fmt.Errorf("update referral: %w", err)
+	}
+	
+	return nil
+}
+
+// ReopenConversation marks a conversation unread when the customer writes
+// Closed conversations (snoozed / resolved / archived) reopen; returns their previous status
+func (r *MariaDBRepository) ReopenConversation(ctx context.Context, conversationID int64) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("begin reopen conversation: %w", err)
+	}
+	defer tx.Rollback()
+	
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM conversations WHERE id = ? FOR UPDATE`, conversationID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("lock conversation status: %w", err)
+	}
+	if status == domain.ConversationStatusUnread {
+		return "", nil
+	}
+	
+	_, err = tx.ExecContext(ctx, `
+		UPDATE conversations
+		SET status = ?, snoozed_until = NULL, updated_at = NOW()
+		WHERE id = ?
+	`, domain.ConversationStatusUnread, conversationID)
+	if err != nil {
+		return "", fmt.Errorf("reopen conversation: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("commit reopen conversation: %w", err)
+	}
+	
+	if status == domain.ConversationStatusRead {
+		return "", nil // Was open already, only the unread badge is back
+	}
+	return status, nil
+}
+
 // ============================================================================
 // Phase 3: Conversation Management & Reply System
 // ============================================================================
 
 // ConversationWithSnippet represents a conversation with latest message preview
 type ConversationWithSnippet struct {
-	ID                 int64  `json:"id"`
-	TenantID           int    `json:"tenant_id"`
-	PlatformID         string `json:"platform_id"`
-	PageID             string `json:"page_id"`
-	CustomerName       string `json:"customer_name"`
-	LastMessageContent string `json:"last_message_content"`
-	LastMessageAt      string `json:"last_message_at"`
-	Status             string `json:"status"`
+	ID                 int64      `json:"id"`
+	TenantID           int        `json:"tenant_id"`
+	Platform           string     `json:"platform"`
+	PlatformID         string     `json:"platform_id"`
+	PageID             string     `json:"page_id"`
+	CustomerName       string     `json:"customer_name"`
+	LastMessageContent string     `json:"last_message_content"`
+	LastMessageAt      string     `json:"last_message_at"`
+	Status             string     `json:"status"`
+	ReferralSource     string     `json:"referral_source,omitempty"` // Ad / m.me attribution
+	AssigneeID         *int       `json:"assignee_id"`
+	AssignedTeamID     *int       `json:"assigned_team_id"`
+	Tags               []string   `json:"tags"`
+	SnoozedUntil       *time.Time `json:"snoozed_until,omitempty"`
 }
 
-// GetConversations retrieves list of conversations ordered by last activity
+// Conversation / message page sizes
+const (
+	DefaultConversationPageSize = 50
+	MaxConversationPageSize     = 100
+	DefaultMessagePageSize      = 100
+	MaxMessagePageSize          = 500
+)
+
+// conversationSortKey is the keyset sort expression of the conversation list
+const conversationSortKey = "COALESCE(c.last_message_at, c.created_at)"
+
+// GetConversations retrieves one page of conversations, most recent activity first
 // Joins with messages to get latest message snippet (Phase 3 Dashboard requirement)
-func (r *MariaDBRepository) GetConversations(ctx context.Context, pageID string) ([]ConversationWithSnippet, error) {
+// Restricted to the staff's data scope, then narrowed by the filter
+// Keyset pagination on (last_message_at, id): Next = older conversations, Prev = newer ones
+func (r *MariaDBRepository) GetConversations(ctx context.Context, scope domain.ConversationScope, filter domain.ConversationFilter, page domain.PageQuery) ([]ConversationWithSnippet, domain.PageCursors, error) {
+	if page.Limit <= 0 || page.Limit > MaxConversationPageSize {
+		page.Limit = DefaultConversationPageSize
+	}
+	scopeSQL, args := conversationScopeFilter(scope)
+	conditions := []string{scopeSQL}
+	
+	if filter.PageID != "" {
+		conditions = append(conditions, "c.page_id = ?")
+		args = append(args, filter.PageID)
+	}
+	if filter.AssigneeID != nil {
+		conditions = append(conditions, "c.assignee_id = ?")
+		args = append(args, *filter.AssigneeID)
+	}
+	if filter.Unassigned {
+		conditions = append(conditions, "c.assignee_id IS NULL")
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, "JSON_CONTAINS(c.tags, JSON_QUOTE(?))")
+		args = append(args, filter.Tag)
+	}
+	if len(filter.Statuses) > 0 {
+		placeholders := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			placeholders[i] = "?"
+			args = append(args, status)
+		}
+		conditions = append(conditions, "c.status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	
+	keysetSQL, keysetArgs, desc := keysetCondition(conversationSortKey, "c.id", page)
+	if keysetSQL != "" {
+		conditions = append(conditions, keysetSQL)
+		args = append(args, keysetArgs...)
+	}
+	order := "DESC"
+	if !desc {
+		order = "ASC"
+	}
+	args = append(args, page.Limit+1)
+
 	query := `
 		SELECT 
 			c.id,
 			c.tenant_id,
+			c.platform,
 			c.platform_id,
 			c.page_id,
 			COALESCE(c.customer_name, c.platform_id) as customer_name,
 			COALESCE(c.last_message_content, '') as last_message_content,
 			COALESCE(c.last_message_at, c.created_at) as last_message_at,
-			c.status
+			c.status,
+			COALESCE(c.referral_source, '') as referral_source,
+			c.assignee_id,
+			c.assigned_team_id,
+			c.tags,
+			c.snoozed_until
 		FROM conversations c
-		WHERE c.page_id = ?
-		ORDER BY COALESCE(c.last_message_at, c.created_at) DESC
-		LIMIT 100
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY ` + conversationSortKey + ` ` + order + `, c.id ` + order + `
+		LIMIT ?
 	`
 	
-	rows, err := r.db.QueryContext(ctx, query, pageID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		slog.Error("Failed to get conversations",
 			"error", err,
-			"page_id", pageID,
+			"tenant_id", scope.TenantID,
+			"page_id", filter.PageID,
 		)
-		return nil, fmt.Errorf("get conversations: %w", err)
+		return nil, domain.PageCursors{}, fmt.Errorf("get conversations: %w", err)
 	}
 	defer rows.Close()
 	
-	var conversations []ConversationWithSnippet
+	var (
+		conversations []ConversationWithSnippet
+		keys          []domain.Cursor
+	)
 	for rows.Next() {
 		var conv ConversationWithSnippet
+		var tagsJSON []byte
+		var lastMessageAt time.Time
 		err := rows.Scan(
 			&conv.ID,
 			&conv.TenantID,
+			&conv.Platform,
 			&conv.PlatformID,
 			&conv.PageID,
 			&conv.CustomerName,
 			&conv.LastMessageContent,
-			&conv.LastMessageAt,
+			&lastMessageAt,
 			&conv.Status,
+			&conv.ReferralSource,
+			&conv.AssigneeID,
+			&conv.AssignedTeamID,
+			&tagsJSON,
+			&conv.SnoozedUntil,
 		)
 		if err != nil {
 			slog.Error("Failed to scan conversation row", "error", err)
 			continue
 		}
+		if conv.Tags, err = decodeTags(tagsJSON); err != nil {
+			slog.Warn("Invalid tags on conversation", "conversation_id", conv.ID, "error", err)
+			conv.Tags = []string{}
+		}
+		conv.LastMessageAt = lastMessageAt.Format(time.RFC3339Nano)
 		conversations = append(conversations, conv)
+		keys = append(keys, domain.Cursor{At: lastMessageAt, ID: conv.ID})
+	}
+	
+	more := len(conversations) > page.Limit
+	if more {
+		conversations, keys = conversations[:page.Limit], keys[:page.Limit]
+	}
+	if !desc {
+		// Fetched oldest first (after cursor), the list is newest first
+		for i, j := 0, len(conversations)-1; i < j; i, j = i+1, j-1 {
+			conversations[i], conversations[j] = conversations[j], conversations[i]
+			keys[i], keys[j] = keys[j], keys[i]
+		}
 	}
 	
 	slog.Info("Retrieved conversations",
-		"page_id", pageID,
+		"tenant_id", scope.TenantID,
+		"staff_id", scope.StaffID,
+		"page_id", filter.PageID,
 		"count", len(conversations),
 	)
 	
-	return conversations, nil
+	return conversations, keysetCursors(page, keys, desc, true, more), nil
+}
+
+// CanAccessConversation reports whether a conversation exists within the staff's data scope
+func (r *MariaDBRepository) CanAccessConversation(ctx context.Context, conversationID int64, scope domain.ConversationScope) (bool, error) {
+	scopeSQL, scopeArgs := conversationScopeFilter(scope)
+	
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM conversations c WHERE c.id = ? AND `+scopeSQL+`)`,
+		append([]interface{}{conversationID}, scopeArgs...)...,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check conversation access: %w", err)
+	}
+	return exists, nil
 }
 
-// GetMessages retrieves all messages for a specific conversation
-// Ordered by created_at ASC (oldest first) for chat display (Phase 3)
-func (r *MariaDBRepository) GetMessages(ctx context.Context, conversationID int64) ([]*domain.Message, error) {
+// conversationScopeFilter returns the WHERE condition (on alias c) and args of a data scope
+// Agents only see conversations assigned to them or to their team
+// (SQL form of domain.ConversationScope.Includes, used for live events)
+func conversationScopeFilter(scope domain.ConversationScope) (string, []interface{}) {
+	if !scope.AssignedOnly {
+		return "c.tenant_id = ?", []interface{}{scope.TenantID}
+	}
+	if scope.TeamID == nil {
+		return "c.tenant_id = ? AND c.assignee_id = ?", []interface{}{scope.TenantID, scope.StaffID}
+	}
+	return "c.tenant_id = ? AND (c.assignee_id = ? OR c.assigned_team_id = ?)",
+		[]interface{}{scope.TenantID, scope.StaffID, *scope.TeamID}
+}
+
+// GetMessages retrieves one page of a conversation's messages
+// Ordered by created_at ASC (oldest first) for chat display (Phase 3); without a
+// cursor the most recent page is returned
+// Keyset pagination on (created_at, id): Next = newer messages, Prev = older ones
+func (r *MariaDBRepository) GetMessages(ctx context.Context, conversationID int64, page domain.PageQuery) ([]*domain.Message, domain.PageCursors, error) {
+	if page.Limit <= 0 || page.Limit > MaxMessagePageSize {
+		page.Limit = DefaultMessagePageSize
+	}
+	
+	conditions := "conversation_id = ?"
+	args := []interface{}{conversationID}
+	keysetSQL, keysetArgs, desc := keysetCondition("created_at", "id", page)
+	if keysetSQL != "" {
+		conditions += " AND " + keysetSQL
+		args = append(args, keysetArgs...)
+	}
+	order := "DESC"
+	if !desc {
+		order = "ASC"
+	}
+	args = append(args, page.Limit+1)
+	
 	query := `
 		SELECT 
 			id, conversation_id, sender_id, sender_type, content,
-			attachments, type, is_synced, external_msg_id, created_at
+			attachments, type, is_synced, external_msg_id, payload, delivery_status, delivery_error, is_external,
+			edit_history, edited_at, is_deleted, deleted_at, reactions, message_tag, created_at
 		FROM messages
-		WHERE conversation_id = ?
-		ORDER BY created_at ASC
-		LIMIT 1000
+		WHERE ` + conditions + `
+		ORDER BY created_at ` + order + `, id ` + order + `
+		LIMIT ?
 	`
 	
-	rows, err := r.db.QueryContext(ctx, query, conversationID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		slog.Error("Failed to get messages",
 			"error", err,
 			"conversation_id", conversationID,
 		)
-		return nil, fmt.Errorf("get messages: %w", err)
+		return nil, domain.PageCursors{}, fmt.Errorf("get messages: %w", err)
 	}
 	defer rows.Close()
 	
@@ -379,6 +840,16 @@ func (r *MariaDBRepository) GetMessages(ctx context.Context, conversationID int6
 			&msg.Type,
 			&msg.IsSynced,
 			&msg.ExternalMsgID,
+			&msg.Payload,
+			&msg.DeliveryStatus,
+			&msg.DeliveryError,
+			&msg.IsExternal,
+			(*[]byte)(&msg.EditHistory),
+			&msg.EditedAt,
+			&msg.IsDeleted,
+			&msg.DeletedAt,
+			(*[]byte)(&msg.Reactions),
+			&msg.MessageTag,
 			&msg.CreatedAt,
 		)
 		if err != nil {
@@ -388,37 +859,126 @@ func (r *MariaDBRepository) GetMessages(ctx context.Context, conversationID int6
 		messages = append(messages, &msg)
 	}
 	
+	more := len(messages) > page.Limit
+	if more {
+		messages = messages[:page.Limit]
+	}
+	if desc {
+		// Fetched newest first (latest page / before cursor), the chat shows oldest first
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+	keys := make([]domain.Cursor, len(messages))
+	for i, msg := range messages {
+		keys[i] = domain.Cursor{At: msg.CreatedAt, ID: msg.ID}
+	}
+	
 	slog.Info("Retrieved messages",
 		"conversation_id", conversationID,
 		"count", len(messages),
 	)
 	
-	return messages, nil
+	return messages, keysetCursors(page, keys, desc, false, more), nil
+}
+
+// keysetCondition returns the keyset condition (on sortExpr, idExpr) of a page and
+// whether rows are fetched newest first: walking away from the cursor, descending
+// for Before and ascending for After; pages without a cursor start at the newest row
+func keysetCondition(sortExpr, idExpr string, page domain.PageQuery) (string, []interface{}, bool) {
+	switch {
+	case page.Before != nil:
+		return "(" + sortExpr + " < ? OR (" + sortExpr + " = ? AND " + idExpr + " < ?))",
+			[]interface{}{page.Before.At, page.Before.At, page.Before.ID}, true
+	case page.After != nil:
+		return "(" + sortExpr + " > ? OR (" + sortExpr + " = ? AND " + idExpr + " > ?))",
+			[]interface{}{page.After.At, page.After.At, page.After.ID}, false
+	default:
+		return "", nil, true
+	}
+}
+
+// keysetCursors returns the neighbouring page cursors of a fetched page
+// keys are the page's sort keys in list order; listDesc tells whether the list is
+// newest first; more reports a row beyond the limit in the fetch direction
+// The side the page was fetched from exists whenever a cursor was given
+func keysetCursors(page domain.PageQuery, keys []domain.Cursor, fetchedDesc, listDesc, more bool) domain.PageCursors {
+	var cursors domain.PageCursors
+	if len(keys) == 0 {
+		return cursors
+	}
+	first, last := keys[0], keys[len(keys)-1]
+	hasCursor := page.Before != nil || page.After != nil
+	
+	// Fetched in list order: "more" lies after the page, the cursor side before it
+	forward := fetchedDesc == listDesc
+	if (forward && more) || (!forward && hasCursor) {
+		cursors.Next = &last
+	}
+	if (!forward && more) || (forward && hasCursor) {
+		cursors.Prev = &first
+	}
+	return cursors
 }
 
 // SaveOutboundMessage persists a reply message sent by Admin to customer
-// Used after successful Facebook Send API call (Phase 3)
+// Used after successful platform send API call (Phase 3)
 func (r *MariaDBRepository) SaveOutboundMessage(ctx context.Context, msg *domain.Message) error {
+	// The platform echo can be processed before we get here: it was stored as an
+	// external message, claim it instead of inserting a duplicate
+	if msg.ExternalMsgID != nil && *msg.ExternalMsgID != "" {
+		result, err := r.db.ExecContext(ctx, `
+			UPDATE messages
+			SET sender_id = ?, is_external = FALSE, message_tag = ?
+			WHERE external_msg_id = ? AND sender_type = 'agent'
+		`, msg.SenderID, msg.MessageTag, *msg.ExternalMsgID)
+		if err != nil {
+			return fmt.Errorf("claim echoed outbound message: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows > 0 {
+			slog.Info("Outbound message already stored from echo",
+				"conversation_id", msg.ConversationID,
+				"external_msg_id", *msg.ExternalMsgID,
+			)
+			return nil
+		}
+	}
+	
 	query := `
 		INSERT INTO messages (
 			conversation_id, sender_id, sender_type, content,
-			attachments, type, is_synced, created_at
+			attachments, type, is_synced, external_msg_id, delivery_status, message_tag, created_at
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, NOW())
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
 	`
 	
-	// For outbound messages, we use empty attachments and set type to 'text'
-	emptyAttachments := json.RawMessage("[]")
-	textType := "text"
+	// Text replies have no attachments; media replies carry their type and attachments
+	attachments := json.RawMessage("[]")
+	if len(msg.Attachments) > 0 {
+		attachments = msg.Attachments
+	}
+	messageType := domain.MessageTypeText
+	if msg.Type != nil && *msg.Type != "" {
+		messageType = *msg.Type
+	}
+	
+	// Accepted by the platform = sent; receipts move it to delivered/read later
+	deliveryStatus := domain.DeliveryStatusSent
+	if msg.DeliveryStatus != nil {
+		deliveryStatus = *msg.DeliveryStatus
+	}
 	
 	_, err := r.db.ExecContext(ctx, query,
 		msg.ConversationID,
 		msg.SenderID,      // Admin ID or "system"
 		domain.SenderTypeAgent, // Always 'agent' for admin replies
 		msg.Content,
-		emptyAttachments,
-		textType,
+		attachments,
+		messageType,
 		false, // is_synced = false initially
+		msg.ExternalMsgID, // Platform message ID returned by the send API (may be nil)
+		deliveryStatus,
+		msg.MessageTag, // Set when sent outside the 24-hour window
 	)
 	
 	if err != nil {
@@ -432,95 +992,418 @@ func (r *MariaDBRepository) SaveOutboundMessage(ctx context.Context, msg *domain
 	slog.Info("Outbound message saved",
 		"conversation_id", msg.ConversationID,
 		"sender_type", "agent",
+		"type", messageType,
+		"delivery_status", deliveryStatus,
 	)
 	
 	return nil
 }
 
-// GetPageAccessToken retrieves the access token for a Facebook page
-// Required for Send API calls (Phase 3)
-func (r *MariaDBRepository) GetPageAccessToken(ctx context.Context, pageID string) (string, error) {
-	query := `SELECT access_token FROM pages WHERE page_id = ? AND is_active = TRUE LIMIT 1`
-	
-	var accessToken string
-	err := r.db.QueryRowContext(ctx, query, pageID).Scan(&accessToken)
-	
-	if err == sql.ErrNoRows {
-		slog.Warn("No active page found", "page_id", pageID)
-		return "", fmt.Errorf("page not found or inactive")
+// UpdateDeliveryStatus applies a delivery / read receipt to outbound messages
+// Status only moves forward (sent -> delivered -> read); failed is final
+func (r *MariaDBRepository) UpdateDeliveryStatus(ctx context.Context, update domain.DeliveryUpdate) (int64, error) {
+	var from []string
+	switch update.Status {
+	case domain.DeliveryStatusDelivered:
+		from = []string{domain.DeliveryStatusSent}
+	case domain.DeliveryStatusRead:
+		from = []string{domain.DeliveryStatusSent, domain.DeliveryStatusDelivered}
+	default:
+		return 0, fmt.Errorf("unsupported delivery status %q", update.Status)
 	}
 	
-	if err != nil {
-		slog.Error("Failed to get page access token",
-			"error", err,
-			"page_id", pageID,
-		)
-		return "", fmt.Errorf("get page access token: %w", err)
+	// Match by message IDs and/or watermark; nothing to do without either
+	var match []string
+	args := []interface{}{update.Status, update.TenantID, update.Platform, update.PageID, update.CustomerID, from[0]}
+	if len(from) > 1 {
+		args = append(args, from[1])
+	}
+	if len(update.MessageIDs) > 0 {
+		match = append(match, "m.external_msg_id IN (?"+strings.Repeat(", ?", len(update.MessageIDs)-1)+")")
+		for _, id := range update.MessageIDs {
+			args = append(args, id)
+		}
+	}
+	if !update.Watermark.IsZero() {
+		match = append(match, "m.created_at <= ?")
+		args = append(args, update.Watermark)
+	}
+	if len(match) == 0 {
+		return 0, nil
 	}
 	
-	slog.Debug("Retrieved page access token", "page_id", pageID)
-	return accessToken, nil
-}
-
-// UpdateConversationLastMessage updates conversation metadata after new message
-// Used to keep conversation list fresh (Phase 3)
-func (r *MariaDBRepository) UpdateConversationLastMessage(ctx context.Context, conversationID int64, content string) error {
 	query := `
-		UPDATE conversations
-		SET last_message_content = ?,
-			last_message_at = NOW(),
-			updated_at = NOW()
-		WHERE id = ?
+		UPDATE messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		SET m.delivery_status = ?,
+			m.delivery_updated_at = NOW()
+		WHERE c.tenant_id = ? AND c.platform = ? AND c.page_id = ? AND c.platform_id = ?
+			AND m.sender_type = 'agent'
+			AND m.delivery_status IN (?` + strings.Repeat(", ?", len(from)-1) + `)
+			AND (` + strings.Join(match, " OR ") + `)
 	`
 	
-	_, err := r.db.ExecContext(ctx, query, content, conversationID)
+	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
-		slog.Error("Failed to update conversation last message",
+		slog.Error("Failed to update delivery status",
 			"error", err,
-			"conversation_id", conversationID,
+			"status", update.Status,
+			"page_id", update.PageID,
+			"customer_id", update.CustomerID,
 		)
-		return fmt.Errorf("update conversation: %w", err)
+		return 0, fmt.Errorf("update delivery status: %w", err)
 	}
 	
-	return nil
+	rows, _ := result.RowsAffected()
+	return rows, nil
 }
 
-// ============================================================================
-// Phase 3 Enhancement: Resilience & Error Recovery
-// ============================================================================
-
-// DeactivatePage disables a page when token expires or becomes invalid
-// Per "Core hệ thống lỗi": AUTO deactivate to prevent futile API calls
-func (r *MariaDBRepository) DeactivatePage(ctx context.Context, pageID string) error {
-	query := `
-		UPDATE pages
-		SET is_active = FALSE
-		WHERE page_id = ?
-	`
+// ApplyMessageChange applies an edit, unsend or reaction to a stored message
+// Only messages of the customer's own conversation on that page can be changed;
+// edits and unsends additionally require the customer to be the sender
+// Changes not newer than the stored ones (redelivered / replayed webhooks) return nil
+func (r *MariaDBRepository) ApplyMessageChange(ctx context.Context, change domain.MessageChange) (*domain.Message, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin message change: %w", err)
+	}
+	defer tx.Rollback()
 	
-	result, err := r.db.ExecContext(ctx, query, pageID)
+	var (
+		id          int64
+		state       messageChangeState
+		content     sql.NullString
+		editHistory []byte
+		reactions   []byte
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT m.id, m.sender_type, m.content, m.is_deleted, m.edited_at, m.reacted_at, m.edit_history, m.reactions
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE m.external_msg_id = ?
+			AND c.tenant_id = ? AND c.platform = ? AND c.page_id = ? AND c.platform_id = ?
+		LIMIT 1
+		FOR UPDATE
+	`, change.ExternalMsgID, change.TenantID, change.Platform, change.PageID, change.CustomerID).Scan(
+		&id, &state.senderType, &content, &state.isDeleted, &state.editedAt, &state.reactedAt, &editHistory, &reactions,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil // Unknown message (sent before we were connected, other conversation)
+	}
 	if err != nil {
-		slog.Error("Failed to deactivate page",
+		return nil, fmt.Errorf("lock message for change: %w", err)
+	}
+	state.content = content.String
+	if len(editHistory) > 0 {
+		if err := json.Unmarshal(editHistory, &state.editHistory); err != nil {
+			return nil, fmt.Errorf("decode edit history of message %d: %w", id, err)
+		}
+	}
+	if len(reactions) > 0 {
+		if err := json.Unmarshal(reactions, &state.reactions); err != nil {
+			return nil, fmt.Errorf("decode reactions of message %d: %w", id, err)
+		}
+	}
+	
+	applied, err := applyMessageChange(&state, change)
+	if err != nil || !applied {
+		return nil, err
+	}
+	
+	switch change.Kind {
+	case domain.EventTypeEdit:
+		historyJSON, _ := json.Marshal(state.editHistory)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE messages SET content = ?, edit_history = ?, edited_at = ? WHERE id = ?
+		`, state.content, historyJSON, change.At, id)
+	case domain.EventTypeUnsend:
+		// Soft delete: the content stays for audit, the dashboard hides it
+		_, err = tx.ExecContext(ctx, `
+			UPDATE messages SET is_deleted = TRUE, deleted_at = ? WHERE id = ?
+		`, change.At, id)
+	case domain.EventTypeReaction:
+		reactionsJSON, _ := json.Marshal(state.reactions)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE messages SET reactions = ?, reacted_at = ? WHERE id = ?
+		`, reactionsJSON, change.At, id)
+	}
+	
+	if err != nil {
+		slog.Error("Failed to apply message change",
 			"error", err,
-			"page_id", pageID,
+			"kind", change.Kind,
+			"message_id", id,
 		)
-		return fmt.Errorf("deactivate page: %w", err)
+		return nil, fmt.Errorf("apply message %s: %w", change.Kind, err)
 	}
 	
-	rows, _ := result.RowsAffected()
-	if rows > 0 {
-		slog.Warn("🔴 PAGE DEACTIVATED - Token expired or invalid",
-			"page_id", pageID,
-			"action", "Admin must reconnect Facebook",
-		)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit message change: %w", err)
 	}
 	
-	return nil
+	return r.GetByID(ctx, strconv.FormatInt(id, 10))
 }
 
-// MarkConversationAsRead updates conversation status to 'read'
-// Called when Admin opens chat window to prevent perpetual "unread" badge
-func (r *MariaDBRepository) MarkConversationAsRead(ctx context.Context, conversationID int64) error {
+// messageChangeState is the part of a message that edits, unsends and reactions change
+type messageChangeState struct {
+	senderType  string
+	content     string
+	isDeleted   bool
+	editedAt    sql.NullTime // Time of the last applied edit
+	reactedAt   sql.NullTime // Time of the last applied reaction / unreaction
+	editHistory []domain.MessageEdit
+	reactions   []domain.MessageReaction
+}
+
+// applyMessageChange applies change to state and reports whether anything changed
+// A change is ignored when it is not the customer's to make, or when it is not newer
+// than the last one of its kind: a redelivered or replayed webhook must neither be
+// applied twice nor roll back a later edit / reaction
+func applyMessageChange(state *messageChangeState, change domain.MessageChange) (bool, error) {
+	switch change.Kind {
+	case domain.EventTypeEdit:
+		if state.senderType != domain.SenderTypeUser || state.content == change.Content ||
+			(state.editedAt.Valid && !change.At.After(state.editedAt.Time)) {
+			return false, nil
+		}
+		// Keep the replaced version in the history
+		state.editHistory = append(state.editHistory, domain.MessageEdit{Content: state.content, EditedAt: change.At})
+		state.content = change.Content
+		state.editedAt = sql.NullTime{Time: change.At, Valid: true}
+	
+	case domain.EventTypeUnsend:
+		if state.senderType != domain.SenderTypeUser || state.isDeleted {
+			return false, nil
+		}
+		state.isDeleted = true
+	
+	case domain.EventTypeReaction:
+		if change.Reaction == nil {
+			return false, fmt.Errorf("reaction change without reaction")
+		}
+		if state.reactedAt.Valid && !change.At.After(state.reactedAt.Time) {
+			return false, nil
+		}
+	
+		// One reaction per user: drop the previous one, then add the new one
+		kept := state.reactions[:0]
+		for _, reaction := range state.reactions {
+			if reaction.UserID != change.CustomerID {
+				kept = append(kept, reaction)
+			}
+		}
+		if change.Reaction.Action == domain.ReactionActionReact {
+			kept = append(kept, domain.MessageReaction{
+				UserID:    change.CustomerID,
+				Reaction:  change.Reaction.Reaction,
+				Emoji:     change.Reaction.Emoji,
+				ReactedAt: change.At,
+			})
+		}
+		state.reactions = kept
+		state.reactedAt = sql.NullTime{Time: change.At, Valid: true}
+	
+	default:
+		return false, fmt.Errorf("unsupported message change %q", change.Kind)
+	}
+	return true, nil
+}
+
+// GetPage retrieves a page by platform and platform page ID (nil if not found)
+// Used to route inbound events to the owning tenant
+func (r *MariaDBRepository) GetPage(ctx context.Context, platform, pageID string) (*domain.Page, error) {
+	query := `
+		SELECT id, tenant_id, platform, page_id, page_name, access_token, is_active
+		FROM pages
+		WHERE platform = ? AND page_id = ?
+	`
+	
+	var page domain.Page
+	err := r.db.QueryRowContext(ctx, query, platform, pageID).Scan(
+		&page.ID,
+		&page.TenantID,
+		&page.Platform,
+		&page.PageID,
+		&page.PageName,
+		&page.AccessToken,
+		&page.IsActive,
+	)
+	
+	if err == sql.ErrNoRows {
+		return nil, nil // Not connected
+	}
+	
+	if err != nil {
+		slog.Error("Failed to get page",
+			"error", err,
+			"platform", platform,
+			"page_id", pageID,
+		)
+		return nil, fmt.Errorf("get page: %w", err)
+	}
+	
+	return &page, nil
+}
+
+// QuarantineEvent stores an event for a page we cannot route to a tenant
+// The unique event_hash makes redelivered webhooks a no-op
+func (r *MariaDBRepository) QuarantineEvent(ctx context.Context, event *domain.QuarantinedEvent) error {
+	query := `
+		INSERT IGNORE INTO quarantined_events (platform, page_id, reason, event_json, event_hash, created_at)
+		VALUES (?, ?, ?, ?, SHA2(?, 256), NOW())
+	`
+	
+	result, err := r.db.ExecContext(ctx, query,
+		event.Platform,
+		event.PageID,
+		event.Reason,
+		event.Event,
+		event.Event,
+	)
+	if err != nil {
+		slog.Error("Failed to quarantine event",
+			"error", err,
+			"platform", event.Platform,
+			"page_id", event.PageID,
+		)
+		return fmt.Errorf("quarantine event: %w", err)
+	}
+	
+	event.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// ListQuarantined returns the quarantined events of a page after afterID (keyset pages)
+func (r *MariaDBRepository) ListQuarantined(ctx context.Context, platform, pageID string, afterID int64, limit int) ([]*domain.QuarantinedEvent, error) {
+	query := `
+		SELECT id, platform, page_id, reason, event_json, created_at
+		FROM quarantined_events
+		WHERE platform = ? AND page_id = ? AND id > ?
+		ORDER BY id
+		LIMIT ?
+	`
+	
+	rows, err := r.db.QueryContext(ctx, query, platform, pageID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list quarantined events: %w", err)
+	}
+	defer rows.Close()
+	
+	var events []*domain.QuarantinedEvent
+	for rows.Next() {
+		var event domain.QuarantinedEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.Platform,
+			&event.PageID,
+			&event.Reason,
+			(*[]byte)(&event.Event),
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan quarantined event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate quarantined events: %w", err)
+	}
+	
+	return events, nil
+}
+
+// DeleteQuarantined removes a replayed quarantined event
+func (r *MariaDBRepository) DeleteQuarantined(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM quarantined_events WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete quarantined event: %w", err)
+	}
+	return nil
+}
+
+// GetPageAccessToken retrieves the access token for a page / OA
+// Required for Send API calls (Phase 3); pages are unique per (platform, page_id)
+func (r *MariaDBRepository) GetPageAccessToken(ctx context.Context, platform, pageID string) (string, error) {
+	query := `SELECT access_token FROM pages WHERE platform = ? AND page_id = ? AND is_active = TRUE LIMIT 1`
+	
+	var accessToken string
+	err := r.db.QueryRowContext(ctx, query, platform, pageID).Scan(&accessToken)
+	
+	if err == sql.ErrNoRows {
+		slog.Warn("No active page found", "platform", platform, "page_id", pageID)
+		return "", ports.ErrPageInactive
+	}
+	
+	if err != nil {
+		slog.Error("Failed to get page access token",
+			"error", err,
+			"page_id", pageID,
+		)
+		return "", fmt.Errorf("get page access token: %w", err)
+	}
+	
+	slog.Debug("Retrieved page access token", "page_id", pageID)
+	return accessToken, nil
+}
+
+// UpdateConversationLastMessage updates conversation metadata after new message
+// Used to keep conversation list fresh (Phase 3)
+func (r *MariaDBRepository) UpdateConversationLastMessage(ctx context.Context, conversationID int64, content string) error {
+	query := `
+		UPDATE conversations
+		SET last_message_content = ?,
+			last_message_at = NOW(),
+			updated_at = NOW()
+		WHERE id = ?
+	`
+	
+	_, err := r.db.ExecContext(ctx, query, content, conversationID)
+	if err != nil {
+		slog.Error("Failed to update conversation last message",
+			"error", err,
+			"conversation_id", conversationID,
+		)
+		return fmt.Errorf("update conversation: %w", err)
+	}
+	
+	return nil
+}
+
+// ============================================================================
+// Phase 3 Enhancement: Resilience & Error Recovery
+// ============================================================================
+
+// DeactivatePage disables a page when token expires or becomes invalid
+// Per "Core hệ thống lỗi": AUTO deactivate to prevent futile API calls
+func (r *MariaDBRepository) DeactivatePage(ctx context.Context, platform, pageID string) error {
+	query := `
+		UPDATE pages
+		SET is_active = FALSE
+		WHERE platform = ? AND page_id = ?
+	`
+	
+	result, err := r.db.ExecContext(ctx, query, platform, pageID)
+	if err != nil {
+		slog.Error("Failed to deactivate page",
+			"error", err,
+			"page_id", pageID,
+		)
+		return fmt.Errorf("deactivate page: %w", err)
+	}
+	
+	rows, _ := result.RowsAffected()
+	if rows > 0 {
+		slog.Warn("🔴 PAGE DEACTIVATED - Token expired or invalid",
+			"platform", platform,
+			"page_id", pageID,
+			"action", "Admin must reconnect the page",
+		)
+	}
+	
+	return nil
+}
+
+// MarkConversationAsRead updates conversation status to 'read'
+// Called when Admin opens chat window to prevent perpetual "unread" badge
+func (r *MariaDBRepository) MarkConversationAsRead(ctx context.Context, conversationID int64) error {
 	query := `
 		UPDATE conversations
 		SET status = 'read',
@@ -547,10 +1430,1196 @@ func (r *MariaDBRepository) MarkConversationAsRead(ctx context.Context, conversa
 	return nil
 }
 
-// GetStaffInfo retrieves staff information for message metadata
-// Returns staff_id and name for audit trail (not hardcoded "admin")
-func (r *MariaDBRepository) GetStaffInfo(ctx context.Context, staffID int) (string, string, error) {
-	// TODO: Implement after staff table is created
-	// For now, return default
-	return "1", "Admin", nil
+// ============================================================================
+// TenantRepository Implementation
+// ============================================================================
+
+// GetTenant retrieves a tenant by ID (nil if not found)
+func (r *MariaDBRepository) GetTenant(ctx context.Context, tenantID int) (*domain.Tenant, error) {
+	query := `SELECT id, name, plan, expired_at, config, is_active FROM tenants WHERE id = ?`
+	
+	tenant, err := scanTenant(r.db.QueryRowContext(ctx, query, tenantID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Failed to get tenant", "error", err, "tenant_id", tenantID)
+		return nil, fmt.Errorf("get tenant: %w", err)
+	}
+	
+	return tenant, nil
+}
+
+// ListTenants returns all tenants ordered by ID
+func (r *MariaDBRepository) ListTenants(ctx context.Context) ([]*domain.Tenant, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name, plan, expired_at, config, is_active FROM tenants ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list tenants: %w", err)
+	}
+	defer rows.Close()
+	
+	var tenants []*domain.Tenant
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan tenant: %w", err)
+		}
+		tenants = append(tenants, tenant)
+	}
+	
+	return tenants, rows.Err()
+}
+
+// scanTenant maps a tenants row (plan and config are nullable)
+func scanTenant(row rowScanner) (*domain.Tenant, error) {
+	var (
+		tenant domain.Tenant
+		plan   sql.NullString
+		active sql.NullBool
+	)
+	if err := row.Scan(&tenant.ID, &tenant.Name, &plan, &tenant.ExpiredAt, (*[]byte)(&tenant.Config), &active); err != nil {
+		return nil, err
+	}
+	tenant.Plan = plan.String
+	tenant.IsActive = !active.Valid || active.Bool // Column defaults to TRUE
+	
+	return &tenant, nil
+}
+
+// CountActivePages counts the tenant's active pages / OAs / bots
+func (r *MariaDBRepository) CountActivePages(ctx context.Context, tenantID int) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pages WHERE tenant_id = ? AND is_active = TRUE`, tenantID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count active pages: %w", err)
+	}
+	return count, nil
+}
+
+// CountMessagesSince counts inbound and outbound messages of the tenant since a time
+func (r *MariaDBRepository) CountMessagesSince(ctx context.Context, tenantID int, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE c.tenant_id = ? AND m.created_at >= ?
+	`
+	
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, tenantID, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count messages: %w", err)
+	}
+	return count, nil
+}
+
+// PurgeMessagesBefore deletes up to limit messages of the tenant older than before
+func (r *MariaDBRepository) PurgeMessagesBefore(ctx context.Context, tenantID int, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM messages
+		WHERE conversation_id IN (SELECT id FROM conversations WHERE tenant_id = ?)
+			AND created_at < ?
+		LIMIT ?
+	`
+	
+	result, err := r.db.ExecContext(ctx, query, tenantID, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("purge messages: %w", err)
+	}
+	
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
+
+// CountActiveStaff counts the tenant's active staff accounts
+func (r *MariaDBRepository) CountActiveStaff(ctx context.Context, tenantID int) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM staff WHERE tenant_id = ? AND is_active = TRUE`, tenantID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count active staff: %w", err)
+	}
+	return count, nil
+}
+
+// ============================================================================
+// StaffRepository Implementation
+// ============================================================================
+
+const staffColumns = `id, tenant_id, email, name, role, team_id, password_hash, is_active, last_login_at, created_at`
+
+// GetStaff retrieves a staff account by ID (nil if not found)
+func (r *MariaDBRepository) GetStaff(ctx context.Context, staffID int) (*domain.Staff, error) {
+	staff, err := scanStaff(r.db.QueryRowContext(ctx, `SELECT `+staffColumns+` FROM staff WHERE id = ?`, staffID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get staff: %w", err)
+	}
+	return staff, nil
+}
+
+// GetStaffByEmail retrieves a staff account by login email (nil if not found)
+func (r *MariaDBRepository) GetStaffByEmail(ctx context.Context, email string) (*domain.Staff, error) {
+	staff, err := scanStaff(r.db.QueryRowContext(ctx, `SELECT `+staffColumns+` FROM staff WHERE email = ?`, email))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get staff by email: %w", err)
+	}
+	return staff, nil
+}
+
+// CreateStaff inserts a staff account and sets staff.ID
+func (r *MariaDBRepository) CreateStaff(ctx context.Context, staff *domain.Staff) error {
+	query := `
+		INSERT INTO staff (tenant_id, email, name, role, team_id, password_hash, is_active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	
+	result, err := r.db.ExecContext(ctx, query,
+		staff.TenantID,
+		staff.Email,
+		staff.Name,
+		staff.Role,
+		staff.TeamID,
+		staff.PasswordHash,
+		staff.IsActive,
+		staff.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert staff: %w", err)
+	}
+	
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("get staff id: %w", err)
+	}
+	staff.ID = int(id)
+	
+	return nil
+}
+
+// UpdateLastLogin records a successful login
+func (r *MariaDBRepository) UpdateLastLogin(ctx context.Context, staffID int) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE staff SET last_login_at = NOW() WHERE id = ?`, staffID); err != nil {
+		return fmt.Errorf("update last login: %w", err)
+	}
+	return nil
+}
+
+// ListStaff returns the tenant's staff accounts ordered by name
+func (r *MariaDBRepository) ListStaff(ctx context.Context, tenantID int) ([]*domain.Staff, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+staffColumns+` FROM staff WHERE tenant_id = ? ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list staff: %w", err)
+	}
+	defer rows.Close()
+	
+	var staff []*domain.Staff
+	for rows.Next() {
+		s, err := scanStaff(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan staff: %w", err)
+		}
+		staff = append(staff, s)
+	}
+	
+	return staff, rows.Err()
+}
+
+// GetTeam retrieves a team of the tenant (nil if not found)
+func (r *MariaDBRepository) GetTeam(ctx context.Context, tenantID, teamID int) (*domain.Team, error) {
+	var team domain.Team
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, tenant_id, name, created_at FROM teams WHERE id = ? AND tenant_id = ?`,
+		teamID, tenantID,
+	).Scan(&team.ID, &team.TenantID, &team.Name, &team.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get team: %w", err)
+	}
+	return &team, nil
+}
+
+// ListTeams returns the tenant's teams ordered by name
+func (r *MariaDBRepository) ListTeams(ctx context.Context, tenantID int) ([]*domain.Team, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, tenant_id, name, created_at FROM teams WHERE tenant_id = ? ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list teams: %w", err)
+	}
+	defer rows.Close()
+	
+	var teams []*domain.Team
+	for rows.Next() {
+		var team domain.Team
+		if err := rows.Scan(&team.ID, &team.TenantID, &team.Name, &team.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan team: %w", err)
+		}
+		teams = append(teams, &team)
+	}
+	
+	return teams, rows.Err()
+}
+
+// CreateTeam inserts a team and sets team.ID
+func (r *MariaDBRepository) CreateTeam(ctx context.Context, team *domain.Team) error {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO teams (tenant_id, name, created_at) VALUES (?, ?, ?)`,
+		team.TenantID, team.Name, team.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert team: %w", err)
+	}
+	
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("get team id: %w", err)
+	}
+	team.ID = int(id)
+	
+	return nil
+}
+
+// scanStaff maps a staff row selected with staffColumns
+func scanStaff(row rowScanner) (*domain.Staff, error) {
+	var staff domain.Staff
+	err := row.Scan(
+		&staff.ID,
+		&staff.TenantID,
+		&staff.Email,
+		&staff.Name,
+		&staff.Role,
+		&staff.TeamID,
+		&staff.PasswordHash,
+		&staff.IsActive,
+		&staff.LastLoginAt,
+		&staff.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &staff, nil
+}
+
+// GetStaffInfo retrieves staff information for message metadata
+// Returns staff_id and name for audit trail (not hardcoded "admin")
+func (r *MariaDBRepository) GetStaffInfo(ctx context.Context, staffID int) (string, string, error) {
+	staff, err := r.GetStaff(ctx, staffID)
+	if err != nil {
+		return "", "", err
+	}
+	if staff == nil {
+		return "", "", fmt.Errorf("staff %d not found", staffID)
+	}
+	return strconv.Itoa(staff.ID), staff.Name, nil
+}
+
+// ============================================================================
+// AssignmentRepository Implementation
+// ============================================================================
+
+// GetAssignment returns the conversation's tenant and assignment (nil if not found)
+func (r *MariaDBRepository) GetAssignment(ctx context.Context, conversationID int64) (*domain.ConversationAssignment, error) {
+	assignment := domain.ConversationAssignment{ConversationID: conversationID}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT tenant_id, assignee_id, assigned_team_id FROM conversations WHERE id = ?`,
+		conversationID,
+	).Scan(&assignment.TenantID, &assignment.AssigneeID, &assignment.AssignedTeamID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get assignment: %w", err)
+	}
+	return &assignment, nil
+}
+
+// SetAssignment updates assignee / team of a conversation
+// onlyIfUnassigned makes automatic routing lose against a concurrent manual assignment
+func (r *MariaDBRepository) SetAssignment(ctx context.Context, assignment domain.ConversationAssignment, onlyIfUnassigned bool) (bool, error) {
+	query := `UPDATE conversations SET assignee_id = ?, assigned_team_id = ? WHERE id = ? AND tenant_id = ?`
+	if onlyIfUnassigned {
+		query += ` AND assignee_id IS NULL`
+	}
+	
+	result, err := r.db.ExecContext(ctx, query,
+		assignment.AssigneeID,
+		assignment.AssignedTeamID,
+		assignment.ConversationID,
+		assignment.TenantID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("set assignment: %w", err)
+	}
+	
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// CountOpenByAssignee counts open (unread / read) conversations per staff of the tenant
+func (r *MariaDBRepository) CountOpenByAssignee(ctx context.Context, tenantID int) (map[int]int, error) {
+	query := `
+		SELECT assignee_id, COUNT(*)
+		FROM conversations
+		WHERE tenant_id = ? AND assignee_id IS NOT NULL AND status IN ('unread', 'read')
+		GROUP BY assignee_id
+	`
+	
+	rows, err := r.db.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("count open conversations: %w", err)
+	}
+	defer rows.Close()
+	
+	counts := make(map[int]int)
+	for rows.Next() {
+		var staffID, count int
+		if err := rows.Scan(&staffID, &count); err != nil {
+			return nil, fmt.Errorf("scan open count: %w", err)
+		}
+		counts[staffID] = count
+	}
+	
+	return counts, rows.Err()
+}
+
+// ============================================================================
+// TagRepository Implementation
+// ============================================================================
+
+// ListTags returns the tenant's tags with the number of conversations carrying them within the scope
+func (r *MariaDBRepository) ListTags(ctx context.Context, scope domain.ConversationScope) ([]*domain.Tag, error) {
+	scopeSQL, scopeArgs := conversationScopeFilter(scope)
+	query := `
+		SELECT t.id, t.tenant_id, t.name, t.color, t.created_at, COUNT(c.id)
+		FROM tags t
+		LEFT JOIN conversations c ON JSON_CONTAINS(c.tags, JSON_QUOTE(t.name)) AND ` + scopeSQL + `
+		WHERE t.tenant_id = ?
+		GROUP BY t.id, t.tenant_id, t.name, t.color, t.created_at
+		ORDER BY t.name
+	`
+	
+	rows, err := r.db.QueryContext(ctx, query, append(scopeArgs, scope.TenantID)...)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	defer rows.Close()
+	
+	var tags []*domain.Tag
+	for rows.Next() {
+		var tag domain.Tag
+		if err := rows.Scan(&tag.ID, &tag.TenantID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.ConversationCount); err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		tags = append(tags, &tag)
+	}
+	
+	return tags, rows.Err()
+}
+
+// GetTag retrieves a tag of the tenant (nil if not found)
+func (r *MariaDBRepository) GetTag(ctx context.Context, tenantID, tagID int) (*domain.Tag, error) {
+	var tag domain.Tag
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, tenant_id, name, color, created_at FROM tags WHERE id = ? AND tenant_id = ?`,
+		tagID, tenantID,
+	).Scan(&tag.ID, &tag.TenantID, &tag.Name, &tag.Color, &tag.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get tag: %w", err)
+	}
+	return &tag, nil
+}
+
+// CreateTag inserts a tag definition and sets tag.ID
+func (r *MariaDBRepository) CreateTag(ctx context.Context, tag *domain.Tag) error {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO tags (tenant_id, name, color, created_at) VALUES (?, ?, ?, ?)`,
+		tag.TenantID, tag.Name, tag.Color, tag.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert tag: %w", err)
+	}
+	
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("get tag id: %w", err)
+	}
+	tag.ID = int(id)
+	
+	return nil
+}
+
+// DeleteTag removes a tag definition and its name from the tenant's conversations
+func (r *MariaDBRepository) DeleteTag(ctx context.Context, tag *domain.Tag) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin delete tag: %w", err)
+	}
+	defer tx.Rollback()
+	
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = ? AND tenant_id = ?`, tag.ID, tag.TenantID); err != nil {
+		return fmt.Errorf("delete tag: %w", err)
+	}
+	
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, tags FROM conversations
+		WHERE tenant_id = ? AND JSON_CONTAINS(tags, JSON_QUOTE(?))
+		FOR UPDATE
+	`, tag.TenantID, tag.Name)
+	if err != nil {
+		return fmt.Errorf("find tagged conversations: %w", err)
+	}
+	updated := make(map[int64][]string)
+	for rows.Next() {
+		var (
+			id  int64
+			raw []byte
+		)
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan tagged conversation: %w", err)
+		}
+		names, err := decodeTags(raw)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("decode tags of conversation %d: %w", id, err)
+		}
+		updated[id] = withoutTag(names, tag.Name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("find tagged conversations: %w", err)
+	}
+	
+	for id, names := range updated {
+		tagsJSON, _ := json.Marshal(names)
+		if _, err := tx.ExecContext(ctx, `UPDATE conversations SET tags = ? WHERE id = ?`, tagsJSON, id); err != nil {
+			return fmt.Errorf("untag conversation %d: %w", id, err)
+		}
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit delete tag: %w", err)
+	}
+	
+	slog.Info("Tag deleted",
+		"tenant_id", tag.TenantID,
+		"tag", tag.Name,
+		"conversations", len(updated),
+	)
+	return nil
+}
+
+// SetConversationTag adds or removes a tag name on a conversation within the scope
+// Adding a tag the conversation already has (or removing a missing one) is a no-op
+func (r *MariaDBRepository) SetConversationTag(ctx context.Context, scope domain.ConversationScope, conversationID int64, name string, present bool) ([]string, error) {
+	scopeSQL, scopeArgs := conversationScopeFilter(scope)
+	
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin conversation tag: %w", err)
+	}
+	defer tx.Rollback()
+	
+	var raw []byte
+	err = tx.QueryRowContext(ctx,
+		`SELECT c.tags FROM conversations c WHERE c.id = ? AND `+scopeSQL+` FOR UPDATE`,
+		append([]interface{}{conversationID}, scopeArgs...)...,
+	).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock conversation tags: %w", err)
+	}
+	
+	names, err := decodeTags(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode tags of conversation %d: %w", conversationID, err)
+	}
+	
+	next := withoutTag(names, name)
+	if present {
+		next = append(next, name)
+	}
+	if len(next) == len(names) {
+		return names, nil // Unchanged
+	}
+	
+	tagsJSON, _ := json.Marshal(next)
+	if _, err := tx.ExecContext(ctx, `UPDATE conversations SET tags = ? WHERE id = ?`, tagsJSON, conversationID); err != nil {
+		return nil, fmt.Errorf("update conversation tags: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit conversation tags: %w", err)
+	}
+	
+	return next, nil
+}
+
+// decodeTags parses conversations.tags (NULL = no tags)
+func decodeTags(raw []byte) ([]string, error) {
+	names := []string{}
+	if len(raw) == 0 {
+		return names, nil
+	}
+	if err := json.Unmarshal(raw, &names); err != nil {
+		return nil, err
+	}
+	if names == nil {
+		names = []string{}
+	}
+	return names, nil
+}
+
+// withoutTag returns names without the given tag (a new slice)
+func withoutTag(names []string, name string) []string {
+	out := make([]string, 0, len(names))
+	for _, n := range names {
+		if n != name {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// ============================================================================
+// LifecycleRepository Implementation
+// ============================================================================
+
+// SetConversationStatus sets the status of a conversation within the scope
+// snoozed_until only stays set for snoozed conversations
+func (r *MariaDBRepository) SetConversationStatus(ctx context.Context, scope domain.ConversationScope, conversationID int64, status string, snoozedUntil *time.Time) (bool, error) {
+	if status != domain.ConversationStatusSnoozed {
+		snoozedUntil = nil
+	}
+	scopeSQL, scopeArgs := conversationScopeFilter(scope)
+	
+	// Multi-table form so the scope filter can use the alias c
+	args := append([]interface{}{status, snoozedUntil, conversationID}, scopeArgs...)
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE conversations c
+		SET c.status = ?, c.snoozed_until = ?, c.updated_at = NOW()
+		WHERE c.id = ? AND `+scopeSQL,
+		args...,
+	)
+	if err != nil {
+		return false, fmt.Errorf("set conversation status: %w", err)
+	}
+	
+	// Without CLIENT_FOUND_ROWS an unchanged row counts as 0, so check existence separately
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		return true, nil
+	}
+	return r.CanAccessConversation(ctx, conversationID, scope)
+}
+
+// WakeSnoozed reopens snoozed conversations whose snoozed_until has passed
+func (r *MariaDBRepository) WakeSnoozed(ctx context.Context, now time.Time, limit int) ([]domain.ConversationStatusChange, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin wake snoozed: %w", err)
+	}
+	defer tx.Rollback()
+	
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, tenant_id FROM conversations
+		WHERE status = ? AND snoozed_until <= ?
+		ORDER BY snoozed_until
+		LIMIT ?
+		FOR UPDATE
+	`, domain.ConversationStatusSnoozed, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("find due snoozed conversations: %w", err)
+	}
+	var (
+		woken        []domain.ConversationStatusChange
+		placeholders []string
+		ids          []interface{}
+	)
+	for rows.Next() {
+		change := domain.ConversationStatusChange{Status: domain.ConversationStatusUnread}
+		if err := rows.Scan(&change.ConversationID, &change.TenantID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan snoozed conversation: %w", err)
+		}
+		woken = append(woken, change)
+		placeholders = append(placeholders, "?")
+		ids = append(ids, change.ConversationID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("find due snoozed conversations: %w", err)
+	}
+	if len(woken) == 0 {
+		return nil, nil
+	}
+	
+	_, err = tx.ExecContext(ctx, `
+		UPDATE conversations
+		SET status = ?, snoozed_until = NULL, updated_at = NOW()
+		WHERE id IN (`+strings.Join(placeholders, ", ")+`)`,
+		append([]interface{}{domain.ConversationStatusUnread}, ids...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("wake snoozed conversations: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit wake snoozed: %w", err)
+	}
+	
+	return woken, nil
+}
+
+// ============================================================================
+// Full-text search (016_fulltext_search.sql)
+// ============================================================================
+
+// SearchMessages returns one page of messages matching all search terms, newest first
+// Unsent messages are left out; keyset pagination on (created_at, id)
+func (r *MariaDBRepository) SearchMessages(ctx context.Context, scope domain.ConversationScope, query domain.SearchQuery, page domain.PageQuery) ([]domain.MessageHit, domain.PageCursors, error) {
+	scopeSQL, args := conversationScopeFilter(scope)
+	conditions := []string{scopeSQL, "MATCH(m.content) AGAINST (? IN BOOLEAN MODE)", "m.is_deleted = FALSE"}
+	args = append(args, booleanSearchExpr(query.Terms))
+	
+	if query.PageID != "" {
+		conditions = append(conditions, "c.page_id = ?")
+		args = append(args, query.PageID)
+	}
+	if query.From != nil {
+		conditions = append(conditions, "m.created_at >= ?")
+		args = append(args, *query.From)
+	}
+	if query.To != nil {
+		conditions = append(conditions, "m.created_at < ?")
+		args = append(args, *query.To)
+	}
+	if query.SenderType != "" {
+		conditions = append(conditions, "m.sender_type = ?")
+		args = append(args, query.SenderType)
+	}
+	if query.MessageType != "" {
+		conditions = append(conditions, "m.type = ?")
+		args = append(args, query.MessageType)
+	}
+	
+	keysetSQL, keysetArgs, desc := keysetCondition("m.created_at", "m.id", page)
+	if keysetSQL != "" {
+		conditions = append(conditions, keysetSQL)
+		args = append(args, keysetArgs...)
+	}
+	order := "DESC"
+	if !desc {
+		order = "ASC"
+	}
+	args = append(args, page.Limit+1)
+	
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			m.id,
+			m.conversation_id,
+			COALESCE(c.customer_name, c.platform_id),
+			c.platform,
+			c.page_id,
+			m.sender_type,
+			COALESCE(m.type, ''),
+			COALESCE(m.content, ''),
+			m.created_at
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY m.created_at `+order+`, m.id `+order+`
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, domain.PageCursors{}, fmt.Errorf("search messages: %w", err)
+	}
+	defer rows.Close()
+	
+	var (
+		hits []domain.MessageHit
+		keys []domain.Cursor
+	)
+	for rows.Next() {
+		var hit domain.MessageHit
+		err := rows.Scan(
+			&hit.MessageID,
+			&hit.ConversationID,
+			&hit.CustomerName,
+			&hit.Platform,
+			&hit.PageID,
+			&hit.SenderType,
+			&hit.Type,
+			&hit.Content,
+			&hit.CreatedAt,
+		)
+		if err != nil {
+			return nil, domain.PageCursors{}, fmt.Errorf("scan message hit: %w", err)
+		}
+		hits = append(hits, hit)
+		keys = append(keys, domain.Cursor{At: hit.CreatedAt, ID: hit.MessageID})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.PageCursors{}, fmt.Errorf("search messages: %w", err)
+	}
+	
+	more := len(hits) > page.Limit
+	if more {
+		hits, keys = hits[:page.Limit], keys[:page.Limit]
+	}
+	if !desc {
+		// Fetched oldest first (after cursor), the results are newest first
+		for i, j := 0, len(hits)-1; i < j; i, j = i+1, j-1 {
+			hits[i], hits[j] = hits[j], hits[i]
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+	
+	return hits, keysetCursors(page, keys, desc, true, more), nil
+}
+
+// SearchCustomers returns conversations whose customer name matches all search terms
+func (r *MariaDBRepository) SearchCustomers(ctx context.Context, scope domain.ConversationScope, query domain.SearchQuery, limit int) ([]domain.CustomerHit, error) {
+	scopeSQL, args := conversationScopeFilter(scope)
+	conditions := []string{scopeSQL, "MATCH(c.customer_name) AGAINST (? IN BOOLEAN MODE)"}
+	args = append(args, booleanSearchExpr(query.Terms))
+	if query.PageID != "" {
+		conditions = append(conditions, "c.page_id = ?")
+		args = append(args, query.PageID)
+	}
+	args = append(args, limit)
+	
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			c.id,
+			c.customer_name,
+			c.platform,
+			c.page_id,
+			c.status,
+			`+conversationSortKey+`
+		FROM conversations c
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY `+conversationSortKey+` DESC, c.id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search customers: %w", err)
+	}
+	defer rows.Close()
+	
+	var hits []domain.CustomerHit
+	for rows.Next() {
+		var hit domain.CustomerHit
+		err := rows.Scan(
+			&hit.ConversationID,
+			&hit.CustomerName,
+			&hit.Platform,
+			&hit.PageID,
+			&hit.Status,
+			&hit.LastMessageAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan customer hit: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search customers: %w", err)
+	}
+	return hits, nil
+}
+
+// booleanSearchExpr requires every term as a word prefix: "+order* +1234*"
+// Terms only contain letters and digits, so they carry no boolean mode operators
+func booleanSearchExpr(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = "+" + term + "*"
+	}
+	return strings.Join(parts, " ")
+}
+
+// ============================================================================
+// Media (017_media.sql)
+// ============================================================================
+
+// SaveMedia inserts a media row
+func (r *MariaDBRepository) SaveMedia(ctx context.Context, media *domain.Media) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO media (id, tenant_id, file_name, content_type, size, type, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, media.ID, media.TenantID, media.FileName, media.ContentType, media.Size, media.Type, media.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("save media: %w", err)
+	}
+	return nil
+}
+
+// GetMedia retrieves a media file of the tenant (nil if not found)
+func (r *MariaDBRepository) GetMedia(ctx context.Context, tenantID int, mediaID string) (*domain.Media, error) {
+	var media domain.Media
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, tenant_id, file_name, content_type, size, type, created_at
+		FROM media
+		WHERE id = ? AND tenant_id = ?
+	`, mediaID, tenantID).Scan(
+		&media.ID,
+		&media.TenantID,
+		&media.FileName,
+		&media.ContentType,
+		&media.Size,
+		&media.Type,
+		&media.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+	return &media, nil
+}
+
+// GetAttachmentID returns the reusable attachment ID of a media file on a page ("" if none)
+func (r *MariaDBRepository) GetAttachmentID(ctx context.Context, mediaID, platform, pageID string) (string, error) {
+	var attachmentID string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT attachment_id FROM media_attachments
+		WHERE media_id = ? AND platform = ? AND page_id = ?
+	`, mediaID, platform, pageID).Scan(&attachmentID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get attachment id: %w", err)
+	}
+	return attachmentID, nil
+}
+
+// SaveAttachmentID remembers the reusable attachment ID of a media file on a page
+func (r *MariaDBRepository) SaveAttachmentID(ctx context.Context, mediaID, platform, pageID, attachmentID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO media_attachments (media_id, platform, page_id, attachment_id)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE attachment_id = VALUES(attachment_id)
+	`, mediaID, platform, pageID, attachmentID)
+	if err != nil {
+		return fmt.Errorf("save attachment id: %w", err)
+	}
+	return nil
+}
+
+// ============================================================================
+// MediaMirrorRepository Implementation
+// ============================================================================
+
+// EnqueueMediaMirror adds a pending mirror job for a message (no-op if it already has one)
+func (r *MariaDBRepository) EnqueueMediaMirror(ctx context.Context, messageID int64, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT IGNORE INTO media_mirrors (message_id, status, next_attempt_at)
+		VALUES (?, ?, ?)
+	`, messageID, domain.MediaMirrorStatusPending, now)
+	if err != nil {
+		return fmt.Errorf("enqueue media mirror: %w", err)
+	}
+	return nil
+}
+
+// FindDueMediaMirrors returns pending mirror jobs whose next attempt is due, oldest first
+func (r *MariaDBRepository) FindDueMediaMirrors(ctx context.Context, now time.Time, limit int) ([]domain.MediaMirror, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT j.message_id, j.status, j.attempts, j.next_attempt_at, j.last_error,
+			   m.id IS NOT NULL, m.content, m.attachments
+		FROM media_mirrors j
+		LEFT JOIN messages m ON m.id = j.message_id
+		WHERE j.status = ? AND j.next_attempt_at <= ?
+		ORDER BY j.next_attempt_at
+		LIMIT ?
+	`, domain.MediaMirrorStatusPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("find due media mirrors: %w", err)
+	}
+	defer rows.Close()
+	
+	var mirrors []domain.MediaMirror
+	for rows.Next() {
+		var (
+			mirror      domain.MediaMirror
+			attachments []byte
+		)
+		err := rows.Scan(
+			&mirror.MessageID,
+			&mirror.Status,
+			&mirror.Attempts,
+			&mirror.NextAttemptAt,
+			&mirror.LastError,
+			&mirror.MessageExists,
+			&mirror.Content,
+			&attachments,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan media mirror: %w", err)
+		}
+		mirror.Attachments = attachments
+		mirrors = append(mirrors, mirror)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("find due media mirrors: %w", err)
+	}
+	return mirrors, nil
+}
+
+// SaveMediaMirror stores the outcome of a mirror attempt, rewriting the message in the same transaction
+func (r *MariaDBRepository) SaveMediaMirror(ctx context.Context, mirror *domain.MediaMirror, rewrite bool) error {
+	if !mirror.MessageExists {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM media_mirrors WHERE message_id = ?`, mirror.MessageID); err != nil {
+			return fmt.Errorf("delete media mirror: %w", err)
+		}
+		return nil
+	}
+	
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin save media mirror: %w", err)
+	}
+	defer tx.Rollback()
+	
+	if rewrite {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE messages SET content = ?, attachments = ? WHERE id = ?
+		`, mirror.Content, mirror.Attachments, mirror.MessageID)
+		if err != nil {
+			return fmt.Errorf("rewrite mirrored message: %w", err)
+		}
+	}
+	
+	_, err = tx.ExecContext(ctx, `
+		UPDATE media_mirrors
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?
+		WHERE message_id = ?
+	`, mirror.Status, mirror.Attempts, mirror.NextAttemptAt, mirror.LastError, mirror.MessageID)
+	if err != nil {
+		return fmt.Errorf("update media mirror: %w", err)
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit save media mirror: %w", err)
+	}
+	return nil
+}
+
+// ============================================================================
+// OutboundRepository Implementation
+// ============================================================================
+
+// EnqueueOutbound stores a queued reply and its send job in one transaction
+func (r *MariaDBRepository) EnqueueOutbound(ctx context.Context, msg *domain.Message, job *domain.OutboundJob) error {
+	attachments := json.RawMessage("[]")
+	if len(msg.Attachments) > 0 {
+		attachments = msg.Attachments
+	}
+	messageType := domain.MessageTypeText
+	if msg.Type != nil && *msg.Type != "" {
+		messageType = *msg.Type
+	}
+	var structured []byte
+	if job.Structured != nil {
+		raw, err := json.Marshal(job.Structured)
+		if err != nil {
+			return fmt.Errorf("encode structured message: %w", err)
+		}
+		structured = raw
+	}
+	
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin enqueue outbound: %w", err)
+	}
+	defer tx.Rollback()
+	
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO messages (
+			conversation_id, sender_id, sender_type, content,
+			attachments, type, is_synced, delivery_status, message_tag, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, FALSE, ?, ?, NOW())
+	`, msg.ConversationID, msg.SenderID, domain.SenderTypeAgent, msg.Content,
+		attachments, messageType, domain.DeliveryStatusQueued, msg.MessageTag)
+	if err != nil {
+		return fmt.Errorf("insert queued message: %w", err)
+	}
+	messageID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("queued message id: %w", err)
+	}
+	
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbound_jobs (
+			message_id, conversation_id, tenant_id, platform, page_id, recipient_id,
+			message_tag, text, media_id, structured, next_attempt_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?)
+	`, messageID, msg.ConversationID, job.TenantID, job.Platform, job.PageID, job.RecipientID,
+		job.MessageTag, job.Text, job.MediaID, structured, job.NextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("insert outbound job: %w", err)
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit enqueue outbound: %w", err)
+	}
+	
+	queued := domain.DeliveryStatusQueued
+	msg.ID, msg.DeliveryStatus, msg.CreatedAt = messageID, &queued, time.Now()
+	job.MessageID, job.ConversationID = messageID, msg.ConversationID
+	return nil
+}
+
+// FindDueOutbound returns due jobs oldest first, skipping conversations that have an
+// earlier job (still due, backing off or being sent)
+func (r *MariaDBRepository) FindDueOutbound(ctx context.Context, now time.Time, limit int) ([]*domain.OutboundJob, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT j.message_id, j.conversation_id, j.tenant_id, j.platform, j.page_id, j.recipient_id,
+			   COALESCE(j.message_tag, ''), COALESCE(j.text, ''), COALESCE(j.media_id, ''), j.structured,
+			   j.attempts, j.next_attempt_at
+		FROM outbound_jobs j
+		WHERE j.next_attempt_at <= ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM outbound_jobs e
+			WHERE e.conversation_id = j.conversation_id AND e.message_id < j.message_id
+		  )
+		ORDER BY j.message_id
+		LIMIT ?
+	`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("find due outbound jobs: %w", err)
+	}
+	defer rows.Close()
+	
+	var jobs []*domain.OutboundJob
+	for rows.Next() {
+		var (
+			job        domain.OutboundJob
+			structured []byte
+		)
+		err := rows.Scan(
+			&job.MessageID,
+			&job.ConversationID,
+			&job.TenantID,
+			&job.Platform,
+			&job.PageID,
+			&job.RecipientID,
+			&job.MessageTag,
+			&job.Text,
+			&job.MediaID,
+			&structured,
+			&job.Attempts,
+			&job.NextAttemptAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan outbound job: %w", err)
+		}
+		if len(structured) > 0 {
+			job.Structured = &domain.StructuredMessage{}
+			if err := json.Unmarshal(structured, job.Structured); err != nil {
+				return nil, fmt.Errorf("decode structured message of job %d: %w", job.MessageID, err)
+			}
+		}
+		jobs = append(jobs, &job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("find due outbound jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// ClaimOutbound moves next_attempt_at of a due job to until (the lease)
+func (r *MariaDBRepository) ClaimOutbound(ctx context.Context, messageID int64, now, until time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE outbound_jobs SET next_attempt_at = ?
+		WHERE message_id = ? AND next_attempt_at <= ?
+	`, until, messageID, now)
+	if err != nil {
+		return false, fmt.Errorf("claim outbound job: %w", err)
+	}
+	claimed, _ := result.RowsAffected()
+	return claimed > 0, nil
+}
+
+// RetryOutbound records a failed attempt and schedules the next one
+func (r *MariaDBRepository) RetryOutbound(ctx context.Context, messageID int64, attempts int, nextAttemptAt time.Time, lastError string) error {
+	if len(lastError) > 500 {
+		lastError = lastError[:500]
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbound_jobs SET attempts = ?, next_attempt_at = ?, last_error = ?
+		WHERE message_id = ?
+	`, attempts, nextAttemptAt, lastError, messageID)
+	if err != nil {
+		return fmt.Errorf("retry outbound job: %w", err)
+	}
+	return nil
+}
+
+// CompleteOutbound deletes the job and sets the final delivery status of the message
+// The platform echo can be processed before the send returns: it was stored as an
+// external message with the same external_msg_id, it is deleted and its receipts kept
+func (r *MariaDBRepository) CompleteOutbound(ctx context.Context, messageID int64, status string, externalMsgID, deliveryError *string) (*domain.Message, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin complete outbound: %w", err)
+	}
+	defer tx.Rollback()
+	
+	if externalMsgID != nil && *externalMsgID != "" {
+		var (
+			echoID     int64
+			echoStatus sql.NullString
+		)
+		err := tx.QueryRowContext(ctx, `
+			SELECT id, delivery_status FROM messages
+			WHERE external_msg_id = ? AND sender_type = 'agent' AND id <> ?
+			LIMIT 1
+		`, *externalMsgID, messageID).Scan(&echoID, &echoStatus)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return nil, fmt.Errorf("find echo of outbound message: %w", err)
+		default:
+			if _, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE id = ?`, echoID); err != nil {
+				return nil, fmt.Errorf("delete echo of outbound message: %w", err)
+			}
+			if echoStatus.String == domain.DeliveryStatusDelivered || echoStatus.String == domain.DeliveryStatusRead {
+				status = echoStatus.String
+			}
+			slog.Info("Outbound message merged with its echo",
+				"message_id", messageID,
+				"external_msg_id", *externalMsgID,
+			)
+		}
+	}
+	
+	_, err = tx.ExecContext(ctx, `
+		UPDATE messages
+		SET delivery_status = ?, delivery_error = ?, external_msg_id = COALESCE(?, external_msg_id),
+			delivery_updated_at = NOW()
+		WHERE id = ?
+	`, status, deliveryError, externalMsgID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("update outbound message: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM outbound_jobs WHERE message_id = ?`, messageID); err != nil {
+		return nil, fmt.Errorf("delete outbound job: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit complete outbound: %w", err)
+	}
+	
+	return r.GetByID(ctx, strconv.FormatInt(messageID, 10))
 }
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+func TestApplyMessageChangeIgnoresReplays(t *testing.T) {
+	t0 := time.UnixMilli(1716200000123)
+	edit := func(text string, at time.Time) domain.MessageChange {
+		return domain.MessageChange{Kind: domain.EventTypeEdit, CustomerID: "PSID", Content: text, At: at}
+	}
+	react := func(action, reaction string, at time.Time) domain.MessageChange {
+		return domain.MessageChange{Kind: domain.EventTypeReaction, CustomerID: "PSID",
+			Reaction: &domain.Reaction{Action: action, Reaction: reaction}, At: at}
+	}
+
+	state := messageChangeState{senderType: domain.SenderTypeUser, content: "size M"}
+	for i, step := range []struct {
+		change  domain.MessageChange
+		applied bool
+	}{
+		{edit("size L", t0), true},
+		{edit("size L", t0), false},                       // Redelivered
+		{edit("size XL", t0.Add(time.Millisecond)), true}, // A second edit in the same second
+		{edit("size L", t0), false},                       // Replay of the first edit: no rollback
+		{react("react", "love", t0), true},
+		{react("unreact", "", t0.Add(time.Second)), true},
+		{react("react", "love", t0), false}, // Replayed reaction stays removed
+		{react("react", "like", t0.Add(2*time.Second)), true},
+		{domain.MessageChange{Kind: domain.EventTypeUnsend, At: t0}, true},
+		{domain.MessageChange{Kind: domain.EventTypeUnsend, At: t0}, false},
+	} {
+		applied, err := applyMessageChange(&state, step.change)
+		if err != nil || applied != step.applied {
+			t.Errorf("step %d (%s): applied = %v, err = %v, want %v", i, step.change.Kind, applied, err, step.applied)
+		}
+	}
+
+	if state.content != "size XL" || len(state.editHistory) != 2 || state.editHistory[1].Content != "size L" {
+		t.Errorf("content %q, history %+v", state.content, state.editHistory)
+	}
+	if len(state.reactions) != 1 || state.reactions[0].Reaction != "like" || !state.isDeleted {
+		t.Errorf("reactions %+v, deleted %v", state.reactions, state.isDeleted)
+	}
+}
+
+func TestApplyMessageChangeOnlyChangesCustomerMessages(t *testing.T) {
+	for _, kind := range []string{domain.EventTypeEdit, domain.EventTypeUnsend} {
+		state := messageChangeState{senderType: domain.SenderTypeAgent, content: "Dạ còn ạ"}
+		applied, err := applyMessageChange(&state, domain.MessageChange{Kind: kind, Content: "hacked", At: time.Now()})
+		if applied || err != nil || state.content != "Dạ còn ạ" || state.isDeleted {
+			t.Errorf("%s of an agent message: applied = %v, err = %v, state = %+v", kind, applied, err, state)
+		}
+	}
+
+	// Customers react to agent messages too
+	state := messageChangeState{senderType: domain.SenderTypeAgent}
+	applied, err := applyMessageChange(&state, domain.MessageChange{Kind: domain.EventTypeReaction, CustomerID: "PSID",
+		Reaction: &domain.Reaction{Action: domain.ReactionActionReact, Reaction: "like"}, At: time.Now()})
+	if !applied || err != nil || len(state.reactions) != 1 {
+		t.Errorf("reaction to an agent message: applied = %v, err = %v", applied, err)
+	}
+}
@@ -0,0 +1,274 @@
+// Package repository implements data persistence adapters
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// Ensure RedisWebhookQueue implements WebhookQueue
+var _ ports.WebhookQueue = (*RedisWebhookQueue)(nil)
+
+const (
+	// Stream & consumer group names for the webhook work queue
+	webhookStream     = "queue:webhooks"
+	webhookDeadStream = "queue:webhooks:dead"
+	webhookGroup      = "dispatchers"
+
+	// Cap the stream length so Acked history does not grow forever (approximate trim)
+	webhookStreamMaxLen = 100000
+
+	// Page size when walking the whole pending entries list
+	webhookPendingPageSize = 1000
+)
+
+// RedisWebhookQueue implements the durable webhook queue using Redis Streams
+// Consumer group + pending entries list (PEL) give us at-least-once delivery:
+// entries stay pending until XACK, and stale entries are re-claimed by other workers
+type RedisWebhookQueue struct {
+	client *redis.Client
+}
+
+// NewRedisWebhookQueue creates the queue and makes sure the consumer group exists
+func NewRedisWebhookQueue(ctx context.Context, client *redis.Client) (*RedisWebhookQueue, error) {
+	// MKSTREAM creates the stream if missing, "0" lets the group see entries
+	// enqueued before the group existed (e.g. first boot after an upgrade)
+	err := client.XGroupCreateMkStream(ctx, webhookStream, webhookGroup, "0").Err()
+	if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+		return nil, fmt.Errorf("create webhook consumer group: %w", err)
+	}
+
+	return &RedisWebhookQueue{
+		client: client,
+	}, nil
+}
+
+// Enqueue appends a webhook job to the stream
+func (q *RedisWebhookQueue) Enqueue(ctx context.Context, job *domain.WebhookJob) error {
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
+
+	id, err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: webhookStream,
+		MaxLen: webhookStreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"log_id":      job.LogID,
+			"platform":    job.Platform,
+			"payload":     string(job.Payload),
+			"enqueued_at": job.EnqueuedAt.UnixMilli(),
+		},
+	}).Result()
+	if err != nil {
+		slog.Error("Failed to enqueue webhook",
+			"error", err,
+			"platform", job.Platform,
+		)
+		return fmt.Errorf("enqueue webhook: %w", err)
+	}
+
+	job.ID = id
+	return nil
+}
+
+// Dequeue reads new (never delivered) jobs for this consumer
+func (q *RedisWebhookQueue) Dequeue(ctx context.Context, consumer string, count int, block time.Duration) ([]*domain.WebhookJob, error) {
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    webhookGroup,
+		Consumer: consumer,
+		Streams:  []string{webhookStream, ">"},
+		Count:    int64(count),
+		Block:    block,
+	}).Result()
+
+	if err == redis.Nil {
+		return nil, nil // Timeout, nothing new
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dequeue webhook: %w", err)
+	}
+
+	var jobs []*domain.WebhookJob
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			job := toWebhookJob(msg)
+			job.Deliveries = 1
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+// ClaimStale re-assigns jobs whose worker did not Ack within minIdle
+// Uses XPENDING first so we know each job's delivery count (for dead-lettering)
+func (q *RedisWebhookQueue) ClaimStale(ctx context.Context, consumer string, minIdle time.Duration, count int) ([]*domain.WebhookJob, error) {
+	pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: webhookStream,
+		Group:  webhookGroup,
+		Idle:   minIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  int64(count),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("list pending webhooks: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(pending))
+	deliveries := make(map[string]int, len(pending))
+	for _, p := range pending {
+		ids = append(ids, p.ID)
+		deliveries[p.ID] = int(p.RetryCount)
+	}
+
+	// XCLAIM re-checks MinIdle, so two workers racing here cannot both win
+	msgs, err := q.client.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   webhookStream,
+		Group:    webhookGroup,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("claim stale webhooks: %w", err)
+	}
+
+	jobs := make([]*domain.WebhookJob, 0, len(msgs))
+	for _, msg := range msgs {
+		job := toWebhookJob(msg)
+		job.Deliveries = deliveries[msg.ID] + 1 // XCLAIM counts as a new delivery
+		jobs = append(jobs, job)
+	}
+
+	if len(jobs) > 0 {
+		slog.Warn("Re-claimed stale webhook jobs",
+			"count", len(jobs),
+			"consumer", consumer,
+		)
+	}
+
+	return jobs, nil
+}
+
+// Ack removes a job from the pending entries list
+func (q *RedisWebhookQueue) Ack(ctx context.Context, jobID string) error {
+	if err := q.client.XAck(ctx, webhookStream, webhookGroup, jobID).Err(); err != nil {
+		return fmt.Errorf("ack webhook job: %w", err)
+	}
+	return nil
+}
+
+// DeadLetter copies a poison job to the dead-letter stream and Acks the original
+func (q *RedisWebhookQueue) DeadLetter(ctx context.Context, job *domain.WebhookJob, reason string) error {
+	err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: webhookDeadStream,
+		MaxLen: webhookStreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"job_id":      job.ID,
+			"log_id":      job.LogID,
+			"platform":    job.Platform,
+			"payload":     string(job.Payload),
+			"enqueued_at": job.EnqueuedAt.UnixMilli(),
+			"deliveries":  job.Deliveries,
+			"reason":      reason,
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("dead-letter webhook job: %w", err)
+	}
+
+	slog.Error("Webhook job moved to dead-letter stream",
+		"job_id", job.ID,
+		"platform", job.Platform,
+		"deliveries", job.Deliveries,
+		"reason", reason,
+	)
+
+	return q.Ack(ctx, job.ID)
+}
+
+// PendingLogIDs walks the pending entries list and reads the log_id of each entry
+// Entries trimmed from the stream (MAXLEN) have no payload left and are skipped
+func (q *RedisWebhookQueue) PendingLogIDs(ctx context.Context) (map[int64]bool, error) {
+	var ids []string
+	start := "-"
+	for {
+		pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: webhookStream,
+			Group:  webhookGroup,
+			Start:  start,
+			End:    "+",
+			Count:  webhookPendingPageSize,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("list pending webhooks: %w", err)
+		}
+		for _, p := range pending {
+			ids = append(ids, p.ID)
+		}
+		if len(pending) < webhookPendingPageSize {
+			break
+		}
+		start = "(" + pending[len(pending)-1].ID // Exclusive start: next page
+	}
+
+	logIDs := make(map[int64]bool, len(ids))
+	if len(ids) == 0 {
+		return logIDs, nil
+	}
+
+	pipe := q.client.Pipeline()
+	cmds := make([]*redis.XMessageSliceCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.XRangeN(ctx, webhookStream, id, id, 1)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("read pending webhooks: %w", err)
+	}
+	for _, cmd := range cmds {
+		for _, msg := range cmd.Val() {
+			if job := toWebhookJob(msg); job.LogID != 0 {
+				logIDs[job.LogID] = true
+			}
+		}
+	}
+
+	return logIDs, nil
+}
+
+// toWebhookJob converts a stream entry into a domain job
+func toWebhookJob(msg redis.XMessage) *domain.WebhookJob {
+	job := &domain.WebhookJob{ID: msg.ID}
+
+	if v, ok := msg.Values["log_id"].(string); ok {
+		job.LogID, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, ok := msg.Values["platform"].(string); ok {
+		job.Platform = v
+	}
+	if v, ok := msg.Values["payload"].(string); ok {
+		job.Payload = []byte(v)
+	}
+	if v, ok := msg.Values["enqueued_at"].(string); ok {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			job.EnqueuedAt = time.UnixMilli(ms)
+		}
+	}
+
+	return job
+}
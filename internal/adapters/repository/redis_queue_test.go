@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestToWebhookJob(t *testing.T) {
+	enqueuedAt := time.UnixMilli(1700000000123)
+	job := toWebhookJob(redis.XMessage{
+		ID: "1700000000123-0",
+		Values: map[string]interface{}{
+			"log_id":      "42",
+			"platform":    "facebook",
+			"payload":     `{"object":"page"}`,
+			"enqueued_at": "1700000000123",
+		},
+	})
+
+	if job.ID != "1700000000123-0" || job.LogID != 42 || job.Platform != "facebook" {
+		t.Errorf("job = %+v", job)
+	}
+	if string(job.Payload) != `{"object":"page"}` {
+		t.Errorf("payload = %s", job.Payload)
+	}
+	if !job.EnqueuedAt.Equal(enqueuedAt) {
+		t.Errorf("enqueued at = %v, want %v", job.EnqueuedAt, enqueuedAt)
+	}
+}
+
+func TestToWebhookJobWithoutAuditRow(t *testing.T) {
+	// Enqueued after the webhook_logs insert failed: LogID 0 is skipped by status updates
+	job := toWebhookJob(redis.XMessage{
+		ID:     "1-0",
+		Values: map[string]interface{}{"log_id": "0", "platform": "facebook", "payload": "{}", "enqueued_at": "not a number"},
+	})
+	if job.LogID != 0 || !job.EnqueuedAt.IsZero() || string(job.Payload) != "{}" {
+		t.Errorf("job = %+v", job)
+	}
+}
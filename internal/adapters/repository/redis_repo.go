@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,8 +13,13 @@ import (
 	"immortal-chat/internal/core/ports"
 )
 
-// Ensure RedisRepository implements DedupRepository
-var _ ports.DedupRepository = (*RedisRepository)(nil)
+// Ensure RedisRepository implements DedupRepository, PageCache, TokenStore and RoutingStore
+var (
+	_ ports.DedupRepository = (*RedisRepository)(nil)
+	_ ports.PageCache       = (*RedisRepository)(nil)
+	_ ports.TokenStore      = (*RedisRepository)(nil)
+	_ ports.RoutingStore    = (*RedisRepository)(nil)
+)
 
 // RedisRepository implements deduplication using Redis cache
 // Per .rulesgemini Section 4: Check dedup before processing webhooks
@@ -94,3 +100,123 @@ func (r *RedisRepository) MarkProcessed(ctx context.Context, eventID string, ttl
 func buildDedupKey(eventID string) string {
 	return fmt.Sprintf("dedup:msg:%s", eventID)
 }
+
+// GetPageTenant returns the cached tenant of a page (found=false on a cache miss)
+func (r *RedisRepository) GetPageTenant(ctx context.Context, platform, pageID string) (int, bool, error) {
+	tenantID, err := r.client.Get(ctx, buildPageTenantKey(platform, pageID)).Int()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("get page tenant: %w", err)
+	}
+	return tenantID, true, nil
+}
+
+// SetPageTenant caches the tenant of a page with TTL
+func (r *RedisRepository) SetPageTenant(ctx context.Context, platform, pageID string, tenantID int, ttl time.Duration) error {
+	if err := r.client.Set(ctx, buildPageTenantKey(platform, pageID), tenantID, ttl).Err(); err != nil {
+		return fmt.Errorf("set page tenant: %w", err)
+	}
+	return nil
+}
+
+// DeletePageTenant drops the cached tenant of a page
+func (r *RedisRepository) DeletePageTenant(ctx context.Context, platform, pageID string) error {
+	if err := r.client.Del(ctx, buildPageTenantKey(platform, pageID)).Err(); err != nil {
+		return fmt.Errorf("delete page tenant: %w", err)
+	}
+	return nil
+}
+
+// buildPageTenantKey constructs the Redis key for the page -> tenant cache
+// Key format: page:tenant:{platform}:{page_id}
+func buildPageTenantKey(platform, pageID string) string {
+	return fmt.Sprintf("page:tenant:%s:%s", platform, pageID)
+}
+
+// SaveRefreshToken records an issued refresh token until it expires
+func (r *RedisRepository) SaveRefreshToken(ctx context.Context, tokenID string, staffID int, ttl time.Duration) error {
+	if err := r.client.Set(ctx, buildRefreshTokenKey(tokenID), staffID, ttl).Err(); err != nil {
+		return fmt.Errorf("save refresh token: %w", err)
+	}
+	return nil
+}
+
+// ConsumeRefreshToken atomically deletes a refresh token and returns its staff ID
+// GETDEL guarantees that two concurrent refreshes cannot both succeed
+func (r *RedisRepository) ConsumeRefreshToken(ctx context.Context, tokenID string) (int, bool, error) {
+	staffID, err := r.client.GetDel(ctx, buildRefreshTokenKey(tokenID)).Int()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("consume refresh token: %w", err)
+	}
+	return staffID, true, nil
+}
+
+// buildRefreshTokenKey constructs the Redis key of a refresh token
+// Key format: auth:refresh:{token_id}
+func buildRefreshTokenKey(tokenID string) string {
+	return fmt.Sprintf("auth:refresh:%s", tokenID)
+}
+
+// TouchPresence records a staff heartbeat
+// Presence is a sorted set per tenant: member = staff ID, score = last heartbeat (unix seconds)
+func (r *RedisRepository) TouchPresence(ctx context.Context, tenantID, staffID int) error {
+	err := r.client.ZAdd(ctx, buildPresenceKey(tenantID), redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: staffID,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("touch presence: %w", err)
+	}
+	return nil
+}
+
+// ClearPresence marks a staff offline
+func (r *RedisRepository) ClearPresence(ctx context.Context, tenantID, staffID int) error {
+	if err := r.client.ZRem(ctx, buildPresenceKey(tenantID), staffID).Err(); err != nil {
+		return fmt.Errorf("clear presence: %w", err)
+	}
+	return nil
+}
+
+// OnlineStaff returns staff IDs with a heartbeat within ttl (stale entries are pruned)
+func (r *RedisRepository) OnlineStaff(ctx context.Context, tenantID int, ttl time.Duration) ([]int, error) {
+	key := buildPresenceKey(tenantID)
+	since := strconv.FormatInt(time.Now().Add(-ttl).Unix(), 10)
+	
+	if err := r.client.ZRemRangeByScore(ctx, key, "-inf", "("+since).Err(); err != nil {
+		slog.Warn("Failed to prune stale presence", "error", err, "tenant_id", tenantID)
+	}
+	
+	members, err := r.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: since, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get online staff: %w", err)
+	}
+	
+	staffIDs := make([]int, 0, len(members))
+	for _, member := range members {
+		if id, err := strconv.Atoi(member); err == nil {
+			staffIDs = append(staffIDs, id)
+		}
+	}
+	return staffIDs, nil
+}
+
+// NextRoundRobin increments the tenant's routing counter
+func (r *RedisRepository) NextRoundRobin(ctx context.Context, tenantID int) (int64, error) {
+	n, err := r.client.Incr(ctx, fmt.Sprintf("routing:rr:%d", tenantID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("next round robin: %w", err)
+	}
+	return n, nil
+}
+
+// buildPresenceKey constructs the Redis key of a tenant's online staff
+// Key format: presence:tenant:{tenant_id}
+func buildPresenceKey(tenantID int) string {
+	return fmt.Sprintf("presence:tenant:%d", tenantID)
+}
@@ -0,0 +1,128 @@
+// Package storage implements file storage adapters
+// Following Hexagonal Architecture: Adapters implement ports defined in core
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"immortal-chat/internal/core/ports"
+)
+
+// Ensure LocalMediaStore implements MediaStore
+var _ ports.MediaStore = (*LocalMediaStore)(nil)
+
+// errInvalidMediaID is returned for IDs that are not plain file names
+var errInvalidMediaID = errors.New("invalid media id")
+
+// LocalMediaStore keeps media files in a local directory, one file per media ID
+// The directory is also served under /media/ (IDs are random, so URLs are unguessable)
+// Mirrored inbound attachments are named by the SHA-256 of their content instead
+type LocalMediaStore struct {
+	dir string
+}
+
+// NewLocalMediaStore creates the store, creating dir if needed
+func NewLocalMediaStore(dir string) (*LocalMediaStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create media dir: %w", err)
+	}
+	return &LocalMediaStore{dir: dir}, nil
+}
+
+// Dir returns the storage directory
+func (s *LocalMediaStore) Dir() string {
+	return s.dir
+}
+
+// Save writes the file through a temporary file, so readers never see a partial file
+func (s *LocalMediaStore) Save(ctx context.Context, mediaID string, r io.Reader) (int64, error) {
+	path, err := s.path(mediaID)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("create media file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after the rename
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("write media file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("write media file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("store media file: %w", err)
+	}
+	return size, nil
+}
+
+// SaveContent writes the file through a temporary file named by its SHA-256 afterwards
+// Storing the same content again replaces the file with identical bytes
+func (s *LocalMediaStore) SaveContent(ctx context.Context, r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(s.dir, ".mirror-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("create media file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after the rename
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		tmp.Close()
+		return "", 0, fmt.Errorf("write media file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, fmt.Errorf("write media file: %w", err)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, sum)); err != nil {
+		return "", 0, fmt.Errorf("store media file: %w", err)
+	}
+	return sum, size, nil
+}
+
+// Open reads the file of a media ID
+func (s *LocalMediaStore) Open(ctx context.Context, mediaID string) (io.ReadCloser, error) {
+	path, err := s.path(mediaID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open media file: %w", err)
+	}
+	return f, nil
+}
+
+// Delete removes the file of a media ID
+func (s *LocalMediaStore) Delete(ctx context.Context, mediaID string) error {
+	path, err := s.path(mediaID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete media file: %w", err)
+	}
+	return nil
+}
+
+// path maps a media ID to its file, rejecting anything that is not a plain name
+func (s *LocalMediaStore) path(mediaID string) (string, error) {
+	if mediaID == "" || mediaID != filepath.Base(mediaID) || mediaID[0] == '.' {
+		return "", errInvalidMediaID
+	}
+	return filepath.Join(s.dir, mediaID), nil
+}
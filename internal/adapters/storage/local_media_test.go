@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalMediaStoreRejectsPathsOutsideItsDir(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalMediaStore(filepath.Join(root, "media"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, mediaID := range []string{"../secret", "..", "a/../../secret", "sub/file", ".upload-123", ""} {
+		if _, err := store.Open(ctx, mediaID); !errors.Is(err, errInvalidMediaID) {
+			t.Errorf("Open(%q): err = %v, want errInvalidMediaID", mediaID, err)
+		}
+		if _, err := store.Save(ctx, mediaID, strings.NewReader("x")); !errors.Is(err, errInvalidMediaID) {
+			t.Errorf("Save(%q): err = %v, want errInvalidMediaID", mediaID, err)
+		}
+		if err := store.Delete(ctx, mediaID); !errors.Is(err, errInvalidMediaID) {
+			t.Errorf("Delete(%q): err = %v, want errInvalidMediaID", mediaID, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "secret")); err != nil {
+		t.Errorf("file outside the media dir touched: %v", err)
+	}
+
+	// A plain ID round-trips
+	if _, err := store.Save(ctx, "0a1b2c", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	f, err := store.Open(ctx, "0a1b2c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got, _ := io.ReadAll(f); string(got) != "hello" {
+		t.Errorf("read %q", got)
+	}
+}
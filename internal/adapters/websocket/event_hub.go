@@ -0,0 +1,174 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// Ensure EventHub implements EventPublisher
+var _ ports.EventPublisher = (*EventHub)(nil)
+
+// Subprotocols of /ws/events: browsers cannot set an Authorization header on a
+// WebSocket, so the access token travels as a second subprotocol
+// new WebSocket(url, ["events", "bearer." + accessToken])
+const (
+	EventsSubprotocol = "events"
+	BearerSubprotocol = "bearer."
+
+	// Timeout of the conversation lookup that scopes each event
+	eventLookupTimeout = 5 * time.Second
+)
+
+// TokenVerifier checks a dashboard access token (services.AuthService)
+type TokenVerifier interface {
+	VerifyAccessToken(token string) (*domain.AuthClaims, error)
+}
+
+// EventHub pushes dashboard events (message edits, unsends, reactions, delivery
+// status, assignments, ...) to connected dashboards as one JSON object per line
+// Each subscriber is a logged-in staff: an event only reaches staff whose tenant and
+// data scope include the event's conversation (drop-if-full for slow clients, as LogHub)
+type EventHub struct {
+	hub         *LogHub // Client registry and write pumps
+	assignments ports.AssignmentRepository
+	events      chan domain.DashboardEvent
+}
+
+// NewEventHub creates a new EventHub instance
+// assignments: looks up the tenant / assignee of an event's conversation
+func NewEventHub(assignments ports.AssignmentRepository) *EventHub {
+	return &EventHub{
+		hub:         NewLogHub(""),
+		assignments: assignments,
+		events:      make(chan domain.DashboardEvent, broadcastBufferSize),
+	}
+}
+
+// Run starts the client registry and delivers published events (call as goroutine)
+func (h *EventHub) Run() {
+	go h.hub.Run()
+	for event := range h.events {
+		h.deliver(event)
+	}
+}
+
+// Publish queues an event for the dashboards allowed to see it (non-blocking)
+func (h *EventHub) Publish(ctx context.Context, event domain.DashboardEvent) {
+	select {
+	case h.events <- event:
+	default:
+		// Buffer full -> drop: live updates are best effort, a reload shows the stored state
+		slog.Warn("Dashboard event dropped", "type", event.Type, "conversation_id", event.ConversationID)
+	}
+}
+
+// deliver sends an event to the subscribers whose scope includes its conversation
+// (checked against the current assignment, so an agent a conversation was moved away
+// from stops receiving its events)
+func (h *EventHub) deliver(event domain.DashboardEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		slog.Warn("Failed to encode dashboard event", "type", event.Type, "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventLookupTimeout)
+	conversation, err := h.assignments.GetAssignment(ctx, event.ConversationID)
+	cancel()
+	if err != nil {
+		slog.Warn("Failed to look up dashboard event conversation",
+			"type", event.Type,
+			"conversation_id", event.ConversationID,
+			"error", err,
+		)
+		return
+	}
+	if conversation == nil {
+		return // Deleted meanwhile: nobody may see it
+	}
+
+	h.hub.mu.RLock()
+	defer h.hub.mu.RUnlock()
+	for client := range h.hub.clients {
+		if client.scope == nil || !client.scope.Includes(conversation) {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			// Client buffer full -> Skip this event for this client
+		}
+	}
+}
+
+// Handler returns the /ws/events handler: the access token is taken from the
+// "bearer.<token>" subprotocol and the connection is closed when the token expires
+// (the dashboard reconnects with a refreshed token)
+func (h *EventHub) Handler(verifier TokenVerifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := subprotocolToken(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		claims, err := verifier.VerifyAccessToken(token)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			log.Printf("[EventHub] ⚠️ Rejected WebSocket with invalid token from %s", r.RemoteAddr)
+			return
+		}
+
+		conn, err := h.hub.upgrader.Upgrade(w, r, http.Header{"Sec-Websocket-Protocol": {EventsSubprotocol}})
+		if err != nil {
+			log.Printf("[EventHub] ❌ WebSocket upgrade failed: %v", err)
+			return
+		}
+
+		scope := domain.ScopeForClaims(claims)
+		client := &Client{
+			hub:   h.hub,
+			conn:  conn,
+			send:  make(chan []byte, clientBufferSize),
+			scope: &scope,
+		}
+		h.hub.register <- client
+
+		expiry := time.AfterFunc(time.Until(claims.ExpiresAt), func() { conn.Close() })
+		go client.writePump()
+		go func() {
+			client.readPump()
+			expiry.Stop()
+		}()
+	}
+}
+
+// ClientCount returns the current number of connected dashboards
+func (h *EventHub) ClientCount() int {
+	return h.hub.ClientCount()
+}
+
+// subprotocolToken returns the access token of an ["events", "bearer.<token>"] handshake
+func subprotocolToken(r *http.Request) (string, bool) {
+	var events bool
+	var token string
+	for _, header := range r.Header.Values("Sec-Websocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			protocol = strings.TrimSpace(protocol)
+			switch {
+			case protocol == EventsSubprotocol:
+				events = true
+			case strings.HasPrefix(protocol, BearerSubprotocol):
+				token = strings.TrimPrefix(protocol, BearerSubprotocol)
+			}
+		}
+	}
+	return token, events && token != ""
+}
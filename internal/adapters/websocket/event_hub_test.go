@@ -0,0 +1,263 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+	"immortal-chat/internal/core/services"
+)
+
+const testPassword = "secret-password"
+
+// fakeStaff serves the accounts the test logs in with
+type fakeStaff struct {
+	ports.StaffRepository
+	byEmail map[string]*domain.Staff
+}
+
+func (f *fakeStaff) GetStaffByEmail(ctx context.Context, email string) (*domain.Staff, error) {
+	return f.byEmail[email], nil
+}
+
+func (f *fakeStaff) UpdateLastLogin(ctx context.Context, staffID int) error { return nil }
+
+type fakeTokens struct{ ports.TokenStore }
+
+func (fakeTokens) SaveRefreshToken(ctx context.Context, tokenID string, staffID int, ttl time.Duration) error {
+	return nil
+}
+
+type fakeAssignments struct {
+	ports.AssignmentRepository
+	conversations map[int64]*domain.ConversationAssignment
+}
+
+func (f *fakeAssignments) GetAssignment(ctx context.Context, conversationID int64) (*domain.ConversationAssignment, error) {
+	return f.conversations[conversationID], nil
+}
+
+// fakeOutbound hands out its jobs once and returns the completed messages
+type fakeOutbound struct {
+	mu   sync.Mutex
+	jobs []*domain.OutboundJob
+}
+
+func (f *fakeOutbound) EnqueueOutbound(ctx context.Context, msg *domain.Message, job *domain.OutboundJob) error {
+	return errors.New("not used")
+}
+
+func (f *fakeOutbound) FindDueOutbound(ctx context.Context, now time.Time, limit int) ([]*domain.OutboundJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	jobs := f.jobs
+	f.jobs = nil
+	return jobs, nil
+}
+
+func (f *fakeOutbound) ClaimOutbound(ctx context.Context, messageID int64, now, until time.Time) (bool, error) {
+	return true, nil
+}
+
+func (f *fakeOutbound) RetryOutbound(ctx context.Context, messageID int64, attempts int, next time.Time, lastError string) error {
+	return nil
+}
+
+func (f *fakeOutbound) CompleteOutbound(ctx context.Context, messageID int64, status string, externalMsgID, deliveryError *string) (*domain.Message, error) {
+	return &domain.Message{
+		ID:             messageID,
+		ConversationID: 100,
+		SenderType:     domain.SenderTypeAgent,
+		DeliveryStatus: &status,
+		DeliveryError:  deliveryError,
+		ExternalMsgID:  externalMsgID,
+	}, nil
+}
+
+func (f *fakeOutbound) GetPageAccessToken(ctx context.Context, platform, pageID string) (string, error) {
+	return "page-token", nil
+}
+
+func (f *fakeOutbound) DeactivatePage(ctx context.Context, platform, pageID string) error {
+	return nil
+}
+
+// fakePlatform accepts "ok" and rejects everything else
+type fakePlatform struct{}
+
+func (fakePlatform) Platform() string { return "fake" }
+
+func (fakePlatform) VerifySignature(header http.Header, body []byte) error { return nil }
+
+func (fakePlatform) ParseEvents(payload []byte) ([]domain.InboundEvent, error) { return nil, nil }
+
+func (fakePlatform) SendText(ctx context.Context, target domain.OutboundTarget, text string) (string, error) {
+	if text == "ok" {
+		return "mid.1", nil
+	}
+	return "", ports.ErrMessageRejected
+}
+
+func TestOutboundStatusReachesScopedSubscribers(t *testing.T) {
+	staff := map[string]*domain.Staff{}
+	for _, s := range []*domain.Staff{
+		{ID: 5, TenantID: 1, Email: "assigned@shop.vn", Role: domain.RoleAgent},
+		{ID: 6, TenantID: 1, Email: "other@shop.vn", Role: domain.RoleAgent},
+		{ID: 7, TenantID: 2, Email: "admin@other.vn", Role: domain.RoleAdmin},
+	} {
+		hash, err := services.HashPassword(testPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.PasswordHash, s.IsActive = hash, true
+		staff[s.Email] = s
+	}
+	auth := services.NewAuthService(&fakeStaff{byEmail: staff}, fakeTokens{}, nil, services.AuthConfig{
+		Secret: []byte("test-secret"),
+	})
+
+	assignee := 5
+	hub := NewEventHub(&fakeAssignments{conversations: map[int64]*domain.ConversationAssignment{
+		100: {ConversationID: 100, TenantID: 1, AssigneeID: &assignee},
+	}})
+	go hub.Run()
+	server := httptest.NewServer(hub.Handler(auth))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	dial := func(email string) *websocket.Conn {
+		t.Helper()
+		pair, _, err := auth.Login(context.Background(), email, testPassword)
+		if err != nil {
+			t.Fatalf("login %s: %v", email, err)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{
+			"Sec-Websocket-Protocol": {EventsSubprotocol + ", " + BearerSubprotocol + pair.AccessToken},
+		})
+		if err != nil {
+			t.Fatalf("dial %s: %v", email, err)
+		}
+		if conn.Subprotocol() != EventsSubprotocol {
+			t.Errorf("subprotocol = %q", conn.Subprotocol())
+		}
+		return conn
+	}
+
+	assigned, other, otherTenant := dial("assigned@shop.vn"), dial("other@shop.vn"), dial("admin@other.vn")
+	defer assigned.Close()
+	defer other.Close()
+	defer otherTenant.Close()
+	for deadline := time.Now().Add(2 * time.Second); hub.ClientCount() < 3; {
+		if time.Now().After(deadline) {
+			t.Fatalf("ClientCount = %d, want 3", hub.ClientCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	platforms := services.NewPlatformRegistry(fakePlatform{})
+	jobs := &fakeOutbound{jobs: []*domain.OutboundJob{
+		{MessageID: 1, ConversationID: 100, TenantID: 1, Platform: "fake", PageID: "p", RecipientID: "r", Text: "ok"},
+		{MessageID: 2, ConversationID: 100, TenantID: 1, Platform: "fake", PageID: "p", RecipientID: "r", Text: "rejected"},
+	}}
+	outbound := services.NewOutboundService(jobs, platforms, nil, nil, hub, services.OutboundConfig{
+		PollInterval: 10 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go outbound.Run(ctx)
+
+	statuses := map[int64]*domain.Message{}
+	assigned.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(statuses) < 2 {
+		for _, event := range readEvents(t, assigned) {
+			var msg domain.Message
+			if err := json.Unmarshal(event.Data, &msg); err != nil {
+				t.Fatal(err)
+			}
+			if event.Type != domain.DashboardEventMessageUpdated || event.ConversationID != 100 {
+				t.Errorf("event = %+v", event)
+			}
+			statuses[msg.ID] = &msg
+		}
+	}
+	if m := statuses[1]; *m.DeliveryStatus != domain.DeliveryStatusSent || m.ExternalMsgID == nil || *m.ExternalMsgID != "mid.1" {
+		t.Errorf("message 1 = %+v", m)
+	}
+	if m := statuses[2]; *m.DeliveryStatus != domain.DeliveryStatusFailed || m.DeliveryError == nil || *m.DeliveryError != domain.DeliveryErrorRejected {
+		t.Errorf("message 2 = %+v", m)
+	}
+
+	// Same tenant but not assigned, and another tenant: nothing
+	for name, conn := range map[string]*websocket.Conn{"unassigned agent": other, "other tenant": otherTenant} {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		_, data, err := conn.ReadMessage()
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("%s received %s (err %v)", name, data, err)
+		}
+	}
+}
+
+func TestEventHubRejectsMissingOrInvalidToken(t *testing.T) {
+	auth := services.NewAuthService(nil, nil, nil, services.AuthConfig{Secret: []byte("test-secret")})
+	hub := NewEventHub(&fakeAssignments{})
+	server := httptest.NewServer(hub.Handler(auth))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	for name, protocols := range map[string]string{
+		"no subprotocol":   "",
+		"no token":         EventsSubprotocol,
+		"invalid token":    EventsSubprotocol + ", " + BearerSubprotocol + "not-a-token",
+		"no events member": BearerSubprotocol + "not-a-token",
+	} {
+		header := http.Header{}
+		if protocols != "" {
+			header.Set("Sec-Websocket-Protocol", protocols)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+		if err == nil {
+			conn.Close()
+			t.Errorf("%s: connected", name)
+			continue
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: resp = %v, err = %v", name, resp, err)
+		}
+	}
+}
+
+type testEvent struct {
+	Type           string          `json:"type"`
+	ConversationID int64           `json:"conversation_id"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// readEvents reads one frame (one JSON event per line)
+func readEvents(t *testing.T, conn *websocket.Conn) []testEvent {
+	t.Helper()
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read event: %v", err)
+	}
+	var events []testEvent
+	for _, line := range strings.Split(string(data), "\n") {
+		var event testEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("decode event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"immortal-chat/internal/core/domain"
 )
 
 // LogHub manages WebSocket connections and broadcasts logs to all connected clients
@@ -42,6 +44,9 @@ type Client struct {
 	hub  *LogHub
 	conn *websocket.Conn
 	send chan []byte
+
+	// EventHub subscribers only: conversations whose events the staff may receive
+	scope *domain.ConversationScope
 }
 
 const (
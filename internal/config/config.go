@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // DBConfig holds database connection parameters
@@ -34,13 +35,116 @@ type FacebookConfig struct {
 	VerifyToken string // For webhook verification handshake
 }
 
+// ZaloConfig holds Zalo Official Account configuration
+// Optional: the Zalo channel is disabled when AppID or OASecretKey is empty
+type ZaloConfig struct {
+	AppID       string // Zalo app linked to the OA (part of the webhook MAC)
+	OASecretKey string // OA secret key for X-ZEvent-Signature validation
+	APIBaseURL  string // OpenAPI host (point at a local fake server for testing)
+}
+
+// Enabled reports whether the Zalo channel is configured
+func (c *ZaloConfig) Enabled() bool {
+	return c.AppID != "" && c.OASecretKey != ""
+}
+
+// TelegramConfig holds Telegram bot configuration
+// Optional: the Telegram channel is disabled when BotToken or WebhookSecret is empty
+type TelegramConfig struct {
+	BotToken      string // Bot token from @BotFather (bot ID = part before ":")
+	WebhookSecret string // secret_token given to setWebhook, checked on every update
+	APIBaseURL    string // Bot API host (point at a local stub for testing)
+}
+
+// Enabled reports whether the Telegram channel is configured
+func (c *TelegramConfig) Enabled() bool {
+	return c.BotToken != "" && c.WebhookSecret != ""
+}
+
+// WebhookQueueConfig holds durable webhook queue worker settings
+type WebhookQueueConfig struct {
+	Workers              int // Bounded worker pool size
+	VisibilityTimeoutSec int // Un-Acked jobs are redelivered after this many seconds
+	MaxDeliveries        int // Jobs delivered more often than this go to the dead-letter stream
+}
+
+// WebhookReplayConfig holds automatic webhook replay settings
+type WebhookReplayConfig struct {
+	IntervalSec     int // How often failed/stale webhooks are looked for
+	MaxRetries      int // Stop automatic replay after this many failed attempts
+	BaseBackoffSec  int // First retry delay, doubled for every further retry
+	MaxBackoffSec   int // Upper bound for the backoff
+	StalePendingMin int // Pending/failed rows younger than this are left to the queue
+}
+
+// RetentionConfig holds the per-tenant message retention job settings
+// Retention days come from the tenant's plan, only the schedule is configured here
+type RetentionConfig struct {
+	IntervalMin int // How often messages past retention are purged
+}
+
+// AuthConfig holds dashboard login / token settings
+type AuthConfig struct {
+	JWTSecret         string // HS256 key for access / refresh tokens
+	AccessTTLMin      int    // Access token lifetime
+	RefreshTTLHours   int    // Refresh token lifetime (single use, rotated on refresh)
+	BootstrapTenantID int    // Tenant of the bootstrap account
+	BootstrapEmail    string // Optional: first account, created at startup if missing
+	BootstrapPassword string
+	BootstrapName     string
+}
+
+// RoutingConfig holds automatic conversation routing settings
+type RoutingConfig struct {
+	Strategy       string // least_open (default), round_robin or off
+	PresenceTTLSec int    // Agents without a dashboard heartbeat for this long are offline
+}
+
+// LifecycleConfig holds the conversation lifecycle job settings
+type LifecycleConfig struct {
+	SnoozeCheckIntervalSec int // How often snoozed conversations past their time are reopened
+}
+
+// MediaConfig holds local media storage settings (uploads from the dashboard)
+type MediaConfig struct {
+	Dir         string // Files are stored here, served under /media/
+	PublicURL   string // Optional: public base URL of this server, lets platforms fetch media by URL
+	MaxUploadMB int    // Largest accepted upload (Messenger accepts up to 25 MB)
+
+	// Inbound attachments are copied from platform CDN URLs (which expire) to Dir
+	MirrorMaxMB         int // Larger attachments are left on the CDN
+	MirrorIntervalSec   int // How often due downloads are attempted
+	MirrorMaxAttempts   int // Failing downloads are given up after this many attempts
+	MirrorMaxBackoffMin int // Cap for the exponential backoff between attempts
+}
+
+// OutboundConfig holds the outbound message queue settings
+type OutboundConfig struct {
+	Workers          int // Messages sent concurrently
+	RatePerSecond    int // Sends per second per page (token bucket refill)
+	Burst            int // Sends a page may make at once after being idle
+	MaxAttempts      int // Messages still failing after this many attempts are marked failed
+	MaxBackoffSec    int // Cap for the jittered exponential backoff between attempts
+	PollIntervalMsec int // How often due messages are looked for (new replies wake the queue at once)
+}
+
 // Config aggregates all configuration sections
 type Config struct {
-	DB         DBConfig
-	Redis      RedisConfig
-	App        AppConfig
-	Facebook   FacebookConfig
-	MeshSecret string // For internal API and WebSocket authentication (X-Mesh-Secret)
+	DB            DBConfig
+	Redis         RedisConfig
+	App           AppConfig
+	Facebook      FacebookConfig
+	Zalo          ZaloConfig
+	Telegram      TelegramConfig
+	WebhookQueue  WebhookQueueConfig
+	WebhookReplay WebhookReplayConfig
+	Retention     RetentionConfig
+	Auth          AuthConfig
+	Routing       RoutingConfig
+	Lifecycle     LifecycleConfig
+	Media         MediaConfig
+	Outbound      OutboundConfig
+	MeshSecret    string // For internal API and WebSocket authentication (X-Mesh-Secret)
 }
 
 // LoadConfig reads configuration from environment variables
@@ -78,6 +182,75 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("FB_VERIFY_TOKEN environment variable is required")
 	}
 
+	// Zalo OA Configuration (optional channel)
+	cfg.Zalo.AppID = getEnv("ZALO_APP_ID", "")
+	cfg.Zalo.OASecretKey = getEnv("ZALO_OA_SECRET_KEY", "")
+	cfg.Zalo.APIBaseURL = getEnv("ZALO_API_BASE_URL", "https://openapi.zalo.me")
+
+	// Telegram Bot Configuration (optional channel)
+	cfg.Telegram.BotToken = getEnv("TELEGRAM_BOT_TOKEN", "")
+	cfg.Telegram.WebhookSecret = getEnv("TELEGRAM_WEBHOOK_SECRET", "")
+	cfg.Telegram.APIBaseURL = getEnv("TELEGRAM_API_BASE_URL", "https://api.telegram.org")
+
+	// Webhook Queue Configuration (durable processing)
+	cfg.WebhookQueue.Workers = getEnvAsInt("WEBHOOK_WORKERS", 4)
+	cfg.WebhookQueue.VisibilityTimeoutSec = getEnvAsInt("WEBHOOK_VISIBILITY_TIMEOUT_SEC", 60)
+	cfg.WebhookQueue.MaxDeliveries = getEnvAsInt("WEBHOOK_MAX_DELIVERIES", 5)
+
+	// Webhook Replay Configuration
+	cfg.WebhookReplay.IntervalSec = getEnvAsInt("WEBHOOK_REPLAY_INTERVAL_SEC", 60)
+	cfg.WebhookReplay.MaxRetries = getEnvAsInt("WEBHOOK_REPLAY_MAX_RETRIES", 10)
+	cfg.WebhookReplay.BaseBackoffSec = getEnvAsInt("WEBHOOK_REPLAY_BASE_BACKOFF_SEC", 30)
+	cfg.WebhookReplay.MaxBackoffSec = getEnvAsInt("WEBHOOK_REPLAY_MAX_BACKOFF_SEC", 3600)
+	cfg.WebhookReplay.StalePendingMin = getEnvAsInt("WEBHOOK_REPLAY_STALE_PENDING_MIN", 15)
+
+	// Message Retention (per-tenant plan)
+	cfg.Retention.IntervalMin = getEnvAsInt("RETENTION_INTERVAL_MIN", 60)
+
+	// Dashboard Authentication
+	cfg.Auth.JWTSecret = getEnv("AUTH_JWT_SECRET", "")
+	cfg.Auth.AccessTTLMin = getEnvAsInt("AUTH_ACCESS_TTL_MIN", 15)
+	cfg.Auth.RefreshTTLHours = getEnvAsInt("AUTH_REFRESH_TTL_HOURS", 168)
+	cfg.Auth.BootstrapTenantID = getEnvAsInt("BOOTSTRAP_TENANT_ID", 1)
+	cfg.Auth.BootstrapEmail = getEnv("BOOTSTRAP_ADMIN_EMAIL", "")
+	cfg.Auth.BootstrapPassword = getEnv("BOOTSTRAP_ADMIN_PASSWORD", "")
+	cfg.Auth.BootstrapName = getEnv("BOOTSTRAP_ADMIN_NAME", "Admin")
+
+	// Conversation Routing
+	cfg.Routing.Strategy = getEnv("ROUTING_STRATEGY", "least_open")
+	cfg.Routing.PresenceTTLSec = getEnvAsInt("PRESENCE_TTL_SEC", 120)
+
+	// Conversation Lifecycle
+	cfg.Lifecycle.SnoozeCheckIntervalSec = getEnvAsInt("SNOOZE_CHECK_INTERVAL_SEC", 60)
+
+	// Media Storage
+	cfg.Media.Dir = getEnv("MEDIA_DIR", "./data/media")
+	cfg.Media.PublicURL = strings.TrimSuffix(getEnv("MEDIA_PUBLIC_URL", ""), "/")
+	cfg.Media.MaxUploadMB = getEnvAsInt("MEDIA_MAX_UPLOAD_MB", 25)
+	cfg.Media.MirrorMaxMB = getEnvAsInt("MEDIA_MIRROR_MAX_MB", 25)
+	cfg.Media.MirrorIntervalSec = getEnvAsInt("MEDIA_MIRROR_INTERVAL_SEC", 15)
+	cfg.Media.MirrorMaxAttempts = getEnvAsInt("MEDIA_MIRROR_MAX_ATTEMPTS", 8)
+	cfg.Media.MirrorMaxBackoffMin = getEnvAsInt("MEDIA_MIRROR_MAX_BACKOFF_MIN", 360)
+
+	// Outbound Queue
+	cfg.Outbound.Workers = getEnvAsInt("OUTBOUND_WORKERS", 4)
+	cfg.Outbound.RatePerSecond = getEnvAsInt("OUTBOUND_RATE_PER_SEC", 10)
+	cfg.Outbound.Burst = getEnvAsInt("OUTBOUND_BURST", 20)
+	cfg.Outbound.MaxAttempts = getEnvAsInt("OUTBOUND_MAX_ATTEMPTS", 6)
+	cfg.Outbound.MaxBackoffSec = getEnvAsInt("OUTBOUND_MAX_BACKOFF_SEC", 300)
+	cfg.Outbound.PollIntervalMsec = getEnvAsInt("OUTBOUND_POLL_INTERVAL_MSEC", 1000)
+
+	// Validate the token signing key (short keys make HS256 brute-forceable)
+	if len(cfg.Auth.JWTSecret) < 32 {
+		return nil, fmt.Errorf("AUTH_JWT_SECRET environment variable is required (at least 32 characters)")
+	}
+
+	switch cfg.Routing.Strategy {
+	case "least_open", "round_robin", "off":
+	default:
+		return nil, fmt.Errorf("ROUTING_STRATEGY must be least_open, round_robin or off")
+	}
+
 	// Mesh Network Security (for internal API and WebSocket authentication)
 	// Optional: If not set, System Monitor will be disabled
 	cfg.MeshSecret = getEnv("MESH_SECRET", "")
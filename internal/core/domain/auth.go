@@ -0,0 +1,165 @@
+// Package domain contains core business entities
+package domain
+
+import "time"
+
+// Staff is a dashboard user (owner / agent of a tenant)
+type Staff struct {
+	ID           int        `json:"id" db:"id"`
+	TenantID     int        `json:"tenant_id" db:"tenant_id"`
+	Email        string     `json:"email" db:"email"`
+	Name         string     `json:"name" db:"name"`
+	Role         string     `json:"role" db:"role"` // RoleOwner, RoleAdmin, RoleAgent, RoleViewer
+	TeamID       *int       `json:"team_id,omitempty" db:"team_id"`
+	PasswordHash string     `json:"-" db:"password_hash"` // Never expose in JSON
+	IsActive     bool       `json:"is_active" db:"is_active"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AuthClaims is the identity carried by a dashboard token
+type AuthClaims struct {
+	StaffID   int       `json:"sub"`
+	TenantID  int       `json:"tid"`
+	Role      string    `json:"role"`
+	TeamID    *int      `json:"team_id,omitempty"`
+	TokenType string    `json:"typ"` // TokenTypeAccess or TokenTypeRefresh
+	TokenID   string    `json:"jti"` // Refresh tokens: key of the single-use record
+	IssuedAt  time.Time `json:"-"`
+	ExpiresAt time.Time `json:"-"`
+}
+
+// Token types
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// TokenPair is returned by login / refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"` // Always "Bearer"
+	ExpiresIn    int    `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// Team groups agents; conversations can be assigned to a team
+type Team struct {
+	ID        int       `json:"id" db:"id"`
+	TenantID  int       `json:"tenant_id" db:"tenant_id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Staff roles
+const (
+	RoleOwner  = "owner"  // Everything, including promoting other owners
+	RoleAdmin  = "admin"  // Everything in the tenant except creating owners
+	RoleAgent  = "agent"  // Replies to conversations assigned to them or their team
+	RoleViewer = "viewer" // Read-only access to the whole tenant
+)
+
+// Permissions checked per dashboard route
+const (
+	PermissionViewDashboard     = "dashboard:read"       // Status, platforms, sync status
+	PermissionViewSystem        = "system:read"          // Server metrics
+	PermissionViewConversations = "conversations:read"   // List conversations, read messages (within data scope)
+	PermissionReply             = "messages:reply"       // Send replies (within data scope)
+	PermissionAssign            = "conversations:assign" // Assign / transfer conversations (agents: only their own)
+	PermissionTag               = "conversations:tag"    // Add / remove tags on conversations (within data scope)
+	PermissionChangeStatus      = "conversations:status" // Archive / resolve / snooze / reopen (within data scope)
+	PermissionManageTags        = "tags:manage"          // Create / delete tag definitions
+	PermissionViewStaff         = "staff:read"           // List staff and teams
+	PermissionManageStaff       = "staff:manage"         // Create staff and teams
+	PermissionViewUsage         = "tenant:usage"         // Plan limits and usage
+)
+
+// rolePermissions lists what each role may do
+var rolePermissions = map[string][]string{
+	RoleOwner: {
+		PermissionViewDashboard, PermissionViewSystem, PermissionViewConversations, PermissionReply,
+		PermissionAssign, PermissionTag, PermissionChangeStatus, PermissionManageTags, PermissionViewStaff,
+		PermissionManageStaff, PermissionViewUsage,
+	},
+	RoleAdmin: {
+		PermissionViewDashboard, PermissionViewSystem, PermissionViewConversations, PermissionReply,
+		PermissionAssign, PermissionTag, PermissionChangeStatus, PermissionManageTags, PermissionViewStaff,
+		PermissionManageStaff, PermissionViewUsage,
+	},
+	RoleAgent: {
+		PermissionViewDashboard, PermissionViewConversations, PermissionReply, PermissionAssign,
+		PermissionTag, PermissionChangeStatus, PermissionViewStaff,
+	},
+	RoleViewer: {
+		PermissionViewDashboard, PermissionViewConversations,
+	},
+}
+
+// IsValidRole reports whether role is one of the staff roles
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission reports whether a role grants a permission (unknown roles grant nothing)
+func RoleHasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Data scopes: which conversations a role can see
+const (
+	DataScopeTenant   = "tenant"   // All conversations of the tenant
+	DataScopeAssigned = "assigned" // Conversations assigned to the staff or their team
+)
+
+// DataScopeForRole returns the conversation data scope of a role
+func DataScopeForRole(role string) string {
+	if role == RoleAgent {
+		return DataScopeAssigned
+	}
+	return DataScopeTenant
+}
+
+// ConversationScope restricts conversation queries to what a staff may see
+type ConversationScope struct {
+	TenantID     int
+	StaffID      int
+	TeamID       *int
+	AssignedOnly bool // DataScopeAssigned: assignee_id = StaffID OR assigned_team_id = TeamID
+}
+
+// ManagesConversations reports whether a role can assign any conversation of the tenant
+// (agents can only claim, transfer or release conversations in their own scope)
+func ManagesConversations(role string) bool {
+	return role == RoleOwner || role == RoleAdmin
+}
+
+// Includes reports whether a conversation (its tenant and assignment) is within the
+// scope; same rule as the SQL filter of the conversation list
+func (s ConversationScope) Includes(conversation *ConversationAssignment) bool {
+	if conversation == nil || conversation.TenantID != s.TenantID {
+		return false
+	}
+	if !s.AssignedOnly {
+		return true
+	}
+	if conversation.AssigneeID != nil && *conversation.AssigneeID == s.StaffID {
+		return true
+	}
+	return s.TeamID != nil && conversation.AssignedTeamID != nil && *conversation.AssignedTeamID == *s.TeamID
+}
+
+// ScopeForClaims builds the conversation scope of an authenticated staff
+func ScopeForClaims(claims *AuthClaims) ConversationScope {
+	return ConversationScope{
+		TenantID:     claims.TenantID,
+		StaffID:      claims.StaffID,
+		TeamID:       claims.TeamID,
+		AssignedOnly: DataScopeForRole(claims.Role) == DataScopeAssigned,
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "testing"
+
+func TestConversationScopeIncludes(t *testing.T) {
+	staff, team, otherStaff, otherTeam := 5, 2, 6, 3
+	tenantWide := ConversationScope{TenantID: 1, StaffID: 5}
+	agent := ConversationScope{TenantID: 1, StaffID: 5, AssignedOnly: true}
+	teamAgent := ConversationScope{TenantID: 1, StaffID: 5, TeamID: &team, AssignedOnly: true}
+
+	tests := []struct {
+		name         string
+		scope        ConversationScope
+		conversation *ConversationAssignment
+		want         bool
+	}{
+		{"admin, own tenant", tenantWide, &ConversationAssignment{TenantID: 1}, true},
+		{"admin, other tenant", tenantWide, &ConversationAssignment{TenantID: 2}, false},
+		{"agent, assigned", agent, &ConversationAssignment{TenantID: 1, AssigneeID: &staff}, true},
+		{"agent, assigned elsewhere", agent, &ConversationAssignment{TenantID: 1, AssigneeID: &otherStaff}, false},
+		{"agent, unassigned", agent, &ConversationAssignment{TenantID: 1}, false},
+		{"agent, same id other tenant", agent, &ConversationAssignment{TenantID: 2, AssigneeID: &staff}, false},
+		{"agent without team, team conversation", agent, &ConversationAssignment{TenantID: 1, AssignedTeamID: &team}, false},
+		{"team agent, own team", teamAgent, &ConversationAssignment{TenantID: 1, AssigneeID: &otherStaff, AssignedTeamID: &team}, true},
+		{"team agent, other team", teamAgent, &ConversationAssignment{TenantID: 1, AssignedTeamID: &otherTeam}, false},
+		{"missing conversation", tenantWide, nil, false},
+	}
+	for _, tt := range tests {
+		if got := tt.scope.Includes(tt.conversation); got != tt.want {
+			t.Errorf("%s: Includes = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
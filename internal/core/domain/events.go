@@ -0,0 +1,216 @@
+// Package domain contains core business entities
+package domain
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// InboundEvent is a platform-agnostic event parsed from a webhook payload
+// Produced by a PlatformAdapter so the Dispatcher never sees platform DTOs
+type InboundEvent struct {
+	Type          string          `json:"type"`                  // See EventType constants
+	Platform      string          `json:"platform"`              // "facebook", "zalo", ...
+	PageID        string          `json:"page_id"`               // Receiving page / OA / bot ID
+	TenantID      int             `json:"tenant_id,omitempty"`   // Owner of the page, resolved by the Dispatcher
+	SenderID      string          `json:"sender_id"`             // Customer ID on the platform (conversations.platform_id)
+	SenderName    string          `json:"sender_name,omitempty"` // Display name when the platform sends it (conversations.customer_name)
+	ExternalMsgID string          `json:"external_msg_id,omitempty"`
+	MessageType   string          `json:"message_type,omitempty"` // See MessageType constants
+	Content       string          `json:"content,omitempty"`      // Text or attachment URL
+	Attachments   json.RawMessage `json:"attachments,omitempty"`  // Platform attachments as JSON array
+	Payload       string          `json:"payload,omitempty"`      // Postback / quick reply / opt-in payload
+	Referral      *Referral       `json:"referral,omitempty"`     // Entry point (m.me link, ad, plugin)
+	MessageIDs    []string        `json:"message_ids,omitempty"`  // Delivery / read receipts: acknowledged outbound IDs
+	Watermark     time.Time       `json:"watermark,omitempty"`    // Delivery / read receipts: everything sent before is acknowledged
+	Reaction      *Reaction       `json:"reaction,omitempty"`     // Reaction events
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+// EventType constants
+const (
+	EventTypeMessage  = "message"  // Customer sent a message
+	EventTypeEcho     = "echo"     // Page sent a message (stored when sent outside Immortal Chat)
+	EventTypeDelivery = "delivery" // Delivery receipt
+	EventTypeRead     = "read"     // Read receipt
+	EventTypePostback = "postback" // Customer tapped a button (stored as a message)
+	EventTypeReferral = "referral" // Customer re-entered the thread via link / ad (no message)
+	EventTypeOptin    = "optin"    // Customer opted in (plugin / notification messages)
+	EventTypeEdit     = "edit"     // Customer edited a message (Content is the new text)
+	EventTypeUnsend   = "unsend"   // Customer unsent a message
+	EventTypeReaction = "reaction" // Customer reacted to / unreacted a message
+)
+
+// Reaction describes a reaction event on a message
+type Reaction struct {
+	Action   string `json:"action"`             // ReactionActionReact or ReactionActionUnreact
+	Reaction string `json:"reaction,omitempty"` // "love", "like", ... (platform name)
+	Emoji    string `json:"emoji,omitempty"`
+}
+
+// Reaction actions
+const (
+	ReactionActionReact   = "react"
+	ReactionActionUnreact = "unreact"
+)
+
+// Referral describes how a customer entered the conversation (ad attribution)
+type Referral struct {
+	Source string `json:"source"`          // "SHORTLINK", "ADS", "CUSTOMER_CHAT_PLUGIN", ...
+	Type   string `json:"type,omitempty"`  // "OPEN_THREAD"
+	Ref    string `json:"ref,omitempty"`   // ?ref= of the m.me link
+	AdID   string `json:"ad_id,omitempty"` // Click-to-Messenger ad
+}
+
+// Platform constants (pages.platform, conversations.platform, webhook_logs.platform)
+const (
+	PlatformFacebook  = "facebook"
+	PlatformInstagram = "instagram"
+	PlatformZalo      = "zalo"
+	PlatformTelegram  = "telegram"
+)
+
+// OutboundTarget identifies who receives an outbound message and with which credentials
+type OutboundTarget struct {
+	PageID      string // Sending page / OA / bot ID
+	RecipientID string // Customer ID on the platform (conversations.platform_id)
+	AccessToken string // pages.access_token
+	MessageTag  string // Messenger tag outside the 24-hour window ("" = standard reply)
+}
+
+// OutboundAttachment is a media file to send, by the cheapest means the platform accepts:
+// a reusable AttachmentID of the page, else the public URL, else an upload of the file
+type OutboundAttachment struct {
+	Type         string                        // See AttachmentType constants
+	AttachmentID string                        // Reusable platform attachment ID for this page ("" if none yet)
+	URL          string                        // Public URL the platform can fetch ("" if not reachable)
+	FileName     string                        // For multipart uploads
+	ContentType  string                        // For multipart uploads
+	Open         func() (io.ReadCloser, error) // Reads the file for multipart uploads
+}
+
+// StructuredMessage is a rich outbound message: quick reply chips or a template
+// Type selects the body; QuickReplies may be attached to any type
+// Stored on the sent message as attachments [{"type": "structured", "payload": <message>}]
+type StructuredMessage struct {
+	Type         string            `json:"type"`                    // See StructuredType constants
+	Text         string            `json:"text,omitempty"`          // quick_replies, button
+	Buttons      []MessageButton   `json:"buttons,omitempty"`       // button
+	Elements     []TemplateElement `json:"elements,omitempty"`      // generic (carousel cards)
+	Media        *MediaElement     `json:"media,omitempty"`         // media
+	QuickReplies []QuickReply      `json:"quick_replies,omitempty"` // Chips under the message
+}
+
+// StructuredType constants (StructuredMessage.Type)
+const (
+	StructuredTypeQuickReplies = "quick_replies" // Text with quick reply chips
+	StructuredTypeButton       = "button"        // Text with up to 3 buttons
+	StructuredTypeGeneric      = "generic"       // Carousel of cards (product list)
+	StructuredTypeMedia        = "media"         // Image / video with a button
+)
+
+// QuickReply is a chip; tapping it sends Title back as a message with Payload
+type QuickReply struct {
+	ContentType string `json:"content_type"`        // See QuickReply constants
+	Title       string `json:"title,omitempty"`     // text only
+	Payload     string `json:"payload,omitempty"`   // text only
+	ImageURL    string `json:"image_url,omitempty"` // text only, optional icon
+}
+
+// QuickReply content types
+const (
+	QuickReplyText  = "text"
+	QuickReplyPhone = "user_phone_number" // Offers the customer's phone number
+	QuickReplyEmail = "user_email"        // Offers the customer's email
+)
+
+// MessageButton is a template button
+type MessageButton struct {
+	Type    string `json:"type"` // See Button constants
+	Title   string `json:"title"`
+	URL     string `json:"url,omitempty"`     // web_url
+	Payload string `json:"payload,omitempty"` // postback payload, or phone number (+84...) for phone_number
+}
+
+// Button types
+const (
+	ButtonWebURL      = "web_url"      // Opens URL
+	ButtonPostback    = "postback"     // Sends a postback event with Payload
+	ButtonPhoneNumber = "phone_number" // Calls Payload
+)
+
+// TemplateElement is one card of a generic template
+type TemplateElement struct {
+	Title      string          `json:"title"`
+	Subtitle   string          `json:"subtitle,omitempty"`
+	ImageURL   string          `json:"image_url,omitempty"`
+	DefaultURL string          `json:"default_url,omitempty"` // Opened when the card itself is tapped
+	Buttons    []MessageButton `json:"buttons,omitempty"`
+}
+
+// MediaElement is the image / video of a media template, by platform attachment ID or URL
+type MediaElement struct {
+	MediaType    string          `json:"media_type"` // "image" or "video"
+	AttachmentID string          `json:"attachment_id,omitempty"`
+	URL          string          `json:"url,omitempty"` // Messenger only accepts facebook.com URLs here
+	Buttons      []MessageButton `json:"buttons,omitempty"`
+}
+
+// DeliveryUpdate moves outbound messages of one conversation to a later delivery state
+// Messages match by platform message ID and/or by being sent before Watermark
+type DeliveryUpdate struct {
+	TenantID   int
+	Platform   string
+	PageID     string
+	CustomerID string // conversations.platform_id
+	Status     string // DeliveryStatusDelivered or DeliveryStatusRead
+	MessageIDs []string
+	Watermark  time.Time // Zero means "no watermark"
+}
+
+// MessageChange applies an edit, unsend or reaction to a stored message
+// The message is found by platform message ID within the page's conversations
+type MessageChange struct {
+	Kind          string // EventTypeEdit, EventTypeUnsend or EventTypeReaction
+	TenantID      int
+	Platform      string
+	PageID        string
+	CustomerID    string // Who edited / unsent / reacted (conversations.platform_id)
+	ExternalMsgID string
+	Content       string    // Edit: new text
+	Reaction      *Reaction // Reaction: what changed
+	At            time.Time
+}
+
+// QuarantinedEvent is an event for a page we cannot route to a tenant
+// (page not connected or deactivated); kept so it can be replayed once the page is fixed
+type QuarantinedEvent struct {
+	ID        int64           `json:"id"`
+	Platform  string          `json:"platform"`
+	PageID    string          `json:"page_id"`
+	Reason    string          `json:"reason"` // See QuarantineReason constants
+	Event     json.RawMessage `json:"event"`  // The normalized InboundEvent
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// QuarantineReason constants
+const (
+	QuarantineReasonUnknownPage  = "unknown_page"
+	QuarantineReasonInactivePage = "inactive_page"
+)
+
+// DashboardEvent is pushed to connected dashboards when stored data changes
+type DashboardEvent struct {
+	Type           string      `json:"type"` // See DashboardEvent constants
+	ConversationID int64       `json:"conversation_id"`
+	Data           interface{} `json:"data,omitempty"`
+}
+
+// DashboardEvent types
+const (
+	DashboardEventMessageUpdated       = "message_updated"
+	DashboardEventConversationAssigned = "conversation_assigned" // Data: ConversationAssignment
+	DashboardEventConversationTagged   = "conversation_tagged"   // Data: ConversationTags
+	DashboardEventConversationStatus   = "conversation_status"   // Data: ConversationStatusChange
+)
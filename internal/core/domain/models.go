@@ -10,13 +10,29 @@ import (
 // WebhookLog represents the audit trail for incoming webhook events
 // Updated to match new schema from technical specification document
 type WebhookLog struct {
-	ID          int64           `json:"id" db:"id"`
-	Platform    string          `json:"platform" db:"platform"`                 // "facebook", "zalo"
-	PayloadJSON json.RawMessage `json:"payload_json" db:"payload_json"`         // JSON field
-	Status      string          `json:"status" db:"status"`                     // "pending", "processed", "failed"
-	RetryCount  int             `json:"retry_count" db:"retry_count"`           // Number of retry attempts
-	ErrorLog    *string         `json:"error_log,omitempty" db:"error_log"`     // Error details if failed
-	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	ID            int64           `json:"id" db:"id"`
+	Platform      string          `json:"platform" db:"platform"`                         // "facebook", "zalo"
+	PayloadJSON   json.RawMessage `json:"payload_json" db:"payload_json"`                 // JSON field
+	Status        string          `json:"status" db:"status"`                             // "pending", "processed", "failed"
+	RetryCount    int             `json:"retry_count" db:"retry_count"`                   // Number of retry attempts
+	ErrorLog      *string         `json:"error_log,omitempty" db:"error_log"`             // Error details if failed
+	LastAttemptAt *time.Time      `json:"last_attempt_at,omitempty" db:"last_attempt_at"` // Drives replay backoff
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// WebhookReplayFilter selects webhook_logs rows to feed back through the dispatcher
+// Zero values mean "no constraint" for that field
+type WebhookReplayFilter struct {
+	ID          int64         // Replay a single row
+	Platform    string        // "facebook", "zalo"
+	From        *time.Time    // created_at >= From
+	To          *time.Time    // created_at < To
+	Statuses    []string      // Defaults to failed + pending
+	OlderThan   time.Duration // Only rows created before NOW() - OlderThan (lets the queue finish its own retries)
+	MaxRetries  int           // Skip rows with retry_count >= MaxRetries
+	BaseBackoff time.Duration // Skip rows attempted less than BaseBackoff * 2^retry_count ago
+	MaxBackoff  time.Duration // Cap for the exponential backoff
+	Limit       int
 }
 
 // WebhookStatus constants for lifecycle management
@@ -35,12 +51,37 @@ type Message struct {
 	SenderType     string          `json:"sender_type" db:"sender_type"`           // "user", "bot", "agent"
 	Content        *string         `json:"content,omitempty" db:"content"`
 	Attachments    json.RawMessage `json:"attachments,omitempty" db:"attachments"` // JSON field
-	Type           *string         `json:"type,omitempty" db:"type"`               // "text", "image", "file", "sticker", "voice"
+	Type           *string         `json:"type,omitempty" db:"type"`               // "text", "image", "file", "sticker", "voice", "video", "audio", "share", "story_reply", "story_mention"
 	IsSynced       bool            `json:"is_synced" db:"is_synced"`
 	ExternalMsgID  *string         `json:"external_msg_id,omitempty" db:"external_msg_id"` // Platform message ID (for dedup)
+	Payload        *string         `json:"payload,omitempty" db:"payload"`                 // Postback / quick reply payload (bot flows)
+	DeliveryStatus *string         `json:"delivery_status,omitempty" db:"delivery_status"` // Outbound only: "queued", "sent", "delivered", "read", "failed"
+	DeliveryError  *string         `json:"delivery_error,omitempty" db:"delivery_error"`   // Why a failed outbound message failed, see DeliveryError constants
+	IsExternal     bool            `json:"is_external" db:"is_external"`                   // Agent message sent outside Immortal Chat (native inbox echo)
+	EditHistory    json.RawMessage `json:"edit_history,omitempty" db:"edit_history"`       // Previous versions: [{"content", "edited_at"}]
+	EditedAt       *time.Time      `json:"edited_at,omitempty" db:"edited_at"`
+	IsDeleted      bool            `json:"is_deleted" db:"is_deleted"` // Unsent by the customer (content is kept for audit)
+	DeletedAt      *time.Time      `json:"deleted_at,omitempty" db:"deleted_at"`
+	Reactions      json.RawMessage `json:"reactions,omitempty" db:"reactions"`     // Current reactions: []MessageReaction
+	MessageTag     *string         `json:"message_tag,omitempty" db:"message_tag"` // Outbound only: Messenger tag used outside the 24-hour window
 	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
 }
 
+// MessageEdit is one previous version of an edited message (messages.edit_history)
+type MessageEdit struct {
+	Content  string    `json:"content"`
+	EditedAt time.Time `json:"edited_at"` // When this version was replaced
+}
+
+// MessageReaction is one reaction on a message (messages.reactions)
+// A user has at most one reaction per message: reacting again replaces it
+type MessageReaction struct {
+	UserID    string    `json:"user_id"`
+	Reaction  string    `json:"reaction,omitempty"`
+	Emoji     string    `json:"emoji,omitempty"`
+	ReactedAt time.Time `json:"reacted_at"`
+}
+
 // SenderType constants
 const (
 	SenderTypeUser  = "user"
@@ -48,13 +89,73 @@ const (
 	SenderTypeAgent = "agent"
 )
 
+// DeliveryStatus constants (outbound messages only, only ever move forward)
+// Replies start queued and become sent or failed in the outbound queue
+const (
+	DeliveryStatusQueued    = "queued"
+	DeliveryStatusSent      = "sent"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusRead      = "read"
+	DeliveryStatusFailed    = "failed"
+)
+
+// DeliveryError constants (messages.delivery_error of failed outbound messages)
+const (
+	DeliveryErrorTokenExpired     = "token_expired"     // Page disconnected, it was deactivated
+	DeliveryErrorPageInactive     = "page_inactive"     // Page deactivated before the message was sent
+	DeliveryErrorPermissionDenied = "permission_denied" // Customer blocked the page / missing permission
+	DeliveryErrorOutsideWindow    = "outside_window"    // Messaging window closed / tag refused
+	DeliveryErrorRejected         = "rejected"          // Platform refused the message itself
+	DeliveryErrorSendFailed       = "send_failed"       // Still failing after all retries
+)
+
+// OutboundJob is a queued outbound message (outbound_jobs): exactly one of Text,
+// MediaID and Structured is set. Jobs of a conversation are sent in message order
+type OutboundJob struct {
+	MessageID      int64
+	ConversationID int64
+	TenantID       int
+	Platform       string
+	PageID         string
+	RecipientID    string             // conversations.platform_id
+	MessageTag     string             // "" = standard reply
+	Text           string             // Text message
+	MediaID        string             // Attachment message (media.id)
+	Structured     *StructuredMessage // Quick replies / template
+	Attempts       int                // Failed attempts so far
+	NextAttemptAt  time.Time
+}
+
+// MessageTag constants: Messenger message tags for sending outside the 24-hour window
+// HUMAN_AGENT extends the window to 7 days (Instagram accepts only this tag); the
+// others have no time limit but only cover their use case (no promotions)
+const (
+	MessageTagHumanAgent           = "HUMAN_AGENT"
+	MessageTagPostPurchaseUpdate   = "POST_PURCHASE_UPDATE"
+	MessageTagConfirmedEventUpdate = "CONFIRMED_EVENT_UPDATE"
+	MessageTagAccountUpdate        = "ACCOUNT_UPDATE"
+)
+
+// MessagingWindow tells whether a conversation can be answered with a standard reply
+// Platforms without a window (Zalo, Telegram) are always open
+type MessagingWindow struct {
+	Open          bool       `json:"open"`                      // Standard reply (messaging_type RESPONSE) allowed
+	LastInboundAt *time.Time `json:"last_inbound_at,omitempty"` // Last message from the customer
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`      // End of the standard window (nil = no window)
+	AllowedTags   []string   `json:"allowed_tags"`              // Tags usable now when the window is closed
+}
+
 // MessageType constants
 const (
-	MessageTypeText    = "text"
-	MessageTypeImage   = "image"
-	MessageTypeFile    = "file"
-	MessageTypeSticker = "sticker"
-	MessageTypeVoice   = "voice"
+	MessageTypeText       = "text"
+	MessageTypeImage      = "image"
+	MessageTypeFile       = "file"
+	MessageTypeSticker    = "sticker"
+	MessageTypeVoice      = "voice"
+	MessageTypePostback   = "postback"    // Button click: content = button title, payload = postback payload
+	MessageTypeOptin      = "optin"       // Opt-in: content = title/ref, payload = opt-in payload
+	MessageTypeTemplate   = "template"    // Button / generic / media template: content = preview text
+	MessageTypeStoryReply = "story_reply" // Instagram reply to a story: the story is kept as an attachment
 )
 
 // Conversation represents a chat thread/conversation
@@ -62,6 +163,7 @@ const (
 type Conversation struct {
 	ID                 int64           `json:"id" db:"id"`
 	TenantID           int             `json:"tenant_id" db:"tenant_id"`
+	Platform           string          `json:"platform" db:"platform"`           // "facebook", "zalo"
 	PlatformID         string          `json:"platform_id" db:"platform_id"`     // Platform-specific conversation ID
 	PageID             *string         `json:"page_id,omitempty" db:"page_id"`
 	CustomerName       *string         `json:"customer_name,omitempty" db:"customer_name"`
@@ -69,18 +171,205 @@ type Conversation struct {
 	LastMessageAt      *time.Time      `json:"last_message_at,omitempty" db:"last_message_at"`
 	Tags               json.RawMessage `json:"tags,omitempty" db:"tags"`         // JSON field
 	AssigneeID         *int            `json:"assignee_id,omitempty" db:"assignee_id"`
-	Status             string          `json:"status" db:"status"`               // "unread", "read", "archived"
+	AssignedTeamID     *int            `json:"assigned_team_id,omitempty" db:"assigned_team_id"`
+	Status             string          `json:"status" db:"status"`               // See ConversationStatus constants
+	SnoozedUntil       *time.Time      `json:"snoozed_until,omitempty" db:"snoozed_until"`
+	ReferralSource     *string         `json:"referral_source,omitempty" db:"referral_source"` // Last entry point: "SHORTLINK", "ADS", ...
+	ReferralRef        *string         `json:"referral_ref,omitempty" db:"referral_ref"`
+	ReferralAdID       *string         `json:"referral_ad_id,omitempty" db:"referral_ad_id"`
+	ReferredAt         *time.Time      `json:"referred_at,omitempty" db:"referred_at"`
 	CreatedAt          time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt          *time.Time      `json:"updated_at,omitempty" db:"updated_at"`
 }
 
 // ConversationStatus constants
+// unread / read are open; snoozed, resolved and archived are closed until the customer
+// writes again (snoozed also reopens at snoozed_until)
 const (
 	ConversationStatusUnread   = "unread"
 	ConversationStatusRead     = "read"
+	ConversationStatusSnoozed  = "snoozed"
+	ConversationStatusResolved = "resolved"
 	ConversationStatusArchived = "archived"
 )
 
+// ConversationStatusOpen is the list filter matching unread and read conversations
+const ConversationStatusOpen = "open"
+
+// ConversationStatusesFor returns the statuses matched by a list filter value
+// ("open" or a single status); ok is false for unknown values
+func ConversationStatusesFor(filter string) (statuses []string, ok bool) {
+	switch filter {
+	case ConversationStatusOpen:
+		return []string{ConversationStatusUnread, ConversationStatusRead}, true
+	case ConversationStatusUnread, ConversationStatusRead, ConversationStatusSnoozed,
+		ConversationStatusResolved, ConversationStatusArchived:
+		return []string{filter}, true
+	}
+	return nil, false
+}
+
+// ConversationStatusChange is the new lifecycle status of a conversation
+type ConversationStatusChange struct {
+	ConversationID int64      `json:"conversation_id"`
+	TenantID       int        `json:"tenant_id"`
+	Status         string     `json:"status"`
+	SnoozedUntil   *time.Time `json:"snoozed_until,omitempty"` // Snoozed only
+}
+
+// ConversationAssignment is who handles a conversation
+// AssigneeID = staff; AssignedTeamID = team whose agents all see it
+type ConversationAssignment struct {
+	ConversationID int64 `json:"conversation_id"`
+	TenantID       int   `json:"tenant_id"`
+	AssigneeID     *int  `json:"assignee_id"`
+	AssignedTeamID *int  `json:"assigned_team_id"`
+}
+
+// ConversationFilter narrows the dashboard conversation list (within the data scope)
+type ConversationFilter struct {
+	PageID     string   // "" = all pages
+	AssigneeID *int     // Only conversations assigned to this staff ("assigned to me")
+	Unassigned bool     // Only conversations without an assignee
+	Tag        string   // Only conversations carrying this tag name
+	Statuses   []string // Only conversations in one of these statuses (see ConversationStatusesFor)
+}
+
+// Cursor is a keyset pagination position: the sort timestamp plus the row ID as tie-breaker
+// Conversations use (last_message_at, id), messages (created_at, id)
+type Cursor struct {
+	At time.Time
+	ID int64
+}
+
+// PageQuery selects one page of a keyset-paginated list (at most one of Before / After)
+type PageQuery struct {
+	Limit  int
+	Before *Cursor // Rows older than the cursor
+	After  *Cursor // Rows newer than the cursor
+}
+
+// PageCursors points at the neighbouring pages in list order (nil = no such page)
+type PageCursors struct {
+	Next *Cursor // Continues after the last row of the page
+	Prev *Cursor // Continues before the first row of the page
+}
+
+// SearchQuery is a full-text search within the staff's data scope
+// Terms are the normalized words of the text, all of them must match (as word prefixes)
+// PageID applies to customers and messages, the other filters only to messages
+type SearchQuery struct {
+	Terms       []string
+	PageID      string     // "" = all pages
+	From        *time.Time // Messages created at or after
+	To          *time.Time // Messages created before
+	SenderType  string     // "" = any, see SenderType constants
+	MessageType string     // "" = any, see MessageType constants
+}
+
+// MessageHit is a message matching a search, with its conversation
+type MessageHit struct {
+	MessageID      int64     `json:"message_id"`
+	ConversationID int64     `json:"conversation_id"`
+	CustomerName   string    `json:"customer_name"`
+	Platform       string    `json:"platform"`
+	PageID         string    `json:"page_id"`
+	SenderType     string    `json:"sender_type"`
+	Type           string    `json:"type"`
+	Content        string    `json:"-"`
+	Highlight      string    `json:"highlight"` // HTML-escaped excerpt of the content, matches wrapped in <mark>
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CustomerHit is a conversation whose customer name matches a search
+type CustomerHit struct {
+	ConversationID int64     `json:"conversation_id"`
+	CustomerName   string    `json:"customer_name"`
+	Platform       string    `json:"platform"`
+	PageID         string    `json:"page_id"`
+	Status         string    `json:"status"`
+	Highlight      string    `json:"highlight"` // HTML-escaped customer name, matches wrapped in <mark>
+	LastMessageAt  time.Time `json:"last_message_at"`
+}
+
+// SearchResults are the customers and one page of messages matching a search
+type SearchResults struct {
+	Customers []CustomerHit `json:"customers"` // Only on the first page
+	Messages  []MessageHit  `json:"messages"`  // Newest first
+}
+
+// Media is a file stored by Immortal Chat (dashboard uploads), served under /media/{id}
+type Media struct {
+	ID          string    `json:"id" db:"id"` // Random hex, also the file name on disk
+	TenantID    int       `json:"tenant_id" db:"tenant_id"`
+	FileName    string    `json:"file_name" db:"file_name"` // Original file name
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	Type        string    `json:"type" db:"type"` // See AttachmentType constants
+	URL         string    `json:"url" db:"-"`     // Public URL when MEDIA_PUBLIC_URL is set, else /media/{id}
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// AttachmentType constants (media.type, messages.attachments[].type, messages.type)
+const (
+	AttachmentTypeImage = "image"
+	AttachmentTypeVideo = "video"
+	AttachmentTypeAudio = "audio"
+	AttachmentTypeFile  = "file"
+
+	// Inbound only: platform-specific types, all but share carry a media payload.url
+	AttachmentTypeVoice        = "voice"         // Telegram / Zalo voice note
+	AttachmentTypeSticker      = "sticker"       // Telegram / Zalo sticker image
+	AttachmentTypeGIF          = "gif"           // Zalo animated image
+	AttachmentTypeStory        = "story"         // Story replied to (Instagram, added by the adapter)
+	AttachmentTypeStoryMention = "story_mention" // Instagram story mentioning the account
+	AttachmentTypeShare        = "share"         // Instagram shared post (payload.url is a web page)
+)
+
+// MediaMirror tracks copying the remote attachments of a message to local storage (media_mirrors)
+// Content and Attachments are the message's current values, rewritten on success
+type MediaMirror struct {
+	MessageID     int64           `json:"message_id" db:"message_id"`
+	Status        string          `json:"status" db:"status"` // See MediaMirrorStatus constants
+	Attempts      int             `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     *string         `json:"last_error,omitempty" db:"last_error"`
+	MessageExists bool            `json:"-" db:"-"` // False once the message was purged
+	Content       *string         `json:"-" db:"content"`
+	Attachments   json.RawMessage `json:"-" db:"attachments"`
+}
+
+// MediaMirrorStatus constants (media_mirrors.status)
+const (
+	MediaMirrorStatusPending = "pending" // Waiting for (another) attempt
+	MediaMirrorStatusDone    = "done"
+	MediaMirrorStatusFailed  = "failed" // Gave up: expired URL, too large, not media or out of attempts
+)
+
+// Tag is a tenant-defined conversation label
+// conversations.tags holds the names of the tags set on a conversation (JSON array)
+type Tag struct {
+	ID                int       `json:"id" db:"id"`
+	TenantID          int       `json:"tenant_id" db:"tenant_id"`
+	Name              string    `json:"name" db:"name"`
+	Color             string    `json:"color" db:"color"`          // "#RRGGBB"
+	ConversationCount int       `json:"conversation_count" db:"-"` // Conversations carrying the tag within the staff's data scope
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+}
+
+// ConversationTags is the tag list of a conversation after a change
+type ConversationTags struct {
+	ConversationID int64    `json:"conversation_id"`
+	Tags           []string `json:"tags"`
+}
+
+// Routing strategies for new / unassigned conversations
+const (
+	RoutingRoundRobin = "round_robin" // Rotate through online agents
+	RoutingLeastOpen  = "least_open"  // Online agent with the fewest open conversations
+	RoutingOff        = "off"         // Leave conversations unassigned
+)
+
 // Tenant represents a customer/tenant in the multi-tenant system
 type Tenant struct {
 	ID        int             `json:"id" db:"id"`
@@ -91,6 +380,43 @@ type Tenant struct {
 	IsActive  bool            `json:"is_active" db:"is_active"`
 }
 
+// Plan constants (tenants.plan)
+const (
+	PlanBasic = "basic"
+	PlanPro   = "pro"
+	PlanVIP   = "vip"
+)
+
+// PlanLimits caps what a tenant can use; 0 means unlimited
+type PlanLimits struct {
+	MaxPages        int `json:"max_pages"`        // Active pages / OAs / bots
+	MaxAgents       int `json:"max_agents"`       // Staff accounts
+	MonthlyMessages int `json:"monthly_messages"` // Inbound + outbound messages per calendar month (only replies are refused)
+	RetentionDays   int `json:"retention_days"`   // Older messages are purged
+}
+
+// DefaultPlanLimits are the limits of each plan unless tenants.config overrides them
+// with {"limits": {"max_pages": ..., ...}}
+var DefaultPlanLimits = map[string]PlanLimits{
+	PlanBasic: {MaxPages: 1, MaxAgents: 3, MonthlyMessages: 5000, RetentionDays: 90},
+	PlanPro:   {MaxPages: 5, MaxAgents: 15, MonthlyMessages: 50000, RetentionDays: 365},
+	PlanVIP:   {MaxPages: 0, MaxAgents: 0, MonthlyMessages: 0, RetentionDays: 0},
+}
+
+// TenantUsage reports a tenant's consumption against its plan limits
+type TenantUsage struct {
+	TenantID        int        `json:"tenant_id"`
+	Plan            string     `json:"plan"`
+	IsActive        bool       `json:"is_active"`
+	ExpiredAt       *time.Time `json:"expired_at,omitempty"`
+	Expired         bool       `json:"expired"`
+	Limits          PlanLimits `json:"limits"`
+	Pages           int        `json:"pages"`
+	Agents          int        `json:"agents"` // Active staff accounts
+	MonthlyMessages int        `json:"monthly_messages"`
+	PeriodStart     time.Time  `json:"period_start"` // Start of the current calendar month
+}
+
 // Page represents a connected Facebook/Zalo page
 type Page struct {
 	ID          int64   `json:"id" db:"id"`
@@ -101,3 +427,14 @@ type Page struct {
 	AccessToken string  `json:"-" db:"access_token"`          // Never expose in JSON
 	IsActive    bool    `json:"is_active" db:"is_active"`
 }
+
+// WebhookJob represents an accepted webhook waiting in the durable work queue
+// Delivered at-least-once: consumers must Ack only after processing completes
+type WebhookJob struct {
+	ID         string          `json:"id"`       // Queue-assigned ID (Redis Stream entry ID)
+	LogID      int64           `json:"log_id"`   // webhook_logs.id for lifecycle tracking (0 if audit save failed)
+	Platform   string          `json:"platform"` // "facebook", "zalo"
+	Payload    json.RawMessage `json:"payload"`  // Raw webhook body
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	Deliveries int             `json:"deliveries"` // Number of times this job has been handed to a worker
+}
@@ -0,0 +1,19 @@
+// Package ports defines interfaces for dependency inversion
+package ports
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrMediaUnavailable is returned by MediaDownloader when retrying cannot help
+// (expired or deleted URL, access denied, unsupported URL)
+var ErrMediaUnavailable = errors.New("media no longer available")
+
+// MediaDownloader fetches remote media such as platform CDN attachment URLs
+type MediaDownloader interface {
+	// Download opens the media at url and returns its body and the Content-Type sent
+	// with it ("" if none); the caller closes the body
+	Download(ctx context.Context, url string) (body io.ReadCloser, contentType string, err error)
+}
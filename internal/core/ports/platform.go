@@ -0,0 +1,64 @@
+// Package ports defines interfaces for dependency inversion
+package ports
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"immortal-chat/internal/core/domain"
+)
+
+// Send errors returned by platform adapters (gateway clients map API error codes to them)
+// The outbound queue retries ErrRateLimited and unclassified (transport) errors and
+// gives up on the others
+var (
+	// ErrTokenExpired: the page access token is expired or invalid, the page is deactivated
+	ErrTokenExpired = errors.New("platform access token expired or invalid")
+
+	// ErrRateLimited: the platform rate limit was exceeded, the page is slowed down
+	ErrRateLimited = errors.New("platform rate limit exceeded")
+
+	// ErrPermissionDenied: the page may not message this customer (blocked, missing permission)
+	ErrPermissionDenied = errors.New("platform permission denied")
+
+	// ErrOutsideMessagingWindow: standard reply after the 24-hour window or a refused message tag
+	ErrOutsideMessagingWindow = errors.New("outside the platform messaging window")
+
+	// ErrMessageRejected: the platform refused the message itself (invalid parameter, unsupported media)
+	ErrMessageRejected = errors.New("message rejected by the platform")
+
+	// ErrPageInactive: the page is not connected (anymore), there is no token to send with
+	ErrPageInactive = errors.New("page not found or inactive")
+)
+
+// PlatformAdapter hides everything platform-specific (DTOs, signatures, send APIs)
+// One adapter per channel (Facebook, Zalo, ...), looked up by Platform() in a registry
+type PlatformAdapter interface {
+	// Platform returns the registry key, e.g. "facebook" (matches pages.platform)
+	Platform() string
+
+	// VerifySignature validates the webhook request (HMAC header, secret token, ...)
+	// Returns a non-nil error when the request must be rejected
+	VerifySignature(header http.Header, body []byte) error
+
+	// ParseEvents converts a raw webhook payload into normalized domain events
+	ParseEvents(payload []byte) ([]domain.InboundEvent, error)
+
+	// SendText sends a text reply and returns the platform message ID
+	SendText(ctx context.Context, target domain.OutboundTarget, text string) (string, error)
+}
+
+// AttachmentSender is implemented by adapters that can send media (optional)
+// Callers type-assert a PlatformAdapter to find out whether a channel supports it
+type AttachmentSender interface {
+	// SendAttachment sends a media message and returns the platform message ID plus
+	// the reusable attachment ID for this page when the platform issued one ("" otherwise)
+	SendAttachment(ctx context.Context, target domain.OutboundTarget, attachment domain.OutboundAttachment) (messageID, attachmentID string, err error)
+}
+
+// StructuredSender is implemented by adapters that can send quick replies and templates (optional)
+type StructuredSender interface {
+	// SendStructured sends a validated structured message and returns the platform message ID
+	SendStructured(ctx context.Context, target domain.OutboundTarget, message domain.StructuredMessage) (string, error)
+}
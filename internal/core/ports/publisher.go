@@ -0,0 +1,14 @@
+// Package ports defines interfaces for dependency inversion
+package ports
+
+import (
+	"context"
+
+	"immortal-chat/internal/core/domain"
+)
+
+// EventPublisher pushes live updates to connected dashboards
+// Delivery is best effort: dashboards reload data on reconnect anyway
+type EventPublisher interface {
+	Publish(ctx context.Context, event domain.DashboardEvent)
+}
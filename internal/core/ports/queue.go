@@ -0,0 +1,35 @@
+// Package ports defines interfaces for dependency inversion
+package ports
+
+import (
+	"context"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+// WebhookQueue is a durable work queue for accepted webhooks
+// Per .rulesgemini Section 4: Webhook must be acknowledged fast, processing happens later
+// Delivery is at-least-once: a job that is not Acked within the visibility timeout
+// is handed to another worker (processing must stay idempotent via DedupRepository)
+type WebhookQueue interface {
+	// Enqueue durably stores a job. Only after this returns nil may we answer 200 OK
+	Enqueue(ctx context.Context, job *domain.WebhookJob) error
+
+	// Dequeue blocks up to `block` waiting for new jobs for this consumer
+	Dequeue(ctx context.Context, consumer string, count int, block time.Duration) ([]*domain.WebhookJob, error)
+
+	// ClaimStale takes over jobs that were delivered but not Acked for longer than minIdle
+	// (worker crashed, container restarted, processing failed)
+	ClaimStale(ctx context.Context, consumer string, minIdle time.Duration, count int) ([]*domain.WebhookJob, error)
+
+	// Ack marks a job as done so it is never delivered again
+	Ack(ctx context.Context, jobID string) error
+
+	// DeadLetter moves a poison job out of the main queue (and Acks it)
+	DeadLetter(ctx context.Context, job *domain.WebhookJob, reason string) error
+
+	// PendingLogIDs returns the webhook_logs IDs of jobs delivered but not yet Acked
+	// The queue still redelivers those, so the replay engine must leave them alone
+	PendingLogIDs(ctx context.Context) (map[int64]bool, error)
+}
@@ -4,26 +4,44 @@ package ports
 
 import (
 	"context"
+	"errors"
+	"io"
 	"time"
 
 	"immortal-chat/internal/core/domain"
 )
 
+// ErrDuplicateMessage is returned by SaveMessage when the platform message is already
+// stored (the same webhook processed twice concurrently); callers skip it
+var ErrDuplicateMessage = errors.New("message already stored")
+
 // WebhookRepository handles persistence of webhook audit logs
 // Per .rulesgemini Section 3: All webhooks must be logged for audit and replay
 type WebhookRepository interface {
 	// SaveLog persists a webhook event to the audit log
+	// On success log.ID is set to the inserted row ID
 	SaveLog(ctx context.Context, log *domain.WebhookLog) error
 	
 	// UpdateStatus updates the processing status of a webhook log
 	// Used to track lifecycle: pending -> processed/failed
-	UpdateStatus(ctx context.Context, id string, status string) error
+	// A failed status increments retry_count and stores errorLog
+	UpdateStatus(ctx context.Context, id int64, status string, errorLog *string) error
+	
+	// GetLog retrieves a single webhook log (nil if not found)
+	GetLog(ctx context.Context, id int64) (*domain.WebhookLog, error)
+	
+	// FindForReplay lists webhook logs matching the filter, oldest first
+	FindForReplay(ctx context.Context, filter domain.WebhookReplayFilter) ([]*domain.WebhookLog, error)
+	
+	// CountByStatus returns the number of webhook logs per status (all tenants)
+	CountByStatus(ctx context.Context) (map[string]int, error)
 }
 
 // MessageRepository handles persistence of parsed chat messages
 // Per .rulesgemini Section 3: Local-First data storage
 type MessageRepository interface {
-	// SaveMessage persists a parsed message to the database
+	// SaveMessage persists a parsed message to the database (msg.ID is set to the new row ID)
+	// Returns ErrDuplicateMessage if the conversation already has this external message ID
 	SaveMessage(ctx context.Context, msg *domain.Message) error
 	
 	// GetByID retrieves a message by its platform-specific ID
@@ -32,14 +50,271 @@ type MessageRepository interface {
 	// Exists checks if a message with the given ID already exists
 	// Used for idempotency checks (alternative to Redis dedup)
 	Exists(ctx context.Context, id string) (bool, error)
+	
+	// UpdateDeliveryStatus applies a delivery / read receipt to outbound messages
+	// Returns the number of messages whose status changed
+	UpdateDeliveryStatus(ctx context.Context, update domain.DeliveryUpdate) (int64, error)
+	
+	// ApplyMessageChange applies an edit, unsend or reaction to a stored message
+	// Returns the updated message, or nil if the message is unknown or the change
+	// is not newer than the stored one (redelivered / replayed webhook)
+	ApplyMessageChange(ctx context.Context, change domain.MessageChange) (*domain.Message, error)
+	
+	// GetLastInboundAt returns the time of the customer's last message in a conversation
+	// (nil if the customer never wrote); Messenger's 24-hour window starts there
+	GetLastInboundAt(ctx context.Context, conversationID int64) (*time.Time, error)
 }
 
 // ConversationRepository handles conversation/thread management
 type ConversationRepository interface {
 	// GetOrCreateByPlatformID retrieves an existing conversation or creates a new one
-	// Uses platform_id (e.g., Facebook PSID) instead of external_id
+	// Uses platform_id (e.g., Facebook PSID, Zalo user ID) instead of external_id
+	// platform ("facebook", "zalo") is stored on new conversations for outbound routing
 	// Returns conversation database ID for linking messages
-	GetOrCreateByPlatformID(ctx context.Context, tenantID int, platformID, pageID string) (int64, error)
+	GetOrCreateByPlatformID(ctx context.Context, tenantID int, platform, platformID, pageID string) (int64, error)
+
+	// UpdateCustomerName stores the display name sent by the platform (e.g. Telegram)
+	UpdateCustomerName(ctx context.Context, conversationID int64, name string) error
+
+	// UpdateReferral stores the latest entry point (m.me ref, ad, plugin) for attribution
+	UpdateReferral(ctx context.Context, conversationID int64, referral *domain.Referral, referredAt time.Time) error
+
+	// ReopenConversation marks a conversation unread when the customer writes
+	// Returns the previous status when it was closed (snoozed / resolved / archived), "" otherwise
+	ReopenConversation(ctx context.Context, conversationID int64) (string, error)
+}
+
+// PageRepository looks up connected pages / OAs / bots
+type PageRepository interface {
+	// GetPage retrieves a page by platform and platform page ID (nil if not found)
+	GetPage(ctx context.Context, platform, pageID string) (*domain.Page, error)
+}
+
+// PageCache caches the page -> tenant mapping used to route inbound events
+type PageCache interface {
+	// GetPageTenant returns the cached tenant of a page; found is false on a cache miss
+	// Values <= 0 are markers for unroutable pages chosen by the caller
+	GetPageTenant(ctx context.Context, platform, pageID string) (tenantID int, found bool, err error)
+	
+	// SetPageTenant caches the tenant of a page (or an unroutable marker)
+	SetPageTenant(ctx context.Context, platform, pageID string, tenantID int, ttl time.Duration) error
+	
+	// DeletePageTenant drops the cached entry of a page after the page changed
+	DeletePageTenant(ctx context.Context, platform, pageID string) error
+}
+
+// TenantRepository reads tenants and their usage for plan enforcement
+type TenantRepository interface {
+	// GetTenant retrieves a tenant (nil if not found)
+	GetTenant(ctx context.Context, tenantID int) (*domain.Tenant, error)
+	
+	// ListTenants returns all tenants (used by the retention job)
+	ListTenants(ctx context.Context) ([]*domain.Tenant, error)
+	
+	// CountActivePages counts the tenant's active pages / OAs / bots
+	CountActivePages(ctx context.Context, tenantID int) (int, error)
+	
+	// CountMessagesSince counts inbound and outbound messages of the tenant since a time
+	CountMessagesSince(ctx context.Context, tenantID int, since time.Time) (int, error)
+	
+	// PurgeMessagesBefore deletes up to limit messages of the tenant older than before
+	// Returns the number of deleted messages
+	PurgeMessagesBefore(ctx context.Context, tenantID int, before time.Time, limit int) (int64, error)
+	
+	// CountActiveStaff counts the tenant's active staff accounts (agent seats)
+	CountActiveStaff(ctx context.Context, tenantID int) (int, error)
+}
+
+// StaffRepository handles dashboard accounts
+type StaffRepository interface {
+	// GetStaff retrieves a staff account by ID (nil if not found)
+	GetStaff(ctx context.Context, staffID int) (*domain.Staff, error)
+	
+	// GetStaffByEmail retrieves a staff account by login email (nil if not found)
+	GetStaffByEmail(ctx context.Context, email string) (*domain.Staff, error)
+	
+	// CreateStaff inserts a staff account; on success staff.ID is set
+	CreateStaff(ctx context.Context, staff *domain.Staff) error
+	
+	// UpdateLastLogin records a successful login
+	UpdateLastLogin(ctx context.Context, staffID int) error
+	
+	// ListStaff returns the tenant's staff accounts ordered by name
+	ListStaff(ctx context.Context, tenantID int) ([]*domain.Staff, error)
+	
+	// GetTeam retrieves a team of the tenant (nil if not found)
+	GetTeam(ctx context.Context, tenantID, teamID int) (*domain.Team, error)
+	
+	// ListTeams returns the tenant's teams ordered by name
+	ListTeams(ctx context.Context, tenantID int) ([]*domain.Team, error)
+	
+	// CreateTeam inserts a team; on success team.ID is set
+	CreateTeam(ctx context.Context, team *domain.Team) error
+}
+
+// TokenStore keeps refresh tokens so they can be used once and revoked
+type TokenStore interface {
+	// SaveRefreshToken records an issued refresh token until it expires
+	SaveRefreshToken(ctx context.Context, tokenID string, staffID int, ttl time.Duration) error
+	
+	// ConsumeRefreshToken deletes the token and returns whom it was issued to
+	// found is false when the token was already used, revoked or expired
+	ConsumeRefreshToken(ctx context.Context, tokenID string) (staffID int, found bool, err error)
+}
+
+// AssignmentRepository stores who handles each conversation
+type AssignmentRepository interface {
+	// GetAssignment returns the conversation's tenant and assignment (nil if not found)
+	GetAssignment(ctx context.Context, conversationID int64) (*domain.ConversationAssignment, error)
+	
+	// SetAssignment updates assignee / team of a conversation
+	// With onlyIfUnassigned the update is skipped when someone claimed it meanwhile;
+	// returns false when no row was updated
+	SetAssignment(ctx context.Context, assignment domain.ConversationAssignment, onlyIfUnassigned bool) (bool, error)
+	
+	// CountOpenByAssignee counts open (unread / read) conversations per staff of the tenant
+	CountOpenByAssignee(ctx context.Context, tenantID int) (map[int]int, error)
+}
+
+// TagRepository stores tag definitions and the tags of conversations
+type TagRepository interface {
+	// ListTags returns the tenant's tags ordered by name, with conversation counts within the scope
+	ListTags(ctx context.Context, scope domain.ConversationScope) ([]*domain.Tag, error)
+	
+	// GetTag retrieves a tag of the tenant (nil if not found)
+	GetTag(ctx context.Context, tenantID, tagID int) (*domain.Tag, error)
+	
+	// CreateTag inserts a tag definition; on success tag.ID is set
+	CreateTag(ctx context.Context, tag *domain.Tag) error
+	
+	// DeleteTag removes a tag definition and takes it off all conversations of the tenant
+	DeleteTag(ctx context.Context, tag *domain.Tag) error
+	
+	// SetConversationTag adds (present = true) or removes a tag name on a conversation within the scope
+	// Returns the resulting tag list, or nil if the conversation is not found / outside the scope
+	SetConversationTag(ctx context.Context, scope domain.ConversationScope, conversationID int64, name string, present bool) ([]string, error)
+}
+
+// LifecycleRepository changes conversation statuses (archive / resolve / snooze / reopen)
+type LifecycleRepository interface {
+	// SetConversationStatus sets the status of a conversation within the scope
+	// snoozedUntil is stored for snoozed and cleared for any other status
+	// Returns false if the conversation is not found / outside the scope
+	SetConversationStatus(ctx context.Context, scope domain.ConversationScope, conversationID int64, status string, snoozedUntil *time.Time) (bool, error)
+	
+	// WakeSnoozed reopens (unread) snoozed conversations due at now, at most limit per call
+	WakeSnoozed(ctx context.Context, now time.Time, limit int) ([]domain.ConversationStatusChange, error)
+}
+
+// SearchRepository runs full-text searches within a data scope
+type SearchRepository interface {
+	// SearchMessages returns one page of matching messages, newest first
+	SearchMessages(ctx context.Context, scope domain.ConversationScope, query domain.SearchQuery, page domain.PageQuery) ([]domain.MessageHit, domain.PageCursors, error)
+	
+	// SearchCustomers returns conversations whose customer name matches, most recent activity first
+	SearchCustomers(ctx context.Context, scope domain.ConversationScope, query domain.SearchQuery, limit int) ([]domain.CustomerHit, error)
+}
+
+// MediaRepository stores media metadata and reusable platform attachment IDs
+type MediaRepository interface {
+	// SaveMedia inserts a media row (media.ID is chosen by the caller)
+	SaveMedia(ctx context.Context, media *domain.Media) error
+	
+	// GetMedia retrieves a media file of the tenant (nil if not found)
+	GetMedia(ctx context.Context, tenantID int, mediaID string) (*domain.Media, error)
+	
+	// GetAttachmentID returns the reusable attachment ID of a media file on a page ("" if none)
+	GetAttachmentID(ctx context.Context, mediaID, platform, pageID string) (string, error)
+	
+	// SaveAttachmentID remembers the reusable attachment ID of a media file on a page
+	SaveAttachmentID(ctx context.Context, mediaID, platform, pageID, attachmentID string) error
+}
+
+// MediaStore keeps the media file contents (local disk, object storage, ...)
+type MediaStore interface {
+	// Save writes the file of a media ID and returns its size
+	Save(ctx context.Context, mediaID string, r io.Reader) (int64, error)
+	
+	// Open reads the file of a media ID
+	Open(ctx context.Context, mediaID string) (io.ReadCloser, error)
+	
+	// Delete removes the file of a media ID (no error if it does not exist)
+	Delete(ctx context.Context, mediaID string) error
+	
+	// SaveContent writes a file named by the hex SHA-256 of its content and returns
+	// the hash and size; the same content is stored once
+	SaveContent(ctx context.Context, r io.Reader) (hash string, size int64, err error)
+}
+
+// MediaMirrorRepository persists the jobs copying inbound attachments to local storage
+type MediaMirrorRepository interface {
+	// EnqueueMediaMirror adds a pending job for a message (no-op if it already has one)
+	EnqueueMediaMirror(ctx context.Context, messageID int64, now time.Time) error
+	
+	// FindDueMediaMirrors returns up to limit pending jobs whose next attempt is due,
+	// with the message's current content and attachments
+	FindDueMediaMirrors(ctx context.Context, now time.Time, limit int) ([]domain.MediaMirror, error)
+	
+	// SaveMediaMirror stores the outcome of an attempt: status, attempts, next_attempt_at and
+	// last_error of the job; content and attachments of the message when rewrite is true
+	// Jobs of purged messages (MessageExists false) are deleted
+	SaveMediaMirror(ctx context.Context, mirror *domain.MediaMirror, rewrite bool) error
+}
+
+// OutboundRepository persists the outbound message queue (outbound_jobs)
+type OutboundRepository interface {
+	// EnqueueOutbound stores a queued outbound message and its send job in one
+	// transaction; msg.ID and job.MessageID are set to the new message ID
+	EnqueueOutbound(ctx context.Context, msg *domain.Message, job *domain.OutboundJob) error
+	
+	// FindDueOutbound returns up to limit jobs due at now, oldest first; only the
+	// first job of each conversation is returned so messages go out in order
+	FindDueOutbound(ctx context.Context, now time.Time, limit int) ([]*domain.OutboundJob, error)
+	
+	// ClaimOutbound leases a due job until the given time so no other worker sends it
+	// Returns false if the job is gone or was claimed by someone else
+	ClaimOutbound(ctx context.Context, messageID int64, now, until time.Time) (bool, error)
+	
+	// RetryOutbound records a failed attempt and schedules the next one
+	RetryOutbound(ctx context.Context, messageID int64, attempts int, nextAttemptAt time.Time, lastError string) error
+	
+	// CompleteOutbound deletes the job and sets the message's final delivery status
+	// (sent with the platform message ID, or failed with deliveryError); an echo of
+	// the message stored meanwhile is merged into it. Returns the updated message
+	CompleteOutbound(ctx context.Context, messageID int64, status string, externalMsgID, deliveryError *string) (*domain.Message, error)
+	
+	// GetPageAccessToken returns the token of an active page (ErrPageInactive otherwise)
+	GetPageAccessToken(ctx context.Context, platform, pageID string) (string, error)
+	
+	// DeactivatePage marks a page inactive after its token expired
+	DeactivatePage(ctx context.Context, platform, pageID string) error
+}
+
+// RoutingStore tracks agent presence and round-robin position
+type RoutingStore interface {
+	// TouchPresence marks a staff online (heartbeat)
+	TouchPresence(ctx context.Context, tenantID, staffID int) error
+	
+	// ClearPresence marks a staff offline
+	ClearPresence(ctx context.Context, tenantID, staffID int) error
+	
+	// OnlineStaff returns staff IDs of the tenant seen within the last ttl
+	OnlineStaff(ctx context.Context, tenantID int, ttl time.Duration) ([]int, error)
+	
+	// NextRoundRobin returns an ever increasing counter per tenant
+	NextRoundRobin(ctx context.Context, tenantID int) (int64, error)
+}
+
+// QuarantineRepository keeps events that could not be routed to a tenant
+type QuarantineRepository interface {
+	// QuarantineEvent stores the event; storing the same event twice is a no-op
+	QuarantineEvent(ctx context.Context, event *domain.QuarantinedEvent) error
+	
+	// ListQuarantined returns up to limit events of a page with ID > afterID, oldest first
+	ListQuarantined(ctx context.Context, platform, pageID string, afterID int64, limit int) ([]*domain.QuarantinedEvent, error)
+	
+	// DeleteQuarantined removes an event once it was replayed
+	DeleteQuarantined(ctx context.Context, id int64) error
 }
 
 // DedupRepository handles deduplication of webhook events using cache
@@ -0,0 +1,362 @@
+// Package services contains conversation assignment and routing to agents
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+var (
+	// ErrConversationNotFound is returned for a conversation outside the staff's data scope
+	ErrConversationNotFound = errors.New("conversation not found")
+
+	// ErrAssignNotAllowed is returned when an agent tries to move a conversation they do not own
+	ErrAssignNotAllowed = errors.New("assignment not allowed")
+
+	// ErrInvalidAssignee is returned for a target that cannot handle conversations
+	// (missing, other tenant, inactive, viewer) or a target naming both / neither staff and team
+	ErrInvalidAssignee = errors.New("invalid assignee")
+)
+
+// AssignTarget is the new owner of a conversation: exactly one of StaffID / TeamID
+type AssignTarget struct {
+	StaffID *int
+	TeamID  *int
+}
+
+// RoutingConfig holds automatic routing settings
+type RoutingConfig struct {
+	Strategy    string        // domain.RoutingLeastOpen (default), domain.RoutingRoundRobin or domain.RoutingOff
+	PresenceTTL time.Duration // Agents without a heartbeat for this long are offline
+}
+
+// AssignmentService assigns conversations to staff / teams, manually or automatically
+//
+// Manual rules: owners and admins move any conversation of the tenant; agents can
+// claim a conversation of their team queue, and transfer or release their own
+type AssignmentService struct {
+	assignments ports.AssignmentRepository
+	staff       ports.StaffRepository
+	routing     ports.RoutingStore
+	publisher   ports.EventPublisher // Optional: nil disables live dashboard updates
+	config      RoutingConfig
+}
+
+// NewAssignmentService creates an assignment service
+func NewAssignmentService(
+	assignments ports.AssignmentRepository,
+	staff ports.StaffRepository,
+	routing ports.RoutingStore,
+	publisher ports.EventPublisher,
+	config RoutingConfig,
+) *AssignmentService {
+	if config.Strategy == "" {
+		config.Strategy = domain.RoutingLeastOpen
+	}
+	if config.PresenceTTL <= 0 {
+		config.PresenceTTL = 2 * time.Minute
+	}
+	return &AssignmentService{
+		assignments: assignments,
+		staff:       staff,
+		routing:     routing,
+		publisher:   publisher,
+		config:      config,
+	}
+}
+
+// Assign gives a conversation to a staff or team
+// Agents may only claim a conversation visible to them for themselves
+func (s *AssignmentService) Assign(ctx context.Context, actor *domain.AuthClaims, conversationID int64, target AssignTarget) (*domain.ConversationAssignment, error) {
+	return s.change(ctx, actor, conversationID, &target, func(current *domain.ConversationAssignment) error {
+		if target.StaffID == nil || *target.StaffID != actor.StaffID {
+			return ErrAssignNotAllowed
+		}
+		return nil
+	})
+}
+
+// Transfer hands a conversation over to another staff or team
+// Agents may only transfer conversations assigned to them
+func (s *AssignmentService) Transfer(ctx context.Context, actor *domain.AuthClaims, conversationID int64, target AssignTarget) (*domain.ConversationAssignment, error) {
+	return s.change(ctx, actor, conversationID, &target, requireAssignee(actor))
+}
+
+// Unassign removes the staff assignee; the conversation stays in its team queue (if any)
+// Agents may only release conversations assigned to them
+func (s *AssignmentService) Unassign(ctx context.Context, actor *domain.AuthClaims, conversationID int64) (*domain.ConversationAssignment, error) {
+	return s.change(ctx, actor, conversationID, nil, requireAssignee(actor))
+}
+
+// requireAssignee allows an agent to act only on conversations assigned to them
+func requireAssignee(actor *domain.AuthClaims) func(*domain.ConversationAssignment) error {
+	return func(current *domain.ConversationAssignment) error {
+		if current.AssigneeID == nil || *current.AssigneeID != actor.StaffID {
+			return ErrAssignNotAllowed
+		}
+		return nil
+	}
+}
+
+// change applies a manual assignment (target nil = unassign)
+// agentRule is checked for agents only, after the data scope check
+func (s *AssignmentService) change(
+	ctx context.Context,
+	actor *domain.AuthClaims,
+	conversationID int64,
+	target *AssignTarget,
+	agentRule func(*domain.ConversationAssignment) error,
+) (*domain.ConversationAssignment, error) {
+	current, err := s.assignments.GetAssignment(ctx, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("get assignment: %w", err)
+	}
+	if current == nil || !inScope(actor, current) {
+		return nil, ErrConversationNotFound
+	}
+	if !domain.ManagesConversations(actor.Role) {
+		if err := agentRule(current); err != nil {
+			return nil, err
+		}
+	}
+
+	next := domain.ConversationAssignment{
+		ConversationID: conversationID,
+		TenantID:       current.TenantID,
+		AssignedTeamID: current.AssignedTeamID,
+	}
+	if target != nil {
+		if next, err = s.resolveTarget(ctx, current, *target); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.assignments.SetAssignment(ctx, next, false); err != nil {
+		return nil, fmt.Errorf("set assignment: %w", err)
+	}
+
+	slog.Info("Conversation assignment changed",
+		"conversation_id", conversationID,
+		"tenant_id", current.TenantID,
+		"by_staff_id", actor.StaffID,
+		"assignee_id", intOrNil(next.AssigneeID),
+		"team_id", intOrNil(next.AssignedTeamID),
+	)
+	s.publish(ctx, &next)
+	return &next, nil
+}
+
+// inScope applies the staff's data scope to a conversation (same rule as the conversation list)
+func inScope(actor *domain.AuthClaims, current *domain.ConversationAssignment) bool {
+	return domain.ScopeForClaims(actor).Includes(current)
+}
+
+// resolveTarget validates the target within the conversation's tenant
+// A staff target also moves the conversation to the staff's team
+func (s *AssignmentService) resolveTarget(ctx context.Context, current *domain.ConversationAssignment, target AssignTarget) (domain.ConversationAssignment, error) {
+	next := domain.ConversationAssignment{
+		ConversationID: current.ConversationID,
+		TenantID:       current.TenantID,
+	}
+
+	switch {
+	case target.StaffID != nil && target.TeamID == nil:
+		staff, err := s.staff.GetStaff(ctx, *target.StaffID)
+		if err != nil {
+			return next, fmt.Errorf("get staff: %w", err)
+		}
+		if staff == nil || staff.TenantID != current.TenantID || !staff.IsActive ||
+			!domain.RoleHasPermission(staff.Role, domain.PermissionReply) {
+			return next, ErrInvalidAssignee
+		}
+		next.AssigneeID = &staff.ID
+		next.AssignedTeamID = staff.TeamID
+
+	case target.TeamID != nil && target.StaffID == nil:
+		team, err := s.staff.GetTeam(ctx, current.TenantID, *target.TeamID)
+		if err != nil {
+			return next, fmt.Errorf("get team: %w", err)
+		}
+		if team == nil {
+			return next, ErrTeamNotFound
+		}
+		next.AssignedTeamID = &team.ID
+
+	default:
+		return next, ErrInvalidAssignee
+	}
+
+	return next, nil
+}
+
+// Route assigns an unassigned conversation to an online agent (of its team, if it has one)
+// Called for every inbound customer message; a no-op when the conversation already has
+// an assignee, routing is off or no agent is online. Errors are logged, never returned:
+// routing must not make message processing fail
+func (s *AssignmentService) Route(ctx context.Context, tenantID int, conversationID int64) {
+	if s.config.Strategy == domain.RoutingOff {
+		return
+	}
+	if err := s.route(ctx, tenantID, conversationID); err != nil {
+		slog.Warn("Conversation routing failed",
+			"error", err,
+			"tenant_id", tenantID,
+			"conversation_id", conversationID,
+		)
+	}
+}
+
+func (s *AssignmentService) route(ctx context.Context, tenantID int, conversationID int64) error {
+	current, err := s.assignments.GetAssignment(ctx, conversationID)
+	if err != nil {
+		return fmt.Errorf("get assignment: %w", err)
+	}
+	if current == nil || current.AssigneeID != nil {
+		return nil
+	}
+
+	candidates, err := s.onlineAgents(ctx, tenantID, current.AssignedTeamID)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		slog.Debug("No online agent to route conversation to",
+			"tenant_id", tenantID,
+			"conversation_id", conversationID,
+		)
+		return nil
+	}
+
+	agent, err := s.pick(ctx, tenantID, candidates)
+	if err != nil {
+		return err
+	}
+
+	next := domain.ConversationAssignment{
+		ConversationID: conversationID,
+		TenantID:       tenantID,
+		AssigneeID:     &agent.ID,
+		AssignedTeamID: current.AssignedTeamID,
+	}
+	if next.AssignedTeamID == nil {
+		next.AssignedTeamID = agent.TeamID
+	}
+
+	// Conditional update: a manual assignment in the meantime wins
+	assigned, err := s.assignments.SetAssignment(ctx, next, true)
+	if err != nil {
+		return fmt.Errorf("set assignment: %w", err)
+	}
+	if !assigned {
+		return nil
+	}
+
+	slog.Info("Conversation routed",
+		"conversation_id", conversationID,
+		"tenant_id", tenantID,
+		"assignee_id", agent.ID,
+		"strategy", s.config.Strategy,
+	)
+	s.publish(ctx, &next)
+	return nil
+}
+
+// onlineAgents returns active, online agents of the tenant (of the team when teamID is set), by ID
+func (s *AssignmentService) onlineAgents(ctx context.Context, tenantID int, teamID *int) ([]*domain.Staff, error) {
+	online, err := s.routing.OnlineStaff(ctx, tenantID, s.config.PresenceTTL)
+	if err != nil {
+		return nil, fmt.Errorf("get online staff: %w", err)
+	}
+	if len(online) == 0 {
+		return nil, nil
+	}
+	isOnline := make(map[int]bool, len(online))
+	for _, id := range online {
+		isOnline[id] = true
+	}
+
+	staff, err := s.staff.ListStaff(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list staff: %w", err)
+	}
+
+	var agents []*domain.Staff
+	for _, st := range staff {
+		if !st.IsActive || st.Role != domain.RoleAgent || !isOnline[st.ID] {
+			continue
+		}
+		if teamID != nil && (st.TeamID == nil || *st.TeamID != *teamID) {
+			continue
+		}
+		agents = append(agents, st)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	return agents, nil
+}
+
+// pick chooses an agent by the configured strategy
+// least_open breaks ties by rotating the starting point like round_robin
+func (s *AssignmentService) pick(ctx context.Context, tenantID int, candidates []*domain.Staff) (*domain.Staff, error) {
+	turn, err := s.routing.NextRoundRobin(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("next round robin: %w", err)
+	}
+	start := int(turn % int64(len(candidates)))
+
+	if s.config.Strategy == domain.RoutingRoundRobin {
+		return candidates[start], nil
+	}
+
+	open, err := s.assignments.CountOpenByAssignee(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("count open conversations: %w", err)
+	}
+	best := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		c := candidates[(start+i)%len(candidates)]
+		if open[c.ID] < open[best.ID] {
+			best = c
+		}
+	}
+	return best, nil
+}
+
+// Heartbeat records that a staff has the dashboard open (online = false logs them off)
+func (s *AssignmentService) Heartbeat(ctx context.Context, tenantID, staffID int, online bool) error {
+	if !online {
+		return s.routing.ClearPresence(ctx, tenantID, staffID)
+	}
+	return s.routing.TouchPresence(ctx, tenantID, staffID)
+}
+
+// OnlineStaff returns the IDs of the tenant's staff currently online
+func (s *AssignmentService) OnlineStaff(ctx context.Context, tenantID int) ([]int, error) {
+	return s.routing.OnlineStaff(ctx, tenantID, s.config.PresenceTTL)
+}
+
+// publish pushes the new assignment to the dashboards (no-op without a publisher)
+func (s *AssignmentService) publish(ctx context.Context, assignment *domain.ConversationAssignment) {
+	if s.publisher == nil {
+		return
+	}
+	s.publisher.Publish(ctx, domain.DashboardEvent{
+		Type:           domain.DashboardEventConversationAssigned,
+		ConversationID: assignment.ConversationID,
+		Data:           assignment,
+	})
+}
+
+// intOrNil formats an optional ID for logs
+func intOrNil(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
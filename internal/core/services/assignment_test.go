@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// memAssignments keeps conversation assignments in memory
+type memAssignments struct {
+	ports.AssignmentRepository
+	conversations map[int64]domain.ConversationAssignment
+	open          map[int]int // Open conversations per assignee
+}
+
+func (m *memAssignments) GetAssignment(ctx context.Context, conversationID int64) (*domain.ConversationAssignment, error) {
+	current, ok := m.conversations[conversationID]
+	if !ok {
+		return nil, nil
+	}
+	return &current, nil
+}
+
+func (m *memAssignments) SetAssignment(ctx context.Context, assignment domain.ConversationAssignment, onlyIfUnassigned bool) (bool, error) {
+	if onlyIfUnassigned && m.conversations[assignment.ConversationID].AssigneeID != nil {
+		return false, nil
+	}
+	m.conversations[assignment.ConversationID] = assignment
+	return true, nil
+}
+
+func (m *memAssignments) CountOpenByAssignee(ctx context.Context, tenantID int) (map[int]int, error) {
+	return m.open, nil
+}
+
+// fakeStaff serves a fixed set of staff accounts and teams
+type fakeStaff struct {
+	ports.StaffRepository
+	staff []*domain.Staff
+	teams []*domain.Team
+}
+
+func (f *fakeStaff) GetStaff(ctx context.Context, staffID int) (*domain.Staff, error) {
+	for _, st := range f.staff {
+		if st.ID == staffID {
+			return st, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeStaff) ListStaff(ctx context.Context, tenantID int) ([]*domain.Staff, error) {
+	var list []*domain.Staff
+	for _, st := range f.staff {
+		if st.TenantID == tenantID {
+			list = append(list, st)
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeStaff) GetTeam(ctx context.Context, tenantID, teamID int) (*domain.Team, error) {
+	for _, team := range f.teams {
+		if team.TenantID == tenantID && team.ID == teamID {
+			return team, nil
+		}
+	}
+	return nil, nil
+}
+
+// fakeRouting reports a fixed set of online staff and counts round robin turns
+type fakeRouting struct {
+	ports.RoutingStore
+	online []int
+	turn   int64
+}
+
+func (f *fakeRouting) OnlineStaff(ctx context.Context, tenantID int, ttl time.Duration) ([]int, error) {
+	return f.online, nil
+}
+
+func (f *fakeRouting) NextRoundRobin(ctx context.Context, tenantID int) (int64, error) {
+	f.turn++
+	return f.turn, nil
+}
+
+// recordingPublisher keeps every published dashboard event
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []domain.DashboardEvent
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event domain.DashboardEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+func intPtr(v int) *int { return &v }
+
+// Tenant 1: owner 1, agents 2 and 4 in team 10, agent 3 without team, inactive
+// agent 5, viewer 6; tenant 2: agent 7
+func newAssignmentTest(strategy string, online ...int) (*AssignmentService, *memAssignments, *recordingPublisher) {
+	staff := &fakeStaff{
+		staff: []*domain.Staff{
+			{ID: 1, TenantID: 1, Role: domain.RoleOwner, IsActive: true},
+			{ID: 2, TenantID: 1, Role: domain.RoleAgent, TeamID: intPtr(10), IsActive: true},
+			{ID: 3, TenantID: 1, Role: domain.RoleAgent, IsActive: true},
+			{ID: 4, TenantID: 1, Role: domain.RoleAgent, TeamID: intPtr(10), IsActive: true},
+			{ID: 5, TenantID: 1, Role: domain.RoleAgent, IsActive: false},
+			{ID: 6, TenantID: 1, Role: domain.RoleViewer, IsActive: true},
+			{ID: 7, TenantID: 2, Role: domain.RoleAgent, IsActive: true},
+		},
+		teams: []*domain.Team{{ID: 10, TenantID: 1, Name: "Sales"}},
+	}
+	assignments := &memAssignments{
+		conversations: map[int64]domain.ConversationAssignment{
+			100: {ConversationID: 100, TenantID: 1, AssigneeID: intPtr(3)},      // Agent 3's
+			101: {ConversationID: 101, TenantID: 1, AssignedTeamID: intPtr(10)}, // Team 10 queue
+			102: {ConversationID: 102, TenantID: 1},                             // Unassigned
+			200: {ConversationID: 200, TenantID: 2, AssigneeID: intPtr(7)},      // Other tenant
+			103: {ConversationID: 103, TenantID: 1, AssigneeID: intPtr(2), AssignedTeamID: intPtr(10)},
+		},
+		open: map[int]int{2: 3, 3: 5, 4: 1},
+	}
+	publisher := &recordingPublisher{}
+	service := NewAssignmentService(assignments, staff, &fakeRouting{online: online}, publisher, RoutingConfig{Strategy: strategy})
+	return service, assignments, publisher
+}
+
+func TestAssignmentRespectsScopeAndRoles(t *testing.T) {
+	owner := &domain.AuthClaims{StaffID: 1, TenantID: 1, Role: domain.RoleOwner}
+	agent2 := &domain.AuthClaims{StaffID: 2, TenantID: 1, Role: domain.RoleAgent, TeamID: intPtr(10)}
+	agent3 := &domain.AuthClaims{StaffID: 3, TenantID: 1, Role: domain.RoleAgent}
+
+	for name, tc := range map[string]struct {
+		actor          *domain.AuthClaims
+		conversationID int64
+		action         func(s *AssignmentService, actor *domain.AuthClaims, id int64) (*domain.ConversationAssignment, error)
+		want           error
+	}{
+		"agent transfers another agent's conversation": {agent2, 100, transferTo(4), ErrConversationNotFound},
+		"agent releases another agent's conversation":  {agent2, 100, unassign, ErrConversationNotFound},
+		"agent claims from another tenant":             {agent3, 200, claim(3), ErrConversationNotFound},
+		"owner of another tenant":                      {owner, 200, transferTo(1), ErrConversationNotFound},
+		"agent claims a team conversation for someone": {agent2, 101, claim(4), ErrAssignNotAllowed},
+		"team mate transfers a claimed conversation":   {&domain.AuthClaims{StaffID: 4, TenantID: 1, Role: domain.RoleAgent, TeamID: intPtr(10)}, 103, transferTo(4), ErrAssignNotAllowed},
+		"transfer to a viewer":                         {owner, 102, transferTo(6), ErrInvalidAssignee},
+		"transfer to an inactive agent":                {owner, 102, transferTo(5), ErrInvalidAssignee},
+		"transfer to another tenant's agent":           {owner, 102, transferTo(7), ErrInvalidAssignee},
+		"agent claims from the team queue":             {agent2, 101, claim(2), nil},
+		"agent transfers their own conversation":       {agent3, 100, transferTo(2), nil},
+		"owner moves any conversation":                 {owner, 100, transferTo(4), nil},
+	} {
+		service, _, _ := newAssignmentTest(domain.RoutingLeastOpen)
+		if _, err := tc.action(service, tc.actor, tc.conversationID); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+
+	// A staff target also moves the conversation to the staff's team
+	service, assignments, publisher := newAssignmentTest(domain.RoutingLeastOpen)
+	if _, err := service.Transfer(context.Background(), agent3, 100, AssignTarget{StaffID: intPtr(2)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := assignments.conversations[100]; *got.AssigneeID != 2 || got.AssignedTeamID == nil || *got.AssignedTeamID != 10 {
+		t.Errorf("after transfer: %+v", got)
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Type != domain.DashboardEventConversationAssigned {
+		t.Errorf("events = %+v", publisher.events)
+	}
+}
+
+func claim(staffID int) func(*AssignmentService, *domain.AuthClaims, int64) (*domain.ConversationAssignment, error) {
+	return func(s *AssignmentService, actor *domain.AuthClaims, id int64) (*domain.ConversationAssignment, error) {
+		return s.Assign(context.Background(), actor, id, AssignTarget{StaffID: intPtr(staffID)})
+	}
+}
+
+func transferTo(staffID int) func(*AssignmentService, *domain.AuthClaims, int64) (*domain.ConversationAssignment, error) {
+	return func(s *AssignmentService, actor *domain.AuthClaims, id int64) (*domain.ConversationAssignment, error) {
+		return s.Transfer(context.Background(), actor, id, AssignTarget{StaffID: intPtr(staffID)})
+	}
+}
+
+func unassign(s *AssignmentService, actor *domain.AuthClaims, id int64) (*domain.ConversationAssignment, error) {
+	return s.Unassign(context.Background(), actor, id)
+}
+
+func TestRoutePicksAmongOnlineAgents(t *testing.T) {
+	// Online: owner 1, agents 2 (3 open), 3 (5 open), 4 (1 open), inactive 5, viewer 6
+	online := []int{1, 2, 3, 4, 5, 6}
+
+	for name, tc := range map[string]struct {
+		strategy       string
+		online         []int
+		conversationID int64
+		want           *int // nil = stays unassigned
+	}{
+		"least open":                 {domain.RoutingLeastOpen, online, 102, intPtr(4)},
+		"least open busy agent only": {domain.RoutingLeastOpen, []int{1, 3, 6}, 102, intPtr(3)},
+		"team queue stays in team":   {domain.RoutingLeastOpen, []int{2, 3}, 101, intPtr(2)},
+		"no team agent online":       {domain.RoutingLeastOpen, []int{3}, 101, nil},
+		"nobody online":              {domain.RoutingLeastOpen, nil, 102, nil},
+		"only owner and viewer":      {domain.RoutingLeastOpen, []int{1, 5, 6}, 102, nil},
+		"round robin, first turn":    {domain.RoutingRoundRobin, online, 102, intPtr(3)}, // Turn 1 of [2 3 4]
+		"routing off":                {domain.RoutingOff, online, 102, nil},
+		"already assigned is kept":   {domain.RoutingLeastOpen, online, 100, intPtr(3)},
+		"unknown conversation is ok": {domain.RoutingLeastOpen, online, 999, nil},
+	} {
+		service, assignments, _ := newAssignmentTest(tc.strategy, tc.online...)
+		service.Route(context.Background(), 1, tc.conversationID)
+
+		got := assignments.conversations[tc.conversationID].AssigneeID
+		if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+			t.Errorf("%s: assignee = %v, want %v", name, intOrNil(got), intOrNil(tc.want))
+		}
+	}
+
+	// Round robin rotates through the online agents
+	service, assignments, publisher := newAssignmentTest(domain.RoutingRoundRobin, online...)
+	var picked []int
+	for i := 0; i < 4; i++ {
+		assignments.conversations[102] = domain.ConversationAssignment{ConversationID: 102, TenantID: 1}
+		service.Route(context.Background(), 1, 102)
+		picked = append(picked, *assignments.conversations[102].AssigneeID)
+	}
+	if want := []int{3, 4, 2, 3}; !equalInts(picked, want) {
+		t.Errorf("round robin picked %v, want %v", picked, want)
+	}
+	if len(publisher.events) != 4 {
+		t.Errorf("%d events published, want 4", len(publisher.events))
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
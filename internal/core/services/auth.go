@@ -0,0 +1,374 @@
+// Package services contains dashboard authentication
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown email, wrong password or disabled account
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrEmailTaken is returned when creating staff with an email already in use
+	ErrEmailTaken = errors.New("email already in use")
+
+	// ErrWeakPassword is returned for passwords shorter than MinPasswordLength or
+	// longer than MaxPasswordLength
+	ErrWeakPassword = errors.New("password too short or too long")
+
+	// ErrInvalidRole is returned for a role that is not one of the staff roles
+	ErrInvalidRole = errors.New("invalid role")
+
+	// ErrRoleNotAllowed is returned when the caller may not grant the requested role
+	ErrRoleNotAllowed = errors.New("role not allowed")
+
+	// ErrTeamNotFound is returned for a team that does not belong to the tenant
+	ErrTeamNotFound = errors.New("team not found")
+
+	// ErrTeamExists is returned when creating a team with a name already in use
+	ErrTeamExists = errors.New("team already exists")
+)
+
+// NewStaff describes an account to create
+type NewStaff struct {
+	Email    string
+	Name     string
+	Password string
+	Role     string // Default RoleAgent
+	TeamID   *int
+}
+
+// AuthConfig holds token signing settings
+type AuthConfig struct {
+	Secret     []byte        // HS256 signing key
+	AccessTTL  time.Duration // Lifetime of access tokens (sent on every API call)
+	RefreshTTL time.Duration // Lifetime of single-use refresh tokens
+}
+
+// AuthService handles staff login and dashboard tokens
+// Access tokens are stateless JWTs; refresh tokens are JWTs whose ID is also
+// kept in the TokenStore so each one can be used once and revoked on logout
+type AuthService struct {
+	staff  ports.StaffRepository
+	tokens ports.TokenStore
+	quota  *QuotaService
+	config AuthConfig
+	now    func() time.Time
+}
+
+// NewAuthService creates an auth service
+func NewAuthService(staff ports.StaffRepository, tokens ports.TokenStore, quota *QuotaService, config AuthConfig) *AuthService {
+	if config.AccessTTL <= 0 {
+		config.AccessTTL = 15 * time.Minute
+	}
+	if config.RefreshTTL <= 0 {
+		config.RefreshTTL = 7 * 24 * time.Hour
+	}
+	return &AuthService{
+		staff:  staff,
+		tokens: tokens,
+		quota:  quota,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Login checks email / password and issues a token pair
+func (s *AuthService) Login(ctx context.Context, email, password string) (*domain.TokenPair, *domain.Staff, error) {
+	staff, err := s.staff.GetStaffByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		return nil, nil, fmt.Errorf("get staff: %w", err)
+	}
+	if staff == nil {
+		// Spend the same time as a real check so response time does not reveal which emails exist
+		CheckPassword(dummyPasswordHash(), password)
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	ok, err := CheckPassword(staff.PasswordHash, password)
+	if err != nil {
+		slog.Error("Stored password hash is invalid", "error", err, "staff_id", staff.ID)
+		return nil, nil, ErrInvalidCredentials
+	}
+	if !ok || !staff.IsActive {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	pair, err := s.issueTokens(ctx, staff)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.staff.UpdateLastLogin(ctx, staff.ID); err != nil {
+		slog.Warn("Failed to update last login", "error", err, "staff_id", staff.ID)
+	}
+
+	slog.Info("Staff logged in", "staff_id", staff.ID, "tenant_id", staff.TenantID)
+	return pair, staff, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair (rotation)
+// The presented token is consumed, so a stolen token works at most once
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error) {
+	claims, err := parseToken(refreshToken, s.config.Secret, s.now())
+	if err != nil || claims.TokenType != domain.TokenTypeRefresh || claims.TokenID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	staffID, found, err := s.tokens.ConsumeRefreshToken(ctx, claims.TokenID)
+	if err != nil {
+		return nil, fmt.Errorf("consume refresh token: %w", err)
+	}
+	if !found || staffID != claims.StaffID {
+		slog.Warn("Refresh token reused or revoked", "staff_id", claims.StaffID)
+		return nil, ErrInvalidToken
+	}
+
+	// Re-read the account: disabled staff must not get new tokens
+	staff, err := s.staff.GetStaff(ctx, claims.StaffID)
+	if err != nil {
+		return nil, fmt.Errorf("get staff: %w", err)
+	}
+	if staff == nil || !staff.IsActive {
+		return nil, ErrInvalidToken
+	}
+
+	return s.issueTokens(ctx, staff)
+}
+
+// Logout revokes a refresh token (access tokens expire on their own)
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	claims, err := parseToken(refreshToken, s.config.Secret, s.now())
+	if err != nil || claims.TokenType != domain.TokenTypeRefresh {
+		return nil // Nothing to revoke
+	}
+	if _, _, err := s.tokens.ConsumeRefreshToken(ctx, claims.TokenID); err != nil {
+		return fmt.Errorf("revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+// VerifyAccessToken validates an access token and returns its claims
+func (s *AuthService) VerifyAccessToken(token string) (*domain.AuthClaims, error) {
+	claims, err := parseToken(token, s.config.Secret, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != domain.TokenTypeAccess {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// GetStaff returns a staff account (nil if not found)
+func (s *AuthService) GetStaff(ctx context.Context, staffID int) (*domain.Staff, error) {
+	return s.staff.GetStaff(ctx, staffID)
+}
+
+// CreateStaff adds an account to a tenant, within the plan's agent limit
+// Only owners may create owners (callerRole is the role of the logged-in staff)
+// Errors: ErrInvalidRole, ErrRoleNotAllowed, ErrTeamNotFound, ErrWeakPassword,
+// ErrEmailTaken, plus QuotaService.CheckAgentSeat errors
+func (s *AuthService) CreateStaff(ctx context.Context, tenantID int, callerRole string, input NewStaff) (*domain.Staff, error) {
+	if input.Role == "" {
+		input.Role = domain.RoleAgent
+	}
+	if !domain.IsValidRole(input.Role) {
+		return nil, ErrInvalidRole
+	}
+	if input.Role == domain.RoleOwner && callerRole != domain.RoleOwner {
+		return nil, ErrRoleNotAllowed
+	}
+	if input.TeamID != nil {
+		team, err := s.staff.GetTeam(ctx, tenantID, *input.TeamID)
+		if err != nil {
+			return nil, fmt.Errorf("get team: %w", err)
+		}
+		if team == nil {
+			return nil, ErrTeamNotFound
+		}
+	}
+
+	if err := s.quota.CheckAgentSeat(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	return s.createStaff(ctx, tenantID, input)
+}
+
+// ListStaff returns the tenant's staff accounts
+func (s *AuthService) ListStaff(ctx context.Context, tenantID int) ([]*domain.Staff, error) {
+	return s.staff.ListStaff(ctx, tenantID)
+}
+
+// ListTeams returns the tenant's teams
+func (s *AuthService) ListTeams(ctx context.Context, tenantID int) ([]*domain.Team, error) {
+	return s.staff.ListTeams(ctx, tenantID)
+}
+
+// CreateTeam adds a team to the tenant (ErrTeamExists if the name is taken)
+func (s *AuthService) CreateTeam(ctx context.Context, tenantID int, name string) (*domain.Team, error) {
+	name = strings.TrimSpace(name)
+
+	teams, err := s.staff.ListTeams(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list teams: %w", err)
+	}
+	for _, team := range teams {
+		if strings.EqualFold(team.Name, name) {
+			return nil, ErrTeamExists
+		}
+	}
+
+	team := &domain.Team{
+		TenantID:  tenantID,
+		Name:      name,
+		CreatedAt: s.now(),
+	}
+	if err := s.staff.CreateTeam(ctx, team); err != nil {
+		return nil, fmt.Errorf("create team: %w", err)
+	}
+	return team, nil
+}
+
+// EnsureBootstrapAdmin creates the first (owner) account of a tenant if the email is not taken yet
+// Used at startup so a fresh install can log in; the plan's agent limit is not applied
+func (s *AuthService) EnsureBootstrapAdmin(ctx context.Context, tenantID int, email, name, password string) error {
+	_, err := s.createStaff(ctx, tenantID, NewStaff{
+		Email:    email,
+		Name:     name,
+		Password: password,
+		Role:     domain.RoleOwner,
+	})
+	if errors.Is(err, ErrEmailTaken) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Bootstrap staff account created", "tenant_id", tenantID, "email", normalizeEmail(email))
+	return nil
+}
+
+// createStaff validates and inserts a staff account
+func (s *AuthService) createStaff(ctx context.Context, tenantID int, input NewStaff) (*domain.Staff, error) {
+	email := normalizeEmail(input.Email)
+	if len(input.Password) < MinPasswordLength || len(input.Password) > MaxPasswordLength {
+		return nil, ErrWeakPassword
+	}
+
+	existing, err := s.staff.GetStaffByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("get staff: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrEmailTaken
+	}
+
+	hash, err := HashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = email
+	}
+	staff := &domain.Staff{
+		TenantID:     tenantID,
+		Email:        email,
+		Name:         name,
+		Role:         input.Role,
+		TeamID:       input.TeamID,
+		PasswordHash: hash,
+		IsActive:     true,
+		CreatedAt:    s.now(),
+	}
+	if err := s.staff.CreateStaff(ctx, staff); err != nil {
+		return nil, fmt.Errorf("create staff: %w", err)
+	}
+	return staff, nil
+}
+
+// issueTokens signs a new access token and a new (stored) refresh token
+func (s *AuthService) issueTokens(ctx context.Context, staff *domain.Staff) (*domain.TokenPair, error) {
+	now := s.now()
+
+	// Role and team travel in the access token: changes apply at the next refresh
+	access, err := signToken(domain.AuthClaims{
+		StaffID:   staff.ID,
+		TenantID:  staff.TenantID,
+		Role:      staff.Role,
+		TeamID:    staff.TeamID,
+		TokenType: domain.TokenTypeAccess,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(s.config.AccessTTL),
+	}, s.config.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := signToken(domain.AuthClaims{
+		StaffID:   staff.ID,
+		TenantID:  staff.TenantID,
+		TokenType: domain.TokenTypeRefresh,
+		TokenID:   tokenID,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(s.config.RefreshTTL),
+	}, s.config.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tokens.SaveRefreshToken(ctx, tokenID, staff.ID, s.config.RefreshTTL); err != nil {
+		return nil, fmt.Errorf("save refresh token: %w", err)
+	}
+
+	return &domain.TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.config.AccessTTL.Seconds()),
+	}, nil
+}
+
+// newTokenID returns a random 128-bit refresh token ID
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// normalizeEmail makes logins case-insensitive
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash is checked against for unknown emails (computed once)
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = HashPassword("immortal-chat-dummy-password")
+	})
+	return dummyHash
+}
@@ -5,23 +5,33 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strconv"
 	"time"
 
-	"immortal-chat/internal/adapters/dto"
 	"immortal-chat/internal/core/domain"
 	"immortal-chat/internal/core/ports"
 )
 
+// ErrInvalidPayload marks a webhook that can never be processed (malformed JSON)
+// Queue workers Ack these instead of retrying them forever
+var ErrInvalidPayload = errors.New("invalid webhook payload")
+
 // Dispatcher orchestrates webhook processing workflow
-// Per .rulesgemini Section 4: Fire & Forget pattern with async processing
+// Per .rulesgemini Section 4: Accept fast (durable queue), process asynchronously
 type Dispatcher struct {
 	webhookRepo      ports.WebhookRepository
 	messageRepo      ports.MessageRepository
 	conversationRepo ports.ConversationRepository
 	dedupRepo        ports.DedupRepository
+	queue            ports.WebhookQueue
+	platforms        *PlatformRegistry
+	tenants          *TenantResolver
+	quarantine       ports.QuarantineRepository
+	publisher        ports.EventPublisher // Optional: nil disables live dashboard updates
+	router           *AssignmentService   // Optional: nil disables automatic routing to agents
+	mirror           *MediaMirrorService  // Optional: nil leaves inbound attachments on the platform CDN
 }
 
 // NewDispatcher creates a new dispatcher instance with dependencies injected
@@ -30,35 +40,38 @@ func NewDispatcher(
 	messageRepo ports.MessageRepository,
 	conversationRepo ports.ConversationRepository,
 	dedupRepo ports.DedupRepository,
+	queue ports.WebhookQueue,
+	platforms *PlatformRegistry,
+	tenants *TenantResolver,
+	quarantine ports.QuarantineRepository,
+	publisher ports.EventPublisher,
+	router *AssignmentService,
+	mirror *MediaMirrorService,
 ) *Dispatcher {
 	return &Dispatcher{
 		webhookRepo:      webhookRepo,
 		messageRepo:      messageRepo,
 		conversationRepo: conversationRepo,
 		dedupRepo:        dedupRepo,
+		queue:            queue,
+		platforms:        platforms,
+		tenants:          tenants,
+		quarantine:       quarantine,
+		publisher:        publisher,
+		router:           router,
+		mirror:           mirror,
 	}
 }
 
-// ProcessWebhook processes an incoming Facebook webhook payload
-// Per user requirement: Filter echo/delivery/read messages, handle panics gracefully
-// Per .rulesgemini Section 4: Response must be < 3 seconds
-func (d *Dispatcher) ProcessWebhook(ctx context.Context, platform string, payload []byte) {
-	// ========================================================================
-	// CRITICAL: Panic Recovery per user requirement
-	// Prevents Docker container crash when processing fails
+// Enqueue durably stores an accepted webhook for background processing
+// Called by the webhook handler BEFORE answering 200 OK: if this fails the
+// platform gets a 5xx and retries delivery, so no event is silently lost
+//
+// The audit row in webhook_logs is written synchronously first so its ID travels
+// with the job and the worker can move it to processed/failed afterwards
+func (d *Dispatcher) Enqueue(ctx context.Context, platform string, payload []byte) error {
 	// ========================================================================
-	defer func() {
-		if r := recover(); r != nil {
-			slog.Error("PANIC recovered in ProcessWebhook",
-				"panic", r,
-				"platform", platform,
-			)
-			// Log panic but don't crash the application
-		}
-	}()
-
-	// ========================================================================
-	// Step 1: Save webhook to audit log (Async pattern)
+	// Step 1: Save webhook to audit log (synchronous, we need the row ID)
 	// Per .rulesgemini Section 3: All webhook data must be persisted
 	// ========================================================================
 	webhookLog := &domain.WebhookLog{
@@ -69,82 +82,249 @@ func (d *Dispatcher) ProcessWebhook(ctx context.Context, platform string, payloa
 		CreatedAt:   time.Now(),
 	}
 
-	// Save webhook log (don't block on errors)
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				slog.Error("PANIC in webhook log save", "panic", r)
-			}
-		}()
-		
-		if err := d.webhookRepo.SaveLog(context.Background(), webhookLog); err != nil {
-			slog.Error("Failed to save webhook log (async)",
-				"error", err,
+	if err := d.webhookRepo.SaveLog(ctx, webhookLog); err != nil {
+		// The queue is what guarantees delivery; a missing audit row must not
+		// make us reject the event. Job simply runs without lifecycle tracking
+		slog.Error("Failed to save webhook log, enqueueing without audit row",
+			"error", err,
+			"platform", platform,
+		)
+	}
+
+	// ========================================================================
+	// Step 2: Hand over to the durable queue
+	// ========================================================================
+	job := &domain.WebhookJob{
+		LogID:      webhookLog.ID,
+		Platform:   platform,
+		Payload:    json.RawMessage(payload),
+		EnqueuedAt: webhookLog.CreatedAt,
+	}
+
+	if err := d.queue.Enqueue(ctx, job); err != nil {
+		d.updateWebhookStatus(webhookLog.ID, domain.WebhookStatusFailed, err)
+		return fmt.Errorf("enqueue webhook: %w", err)
+	}
+
+	slog.Debug("Webhook enqueued",
+		"job_id", job.ID,
+		"webhook_log_id", job.LogID,
+		"platform", platform,
+	)
+
+	return nil
+}
+
+// ProcessJob runs a queued webhook and records the outcome on its webhook_logs row
+// pending -> processed on success, pending/failed -> failed (retry_count+1, error_log) on error
+func (d *Dispatcher) ProcessJob(ctx context.Context, job *domain.WebhookJob) error {
+	err := d.ProcessWebhook(ctx, job.Platform, job.Payload)
+
+	if err != nil {
+		d.updateWebhookStatus(job.LogID, domain.WebhookStatusFailed, err)
+		return err
+	}
+
+	d.updateWebhookStatus(job.LogID, domain.WebhookStatusProcessed, nil)
+	return nil
+}
+
+// ProcessWebhook processes an incoming webhook payload for any registered platform
+// The platform adapter turns the payload into normalized events; only customer
+// messages are stored (echo/delivery/read are skipped per user requirement)
+// Returns an error when at least one event failed so the queue can redeliver the job
+// (already-saved messages are skipped on redelivery thanks to the dedup cache)
+func (d *Dispatcher) ProcessWebhook(ctx context.Context, platform string, payload []byte) (err error) {
+	// ========================================================================
+	// CRITICAL: Panic Recovery per user requirement
+	// Prevents Docker container crash when processing fails
+	// ========================================================================
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("PANIC recovered in ProcessWebhook",
+				"panic", r,
+				"platform", platform,
 			)
+			// Log panic but don't crash the application; job will be retried
+			err = fmt.Errorf("panic in ProcessWebhook: %v", r)
 		}
 	}()
 
 	// ========================================================================
-	// Step 2: Parse Facebook webhook payload
+	// Step 1: Parse payload via the platform adapter
 	// ========================================================================
-	var fbPayload dto.FacebookWebhookRequest
-	if err := json.Unmarshal(payload, &fbPayload); err != nil {
-		slog.Error("Failed to parse Facebook webhook JSON",
+	adapter, err := d.platforms.Get(platform)
+	if err != nil {
+		// No adapter can ever process this job
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	events, err := adapter.ParseEvents(payload)
+	if err != nil {
+		slog.Error("Failed to parse webhook payload",
 			"error", err,
+			"platform", platform,
 		)
-		// Note: Can't update webhook status without ID from insert
-		return
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
 	}
 
 	// ========================================================================
-	// Step 3: Process each messaging event in the webhook
-	// Facebook can send multiple events in one webhook call
-	// Use background context for async processing (request context will be cancelled)
+	// Step 2: Process each event in the webhook
+	// Platforms can send multiple events in one webhook call
+	// ctx belongs to the queue worker (not the HTTP request) so it outlives the response
 	// ========================================================================
 	processedCount := 0
 	skippedCount := 0
-	
-	// Create background context for async processing
-	bgCtx := context.Background()
-
-	for _, entry := range fbPayload.Entry {
-		for _, messaging := range entry.Messaging {
-			// ================================================================
-			// CRITICAL: Filter non-user messages per user requirement
-			// Do NOT save echo messages, delivery receipts, or read receipts
-			// ================================================================
-			if !messaging.IsUserMessage() {
-				slog.Debug("Skipping non-user message event",
-					"is_echo", messaging.Message != nil && messaging.Message.IsEcho,
-					"has_delivery", messaging.Delivery != nil,
-					"has_read", messaging.Read != nil,
+	failedCount := 0
+	var firstErr error
+
+	for i := range events {
+		event := &events[i]
+
+		// Every stored event belongs to the tenant owning the receiving page
+		if event.Type != "" {
+			routed, routeErr := d.resolveTenant(ctx, event)
+			if routeErr != nil {
+				slog.Error("Failed to resolve tenant",
+					"error", routeErr,
+					"platform", event.Platform,
+					"page_id", event.PageID,
 				)
+				failedCount++
+				if firstErr == nil {
+					firstErr = routeErr
+				}
+				continue
+			}
+			if !routed {
 				skippedCount++
 				continue
 			}
+		}
 
-			// Process the user message with background context
-			if err := d.processMessage(bgCtx, platform, &messaging); err != nil {
-				slog.Error("Failed to process message",
-					"error", err,
-					"message_id", messaging.GetMessageID(),
-				)
-				// Continue processing other messages even if one fails
-			} else {
-				processedCount++
+		handled, procErr := d.processEvent(ctx, event)
+		if !handled {
+			skippedCount++
+			continue
+		}
+
+		if procErr != nil {
+			slog.Error("Failed to process message",
+				"error", procErr,
+				"platform", platform,
+				"event_type", event.Type,
+				"message_id", event.ExternalMsgID,
+			)
+			// Continue processing other messages even if one fails
+			failedCount++
+			if firstErr == nil {
+				firstErr = procErr
 			}
+		} else {
+			processedCount++
 		}
 	}
 
 	slog.Info("Webhook processing completed",
+		"platform", platform,
 		"processed", processedCount,
 		"skipped", skippedCount,
+		"failed", failedCount,
 	)
+
+	if firstErr != nil {
+		return fmt.Errorf("%d event(s) failed: %w", failedCount, firstErr)
+	}
+
+	return nil
 }
 
-// processMessage handles a single messaging event
-func (d *Dispatcher) processMessage(ctx context.Context, platform string, messaging *dto.FacebookMessaging) error {
-	messageID := messaging.GetMessageID()
+// processEvent stores or applies one routed event; handled is false for event
+// types that are not processed
+func (d *Dispatcher) processEvent(ctx context.Context, event *domain.InboundEvent) (handled bool, err error) {
+	// ========================================================================
+	// CRITICAL: Filter non-user messages per user requirement
+	// Postbacks and opt-ins are customer actions and stored as messages
+	// Echoes are stored only when the reply was sent outside Immortal Chat
+	// Receipts only update delivery_status of our outbound messages
+	// Edits, unsends and reactions update the stored message in place
+	// ========================================================================
+	switch event.Type {
+	case domain.EventTypeMessage, domain.EventTypePostback, domain.EventTypeOptin:
+		// Process the user message with the worker context
+		return true, d.processMessage(ctx, event)
+	case domain.EventTypeEcho:
+		// Replies sent outside Immortal Chat (Page inbox, Pages app, OA admin)
+		// Echoes of our own replies are already stored: processMessage dedups by mid
+		return true, d.processMessage(ctx, event)
+	case domain.EventTypeReferral:
+		return true, d.processReferral(ctx, event)
+	case domain.EventTypeDelivery, domain.EventTypeRead:
+		return true, d.processReceipt(ctx, event)
+	case domain.EventTypeEdit, domain.EventTypeUnsend, domain.EventTypeReaction:
+		return true, d.processMessageChange(ctx, event)
+	default:
+		slog.Debug("Skipping non-user message event",
+			"platform", event.Platform,
+			"event_type", event.Type,
+		)
+		return false, nil
+	}
+}
+
+// resolveTenant sets event.TenantID from the page that received the event
+// Events for unknown / inactive pages are quarantined (kept for a later replay)
+// and reported as not routed; lookup failures are returned so the job is retried
+func (d *Dispatcher) resolveTenant(ctx context.Context, event *domain.InboundEvent) (bool, error) {
+	tenantID, err := d.tenants.Resolve(ctx, event.Platform, event.PageID)
+	if err == nil {
+		event.TenantID = tenantID
+		return true, nil
+	}
+
+	var reason string
+	switch {
+	case errors.Is(err, ErrUnknownPage):
+		reason = domain.QuarantineReasonUnknownPage
+	case errors.Is(err, ErrInactivePage):
+		reason = domain.QuarantineReasonInactivePage
+	default:
+		return false, err
+	}
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("encode quarantined event: %w", err)
+	}
+	if err := d.quarantine.QuarantineEvent(ctx, &domain.QuarantinedEvent{
+		Platform: event.Platform,
+		PageID:   event.PageID,
+		Reason:   reason,
+		Event:    eventJSON,
+	}); err != nil {
+		return false, fmt.Errorf("quarantine event: %w", err)
+	}
+
+	slog.Warn("Event quarantined",
+		"platform", event.Platform,
+		"page_id", event.PageID,
+		"event_type", event.Type,
+		"reason", reason,
+	)
+	return false, nil
+}
+
+// processMessage handles a single customer message event, or an echo of a
+// page reply that was sent outside Immortal Chat (stored as an external agent message)
+func (d *Dispatcher) processMessage(ctx context.Context, event *domain.InboundEvent) error {
+	messageID := event.ExternalMsgID
+	if messageID == "" {
+		slog.Warn("Event without message ID, skipping",
+			"platform", event.Platform,
+			"event_type", event.Type,
+		)
+		return nil
+	}
 	
 	// ========================================================================
 	// Step 1: Check for duplicates
@@ -161,36 +341,61 @@ func (d *Dispatcher) processMessage(ctx context.Context, platform string, messag
 		)
 		return nil // Not an error, just skip
 	}
+	
+	// Fallback to the database: replays can be older than the dedup TTL
+	exists, err := d.messageRepo.Exists(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("message existence check failed: %w", err)
+	}
+	
+	if exists {
+		slog.Info("Message already stored, skipping",
+			"message_id", messageID,
+		)
+		return nil
+	}
 
 	// ========================================================================
 	// Step 2: Get or create conversation
-	// Use tenant_id = 1 as default (for single-tenant setup)
-	// In multi-tenant, this would come from page configuration
+	// The tenant was resolved from the receiving page (see resolveTenant)
 	// ========================================================================
-	tenantID := 1 // TODO: Get from page/tenant mapping
-	platformID := messaging.Sender.ID // Facebook PSID
-	pageID := messaging.Recipient.ID  // Facebook Page ID
+	platformID := event.SenderID // Customer ID on the platform (PSID for Facebook)
+	pageID := event.PageID       // Receiving page / OA / bot
 	
-	conversationID, err := d.conversationRepo.GetOrCreateByPlatformID(ctx, tenantID, platformID, pageID)
+	conversationID, err := d.conversationRepo.GetOrCreateByPlatformID(ctx, event.TenantID, event.Platform, platformID, pageID)
 	if err != nil {
 		return fmt.Errorf("get/create conversation failed: %w", err)
 	}
 
+	if event.Referral != nil {
+		d.updateReferral(ctx, conversationID, event)
+	}
+
+	if event.SenderName != "" {
+		if err := d.conversationRepo.UpdateCustomerName(ctx, conversationID, event.SenderName); err != nil {
+			// Cosmetic only, the message itself must still be stored
+			slog.Warn("Failed to update customer name",
+				"error", err,
+				"conversation_id", conversationID,
+			)
+		}
+	}
+
 	// ========================================================================
 	// Step 3: Build domain message entity
 	// ========================================================================
-	content := messaging.GetContent()
-	msgType := messaging.GetMessageType()
+	content := event.Content
+	msgType := event.MessageType
 	
 	// Create empty JSON array for attachments
-	attachmentsJSON := json.RawMessage("[]")
-	if len(messaging.Message.Attachments) > 0 {
-		attachmentsJSON, _ = json.Marshal(messaging.Message.Attachments)
+	attachmentsJSON := event.Attachments
+	if len(attachmentsJSON) == 0 {
+		attachmentsJSON = json.RawMessage("[]")
 	}
 	
 	message := &domain.Message{
 		ConversationID: conversationID,
-		SenderID:       &messaging.Sender.ID,
+		SenderID:       &event.SenderID,
 		SenderType:     domain.SenderTypeUser, // Always user for incoming messages
 		Content:        &content,
 		Attachments:    attachmentsJSON,
@@ -199,15 +404,49 @@ func (d *Dispatcher) processMessage(ctx context.Context, platform string, messag
 		ExternalMsgID:  &messageID,
 		CreatedAt:      time.Now(),
 	}
+	if event.Payload != "" {
+		message.Payload = &event.Payload // Postback / quick reply / opt-in payload for bot flows
+	}
+	if event.Type == domain.EventTypeEcho {
+		// Sent by staff from the native inbox: the page is the sender
+		deliveryStatus := domain.DeliveryStatusSent
+		message.SenderID = &event.PageID
+		message.SenderType = domain.SenderTypeAgent
+		message.IsExternal = true
+		message.DeliveryStatus = &deliveryStatus
+	}
 
 	// ========================================================================
 	// Step 4: Save message to database
 	// Per .rulesgemini Section 3: Local-First data storage
 	// ========================================================================
-	if err := d.messageRepo.SaveMessage(ctx, message); err != nil {
+	err = d.messageRepo.SaveMessage(ctx, message)
+	if errors.Is(err, ports.ErrDuplicateMessage) {
+		// Stored meanwhile by another delivery of the same webhook (replay / reclaim)
+		slog.Info("Message stored concurrently, skipping",
+			"message_id", messageID,
+		)
+		return nil
+	}
+	if err != nil {
 		return fmt.Errorf("save message failed: %w", err)
 	}
 
+	// Platform CDN URLs expire: copy the attachments to local storage in the background
+	if d.mirror != nil {
+		d.mirror.Enqueue(ctx, message)
+	}
+
+	if event.Type != domain.EventTypeEcho {
+		// Customer wrote again: closed (snoozed / resolved / archived) conversations reopen
+		d.reopenConversation(ctx, event.TenantID, conversationID)
+
+		// Customer is waiting: hand unassigned conversations to an online agent
+		if d.router != nil {
+			d.router.Route(ctx, event.TenantID, conversationID)
+		}
+	}
+
 	// ========================================================================
 	// Step 5: Mark as processed in dedup cache
 	// Per .rulesgemini: TTL 10 minutes minimum, we use 24 hours for safety
@@ -228,36 +467,183 @@ func (d *Dispatcher) processMessage(ctx context.Context, platform string, messag
 	slog.Info("Message processed successfully",
 		"message_id", messageID,
 		"conversation_id", conversationID,
-		"sender_id", messaging.Sender.ID,
+		"sender_id", event.SenderID,
 		"content_preview", contentPreview,
 	)
 
 	return nil
 }
 
-// updateWebhookStatus updates webhook log status (fire and forget)
-// Note: Currently disabled because we don't have webhook ID after async insert
-func (d *Dispatcher) updateWebhookStatus(webhookID string, status string) {
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				slog.Error("PANIC in webhook status update", "panic", r, "webhook_id", webhookID)
-			}
-		}()
-		
-		ctx := context.Background()
-		if err := d.webhookRepo.UpdateStatus(ctx, webhookID, status); err != nil {
-			slog.Error("Failed to update webhook status",
-				"error", err,
-				"webhook_id", webhookID,
-				"status", status,
-			)
-		}
-	}()
+// processReceipt moves outbound messages to delivered / read
+// Receipts are idempotent (status only moves forward) so no dedup is needed
+func (d *Dispatcher) processReceipt(ctx context.Context, event *domain.InboundEvent) error {
+	status := domain.DeliveryStatusDelivered
+	if event.Type == domain.EventTypeRead {
+		status = domain.DeliveryStatusRead
+	}
+
+	updated, err := d.messageRepo.UpdateDeliveryStatus(ctx, domain.DeliveryUpdate{
+		TenantID:   event.TenantID,
+		Platform:   event.Platform,
+		PageID:     event.PageID,
+		CustomerID: event.SenderID,
+		Status:     status,
+		MessageIDs: event.MessageIDs,
+		Watermark:  event.Watermark,
+	})
+	if err != nil {
+		return fmt.Errorf("update delivery status failed: %w", err)
+	}
+
+	slog.Debug("Delivery receipt applied",
+		"platform", event.Platform,
+		"status", status,
+		"customer_id", event.SenderID,
+		"updated", updated,
+	)
+
+	return nil
 }
 
-// Helper to convert string to int64
-func toInt64(s string) int64 {
-	i, _ := strconv.ParseInt(s, 10, 64)
-	return i
+// processMessageChange applies an edit / unsend / reaction to the stored message
+// and pushes the new version to the dashboards
+func (d *Dispatcher) processMessageChange(ctx context.Context, event *domain.InboundEvent) error {
+	msg, err := d.messageRepo.ApplyMessageChange(ctx, domain.MessageChange{
+		Kind:          event.Type,
+		TenantID:      event.TenantID,
+		Platform:      event.Platform,
+		PageID:        event.PageID,
+		CustomerID:    event.SenderID,
+		ExternalMsgID: event.ExternalMsgID,
+		Content:       event.Content,
+		Reaction:      event.Reaction,
+		At:            event.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("apply message %s failed: %w", event.Type, err)
+	}
+
+	if msg == nil {
+		// Message received before the page was connected (or not the customer's own),
+		// or the change was already applied: nothing to push
+		slog.Debug("Message change not applied, skipping",
+			"platform", event.Platform,
+			"event_type", event.Type,
+			"external_msg_id", event.ExternalMsgID,
+		)
+		return nil
+	}
+
+	slog.Info("Message change applied",
+		"event_type", event.Type,
+		"message_id", msg.ID,
+		"conversation_id", msg.ConversationID,
+	)
+
+	d.publish(ctx, domain.DashboardEvent{
+		Type:           domain.DashboardEventMessageUpdated,
+		ConversationID: msg.ConversationID,
+		Data:           msg,
+	})
+	return nil
+}
+
+// publish pushes a live update to the dashboards (no-op without a publisher)
+func (d *Dispatcher) publish(ctx context.Context, event domain.DashboardEvent) {
+	if d.publisher == nil {
+		return
+	}
+	d.publisher.Publish(ctx, event)
+}
+
+// processReferral records ad / m.me attribution for a customer re-entering an
+// existing thread (no message is stored for a bare referral)
+func (d *Dispatcher) processReferral(ctx context.Context, event *domain.InboundEvent) error {
+	conversationID, err := d.conversationRepo.GetOrCreateByPlatformID(ctx, event.TenantID, event.Platform, event.SenderID, event.PageID)
+	if err != nil {
+		return fmt.Errorf("get/create conversation failed: %w", err)
+	}
+
+	d.updateReferral(ctx, conversationID, event)
+	return nil
+}
+
+// updateReferral stores the latest entry point on the conversation
+// Attribution is best-effort: a failure must not drop the customer's message
+func (d *Dispatcher) updateReferral(ctx context.Context, conversationID int64, event *domain.InboundEvent) {
+	referredAt := event.Timestamp
+	if referredAt.IsZero() {
+		referredAt = time.Now()
+	}
+
+	if err := d.conversationRepo.UpdateReferral(ctx, conversationID, event.Referral, referredAt); err != nil {
+		slog.Warn("Failed to update conversation referral",
+			"error", err,
+			"conversation_id", conversationID,
+		)
+		return
+	}
+
+	slog.Info("Conversation referral recorded",
+		"conversation_id", conversationID,
+		"source", event.Referral.Source,
+		"ref", event.Referral.Ref,
+		"ad_id", event.Referral.AdID,
+	)
+}
+
+// reopenConversation marks the conversation unread after a customer message
+// Best-effort like updateReferral: the message is already stored
+func (d *Dispatcher) reopenConversation(ctx context.Context, tenantID int, conversationID int64) {
+	previous, err := d.conversationRepo.ReopenConversation(ctx, conversationID)
+	if err != nil {
+		slog.Warn("Failed to reopen conversation",
+			"error", err,
+			"conversation_id", conversationID,
+		)
+		return
+	}
+	if previous == "" {
+		return
+	}
+
+	slog.Info("Conversation reopened by customer message",
+		"conversation_id", conversationID,
+		"previous_status", previous,
+	)
+	d.publish(ctx, domain.DashboardEvent{
+		Type:           domain.DashboardEventConversationStatus,
+		ConversationID: conversationID,
+		Data: domain.ConversationStatusChange{
+			ConversationID: conversationID,
+			TenantID:       tenantID,
+			Status:         domain.ConversationStatusUnread,
+		},
+	})
+}
+
+// updateWebhookStatus records the processing outcome of a webhook log
+// Runs synchronously with a detached context: the status must be written even
+// when the worker context is being cancelled for shutdown
+func (d *Dispatcher) updateWebhookStatus(webhookID int64, status string, procErr error) {
+	if webhookID == 0 {
+		return // Audit row was never saved (see Enqueue)
+	}
+
+	var errorLog *string
+	if procErr != nil {
+		msg := procErr.Error()
+		errorLog = &msg
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := d.webhookRepo.UpdateStatus(ctx, webhookID, status, errorLog); err != nil {
+		slog.Error("Failed to update webhook status",
+			"error", err,
+			"webhook_id", webhookID,
+			"status", status,
+		)
+	}
 }
@@ -0,0 +1,143 @@
+// Package services contains the conversation lifecycle (archive, resolve, snooze, reopen)
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// ErrInvalidSnooze is returned for a snooze time that is not in the future
+var ErrInvalidSnooze = errors.New("snooze time must be in the future")
+
+// snoozeWakeBatchSize bounds the conversations reopened per query
+const snoozeWakeBatchSize = 500
+
+// LifecycleService changes conversation statuses and wakes snoozed conversations
+// Reopening on a new customer message is done by the Dispatcher (ReopenConversation)
+type LifecycleService struct {
+	conversations ports.LifecycleRepository
+	publisher     ports.EventPublisher // Optional: nil disables live dashboard updates
+	now           func() time.Time
+}
+
+// NewLifecycleService creates a lifecycle service
+func NewLifecycleService(conversations ports.LifecycleRepository, publisher ports.EventPublisher) *LifecycleService {
+	return &LifecycleService{
+		conversations: conversations,
+		publisher:     publisher,
+		now:           time.Now,
+	}
+}
+
+// Archive hides a conversation from the open inbox until the customer writes again
+func (s *LifecycleService) Archive(ctx context.Context, scope domain.ConversationScope, conversationID int64) (*domain.ConversationStatusChange, error) {
+	return s.setStatus(ctx, scope, conversationID, domain.ConversationStatusArchived, nil)
+}
+
+// Resolve marks a conversation as handled until the customer writes again
+func (s *LifecycleService) Resolve(ctx context.Context, scope domain.ConversationScope, conversationID int64) (*domain.ConversationStatusChange, error) {
+	return s.setStatus(ctx, scope, conversationID, domain.ConversationStatusResolved, nil)
+}
+
+// Snooze closes a conversation until the given time (or until the customer writes again)
+func (s *LifecycleService) Snooze(ctx context.Context, scope domain.ConversationScope, conversationID int64, until time.Time) (*domain.ConversationStatusChange, error) {
+	if !until.After(s.now()) {
+		return nil, ErrInvalidSnooze
+	}
+	return s.setStatus(ctx, scope, conversationID, domain.ConversationStatusSnoozed, &until)
+}
+
+// Reopen puts a closed conversation back into the open inbox (as read: staff reopened it)
+func (s *LifecycleService) Reopen(ctx context.Context, scope domain.ConversationScope, conversationID int64) (*domain.ConversationStatusChange, error) {
+	return s.setStatus(ctx, scope, conversationID, domain.ConversationStatusRead, nil)
+}
+
+// setStatus applies a status within the staff's data scope (ErrConversationNotFound outside it)
+func (s *LifecycleService) setStatus(ctx context.Context, scope domain.ConversationScope, conversationID int64, status string, snoozedUntil *time.Time) (*domain.ConversationStatusChange, error) {
+	found, err := s.conversations.SetConversationStatus(ctx, scope, conversationID, status, snoozedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("set conversation status: %w", err)
+	}
+	if !found {
+		return nil, ErrConversationNotFound
+	}
+
+	change := &domain.ConversationStatusChange{
+		ConversationID: conversationID,
+		TenantID:       scope.TenantID,
+		Status:         status,
+		SnoozedUntil:   snoozedUntil,
+	}
+	slog.Info("Conversation status changed",
+		"conversation_id", conversationID,
+		"tenant_id", scope.TenantID,
+		"by_staff_id", scope.StaffID,
+		"status", status,
+	)
+	s.publish(ctx, change)
+	return change, nil
+}
+
+// RunSnoozeWaker reopens due snoozed conversations every interval until ctx is cancelled
+func (s *LifecycleService) RunSnoozeWaker(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slog.Info("Snooze waker started", "interval", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.WakeSnoozed(ctx); err != nil {
+			slog.Error("Snooze waker run failed", "error", err)
+		}
+	}
+}
+
+// WakeSnoozed reopens (unread) every snoozed conversation whose time has come
+func (s *LifecycleService) WakeSnoozed(ctx context.Context) error {
+	var total int
+	for {
+		woken, err := s.conversations.WakeSnoozed(ctx, s.now(), snoozeWakeBatchSize)
+		if err != nil {
+			return fmt.Errorf("wake snoozed conversations: %w", err)
+		}
+		for i := range woken {
+			s.publish(ctx, &woken[i])
+		}
+		total += len(woken)
+		if len(woken) < snoozeWakeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		slog.Info("Snoozed conversations reopened", "count", total)
+	}
+	return nil
+}
+
+// publish pushes a status change to the dashboards (no-op without a publisher)
+func (s *LifecycleService) publish(ctx context.Context, change *domain.ConversationStatusChange) {
+	if s.publisher == nil {
+		return
+	}
+	s.publisher.Publish(ctx, domain.DashboardEvent{
+		Type:           domain.DashboardEventConversationStatus,
+		ConversationID: change.ConversationID,
+		Data:           change,
+	})
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// memLifecycle keeps conversation statuses in memory
+type memLifecycle struct {
+	ports.LifecycleRepository
+	conversations map[int64]*lifecycleRow
+}
+
+type lifecycleRow struct {
+	assignment   domain.ConversationAssignment
+	status       string
+	snoozedUntil *time.Time
+}
+
+func (m *memLifecycle) SetConversationStatus(ctx context.Context, scope domain.ConversationScope, conversationID int64, status string, snoozedUntil *time.Time) (bool, error) {
+	row, ok := m.conversations[conversationID]
+	if !ok || !scope.Includes(&row.assignment) {
+		return false, nil
+	}
+	row.status, row.snoozedUntil = status, snoozedUntil
+	return true, nil
+}
+
+func (m *memLifecycle) WakeSnoozed(ctx context.Context, now time.Time, limit int) ([]domain.ConversationStatusChange, error) {
+	var woken []domain.ConversationStatusChange
+	for id, row := range m.conversations {
+		if len(woken) == limit {
+			break
+		}
+		if row.status != domain.ConversationStatusSnoozed || row.snoozedUntil.After(now) {
+			continue
+		}
+		row.status, row.snoozedUntil = domain.ConversationStatusUnread, nil
+		woken = append(woken, domain.ConversationStatusChange{ConversationID: id, TenantID: row.assignment.TenantID, Status: row.status})
+	}
+	return woken, nil
+}
+
+func TestLifecycleStatusChanges(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	repo := &memLifecycle{conversations: map[int64]*lifecycleRow{
+		1: {assignment: domain.ConversationAssignment{ConversationID: 1, TenantID: 1, AssigneeID: intPtr(5)}, status: domain.ConversationStatusUnread},
+		2: {assignment: domain.ConversationAssignment{ConversationID: 2, TenantID: 1, AssigneeID: intPtr(6)}, status: domain.ConversationStatusUnread},
+	}}
+	publisher := &recordingPublisher{}
+	service := NewLifecycleService(repo, publisher)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+	agent := domain.ConversationScope{TenantID: 1, StaffID: 5, AssignedOnly: true}
+	otherTenant := domain.ConversationScope{TenantID: 2, StaffID: 9}
+
+	if _, err := service.Resolve(ctx, agent, 2); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("resolve another agent's conversation: err = %v", err)
+	}
+	if _, err := service.Archive(ctx, otherTenant, 1); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("archive from another tenant: err = %v", err)
+	}
+	for name, until := range map[string]time.Time{"past": now.Add(-time.Minute), "now": now} {
+		if _, err := service.Snooze(ctx, agent, 1, until); !errors.Is(err, ErrInvalidSnooze) {
+			t.Errorf("snooze %s: err = %v", name, err)
+		}
+	}
+	if len(publisher.events) != 0 {
+		t.Fatalf("refused changes published %d events", len(publisher.events))
+	}
+
+	change, err := service.Snooze(ctx, agent, 1, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Status != domain.ConversationStatusSnoozed || repo.conversations[1].snoozedUntil == nil {
+		t.Errorf("snooze: %+v, stored %+v", change, repo.conversations[1])
+	}
+	if _, err := service.Reopen(ctx, agent, 1); err != nil || repo.conversations[1].status != domain.ConversationStatusRead || repo.conversations[1].snoozedUntil != nil {
+		t.Errorf("reopen: err = %v, stored %+v", err, repo.conversations[1])
+	}
+	if len(publisher.events) != 2 {
+		t.Errorf("%d events published, want 2", len(publisher.events))
+	}
+}
+
+func TestWakeSnoozedReopensDueConversations(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	due, later := now.Add(-time.Second), now.Add(time.Minute)
+	repo := &memLifecycle{conversations: map[int64]*lifecycleRow{}}
+	// More due conversations than one batch
+	for id := int64(1); id <= snoozeWakeBatchSize+2; id++ {
+		repo.conversations[id] = &lifecycleRow{assignment: domain.ConversationAssignment{ConversationID: id, TenantID: 1}, status: domain.ConversationStatusSnoozed, snoozedUntil: &due}
+	}
+	repo.conversations[1000] = &lifecycleRow{assignment: domain.ConversationAssignment{ConversationID: 1000, TenantID: 1}, status: domain.ConversationStatusSnoozed, snoozedUntil: &later}
+	repo.conversations[1001] = &lifecycleRow{assignment: domain.ConversationAssignment{ConversationID: 1001, TenantID: 1}, status: domain.ConversationStatusResolved}
+
+	publisher := &recordingPublisher{}
+	service := NewLifecycleService(repo, publisher)
+	service.now = func() time.Time { return now }
+
+	if err := service.WakeSnoozed(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.events) != snoozeWakeBatchSize+2 {
+		t.Errorf("%d conversations woken, want %d", len(publisher.events), snoozeWakeBatchSize+2)
+	}
+	for _, event := range publisher.events {
+		change := event.Data.(*domain.ConversationStatusChange)
+		if event.Type != domain.DashboardEventConversationStatus || change.Status != domain.ConversationStatusUnread {
+			t.Errorf("event %+v", event)
+			break
+		}
+	}
+	if repo.conversations[1000].status != domain.ConversationStatusSnoozed || repo.conversations[1001].status != domain.ConversationStatusResolved {
+		t.Error("conversation not yet due or not snoozed was reopened")
+	}
+}
+
+// closedConversations reports every conversation as closed before the message
+type closedConversations struct {
+	fakeConversations
+	previous string
+}
+
+func (c closedConversations) ReopenConversation(ctx context.Context, conversationID int64) (string, error) {
+	return c.previous, nil
+}
+
+func TestInboundMessageReopensClosedConversation(t *testing.T) {
+	for previous, wantEvent := range map[string]bool{
+		domain.ConversationStatusSnoozed:  true,
+		domain.ConversationStatusResolved: true,
+		"":                                false, // Already open
+	} {
+		publisher := &recordingPublisher{}
+		messages := &fakeMessages{saved: map[string]bool{}, racing: map[string]bool{}}
+		dispatcher := NewDispatcher(&fakeWebhookLogs{statuses: map[int64]string{}}, messages,
+			closedConversations{previous: previous}, fakeDedup{}, &fakeQueue{},
+			NewPlatformRegistry(fakeChannel{}), NewTenantResolver(fakePages{}, fakePages{}), nil, publisher, nil, nil)
+
+		payload, _ := json.Marshal("mid.1")
+		if err := dispatcher.ProcessWebhook(context.Background(), "fake", payload); err != nil {
+			t.Fatal(err)
+		}
+
+		var reopened *domain.ConversationStatusChange
+		for _, event := range publisher.events {
+			if event.Type == domain.DashboardEventConversationStatus {
+				change := event.Data.(domain.ConversationStatusChange)
+				reopened = &change
+			}
+		}
+		if (reopened != nil) != wantEvent {
+			t.Errorf("previous %q: status event = %+v, want one: %v", previous, reopened, wantEvent)
+			continue
+		}
+		if reopened != nil && (reopened.Status != domain.ConversationStatusUnread || reopened.ConversationID != 100 || reopened.TenantID != 1) {
+			t.Errorf("previous %q: reopened %+v", previous, reopened)
+		}
+	}
+}
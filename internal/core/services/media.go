@@ -0,0 +1,199 @@
+// Package services contains media storage for attachments sent from the dashboard
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+var (
+	// ErrMediaTooLarge is returned for uploads above the configured maximum size
+	ErrMediaTooLarge = errors.New("media too large")
+
+	// ErrEmptyMedia is returned for uploads without content
+	ErrEmptyMedia = errors.New("empty media")
+
+	// ErrMediaNotFound is returned for a media ID that does not exist in the tenant
+	ErrMediaNotFound = errors.New("media not found")
+)
+
+// maxMediaFileNameLength matches media.file_name
+const maxMediaFileNameLength = 255
+
+// MediaConfig holds media storage settings
+type MediaConfig struct {
+	PublicURL string // Public base URL of this server ("" = platforms cannot fetch media by URL)
+	MaxSize   int64  // Largest accepted upload in bytes
+}
+
+// MediaService stores uploaded media and prepares it for sending
+type MediaService struct {
+	media  ports.MediaRepository
+	store  ports.MediaStore
+	config MediaConfig
+	now    func() time.Time
+}
+
+// NewMediaService creates a media service
+func NewMediaService(media ports.MediaRepository, store ports.MediaStore, config MediaConfig) *MediaService {
+	return &MediaService{
+		media:  media,
+		store:  store,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Upload stores a file for the tenant; the attachment type follows the content type
+func (s *MediaService) Upload(ctx context.Context, tenantID int, fileName, contentType string, r io.Reader) (*domain.Media, error) {
+	id, err := newMediaID()
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := s.store.Save(ctx, id, io.LimitReader(r, s.config.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("store media: %w", err)
+	}
+	if size == 0 || size > s.config.MaxSize {
+		if err := s.store.Delete(ctx, id); err != nil {
+			slog.Warn("Failed to delete rejected upload", "error", err, "media_id", id)
+		}
+		if size == 0 {
+			return nil, ErrEmptyMedia
+		}
+		return nil, ErrMediaTooLarge
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	media := &domain.Media{
+		ID:          id,
+		TenantID:    tenantID,
+		FileName:    cleanFileName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		Type:        attachmentType(contentType),
+		CreatedAt:   s.now(),
+	}
+	if err := s.media.SaveMedia(ctx, media); err != nil {
+		if delErr := s.store.Delete(ctx, id); delErr != nil {
+			slog.Warn("Failed to delete unsaved upload", "error", delErr, "media_id", id)
+		}
+		return nil, fmt.Errorf("save media: %w", err)
+	}
+	media.URL = s.URL(id)
+
+	slog.Info("Media uploaded",
+		"media_id", id,
+		"tenant_id", tenantID,
+		"type", media.Type,
+		"size", size,
+	)
+	return media, nil
+}
+
+// GetMedia returns a media file of the tenant (ErrMediaNotFound if missing)
+func (s *MediaService) GetMedia(ctx context.Context, tenantID int, mediaID string) (*domain.Media, error) {
+	media, err := s.media.GetMedia(ctx, tenantID, mediaID)
+	if err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+	if media == nil {
+		return nil, ErrMediaNotFound
+	}
+	media.URL = s.URL(media.ID)
+	return media, nil
+}
+
+// URL returns where a media file is served: absolute with MEDIA_PUBLIC_URL, else /media/{id}
+func (s *MediaService) URL(mediaID string) string {
+	return s.config.PublicURL + "/media/" + mediaID
+}
+
+// Attachment prepares a media file for sending on a page: the reusable attachment ID
+// issued for that page before, the public URL (if any) and the file for uploads
+func (s *MediaService) Attachment(ctx context.Context, media *domain.Media, platform, pageID string) (domain.OutboundAttachment, error) {
+	attachmentID, err := s.media.GetAttachmentID(ctx, media.ID, platform, pageID)
+	if err != nil {
+		return domain.OutboundAttachment{}, fmt.Errorf("get attachment id: %w", err)
+	}
+
+	attachment := domain.OutboundAttachment{
+		Type:         media.Type,
+		AttachmentID: attachmentID,
+		FileName:     media.FileName,
+		ContentType:  media.ContentType,
+		Open: func() (io.ReadCloser, error) {
+			return s.store.Open(ctx, media.ID)
+		},
+	}
+	if s.config.PublicURL != "" {
+		attachment.URL = s.URL(media.ID)
+	}
+	return attachment, nil
+}
+
+// RememberAttachmentID keeps the attachment ID a platform issued for the media on a page,
+// so the next send skips the upload (failures are only logged)
+func (s *MediaService) RememberAttachmentID(ctx context.Context, media *domain.Media, platform, pageID, attachmentID string) {
+	if attachmentID == "" {
+		return
+	}
+	if err := s.media.SaveAttachmentID(ctx, media.ID, platform, pageID, attachmentID); err != nil {
+		slog.Warn("Failed to save attachment id",
+			"error", err,
+			"media_id", media.ID,
+			"page_id", pageID,
+		)
+	}
+}
+
+// attachmentType maps a content type to the Send API attachment type
+func attachmentType(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return domain.AttachmentTypeImage
+	case strings.HasPrefix(contentType, "video/"):
+		return domain.AttachmentTypeVideo
+	case strings.HasPrefix(contentType, "audio/"):
+		return domain.AttachmentTypeAudio
+	default:
+		return domain.AttachmentTypeFile
+	}
+}
+
+// cleanFileName keeps the base name of an uploaded file, shortened to fit media.file_name
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+	for len(name) > maxMediaFileNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// newMediaID returns 16 random bytes as hex (also the file name on disk)
+func newMediaID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate media id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,367 @@
+// Package services contains the local mirroring of inbound attachments
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+var (
+	// errNotMedia is returned when a downloaded attachment turns out to be a web page
+	errNotMedia = errors.New("downloaded content is not media")
+
+	// errNothingToMirror marks attachments without a remote media URL (or already mirrored)
+	errNothingToMirror = errors.New("nothing to mirror")
+)
+
+// mirrorAttachmentTypes are the attachment types whose payload.url is a media file
+// (shares, links, locations and templates point at web pages or carry no file)
+var mirrorAttachmentTypes = map[string]bool{
+	domain.AttachmentTypeImage:        true,
+	domain.AttachmentTypeVideo:        true,
+	domain.AttachmentTypeAudio:        true,
+	domain.AttachmentTypeFile:         true,
+	domain.AttachmentTypeVoice:        true,
+	domain.AttachmentTypeSticker:      true,
+	domain.AttachmentTypeGIF:          true,
+	domain.AttachmentTypeStory:        true,
+	domain.AttachmentTypeStoryMention: true,
+}
+
+// MediaMirrorConfig tunes the inbound media mirror
+type MediaMirrorConfig struct {
+	Interval    time.Duration // How often the background loop looks for work
+	MaxSize     int64         // Larger attachments are left on the platform CDN
+	MaxAttempts int           // Jobs still failing after this many attempts are given up
+	BaseBackoff time.Duration // Delay after the first failure, doubled per attempt
+	MaxBackoff  time.Duration // Cap for the exponential backoff
+	BatchSize   int
+}
+
+// MediaMirrorService copies inbound attachments from platform CDN URLs (which expire
+// within days) to the local media store and points the message at /media/{hash}
+// (relative, so stored messages survive a change of MEDIA_PUBLIC_URL)
+// Per .rulesgemini Section 3: Local-First data storage
+//
+// Each attachment keeps its CDN URL in payload.original_url; payload.url becomes the
+// local URL and payload.mime_type / payload.file_size describe the stored file.
+// Telegram attachments carry file IDs instead of URLs and are not mirrored
+type MediaMirrorService struct {
+	mirrors    ports.MediaMirrorRepository
+	store      ports.MediaStore
+	downloader ports.MediaDownloader
+	cfg        MediaMirrorConfig
+	now        func() time.Time
+}
+
+// NewMediaMirrorService creates a mirror service (call Run to start the background loop)
+func NewMediaMirrorService(mirrors ports.MediaMirrorRepository, store ports.MediaStore, downloader ports.MediaDownloader, cfg MediaMirrorConfig) *MediaMirrorService {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 25 << 20
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = time.Minute
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 6 * time.Hour
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+
+	return &MediaMirrorService{
+		mirrors:    mirrors,
+		store:      store,
+		downloader: downloader,
+		cfg:        cfg,
+		now:        time.Now,
+	}
+}
+
+// Enqueue schedules mirroring of a stored message that has remote attachments
+// Failures are only logged: the message itself is already saved
+func (s *MediaMirrorService) Enqueue(ctx context.Context, msg *domain.Message) {
+	if msg.ID == 0 || !hasRemoteMedia(msg.Attachments) {
+		return
+	}
+	if err := s.mirrors.EnqueueMediaMirror(ctx, msg.ID, s.now()); err != nil {
+		slog.Warn("Failed to enqueue media mirror",
+			"error", err,
+			"message_id", msg.ID,
+		)
+	}
+}
+
+// Run mirrors due jobs every Interval until ctx is cancelled
+func (s *MediaMirrorService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	slog.Info("Media mirror started",
+		"interval", s.cfg.Interval,
+		"max_size", s.cfg.MaxSize,
+		"max_attempts", s.cfg.MaxAttempts,
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.MirrorDue(ctx); err != nil {
+			slog.Error("Media mirror run failed", "error", err)
+		}
+	}
+}
+
+// MirrorDue processes one batch of due jobs
+func (s *MediaMirrorService) MirrorDue(ctx context.Context) error {
+	mirrors, err := s.mirrors.FindDueMediaMirrors(ctx, s.now(), s.cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("find due media mirrors: %w", err)
+	}
+
+	for i := range mirrors {
+		if ctx.Err() != nil {
+			break
+		}
+		s.mirrorOne(ctx, &mirrors[i])
+	}
+	return nil
+}
+
+// mirrorOne downloads the remote attachments of one message and records the outcome
+// Attachments stored before a failure are kept, a retry only fetches the rest
+func (s *MediaMirrorService) mirrorOne(ctx context.Context, mirror *domain.MediaMirror) {
+	if !mirror.MessageExists {
+		// Purged by retention meanwhile: drop the job
+		if err := s.mirrors.SaveMediaMirror(ctx, mirror, false); err != nil {
+			slog.Warn("Failed to drop media mirror", "error", err, "message_id", mirror.MessageID)
+		}
+		return
+	}
+
+	var attachments []map[string]json.RawMessage
+	if err := json.Unmarshal(mirror.Attachments, &attachments); err != nil {
+		s.finish(ctx, mirror, false, fmt.Errorf("%w: invalid attachments: %v", ports.ErrMediaUnavailable, err))
+		return
+	}
+
+	var (
+		mirrored int
+		failure  error
+	)
+	for _, attachment := range attachments {
+		err := s.mirrorAttachment(ctx, attachment, mirror)
+		switch {
+		case err == nil:
+			mirrored++
+		case errors.Is(err, errNothingToMirror):
+		default:
+			// A retryable failure wins over a permanent one: the job must run again
+			if failure == nil || isPermanentMirrorError(failure) {
+				failure = err
+			}
+		}
+	}
+
+	if mirrored > 0 {
+		raw, err := json.Marshal(attachments)
+		if err != nil {
+			s.finish(ctx, mirror, false, fmt.Errorf("encode attachments: %w", err))
+			return
+		}
+		mirror.Attachments = raw
+	}
+	s.finish(ctx, mirror, mirrored > 0, failure)
+}
+
+// mirrorAttachment stores one attachment and rewrites its payload in place
+// The message content is rewritten too when it is the attachment URL (Messenger)
+func (s *MediaMirrorService) mirrorAttachment(ctx context.Context, attachment map[string]json.RawMessage, mirror *domain.MediaMirror) error {
+	var payload map[string]json.RawMessage
+	remoteURL, ok := remoteMediaURL(attachment, &payload)
+	if !ok {
+		return errNothingToMirror
+	}
+
+	hash, contentType, size, err := s.download(ctx, remoteURL)
+	if err != nil {
+		slog.Warn("Failed to mirror attachment",
+			"error", err,
+			"message_id", mirror.MessageID,
+			"attempt", mirror.Attempts+1,
+		)
+		return err
+	}
+
+	localURL := "/media/" + hash
+	payload["original_url"], _ = json.Marshal(remoteURL)
+	payload["url"], _ = json.Marshal(localURL)
+	if _, ok := payload["mime_type"]; !ok {
+		payload["mime_type"], _ = json.Marshal(contentType)
+	}
+	payload["file_size"], _ = json.Marshal(size)
+	attachment["payload"], _ = json.Marshal(payload)
+
+	if mirror.Content != nil && *mirror.Content == remoteURL {
+		mirror.Content = &localURL
+	}
+	return nil
+}
+
+// download stores the media at url, sniffing its type from the first bytes
+// Web pages (e.g. an error page answered with 200) are rejected as errNotMedia
+func (s *MediaMirrorService) download(ctx context.Context, url string) (hash, contentType string, size int64, err error) {
+	body, headerType, err := s.downloader.Download(ctx, url)
+	if err != nil {
+		return "", "", 0, err
+	}
+	defer body.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", "", 0, fmt.Errorf("read media: %w", err)
+	}
+	head = head[:n]
+	if n == 0 {
+		return "", "", 0, ErrEmptyMedia
+	}
+
+	contentType = http.DetectContentType(head)
+	if strings.HasPrefix(contentType, "text/html") {
+		return "", "", 0, errNotMedia
+	}
+	if contentType == "application/octet-stream" || strings.HasPrefix(contentType, "text/plain") {
+		// Sniffing knows few audio / document formats: trust the CDN for those
+		if mediaType, _, err := mime.ParseMediaType(headerType); err == nil && mediaType != "text/html" {
+			contentType = mediaType
+		}
+	}
+
+	hash, size, err = s.store.SaveContent(ctx, io.LimitReader(io.MultiReader(bytes.NewReader(head), body), s.cfg.MaxSize+1))
+	if err != nil {
+		return "", "", 0, fmt.Errorf("store media: %w", err)
+	}
+	if size > s.cfg.MaxSize {
+		// Same content always has the same size, so no smaller message shares this file
+		if err := s.store.Delete(ctx, hash); err != nil {
+			slog.Warn("Failed to delete oversized mirror", "error", err, "hash", hash)
+		}
+		return "", "", 0, ErrMediaTooLarge
+	}
+	return hash, contentType, size, nil
+}
+
+// finish records the attempt: done without failure, pending with backoff for a
+// retryable failure, failed for a permanent one or when attempts run out
+func (s *MediaMirrorService) finish(ctx context.Context, mirror *domain.MediaMirror, rewrite bool, failure error) {
+	mirror.Attempts++
+	mirror.LastError = nil
+	switch {
+	case failure == nil:
+		mirror.Status = domain.MediaMirrorStatusDone
+	case isPermanentMirrorError(failure) || mirror.Attempts >= s.cfg.MaxAttempts:
+		mirror.Status = domain.MediaMirrorStatusFailed
+	default:
+		mirror.Status = domain.MediaMirrorStatusPending
+		mirror.NextAttemptAt = s.now().Add(s.backoff(mirror.Attempts))
+	}
+	if failure != nil {
+		msg := failure.Error()
+		mirror.LastError = &msg
+	}
+
+	if err := s.mirrors.SaveMediaMirror(ctx, mirror, rewrite); err != nil {
+		slog.Error("Failed to save media mirror",
+			"error", err,
+			"message_id", mirror.MessageID,
+		)
+		return
+	}
+
+	if mirror.Status != domain.MediaMirrorStatusPending {
+		slog.Info("Media mirror finished",
+			"message_id", mirror.MessageID,
+			"status", mirror.Status,
+			"attempts", mirror.Attempts,
+		)
+	}
+}
+
+// backoff returns BaseBackoff doubled per previous attempt, capped at MaxBackoff
+func (s *MediaMirrorService) backoff(attempts int) time.Duration {
+	delay := s.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < s.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.cfg.MaxBackoff)
+}
+
+// isPermanentMirrorError reports whether retrying a download cannot help
+func isPermanentMirrorError(err error) bool {
+	return errors.Is(err, ports.ErrMediaUnavailable) ||
+		errors.Is(err, ErrMediaTooLarge) ||
+		errors.Is(err, ErrEmptyMedia) ||
+		errors.Is(err, errNotMedia)
+}
+
+// hasRemoteMedia reports whether an attachments JSON array has something to mirror
+func hasRemoteMedia(raw json.RawMessage) bool {
+	var attachments []map[string]json.RawMessage
+	if len(raw) == 0 || json.Unmarshal(raw, &attachments) != nil {
+		return false
+	}
+	for _, attachment := range attachments {
+		var payload map[string]json.RawMessage
+		if _, ok := remoteMediaURL(attachment, &payload); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteMediaURL returns the http(s) media URL of an attachment not mirrored yet,
+// decoding its payload into payload
+func remoteMediaURL(attachment map[string]json.RawMessage, payload *map[string]json.RawMessage) (string, bool) {
+	var attachmentType string
+	if json.Unmarshal(attachment["type"], &attachmentType) != nil || !mirrorAttachmentTypes[attachmentType] {
+		return "", false
+	}
+	if json.Unmarshal(attachment["payload"], payload) != nil || *payload == nil {
+		return "", false
+	}
+	if _, done := (*payload)["original_url"]; done {
+		return "", false
+	}
+
+	var url string
+	if json.Unmarshal((*payload)["url"], &url) != nil {
+		return "", false
+	}
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return "", false
+	}
+	return url, true
+}
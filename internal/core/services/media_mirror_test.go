@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// memMediaStore keeps mirrored files in memory, named by their SHA-256
+type memMediaStore struct {
+	ports.MediaStore
+	files map[string][]byte
+}
+
+func (m *memMediaStore) SaveContent(ctx context.Context, r io.Reader) (string, int64, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", 0, err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	m.files[hash] = content
+	return hash, int64(len(content)), nil
+}
+
+func (m *memMediaStore) Delete(ctx context.Context, mediaID string) error {
+	delete(m.files, mediaID)
+	return nil
+}
+
+// cdnResponse is what the fake CDN answers for a URL
+type cdnResponse struct {
+	body        string
+	contentType string
+	err         error
+}
+
+// fakeCDN serves fixed responses by URL
+type fakeCDN map[string]cdnResponse
+
+func (c fakeCDN) Download(ctx context.Context, url string) (io.ReadCloser, string, error) {
+	resp, ok := c[url]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: HTTP 404", ports.ErrMediaUnavailable)
+	}
+	if resp.err != nil {
+		return nil, "", resp.err
+	}
+	return io.NopCloser(strings.NewReader(resp.body)), resp.contentType, nil
+}
+
+// savedMirrors records the outcome of each attempt
+type savedMirrors struct {
+	ports.MediaMirrorRepository
+	saved   []domain.MediaMirror
+	rewrite bool
+}
+
+func (s *savedMirrors) SaveMediaMirror(ctx context.Context, mirror *domain.MediaMirror, rewrite bool) error {
+	s.saved = append(s.saved, *mirror)
+	s.rewrite = rewrite
+	return nil
+}
+
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+func TestMirrorRewritesAttachmentsAndKeepsOriginalURL(t *testing.T) {
+	const imageURL = "https://cdn.example.com/a.png?oh=token"
+	image := pngHeader + "image data"
+	store := &memMediaStore{files: map[string][]byte{}}
+	mirrors := &savedMirrors{}
+	cdn := fakeCDN{
+		imageURL:                          {body: image, contentType: "image/png"},
+		"https://cdn.example.com/v.m4a":   {body: "\x00\x00\x00\x18ftypM4A audio", contentType: "audio/mp4"},
+		"https://www.example.com/a-post/": {body: "<html>post</html>", contentType: "text/html"},
+	}
+	service := NewMediaMirrorService(mirrors, store, cdn, MediaMirrorConfig{})
+
+	content := imageURL // Messenger stores the URL of an image-only message as content
+	mirror := &domain.MediaMirror{
+		MessageID:     7,
+		MessageExists: true,
+		Content:       &content,
+		Attachments: json.RawMessage(`[
+			{"type":"image","payload":{"url":"` + imageURL + `"}},
+			{"type":"voice","payload":{"url":"https://cdn.example.com/v.m4a","duration":3}},
+			{"type":"share","payload":{"url":"https://www.example.com/a-post/"}},
+			{"type":"file","payload":{"url":"/media/abc","original_url":"https://cdn.example.com/old.pdf"}}
+		]`),
+	}
+	service.mirrorOne(context.Background(), mirror)
+
+	if len(mirrors.saved) != 1 || !mirrors.rewrite {
+		t.Fatalf("saved %d times, rewrite %v", len(mirrors.saved), mirrors.rewrite)
+	}
+	saved := mirrors.saved[0]
+	if saved.Status != domain.MediaMirrorStatusDone || saved.LastError != nil || saved.Attempts != 1 {
+		t.Errorf("job = %+v", saved)
+	}
+
+	var attachments []struct {
+		Type    string
+		Payload map[string]interface{}
+	}
+	if err := json.Unmarshal(saved.Attachments, &attachments); err != nil {
+		t.Fatal(err)
+	}
+	imageSum := sha256.Sum256([]byte(image))
+	localURL := "/media/" + hex.EncodeToString(imageSum[:])
+	if p := attachments[0].Payload; p["url"] != localURL || p["original_url"] != imageURL ||
+		p["mime_type"] != "image/png" || p["file_size"] != float64(len(image)) {
+		t.Errorf("image payload = %v", p)
+	}
+	if p := attachments[1].Payload; p["original_url"] != "https://cdn.example.com/v.m4a" ||
+		p["mime_type"] != "audio/mp4" || p["duration"] != float64(3) {
+		t.Errorf("voice payload = %v", p)
+	}
+	if p := attachments[2].Payload; p["url"] != "https://www.example.com/a-post/" || p["original_url"] != nil {
+		t.Errorf("share payload = %v (web pages are not mirrored)", p)
+	}
+	if p := attachments[3].Payload; p["url"] != "/media/abc" || p["original_url"] != "https://cdn.example.com/old.pdf" {
+		t.Errorf("already mirrored payload = %v", p)
+	}
+	if saved.Content == nil || *saved.Content != localURL {
+		t.Errorf("content = %v, want %s", saved.Content, localURL)
+	}
+	if !bytes.Equal(store.files[hex.EncodeToString(imageSum[:])], []byte(image)) || len(store.files) != 2 {
+		t.Errorf("%d files stored", len(store.files))
+	}
+}
+
+func TestMirrorFailures(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	const url = "https://cdn.example.com/a.jpg"
+
+	for name, tc := range map[string]struct {
+		response   cdnResponse
+		wantStatus string
+		wantError  string
+	}{
+		"error page answered with 200": {cdnResponse{body: "<!DOCTYPE html><html>Not found</html>", contentType: "image/jpeg"}, domain.MediaMirrorStatusFailed, "not media"},
+		"html content type only":       {cdnResponse{body: "<html><body>login</body></html>", contentType: "text/html"}, domain.MediaMirrorStatusFailed, "not media"},
+		"expired url":                  {cdnResponse{err: fmt.Errorf("%w: HTTP 403", ports.ErrMediaUnavailable)}, domain.MediaMirrorStatusFailed, "403"},
+		"blocked address":              {cdnResponse{err: fmt.Errorf("%w: media address is not public: 10.0.0.1", ports.ErrMediaUnavailable)}, domain.MediaMirrorStatusFailed, "not public"},
+		"too large":                    {cdnResponse{body: pngHeader + strings.Repeat("x", 100), contentType: "image/png"}, domain.MediaMirrorStatusFailed, "too large"},
+		"empty body":                   {cdnResponse{body: "", contentType: "image/png"}, domain.MediaMirrorStatusFailed, "empty"},
+		"network error is retried":     {cdnResponse{err: errors.New("connection reset")}, domain.MediaMirrorStatusPending, "connection reset"},
+	} {
+		store := &memMediaStore{files: map[string][]byte{}}
+		mirrors := &savedMirrors{}
+		service := NewMediaMirrorService(mirrors, store, fakeCDN{url: tc.response}, MediaMirrorConfig{MaxSize: 50, BaseBackoff: time.Minute})
+		service.now = func() time.Time { return now }
+
+		attachments := `[{"type":"image","payload":{"url":"` + url + `"}}]`
+		service.mirrorOne(context.Background(), &domain.MediaMirror{MessageID: 7, MessageExists: true, Attachments: json.RawMessage(attachments)})
+
+		saved := mirrors.saved[0]
+		if saved.Status != tc.wantStatus || saved.LastError == nil || !strings.Contains(*saved.LastError, tc.wantError) {
+			t.Errorf("%s: status %s, error %v; want %s, %q", name, saved.Status, saved.LastError, tc.wantStatus, tc.wantError)
+		}
+		if mirrors.rewrite || string(saved.Attachments) != attachments || len(store.files) != 0 {
+			t.Errorf("%s: rewrite %v, attachments %s, %d files kept", name, mirrors.rewrite, saved.Attachments, len(store.files))
+		}
+		if tc.wantStatus == domain.MediaMirrorStatusPending && !saved.NextAttemptAt.Equal(now.Add(time.Minute)) {
+			t.Errorf("%s: next attempt at %v", name, saved.NextAttemptAt)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package services contains the Messenger 24-hour messaging window rules
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+var (
+	// ErrMessagingWindowClosed is returned for a standard reply outside the 24-hour window
+	ErrMessagingWindowClosed = errors.New("messaging window closed")
+
+	// ErrMessageTagNotAllowed is returned for a tag the platform does not accept now
+	ErrMessageTagNotAllowed = errors.New("message tag not allowed")
+)
+
+// Messaging windows, counted from the customer's last message
+const (
+	StandardMessagingWindow   = 24 * time.Hour
+	HumanAgentMessagingWindow = 7 * 24 * time.Hour // HUMAN_AGENT tag
+)
+
+// messageTags lists the tags each platform with a messaging window accepts
+var messageTags = map[string][]string{
+	domain.PlatformFacebook: {
+		domain.MessageTagHumanAgent,
+		domain.MessageTagPostPurchaseUpdate,
+		domain.MessageTagConfirmedEventUpdate,
+		domain.MessageTagAccountUpdate,
+	},
+	domain.PlatformInstagram: {
+		domain.MessageTagHumanAgent,
+	},
+}
+
+// MessagingWindowAt returns the messaging window of a conversation at now
+// lastInboundAt is the customer's last message (nil if the customer never wrote);
+// AllowedTags is only filled while the window is closed
+func MessagingWindowAt(platform string, lastInboundAt *time.Time, now time.Time) domain.MessagingWindow {
+	window := domain.MessagingWindow{
+		LastInboundAt: lastInboundAt,
+		AllowedTags:   []string{},
+	}
+	tags, ok := messageTags[platform]
+	if !ok {
+		window.Open = true
+		return window
+	}
+
+	if lastInboundAt != nil {
+		expiresAt := lastInboundAt.Add(StandardMessagingWindow)
+		window.ExpiresAt = &expiresAt
+		window.Open = now.Before(expiresAt)
+	}
+	if window.Open {
+		return window
+	}
+
+	for _, tag := range tags {
+		if tag == domain.MessageTagHumanAgent &&
+			(lastInboundAt == nil || !now.Before(lastInboundAt.Add(HumanAgentMessagingWindow))) {
+			continue
+		}
+		window.AllowedTags = append(window.AllowedTags, tag)
+	}
+	return window
+}
+
+// ResolveMessageTag returns the tag to send with: "" while the window is open (a
+// requested tag is not needed then), else the requested tag if it is allowed
+func ResolveMessageTag(window domain.MessagingWindow, tag string) (string, error) {
+	if window.Open {
+		return "", nil
+	}
+	if tag == "" {
+		return "", ErrMessagingWindowClosed
+	}
+	for _, allowed := range window.AllowedTags {
+		if tag == allowed {
+			return tag, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrMessageTagNotAllowed, tag)
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+func TestMessagingWindowAt(t *testing.T) {
+	now := time.Date(2024, 5, 20, 10, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	allTags := []string{
+		domain.MessageTagHumanAgent,
+		domain.MessageTagPostPurchaseUpdate,
+		domain.MessageTagConfirmedEventUpdate,
+		domain.MessageTagAccountUpdate,
+	}
+
+	for name, tc := range map[string]struct {
+		platform      string
+		lastInboundAt *time.Time
+		open          bool
+		tags          []string
+	}{
+		"answered within 24h":          {domain.PlatformFacebook, ago(23 * time.Hour), true, []string{}},
+		"window ends at exactly 24h":   {domain.PlatformFacebook, ago(24 * time.Hour), false, allTags},
+		"human agent within 7 days":    {domain.PlatformFacebook, ago(6 * 24 * time.Hour), false, allTags},
+		"human agent ends at 7 days":   {domain.PlatformFacebook, ago(7 * 24 * time.Hour), false, allTags[1:]},
+		"customer never wrote":         {domain.PlatformFacebook, nil, false, allTags[1:]},
+		"instagram only human agent":   {domain.PlatformInstagram, ago(2 * 24 * time.Hour), false, []string{domain.MessageTagHumanAgent}},
+		"instagram after 7 days":       {domain.PlatformInstagram, ago(8 * 24 * time.Hour), false, []string{}},
+		"zalo has no window":           {domain.PlatformZalo, ago(30 * 24 * time.Hour), true, []string{}},
+		"telegram without any message": {domain.PlatformTelegram, nil, true, []string{}},
+	} {
+		window := MessagingWindowAt(tc.platform, tc.lastInboundAt, now)
+		if window.Open != tc.open {
+			t.Errorf("%s: open = %v, want %v", name, window.Open, tc.open)
+		}
+		if !reflect.DeepEqual(window.AllowedTags, tc.tags) {
+			t.Errorf("%s: allowed tags = %v, want %v", name, window.AllowedTags, tc.tags)
+		}
+		if tc.platform == domain.PlatformFacebook && tc.lastInboundAt != nil &&
+			(window.ExpiresAt == nil || !window.ExpiresAt.Equal(tc.lastInboundAt.Add(StandardMessagingWindow))) {
+			t.Errorf("%s: expires at %v", name, window.ExpiresAt)
+		}
+	}
+}
+
+func TestResolveMessageTag(t *testing.T) {
+	open := domain.MessagingWindow{Open: true, AllowedTags: []string{}}
+	closed := domain.MessagingWindow{AllowedTags: []string{domain.MessageTagPostPurchaseUpdate}}
+
+	for name, tc := range map[string]struct {
+		window  domain.MessagingWindow
+		tag     string
+		want    string
+		wantErr error
+	}{
+		"open, no tag":             {open, "", "", nil},
+		"open, tag dropped":        {open, domain.MessageTagHumanAgent, "", nil},
+		"closed, allowed tag":      {closed, domain.MessageTagPostPurchaseUpdate, domain.MessageTagPostPurchaseUpdate, nil},
+		"closed, no tag":           {closed, "", "", ErrMessagingWindowClosed},
+		"closed, tag not allowed":  {closed, domain.MessageTagHumanAgent, "", ErrMessageTagNotAllowed},
+		"closed, tag case differs": {closed, "post_purchase_update", "", ErrMessageTagNotAllowed},
+	} {
+		tag, err := ResolveMessageTag(tc.window, tc.tag)
+		if tag != tc.want || !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: tag = %q, err = %v, want %q, %v", name, tag, err, tc.want, tc.wantErr)
+		}
+	}
+}
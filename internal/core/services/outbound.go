@@ -0,0 +1,446 @@
+// Package services contains the outbound message queue
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// OutboundConfig tunes the outbound message queue
+type OutboundConfig struct {
+	Workers       int           // Messages sent concurrently
+	RatePerSecond float64       // Sends per second per page (token bucket refill)
+	Burst         int           // Token bucket size: sends a page may make at once after being idle
+	MaxAttempts   int           // Messages still failing after this many attempts are marked failed
+	BaseBackoff   time.Duration // Delay after the first failure, doubled per attempt (with jitter)
+	MaxBackoff    time.Duration // Cap for the exponential backoff
+	PollInterval  time.Duration // How often due jobs are looked for when nothing wakes the queue
+	Lease         time.Duration // A job being sent is not handed out again for this long
+	BatchSize     int
+}
+
+// OutboundService sends replies from the outbound_jobs queue: the HTTP handlers only
+// store the message as queued, workers send it within each page's rate limit, retry
+// rate limits and network errors with jittered backoff and push the final status
+// (sent / failed) to the dashboards as message_updated events
+//
+// Messages of a conversation go out one at a time in the order they were queued.
+// Delivery is at least once: a job whose worker died is sent again after the lease
+type OutboundService struct {
+	jobs      ports.OutboundRepository
+	platforms *PlatformRegistry
+	media     *MediaService
+	tenants   *TenantResolver      // Optional: nil leaves a deactivated page cached until its TTL
+	publisher ports.EventPublisher // Optional: nil disables live dashboard updates
+	cfg       OutboundConfig
+	limiter   *pageLimiter
+	wake      chan struct{}
+	now       func() time.Time
+}
+
+// NewOutboundService creates the queue (call Run to start the workers)
+func NewOutboundService(jobs ports.OutboundRepository, platforms *PlatformRegistry, media *MediaService, tenants *TenantResolver, publisher ports.EventPublisher, cfg OutboundConfig) *OutboundService {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.RatePerSecond <= 0 {
+		cfg.RatePerSecond = 10
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = 20
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 6
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 2 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 2 * time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &OutboundService{
+		jobs:      jobs,
+		platforms: platforms,
+		media:     media,
+		tenants:   tenants,
+		publisher: publisher,
+		cfg:       cfg,
+		limiter:   newPageLimiter(cfg.RatePerSecond, cfg.Burst),
+		wake:      make(chan struct{}, 1),
+		now:       time.Now,
+	}
+}
+
+// Enqueue stores msg as queued together with its send job and wakes the queue
+// msg.ID and job.MessageID are set to the new message ID
+func (s *OutboundService) Enqueue(ctx context.Context, msg *domain.Message, job *domain.OutboundJob) error {
+	job.NextAttemptAt = s.now()
+	if err := s.jobs.EnqueueOutbound(ctx, msg, job); err != nil {
+		return fmt.Errorf("enqueue outbound message: %w", err)
+	}
+	s.notify()
+	return nil
+}
+
+// Run starts the workers and hands them due jobs until ctx is cancelled
+func (s *OutboundService) Run(ctx context.Context) {
+	work := make(chan *domain.OutboundJob)
+	var wg sync.WaitGroup
+	for i := 0; i < s.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range work {
+				s.send(ctx, job)
+				s.notify() // The conversation's next message may be due now
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(work)
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	slog.Info("Outbound queue started",
+		"workers", s.cfg.Workers,
+		"rate_per_second", s.cfg.RatePerSecond,
+		"burst", s.cfg.Burst,
+		"max_attempts", s.cfg.MaxAttempts,
+	)
+
+	for {
+		throttled, more := s.dispatchDue(ctx, work)
+		if ctx.Err() != nil {
+			return
+		}
+		if more && throttled == 0 {
+			continue
+		}
+
+		var throttleEnd <-chan time.Time
+		if throttled > 0 {
+			throttleEnd = time.After(throttled)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		case <-throttleEnd:
+		}
+	}
+}
+
+// dispatchDue hands one batch of due jobs to the workers, skipping pages that are out
+// of tokens. Returns how long until the first skipped page may send again (0 if none
+// was skipped) and whether the batch was full
+func (s *OutboundService) dispatchDue(ctx context.Context, work chan<- *domain.OutboundJob) (time.Duration, bool) {
+	jobs, err := s.jobs.FindDueOutbound(ctx, s.now(), s.cfg.BatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("Failed to find due outbound messages", "error", err)
+		}
+		return 0, false
+	}
+
+	var throttled time.Duration
+	for _, job := range jobs {
+		if wait := s.limiter.reserve(pageKey(job), s.now()); wait > 0 {
+			if throttled == 0 || wait < throttled {
+				throttled = wait
+			}
+			continue
+		}
+
+		now := s.now()
+		claimed, err := s.jobs.ClaimOutbound(ctx, job.MessageID, now, now.Add(s.cfg.Lease))
+		if err != nil {
+			slog.Error("Failed to claim outbound message", "error", err, "message_id", job.MessageID)
+			continue
+		}
+		if !claimed {
+			continue // Taken by another instance
+		}
+
+		select {
+		case work <- job:
+		case <-ctx.Done():
+			return 0, false // Claimed but not sent: runs again after the lease
+		}
+	}
+	return throttled, len(jobs) == s.cfg.BatchSize
+}
+
+// send makes one attempt and records the outcome: sent, failed for a permanent error
+// or when attempts run out, else retried after a backoff
+func (s *OutboundService) send(ctx context.Context, job *domain.OutboundJob) {
+	externalMsgID, err := s.deliver(ctx, job)
+
+	// The outcome must be recorded even when shutting down, or the message is sent again
+	ctx = context.WithoutCancel(ctx)
+
+	if err == nil {
+		var sentID *string
+		if externalMsgID != "" {
+			sentID = &externalMsgID // Lets echo/delivery events find this row
+		}
+		s.complete(ctx, job, domain.DeliveryStatusSent, sentID, nil)
+		return
+	}
+
+	job.Attempts++
+	reason, permanent := deliveryError(err)
+	if permanent || job.Attempts >= s.cfg.MaxAttempts {
+		if !permanent {
+			reason = domain.DeliveryErrorSendFailed
+		}
+		slog.Warn("Outbound message failed",
+			"error", err,
+			"message_id", job.MessageID,
+			"conversation_id", job.ConversationID,
+			"platform", job.Platform,
+			"attempts", job.Attempts,
+			"reason", reason,
+		)
+
+		// CRITICAL: Handle token death per "Core hệ thống lỗi"
+		if errors.Is(err, ports.ErrTokenExpired) {
+			if err := s.jobs.DeactivatePage(ctx, job.Platform, job.PageID); err != nil {
+				slog.Error("Failed to deactivate page after token expiry",
+					"error", err,
+					"page_id", job.PageID,
+				)
+			}
+			if s.tenants != nil {
+				// Inbound events for the page are quarantined from now on, not in 5 minutes
+				s.tenants.Invalidate(ctx, job.Platform, job.PageID)
+			}
+			slog.Warn("🔴 PAGE AUTO-DEACTIVATED",
+				"page_id", job.PageID,
+				"conversation_id", job.ConversationID,
+				"reason", "Token expired",
+			)
+		}
+		s.complete(ctx, job, domain.DeliveryStatusFailed, nil, &reason)
+		return
+	}
+
+	delay := s.backoff(job.Attempts)
+	if errors.Is(err, ports.ErrRateLimited) {
+		// Slow the whole page down, not only this message
+		s.limiter.pause(pageKey(job), s.now().Add(delay))
+	}
+	slog.Warn("Outbound message will be retried",
+		"error", err,
+		"message_id", job.MessageID,
+		"platform", job.Platform,
+		"page_id", job.PageID,
+		"attempt", job.Attempts,
+		"retry_in", delay,
+	)
+	if err := s.jobs.RetryOutbound(ctx, job.MessageID, job.Attempts, s.now().Add(delay), err.Error()); err != nil {
+		slog.Error("Failed to reschedule outbound message", "error", err, "message_id", job.MessageID)
+	}
+}
+
+// deliver sends the job through the platform adapter and returns the platform message ID
+func (s *OutboundService) deliver(ctx context.Context, job *domain.OutboundJob) (string, error) {
+	adapter, err := s.platforms.Get(job.Platform)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ports.ErrMessageRejected, err)
+	}
+	accessToken, err := s.jobs.GetPageAccessToken(ctx, job.Platform, job.PageID)
+	if err != nil {
+		return "", err
+	}
+	target := domain.OutboundTarget{
+		PageID:      job.PageID,
+		RecipientID: job.RecipientID,
+		AccessToken: accessToken,
+		MessageTag:  job.MessageTag,
+	}
+
+	switch {
+	case job.Structured != nil:
+		sender, ok := adapter.(ports.StructuredSender)
+		if !ok {
+			return "", fmt.Errorf("%w: %s cannot send structured messages", ports.ErrMessageRejected, job.Platform)
+		}
+		return sender.SendStructured(ctx, target, *job.Structured)
+
+	case job.MediaID != "":
+		sender, ok := adapter.(ports.AttachmentSender)
+		if !ok {
+			return "", fmt.Errorf("%w: %s cannot send attachments", ports.ErrMessageRejected, job.Platform)
+		}
+		media, err := s.media.GetMedia(ctx, job.TenantID, job.MediaID)
+		if errors.Is(err, ErrMediaNotFound) {
+			return "", fmt.Errorf("%w: %v", ports.ErrMessageRejected, err)
+		}
+		if err != nil {
+			return "", err
+		}
+		attachment, err := s.media.Attachment(ctx, media, job.Platform, job.PageID)
+		if err != nil {
+			return "", err
+		}
+		messageID, attachmentID, err := sender.SendAttachment(ctx, target, attachment)
+		if err == nil {
+			s.media.RememberAttachmentID(ctx, media, job.Platform, job.PageID, attachmentID)
+		}
+		return messageID, err
+
+	default:
+		return adapter.SendText(ctx, target, job.Text)
+	}
+}
+
+// complete stores the final status and pushes the updated message to the dashboards
+func (s *OutboundService) complete(ctx context.Context, job *domain.OutboundJob, status string, externalMsgID, deliveryError *string) {
+	msg, err := s.jobs.CompleteOutbound(ctx, job.MessageID, status, externalMsgID, deliveryError)
+	if err != nil {
+		slog.Error("Failed to record outbound message status",
+			"error", err,
+			"message_id", job.MessageID,
+			"status", status,
+		)
+		return
+	}
+	if msg == nil || s.publisher == nil {
+		return // Purged by retention meanwhile / no live updates
+	}
+	s.publisher.Publish(ctx, domain.DashboardEvent{
+		Type:           domain.DashboardEventMessageUpdated,
+		ConversationID: job.ConversationID,
+		Data:           msg,
+	})
+}
+
+// backoff returns BaseBackoff doubled per previous attempt, capped at MaxBackoff; half
+// of it is random so pages throttled together do not retry together
+func (s *OutboundService) backoff(attempts int) time.Duration {
+	delay := s.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < s.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.cfg.MaxBackoff {
+		delay = s.cfg.MaxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// notify wakes the dispatch loop (no-op if a wake-up is already pending)
+func (s *OutboundService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliveryError maps a permanent send error to its DeliveryError constant
+// Returns false for errors worth retrying (rate limits, network, unknown)
+func deliveryError(err error) (string, bool) {
+	switch {
+	case errors.Is(err, ports.ErrTokenExpired):
+		return domain.DeliveryErrorTokenExpired, true
+	case errors.Is(err, ports.ErrPageInactive):
+		return domain.DeliveryErrorPageInactive, true
+	case errors.Is(err, ports.ErrPermissionDenied):
+		return domain.DeliveryErrorPermissionDenied, true
+	case errors.Is(err, ports.ErrOutsideMessagingWindow):
+		return domain.DeliveryErrorOutsideWindow, true
+	case errors.Is(err, ports.ErrMessageRejected):
+		return domain.DeliveryErrorRejected, true
+	default:
+		return "", false
+	}
+}
+
+// pageKey identifies the rate limit bucket of a job's page
+func pageKey(job *domain.OutboundJob) string {
+	return job.Platform + ":" + job.PageID
+}
+
+// pageLimiter is a token bucket per page: RatePerSecond tokens per second up to Burst
+type pageLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens      float64
+	updatedAt   time.Time
+	pausedUntil time.Time // Set after a platform rate limit error
+}
+
+func newPageLimiter(rate float64, burst int) *pageLimiter {
+	return &pageLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// reserve takes a token of key; when none is left it returns how long until there is one
+func (l *pageLimiter) reserve(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key, now)
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now)
+	}
+	if now.After(b.updatedAt) {
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updatedAt).Seconds()*l.rate)
+		b.updatedAt = now
+	}
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+// pause stops sends of key until the given time, with an empty bucket afterwards
+func (l *pageLimiter) pause(key string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key, until)
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+		b.tokens, b.updatedAt = 0, until
+	}
+}
+
+// bucket returns the bucket of key, created full; callers hold mu
+func (l *pageLimiter) bucket(key string, now time.Time) *tokenBucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, updatedAt: now}
+		l.buckets[key] = b
+	}
+	return b
+}
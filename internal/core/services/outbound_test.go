@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+func TestPageLimiter(t *testing.T) {
+	now := time.Unix(1716200000, 0)
+	limiter := newPageLimiter(2, 3) // 2 sends per second, bursts of 3
+
+	// A page starts with a full bucket
+	for i := 0; i < 3; i++ {
+		if wait := limiter.reserve("fb:1", now); wait != 0 {
+			t.Fatalf("send %d waited %v", i, wait)
+		}
+	}
+	if wait := limiter.reserve("fb:1", now); wait != 500*time.Millisecond {
+		t.Errorf("empty bucket wait = %v, want 500ms", wait)
+	}
+
+	// Other pages have their own bucket
+	if wait := limiter.reserve("fb:2", now); wait != 0 {
+		t.Errorf("other page waited %v", wait)
+	}
+
+	// Refill at the rate, never above the burst
+	if wait := limiter.reserve("fb:1", now.Add(500*time.Millisecond)); wait != 0 {
+		t.Errorf("after refill waited %v", wait)
+	}
+	later := now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if wait := limiter.reserve("fb:1", later); wait != 0 {
+			t.Fatalf("send %d after idle waited %v", i, wait)
+		}
+	}
+	if wait := limiter.reserve("fb:1", later); wait == 0 {
+		t.Error("bucket refilled above the burst")
+	}
+}
+
+func TestPageLimiterPause(t *testing.T) {
+	now := time.Unix(1716200000, 0)
+	limiter := newPageLimiter(2, 3)
+
+	limiter.pause("fb:1", now.Add(10*time.Second))
+	if wait := limiter.reserve("fb:1", now); wait != 10*time.Second {
+		t.Errorf("paused wait = %v, want 10s", wait)
+	}
+
+	// A shorter pause does not cut an existing one short
+	limiter.pause("fb:1", now.Add(time.Second))
+	if wait := limiter.reserve("fb:1", now); wait != 10*time.Second {
+		t.Errorf("wait after shorter pause = %v, want 10s", wait)
+	}
+
+	// The bucket is empty when the pause ends
+	if wait := limiter.reserve("fb:1", now.Add(10*time.Second)); wait != 500*time.Millisecond {
+		t.Errorf("wait at the end of the pause = %v, want 500ms", wait)
+	}
+}
+
+func TestOutboundBackoff(t *testing.T) {
+	s := &OutboundService{cfg: OutboundConfig{BaseBackoff: 2 * time.Second, MaxBackoff: 30 * time.Second}}
+
+	for attempts, full := range map[int]time.Duration{
+		1:  2 * time.Second,
+		2:  4 * time.Second,
+		4:  16 * time.Second,
+		5:  30 * time.Second,
+		50: 30 * time.Second,
+	} {
+		for i := 0; i < 100; i++ {
+			// Half of the delay is jitter
+			if delay := s.backoff(attempts); delay < full/2 || delay > full {
+				t.Fatalf("attempt %d: backoff = %v, want between %v and %v", attempts, delay, full/2, full)
+			}
+		}
+	}
+}
+
+func TestDeliveryError(t *testing.T) {
+	for err, want := range map[error]string{
+		ports.ErrTokenExpired:                            domain.DeliveryErrorTokenExpired,
+		ports.ErrPageInactive:                            domain.DeliveryErrorPageInactive,
+		ports.ErrPermissionDenied:                        domain.DeliveryErrorPermissionDenied,
+		ports.ErrOutsideMessagingWindow:                  domain.DeliveryErrorOutsideWindow,
+		fmt.Errorf("zalo: %w", ports.ErrMessageRejected): domain.DeliveryErrorRejected,
+	} {
+		if reason, permanent := deliveryError(err); reason != want || !permanent {
+			t.Errorf("%v: delivery error = %q (permanent %v), want %q", err, reason, permanent, want)
+		}
+	}
+
+	// Worth retrying
+	for _, err := range []error{ports.ErrRateLimited, errors.New("connection reset")} {
+		if reason, permanent := deliveryError(err); permanent {
+			t.Errorf("%v: permanent with %q", err, reason)
+		}
+	}
+}
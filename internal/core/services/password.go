@@ -0,0 +1,47 @@
+// Package services contains staff password hashing
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidPasswordHash is returned for a stored hash in an unknown format
+var ErrInvalidPasswordHash = errors.New("invalid password hash")
+
+// Password hashing parameters
+// bcrypt hashes carry their cost, so it can be raised without invalidating existing accounts
+const (
+	passwordHashCost = 12
+
+	// MinPasswordLength is enforced when accounts are created
+	MinPasswordLength = 8
+
+	// MaxPasswordLength is the bcrypt input limit (bytes); longer passwords are refused
+	// instead of being silently truncated
+	MaxPasswordLength = 72
+)
+
+// HashPassword returns the bcrypt hash of password ("$2a$12$...")
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches a hash from HashPassword
+func CheckPassword(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: %v", ErrInvalidPasswordHash, err)
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$2a$12$") {
+		t.Errorf("hash = %q, want bcrypt cost 12", hash)
+	}
+
+	if ok, err := CheckPassword(hash, "correct horse"); !ok || err != nil {
+		t.Errorf("right password: ok = %v, err = %v", ok, err)
+	}
+	if ok, err := CheckPassword(hash, "wrong horse"); ok || err != nil {
+		t.Errorf("wrong password: ok = %v, err = %v", ok, err)
+	}
+}
+
+func TestCheckPasswordInvalidHash(t *testing.T) {
+	for _, hash := range []string{"", "pbkdf2-sha256$210000$c2FsdA$a2V5", "$2a$12$short"} {
+		if _, err := CheckPassword(hash, "password"); !errors.Is(err, ErrInvalidPasswordHash) {
+			t.Errorf("%q: err = %v", hash, err)
+		}
+	}
+}
+
+func TestHashPasswordTooLong(t *testing.T) {
+	if _, err := HashPassword(strings.Repeat("a", MaxPasswordLength+1)); err == nil {
+		t.Error("password over MaxPasswordLength was hashed")
+	}
+}
@@ -0,0 +1,55 @@
+// Package services contains the platform adapter registry
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"immortal-chat/internal/core/ports"
+)
+
+// ErrUnknownPlatform is returned when no adapter is registered for a platform
+var ErrUnknownPlatform = errors.New("unknown platform")
+
+// PlatformRegistry maps a platform key ("facebook", "zalo", ...) to its adapter
+// Adding a channel = registering one more adapter in main.go, no dispatcher change
+type PlatformRegistry struct {
+	adapters map[string]ports.PlatformAdapter
+}
+
+// NewPlatformRegistry creates a registry with the given adapters
+func NewPlatformRegistry(adapters ...ports.PlatformAdapter) *PlatformRegistry {
+	r := &PlatformRegistry{
+		adapters: make(map[string]ports.PlatformAdapter),
+	}
+	for _, adapter := range adapters {
+		r.Register(adapter)
+	}
+	return r
+}
+
+// Register adds (or replaces) the adapter for adapter.Platform()
+// Not safe for concurrent use: register everything at startup
+func (r *PlatformRegistry) Register(adapter ports.PlatformAdapter) {
+	r.adapters[adapter.Platform()] = adapter
+}
+
+// Get returns the adapter for a platform
+func (r *PlatformRegistry) Get(platform string) (ports.PlatformAdapter, error) {
+	adapter, ok := r.adapters[platform]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPlatform, platform)
+	}
+	return adapter, nil
+}
+
+// Platforms lists registered platform keys (sorted)
+func (r *PlatformRegistry) Platforms() []string {
+	platforms := make([]string, 0, len(r.adapters))
+	for platform := range r.adapters {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
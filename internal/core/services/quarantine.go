@@ -0,0 +1,97 @@
+// Package services contains the replay of quarantined events
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"immortal-chat/internal/core/domain"
+)
+
+// quarantineBatchSize is how many quarantined events are loaded at a time
+const quarantineBatchSize = 100
+
+// QuarantineReplayResult summarises a quarantine replay (returned by the admin endpoint)
+type QuarantineReplayResult struct {
+	Attempted int                     `json:"attempted"`
+	Succeeded int                     `json:"succeeded"`
+	Failed    int                     `json:"failed"`
+	Errors    []QuarantineReplayError `json:"errors,omitempty"`
+}
+
+// QuarantineReplayError describes a quarantined event that failed again
+type QuarantineReplayError struct {
+	EventID int64  `json:"event_id"`
+	Error   string `json:"error"`
+}
+
+// ReplayQuarantined processes the events quarantined for a page once the page is
+// connected (or reactivated) in the pages table. Replayed events are deleted; failed
+// ones stay quarantined for the next attempt
+// Returns ErrUnknownPage / ErrInactivePage while the page still cannot be routed
+func (s *ReplayService) ReplayQuarantined(ctx context.Context, platform, pageID string) (*QuarantineReplayResult, error) {
+	// The cache may still hold the "unroutable" marker of the page
+	s.dispatcher.tenants.Invalidate(ctx, platform, pageID)
+	tenantID, err := s.dispatcher.tenants.Resolve(ctx, platform, pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QuarantineReplayResult{}
+	var afterID int64
+	for ctx.Err() == nil {
+		events, err := s.dispatcher.quarantine.ListQuarantined(ctx, platform, pageID, afterID, quarantineBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("list quarantined events: %w", err)
+		}
+
+		for _, quarantined := range events {
+			afterID = quarantined.ID
+			if err := s.replayQuarantinedEvent(ctx, tenantID, quarantined); err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, QuarantineReplayError{
+					EventID: quarantined.ID,
+					Error:   err.Error(),
+				})
+				slog.Warn("Quarantined event replay failed",
+					"event_id", quarantined.ID,
+					"platform", platform,
+					"page_id", pageID,
+					"error", err,
+				)
+			} else {
+				result.Succeeded++
+			}
+			result.Attempted++
+		}
+
+		if len(events) < quarantineBatchSize {
+			break
+		}
+	}
+
+	slog.Info("Quarantined events replayed",
+		"platform", platform,
+		"page_id", pageID,
+		"tenant_id", tenantID,
+		"succeeded", result.Succeeded,
+		"failed", result.Failed,
+	)
+	return result, nil
+}
+
+// replayQuarantinedEvent processes one stored event for the page's tenant and deletes it
+func (s *ReplayService) replayQuarantinedEvent(ctx context.Context, tenantID int, quarantined *domain.QuarantinedEvent) error {
+	var event domain.InboundEvent
+	if err := json.Unmarshal(quarantined.Event, &event); err != nil {
+		return fmt.Errorf("decode quarantined event: %w", err)
+	}
+	event.TenantID = tenantID
+
+	if _, err := s.dispatcher.processEvent(ctx, &event); err != nil {
+		return err
+	}
+	return s.dispatcher.quarantine.DeleteQuarantined(ctx, quarantined.ID)
+}
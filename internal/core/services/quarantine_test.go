@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// fakeQuarantine stores quarantined events in ID order
+type fakeQuarantine struct {
+	events []*domain.QuarantinedEvent
+}
+
+func (f *fakeQuarantine) QuarantineEvent(ctx context.Context, event *domain.QuarantinedEvent) error {
+	event.ID = int64(len(f.events) + 1)
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakeQuarantine) ListQuarantined(ctx context.Context, platform, pageID string, afterID int64, limit int) ([]*domain.QuarantinedEvent, error) {
+	var events []*domain.QuarantinedEvent
+	for _, event := range f.events {
+		if event.ID > afterID && event.Platform == platform && event.PageID == pageID && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakeQuarantine) DeleteQuarantined(ctx context.Context, id int64) error {
+	for i, event := range f.events {
+		if event.ID == id {
+			f.events = append(f.events[:i], f.events[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// switchablePages is a page table plus cache: the page is connected by setting page
+type switchablePages struct {
+	page  *domain.Page
+	cache map[string]int
+}
+
+func (p *switchablePages) GetPage(ctx context.Context, platform, pageID string) (*domain.Page, error) {
+	return p.page, nil
+}
+
+func (p *switchablePages) GetPageTenant(ctx context.Context, platform, pageID string) (int, bool, error) {
+	tenantID, found := p.cache[platform+"/"+pageID]
+	return tenantID, found, nil
+}
+
+func (p *switchablePages) SetPageTenant(ctx context.Context, platform, pageID string, tenantID int, ttl time.Duration) error {
+	p.cache[platform+"/"+pageID] = tenantID
+	return nil
+}
+
+func (p *switchablePages) DeletePageTenant(ctx context.Context, platform, pageID string) error {
+	delete(p.cache, platform+"/"+pageID)
+	return nil
+}
+
+func TestQuarantinedEventsReplayOnceThePageIsConnected(t *testing.T) {
+	pages := &switchablePages{cache: map[string]int{}}
+	quarantine := &fakeQuarantine{}
+	messages := &fakeMessages{saved: map[string]bool{}, racing: map[string]bool{}}
+	webhooks := &fakeWebhookLogs{statuses: map[int64]string{}}
+	dispatcher := NewDispatcher(webhooks, messages, fakeConversations{}, fakeDedup{}, &fakeQueue{},
+		NewPlatformRegistry(fakeChannel{}), NewTenantResolver(pages, pages), quarantine, nil, nil, nil)
+	replay := NewReplayService(dispatcher, webhooks, &fakeQueue{}, ReplayConfig{})
+
+	for _, mid := range []string{"mid.1", "mid.2"} {
+		payload, _ := json.Marshal(mid)
+		if err := dispatcher.ProcessWebhook(context.Background(), "fake", payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(quarantine.events) != 2 || len(messages.saved) != 0 {
+		t.Fatalf("quarantined %d, saved %d; want 2, 0", len(quarantine.events), len(messages.saved))
+	}
+
+	if _, err := replay.ReplayQuarantined(context.Background(), "fake", "page"); !errors.Is(err, ErrUnknownPage) {
+		t.Fatalf("err = %v, want ErrUnknownPage", err)
+	}
+
+	// Connected while the "unknown page" marker is still cached
+	pages.page = &domain.Page{TenantID: 3, Platform: "fake", PageID: "page", IsActive: true}
+	result, err := replay.ReplayQuarantined(context.Background(), "fake", "page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Attempted != 2 || result.Succeeded != 2 {
+		t.Errorf("result = %+v", result)
+	}
+	if !messages.saved["mid.1"] || !messages.saved["mid.2"] || len(quarantine.events) != 0 {
+		t.Errorf("saved = %v, still quarantined = %d", messages.saved, len(quarantine.events))
+	}
+}
+
+func TestInvalidateDropsDeactivatedPageFromCache(t *testing.T) {
+	pages := &switchablePages{
+		page:  &domain.Page{TenantID: 1, Platform: "fake", PageID: "page", IsActive: true},
+		cache: map[string]int{},
+	}
+	tenants := NewTenantResolver(pages, pages)
+	if tenantID, err := tenants.Resolve(context.Background(), "fake", "page"); err != nil || tenantID != 1 {
+		t.Fatalf("tenant = %d, err = %v", tenantID, err)
+	}
+
+	// Token expired: the page is deactivated while its tenant is still cached
+	pages.page.IsActive = false
+	tenants.Invalidate(context.Background(), "fake", "page")
+
+	if _, err := tenants.Resolve(context.Background(), "fake", "page"); !errors.Is(err, ErrInactivePage) {
+		t.Errorf("err = %v, want ErrInactivePage", err)
+	}
+}
+
+// deactivatingJobs records page deactivations (sends fail with an expired token)
+type deactivatingJobs struct {
+	ports.OutboundRepository
+	deactivated []string
+}
+
+func (j *deactivatingJobs) GetPageAccessToken(ctx context.Context, platform, pageID string) (string, error) {
+	return "page-token", nil
+}
+
+func (j *deactivatingJobs) CompleteOutbound(ctx context.Context, messageID int64, status string, externalMsgID, deliveryError *string) (*domain.Message, error) {
+	return &domain.Message{ID: messageID, DeliveryStatus: &status, DeliveryError: deliveryError}, nil
+}
+
+func (j *deactivatingJobs) DeactivatePage(ctx context.Context, platform, pageID string) error {
+	j.deactivated = append(j.deactivated, platform+"/"+pageID)
+	return nil
+}
+
+func TestDeactivatedPageIsDroppedFromTenantCache(t *testing.T) {
+	pages := &switchablePages{
+		page:  &domain.Page{TenantID: 1, Platform: "fake", PageID: "page", IsActive: true},
+		cache: map[string]int{"fake/page": 1},
+	}
+	jobs := &deactivatingJobs{}
+	outbound := NewOutboundService(jobs, NewPlatformRegistry(expiredTokenChannel{}), nil,
+		NewTenantResolver(pages, pages), nil, OutboundConfig{})
+
+	outbound.send(context.Background(), &domain.OutboundJob{
+		MessageID: 1, ConversationID: 100, TenantID: 1, Platform: "fake", PageID: "page", RecipientID: "r", Text: "hi",
+	})
+
+	if len(jobs.deactivated) != 1 {
+		t.Fatalf("deactivated = %v", jobs.deactivated)
+	}
+	if _, cached := pages.cache["fake/page"]; cached {
+		t.Error("page -> tenant mapping still cached after deactivation")
+	}
+}
+
+type expiredTokenChannel struct{ fakeChannel }
+
+func (expiredTokenChannel) SendText(ctx context.Context, target domain.OutboundTarget, text string) (string, error) {
+	return "", ports.ErrTokenExpired
+}
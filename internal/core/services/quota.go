@@ -0,0 +1,230 @@
+// Package services contains tenant plan enforcement
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+var (
+	// ErrTenantNotFound is returned for an unknown tenant ID
+	ErrTenantNotFound = errors.New("tenant not found")
+
+	// ErrTenantInactive is returned when the tenant was disabled
+	ErrTenantInactive = errors.New("tenant inactive")
+
+	// ErrTenantExpired is returned when the tenant's subscription has expired
+	ErrTenantExpired = errors.New("tenant subscription expired")
+
+	// ErrQuotaExceeded is returned when a plan limit is reached
+	ErrQuotaExceeded = errors.New("plan limit exceeded")
+)
+
+// retentionBatchSize bounds each DELETE so the purge never locks messages for long
+const retentionBatchSize = 1000
+
+// QuotaService enforces per-plan limits and subscription expiry
+// Only outbound actions are blocked: inbound messages are always stored so
+// no customer message is lost while a tenant renews or upgrades. They still count
+// toward MonthlyMessages (usage is counted from the messages table), so a busy
+// month can use up the quota before the first reply
+type QuotaService struct {
+	tenants ports.TenantRepository
+	now     func() time.Time
+}
+
+// NewQuotaService creates a quota service
+func NewQuotaService(tenants ports.TenantRepository) *QuotaService {
+	return &QuotaService{
+		tenants: tenants,
+		now:     time.Now,
+	}
+}
+
+// EffectiveLimits returns the limits of a tenant: plan defaults overridden by
+// tenants.config {"limits": {...}} (only non-zero overrides apply)
+func EffectiveLimits(tenant *domain.Tenant) domain.PlanLimits {
+	limits, ok := domain.DefaultPlanLimits[tenant.Plan]
+	if !ok {
+		// No plan (NULL) or unknown plan: most restrictive defaults
+		limits = domain.DefaultPlanLimits[domain.PlanBasic]
+	}
+
+	if len(tenant.Config) == 0 {
+		return limits
+	}
+	var config struct {
+		Limits *domain.PlanLimits `json:"limits"`
+	}
+	if err := json.Unmarshal(tenant.Config, &config); err != nil || config.Limits == nil {
+		return limits
+	}
+
+	if config.Limits.MaxPages > 0 {
+		limits.MaxPages = config.Limits.MaxPages
+	}
+	if config.Limits.MaxAgents > 0 {
+		limits.MaxAgents = config.Limits.MaxAgents
+	}
+	if config.Limits.MonthlyMessages > 0 {
+		limits.MonthlyMessages = config.Limits.MonthlyMessages
+	}
+	if config.Limits.RetentionDays > 0 {
+		limits.RetentionDays = config.Limits.RetentionDays
+	}
+	return limits
+}
+
+// Usage reports the tenant's consumption for the current calendar month
+func (s *QuotaService) Usage(ctx context.Context, tenantID int) (*domain.TenantUsage, error) {
+	tenant, err := s.tenants.GetTenant(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("get tenant: %w", err)
+	}
+	if tenant == nil {
+		return nil, ErrTenantNotFound
+	}
+
+	now := s.now()
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	pages, err := s.tenants.CountActivePages(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("count pages: %w", err)
+	}
+	agents, err := s.tenants.CountActiveStaff(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("count staff: %w", err)
+	}
+	messages, err := s.tenants.CountMessagesSince(ctx, tenantID, periodStart)
+	if err != nil {
+		return nil, fmt.Errorf("count messages: %w", err)
+	}
+
+	return &domain.TenantUsage{
+		TenantID:        tenant.ID,
+		Plan:            tenant.Plan,
+		IsActive:        tenant.IsActive,
+		ExpiredAt:       tenant.ExpiredAt,
+		Expired:         tenant.ExpiredAt != nil && now.After(*tenant.ExpiredAt),
+		Limits:          EffectiveLimits(tenant),
+		Pages:           pages,
+		Agents:          agents,
+		MonthlyMessages: messages,
+		PeriodStart:     periodStart,
+	}, nil
+}
+
+// CheckOutbound returns nil when the tenant may send a reply
+// Errors: ErrTenantNotFound, ErrTenantInactive, ErrTenantExpired, ErrQuotaExceeded
+func (s *QuotaService) CheckOutbound(ctx context.Context, tenantID int) error {
+	usage, err := s.Usage(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !usage.IsActive:
+		return ErrTenantInactive
+	case usage.Expired:
+		return ErrTenantExpired
+	case usage.Limits.MonthlyMessages > 0 && usage.MonthlyMessages >= usage.Limits.MonthlyMessages:
+		return fmt.Errorf("%w: %d/%d messages this month", ErrQuotaExceeded, usage.MonthlyMessages, usage.Limits.MonthlyMessages)
+	case usage.Limits.MaxPages > 0 && usage.Pages > usage.Limits.MaxPages:
+		return fmt.Errorf("%w: %d/%d active pages", ErrQuotaExceeded, usage.Pages, usage.Limits.MaxPages)
+	}
+	return nil
+}
+
+// CheckAgentSeat returns nil when the tenant may add another staff account
+// Errors: ErrTenantNotFound, ErrTenantInactive, ErrTenantExpired, ErrQuotaExceeded
+func (s *QuotaService) CheckAgentSeat(ctx context.Context, tenantID int) error {
+	usage, err := s.Usage(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !usage.IsActive:
+		return ErrTenantInactive
+	case usage.Expired:
+		return ErrTenantExpired
+	case usage.Limits.MaxAgents > 0 && usage.Agents >= usage.Limits.MaxAgents:
+		return fmt.Errorf("%w: %d/%d agents", ErrQuotaExceeded, usage.Agents, usage.Limits.MaxAgents)
+	}
+	return nil
+}
+
+// RunRetention purges messages older than each tenant's retention every interval
+// until ctx is cancelled
+func (s *QuotaService) RunRetention(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slog.Info("Message retention job started", "interval", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.PurgeExpiredMessages(ctx); err != nil {
+			slog.Error("Message retention run failed", "error", err)
+		}
+	}
+}
+
+// PurgeExpiredMessages deletes messages past the retention of every tenant
+// A failing tenant is logged and skipped so the others are still purged
+func (s *QuotaService) PurgeExpiredMessages(ctx context.Context) error {
+	tenants, err := s.tenants.ListTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("list tenants: %w", err)
+	}
+
+	for _, tenant := range tenants {
+		limits := EffectiveLimits(tenant)
+		if limits.RetentionDays <= 0 {
+			continue // Unlimited retention
+		}
+		before := s.now().AddDate(0, 0, -limits.RetentionDays)
+
+		var purged int64
+		for {
+			deleted, err := s.tenants.PurgeMessagesBefore(ctx, tenant.ID, before, retentionBatchSize)
+			if err != nil {
+				slog.Error("Failed to purge messages",
+					"error", err,
+					"tenant_id", tenant.ID,
+				)
+				break
+			}
+			purged += deleted
+			if deleted < retentionBatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		if purged > 0 {
+			slog.Info("Messages purged by retention",
+				"tenant_id", tenant.ID,
+				"retention_days", limits.RetentionDays,
+				"purged", purged,
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// fakeTenants serves one tenant and fixed usage counts
+type fakeTenants struct {
+	ports.TenantRepository
+	tenant   *domain.Tenant
+	pages    int
+	agents   int
+	messages int // Everything stored this month, inbound included
+}
+
+func (f *fakeTenants) GetTenant(ctx context.Context, tenantID int) (*domain.Tenant, error) {
+	return f.tenant, nil
+}
+
+func (f *fakeTenants) CountActivePages(ctx context.Context, tenantID int) (int, error) {
+	return f.pages, nil
+}
+
+func (f *fakeTenants) CountActiveStaff(ctx context.Context, tenantID int) (int, error) {
+	return f.agents, nil
+}
+
+func (f *fakeTenants) CountMessagesSince(ctx context.Context, tenantID int, since time.Time) (int, error) {
+	return f.messages, nil
+}
+
+func TestCheckOutbound(t *testing.T) {
+	now := time.Date(2024, 5, 20, 10, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour)
+
+	for name, tc := range map[string]struct {
+		tenant   domain.Tenant
+		pages    int
+		messages int
+		want     error
+	}{
+		"within limits":         {domain.Tenant{Plan: domain.PlanBasic, IsActive: true}, 1, 4999, nil},
+		"quota used by inbound": {domain.Tenant{Plan: domain.PlanBasic, IsActive: true}, 1, 5000, ErrQuotaExceeded},
+		"too many pages":        {domain.Tenant{Plan: domain.PlanBasic, IsActive: true}, 2, 0, ErrQuotaExceeded},
+		"expired":               {domain.Tenant{Plan: domain.PlanPro, IsActive: true, ExpiredAt: &yesterday}, 1, 0, ErrTenantExpired},
+		"inactive":              {domain.Tenant{Plan: domain.PlanPro}, 1, 0, ErrTenantInactive},
+		"vip is unlimited":      {domain.Tenant{Plan: domain.PlanVIP, IsActive: true}, 50, 1000000, nil},
+		"config override": {domain.Tenant{
+			Plan: domain.PlanBasic, IsActive: true,
+			Config: json.RawMessage(`{"limits": {"monthly_messages": 10000}}`),
+		}, 1, 7000, nil},
+	} {
+		tenant := tc.tenant
+		quota := NewQuotaService(&fakeTenants{tenant: &tenant, pages: tc.pages, messages: tc.messages})
+		quota.now = func() time.Time { return now }
+
+		if err := quota.CheckOutbound(context.Background(), 1); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+}
+
+func TestUsagePeriodStartsAtTheFirstOfTheMonth(t *testing.T) {
+	quota := NewQuotaService(&fakeTenants{tenant: &domain.Tenant{ID: 1, Plan: domain.PlanPro, IsActive: true}, messages: 12})
+	quota.now = func() time.Time { return time.Date(2024, 5, 20, 10, 0, 0, 0, time.UTC) }
+
+	usage, err := quota.Usage(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC); !usage.PeriodStart.Equal(want) {
+		t.Errorf("period start = %v, want %v", usage.PeriodStart, want)
+	}
+	if usage.MonthlyMessages != 12 || usage.Limits.MonthlyMessages != 50000 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
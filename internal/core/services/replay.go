@@ -0,0 +1,249 @@
+// Package services contains the webhook replay engine
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+var (
+	// ErrWebhookNotFound is returned when replaying an unknown webhook_logs ID
+	ErrWebhookNotFound = errors.New("webhook log not found")
+
+	// ErrWebhookQueued is returned when replaying a webhook the queue has not Acked yet
+	ErrWebhookQueued = errors.New("webhook still pending in the queue")
+)
+
+// ReplayConfig tunes automatic webhook replay
+type ReplayConfig struct {
+	Interval    time.Duration // How often the background loop looks for work
+	MaxRetries  int           // Rows with retry_count >= MaxRetries are left for manual replay
+	BaseBackoff time.Duration // Delay after first failure, doubled per retry
+	MaxBackoff  time.Duration // Cap for the exponential backoff
+	StaleAfter  time.Duration // Only touch rows older than this (queue redeliveries come first)
+	BatchSize   int
+}
+
+// ReplayResult summarises a replay run (returned by the admin endpoint)
+type ReplayResult struct {
+	Attempted int           `json:"attempted"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped,omitempty"` // Still pending in the queue
+	Errors    []ReplayError `json:"errors,omitempty"`
+}
+
+// ReplayError describes a single webhook that failed again during replay
+type ReplayError struct {
+	WebhookID int64  `json:"webhook_id"`
+	Error     string `json:"error"`
+}
+
+// WebhookBacklog counts the webhooks still waiting for a successful run (all tenants)
+type WebhookBacklog struct {
+	Pending int `json:"pending"` // Queued but not processed by a worker yet
+	Failed  int `json:"failed"`  // Last attempt errored, see error_log
+}
+
+// ReplayService feeds failed / stuck webhook_logs rows back through the Dispatcher
+// Per .rulesgemini Section 3: All webhooks are logged for audit and replay
+// Replays are idempotent: processMessage skips events already in the dedup cache or DB,
+// and the messages table has a unique key for two workers racing on the same event
+// Rows whose job is still pending in the queue are skipped: the queue redelivers them
+type ReplayService struct {
+	dispatcher  *Dispatcher
+	webhookRepo ports.WebhookRepository
+	queue       ports.WebhookQueue
+	cfg         ReplayConfig
+}
+
+// NewReplayService creates a replay service (call Run to start the background loop)
+func NewReplayService(dispatcher *Dispatcher, webhookRepo ports.WebhookRepository, queue ports.WebhookQueue, cfg ReplayConfig) *ReplayService {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 10
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 30 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Hour
+	}
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 15 * time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+
+	return &ReplayService{
+		dispatcher:  dispatcher,
+		webhookRepo: webhookRepo,
+		queue:       queue,
+		cfg:         cfg,
+	}
+}
+
+// Run replays due webhooks every Interval until ctx is cancelled
+func (s *ReplayService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	slog.Info("Webhook replay engine started",
+		"interval", s.cfg.Interval,
+		"max_retries", s.cfg.MaxRetries,
+		"base_backoff", s.cfg.BaseBackoff,
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := s.ReplayDue(ctx)
+		if err != nil {
+			slog.Error("Automatic webhook replay failed", "error", err)
+			continue
+		}
+
+		if result.Attempted > 0 {
+			slog.Info("Automatic webhook replay completed",
+				"attempted", result.Attempted,
+				"succeeded", result.Succeeded,
+				"failed", result.Failed,
+				"skipped", result.Skipped,
+			)
+		}
+	}
+}
+
+// ReplayDue replays failed and stale pending rows whose backoff has elapsed
+func (s *ReplayService) ReplayDue(ctx context.Context) (*ReplayResult, error) {
+	return s.replay(ctx, domain.WebhookReplayFilter{
+		Statuses:    []string{domain.WebhookStatusFailed, domain.WebhookStatusPending},
+		OlderThan:   s.cfg.StaleAfter,
+		MaxRetries:  s.cfg.MaxRetries,
+		BaseBackoff: s.cfg.BaseBackoff,
+		MaxBackoff:  s.cfg.MaxBackoff,
+		Limit:       s.cfg.BatchSize,
+	})
+}
+
+// ReplayByID replays one webhook regardless of status, retry count or backoff
+// (explicit admin action, e.g. after fixing a bug or reconnecting a page)
+func (s *ReplayService) ReplayByID(ctx context.Context, id int64) (*ReplayResult, error) {
+	log, err := s.webhookRepo.GetLog(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("load webhook log: %w", err)
+	}
+	if log == nil {
+		return nil, fmt.Errorf("%w: %d", ErrWebhookNotFound, id)
+	}
+
+	pending, err := s.queue.PendingLogIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list queued webhooks: %w", err)
+	}
+	if pending[id] {
+		return nil, fmt.Errorf("%w: %d", ErrWebhookQueued, id)
+	}
+
+	result := &ReplayResult{}
+	s.replayOne(ctx, log, result)
+	return result, nil
+}
+
+// ReplayMatching replays rows by time range and/or platform (admin action)
+// Backoff and max retries are not applied; statuses default to failed + pending
+func (s *ReplayService) ReplayMatching(ctx context.Context, filter domain.WebhookReplayFilter) (*ReplayResult, error) {
+	filter.ID = 0
+	filter.BaseBackoff = 0
+	filter.MaxRetries = 0
+	return s.replay(ctx, filter)
+}
+
+// Backlog counts pending and failed webhook logs (admin action)
+func (s *ReplayService) Backlog(ctx context.Context) (*WebhookBacklog, error) {
+	counts, err := s.webhookRepo.CountByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookBacklog{
+		Pending: counts[domain.WebhookStatusPending],
+		Failed:  counts[domain.WebhookStatusFailed],
+	}, nil
+}
+
+// replay loads matching rows and processes them sequentially
+func (s *ReplayService) replay(ctx context.Context, filter domain.WebhookReplayFilter) (*ReplayResult, error) {
+	logs, err := s.webhookRepo.FindForReplay(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("find webhooks for replay: %w", err)
+	}
+	if len(logs) == 0 {
+		return &ReplayResult{}, nil
+	}
+
+	// Listed after the rows: a job Acked meanwhile is merely skipped until the next run
+	pending, err := s.queue.PendingLogIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list queued webhooks: %w", err)
+	}
+
+	result := &ReplayResult{}
+	for _, log := range logs {
+		if ctx.Err() != nil {
+			break
+		}
+		if pending[log.ID] {
+			result.Skipped++
+			continue
+		}
+		s.replayOne(ctx, log, result)
+	}
+
+	return result, nil
+}
+
+// replayOne pushes a logged payload through the dispatcher; ProcessJob updates
+// status / retry_count / error_log / last_attempt_at on the same row
+func (s *ReplayService) replayOne(ctx context.Context, log *domain.WebhookLog, result *ReplayResult) {
+	result.Attempted++
+
+	job := &domain.WebhookJob{
+		LogID:      log.ID,
+		Platform:   log.Platform,
+		Payload:    log.PayloadJSON,
+		EnqueuedAt: log.CreatedAt,
+	}
+
+	if err := s.dispatcher.ProcessJob(ctx, job); err != nil {
+		result.Failed++
+		result.Errors = append(result.Errors, ReplayError{
+			WebhookID: log.ID,
+			Error:     err.Error(),
+		})
+		slog.Warn("Webhook replay failed",
+			"webhook_id", log.ID,
+			"retry_count", log.RetryCount+1,
+			"error", err,
+		)
+		return
+	}
+
+	result.Succeeded++
+	slog.Info("Webhook replayed successfully",
+		"webhook_id", log.ID,
+		"platform", log.Platform,
+	)
+}
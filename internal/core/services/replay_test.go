@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// fakeWebhookLogs lists every log for replay and records status updates
+type fakeWebhookLogs struct {
+	ports.WebhookRepository
+	mu       sync.Mutex
+	logs     []*domain.WebhookLog
+	statuses map[int64]string
+}
+
+func (f *fakeWebhookLogs) GetLog(ctx context.Context, id int64) (*domain.WebhookLog, error) {
+	for _, log := range f.logs {
+		if log.ID == id {
+			return log, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeWebhookLogs) FindForReplay(ctx context.Context, filter domain.WebhookReplayFilter) ([]*domain.WebhookLog, error) {
+	return f.logs, nil
+}
+
+func (f *fakeWebhookLogs) CountByStatus(ctx context.Context) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, log := range f.logs {
+		status := log.Status
+		if updated, ok := f.statuses[log.ID]; ok {
+			status = updated
+		}
+		counts[status]++
+	}
+	return counts, nil
+}
+
+func (f *fakeWebhookLogs) UpdateStatus(ctx context.Context, id int64, status string, errorLog *string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses[id] = status
+	return nil
+}
+
+// fakeQueue reports a fixed set of log IDs as pending in the consumer group
+type fakeQueue struct {
+	ports.WebhookQueue
+	pending map[int64]bool
+}
+
+func (f *fakeQueue) PendingLogIDs(ctx context.Context) (map[int64]bool, error) {
+	return f.pending, nil
+}
+
+// fakeMessages stores messages by external ID; racing lists IDs another worker
+// stores between the Exists check and the insert
+type fakeMessages struct {
+	ports.MessageRepository
+	mu     sync.Mutex
+	saved  map[string]bool
+	racing map[string]bool
+}
+
+func (f *fakeMessages) Exists(ctx context.Context, id string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.saved[id], nil
+}
+
+func (f *fakeMessages) SaveMessage(ctx context.Context, msg *domain.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.saved[*msg.ExternalMsgID] || f.racing[*msg.ExternalMsgID] {
+		return ports.ErrDuplicateMessage
+	}
+	f.saved[*msg.ExternalMsgID] = true
+	return nil
+}
+
+type fakeConversations struct{ ports.ConversationRepository }
+
+func (fakeConversations) GetOrCreateByPlatformID(ctx context.Context, tenantID int, platform, platformID, pageID string) (int64, error) {
+	return 100, nil
+}
+
+func (fakeConversations) ReopenConversation(ctx context.Context, conversationID int64) (string, error) {
+	return "", nil
+}
+
+type fakeDedup struct{}
+
+func (fakeDedup) IsDuplicate(ctx context.Context, eventID string) (bool, error) { return false, nil }
+
+func (fakeDedup) MarkProcessed(ctx context.Context, eventID string, ttl time.Duration) error {
+	return nil
+}
+
+// fakePages knows one active page of tenant 1; the cache always misses
+type fakePages struct{}
+
+func (fakePages) GetPage(ctx context.Context, platform, pageID string) (*domain.Page, error) {
+	return &domain.Page{TenantID: 1, Platform: platform, PageID: pageID, IsActive: true}, nil
+}
+
+func (fakePages) GetPageTenant(ctx context.Context, platform, pageID string) (int, bool, error) {
+	return 0, false, nil
+}
+
+func (fakePages) SetPageTenant(ctx context.Context, platform, pageID string, tenantID int, ttl time.Duration) error {
+	return nil
+}
+
+func (fakePages) DeletePageTenant(ctx context.Context, platform, pageID string) error { return nil }
+
+// fakeChannel parses a payload of one JSON string into a customer message with that ID
+type fakeChannel struct{}
+
+func (fakeChannel) Platform() string { return "fake" }
+
+func (fakeChannel) VerifySignature(header http.Header, body []byte) error { return nil }
+
+func (fakeChannel) ParseEvents(payload []byte) ([]domain.InboundEvent, error) {
+	var mid string
+	if err := json.Unmarshal(payload, &mid); err != nil {
+		return nil, err
+	}
+	return []domain.InboundEvent{{
+		Platform:      "fake",
+		Type:          domain.EventTypeMessage,
+		PageID:        "page",
+		SenderID:      "customer",
+		ExternalMsgID: mid,
+		Content:       "hello",
+	}}, nil
+}
+
+func (fakeChannel) SendText(ctx context.Context, target domain.OutboundTarget, text string) (string, error) {
+	return "", errors.New("not used")
+}
+
+func newReplayTest(pending map[int64]bool, logs ...*domain.WebhookLog) (*ReplayService, *fakeWebhookLogs, *fakeMessages) {
+	webhooks := &fakeWebhookLogs{logs: logs, statuses: map[int64]string{}}
+	messages := &fakeMessages{saved: map[string]bool{}, racing: map[string]bool{}}
+	queue := &fakeQueue{pending: pending}
+	dispatcher := NewDispatcher(webhooks, messages, fakeConversations{}, fakeDedup{}, queue,
+		NewPlatformRegistry(fakeChannel{}), NewTenantResolver(fakePages{}, fakePages{}), nil, nil, nil, nil)
+	return NewReplayService(dispatcher, webhooks, queue, ReplayConfig{}), webhooks, messages
+}
+
+func webhookLog(id int64, mid string) *domain.WebhookLog {
+	payload, _ := json.Marshal(mid)
+	return &domain.WebhookLog{ID: id, Platform: "fake", PayloadJSON: payload, Status: domain.WebhookStatusFailed}
+}
+
+func TestReplayDueSkipsJobsPendingInQueue(t *testing.T) {
+	replay, webhooks, messages := newReplayTest(map[int64]bool{2: true},
+		webhookLog(1, "mid.1"), webhookLog(2, "mid.2"))
+
+	result, err := replay.ReplayDue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Attempted != 1 || result.Succeeded != 1 || result.Skipped != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if webhooks.statuses[1] != domain.WebhookStatusProcessed {
+		t.Errorf("log 1 status = %q", webhooks.statuses[1])
+	}
+	if _, touched := webhooks.statuses[2]; touched || messages.saved["mid.2"] {
+		t.Error("log 2 was replayed while its job is pending in the queue")
+	}
+}
+
+func TestReplayByIDRefusesJobPendingInQueue(t *testing.T) {
+	replay, _, _ := newReplayTest(map[int64]bool{1: true}, webhookLog(1, "mid.1"))
+
+	if _, err := replay.ReplayByID(context.Background(), 1); !errors.Is(err, ErrWebhookQueued) {
+		t.Errorf("err = %v, want ErrWebhookQueued", err)
+	}
+	if _, err := replay.ReplayByID(context.Background(), 9); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("err = %v, want ErrWebhookNotFound", err)
+	}
+}
+
+func TestReplayOfConcurrentlyStoredMessageIsProcessed(t *testing.T) {
+	replay, webhooks, messages := newReplayTest(nil, webhookLog(1, "mid.1"))
+	messages.racing["mid.1"] = true // Exists misses, the unique key catches it
+
+	result, err := replay.ReplayDue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Succeeded != 1 || result.Failed != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if webhooks.statuses[1] != domain.WebhookStatusProcessed {
+		t.Errorf("status = %q, want processed", webhooks.statuses[1])
+	}
+}
+
+func TestBacklogCountsWebhooksLeftToProcess(t *testing.T) {
+	pending := webhookLog(3, "mid.3")
+	pending.Status = domain.WebhookStatusPending
+	replay, webhooks, _ := newReplayTest(nil, webhookLog(1, "mid.1"), webhookLog(2, "mid.2"), pending)
+	webhooks.statuses[1] = domain.WebhookStatusProcessed
+
+	backlog, err := replay.Backlog(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backlog.Pending != 1 || backlog.Failed != 1 {
+		t.Errorf("backlog = %+v, want 1 pending, 1 failed", backlog)
+	}
+}
@@ -0,0 +1,226 @@
+// Package services contains full-text search over messages and customers
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// ErrInvalidSearch is returned for a search without usable words or with invalid filters
+var ErrInvalidSearch = errors.New("invalid search")
+
+const (
+	// DefaultSearchPageSize / MaxSearchPageSize bound the messages per search page
+	DefaultSearchPageSize = 20
+	MaxSearchPageSize     = 100
+
+	// MinSearchTermLength is the shortest word searched for; many Vietnamese words have
+	// two letters ("áo", "ăn"), so the database runs with innodb_ft_min_token_size = 2
+	MinSearchTermLength = 2
+
+	// maxSearchTerms bounds the words of a search
+	maxSearchTerms = 10
+
+	// searchCustomerLimit bounds the customer matches returned with the first page
+	searchCustomerLimit = 10
+
+	// highlightLength / highlightContext size the message excerpt (characters):
+	// the excerpt starts highlightContext characters before the first match
+	highlightLength  = 160
+	highlightContext = 40
+)
+
+// searchSenderTypes / searchMessageTypes are the accepted filter values (messages ENUMs)
+var (
+	searchSenderTypes = map[string]bool{
+		domain.SenderTypeUser:  true,
+		domain.SenderTypeBot:   true,
+		domain.SenderTypeAgent: true,
+	}
+	searchMessageTypes = map[string]bool{
+		domain.MessageTypeText:            true,
+		domain.MessageTypeImage:           true,
+		domain.MessageTypeFile:            true,
+		domain.MessageTypeSticker:         true,
+		domain.MessageTypeVoice:           true,
+		domain.AttachmentTypeVideo:        true,
+		domain.AttachmentTypeAudio:        true,
+		domain.AttachmentTypeShare:        true,
+		domain.MessageTypeStoryReply:      true,
+		domain.AttachmentTypeStoryMention: true,
+		domain.MessageTypePostback:        true,
+		domain.MessageTypeOptin:           true,
+		domain.MessageTypeTemplate:        true,
+	}
+)
+
+// SearchService finds messages and customers by text within the staff's data scope
+type SearchService struct {
+	search ports.SearchRepository
+}
+
+// NewSearchService creates a search service
+func NewSearchService(search ports.SearchRepository) *SearchService {
+	return &SearchService{
+		search: search,
+	}
+}
+
+// Search matches every word of text (as a word prefix, case-insensitive) against message
+// content and customer names; query carries the filters, its Terms are set from text
+// Customers are only searched for the first page (no cursor)
+func (s *SearchService) Search(ctx context.Context, scope domain.ConversationScope, text string, query domain.SearchQuery, page domain.PageQuery) (*domain.SearchResults, domain.PageCursors, error) {
+	query.Terms = searchTerms(text)
+	if len(query.Terms) == 0 {
+		return nil, domain.PageCursors{}, ErrInvalidSearch
+	}
+	if query.SenderType != "" && !searchSenderTypes[query.SenderType] {
+		return nil, domain.PageCursors{}, ErrInvalidSearch
+	}
+	if query.MessageType != "" && !searchMessageTypes[query.MessageType] {
+		return nil, domain.PageCursors{}, ErrInvalidSearch
+	}
+	if query.From != nil && query.To != nil && !query.To.After(*query.From) {
+		return nil, domain.PageCursors{}, ErrInvalidSearch
+	}
+	if page.Limit <= 0 || page.Limit > MaxSearchPageSize {
+		page.Limit = DefaultSearchPageSize
+	}
+
+	messages, cursors, err := s.search.SearchMessages(ctx, scope, query, page)
+	if err != nil {
+		return nil, domain.PageCursors{}, fmt.Errorf("search messages: %w", err)
+	}
+	for i := range messages {
+		messages[i].Highlight = highlight(messages[i].Content, query.Terms, highlightLength)
+	}
+
+	customers := []domain.CustomerHit{}
+	if page.Before == nil && page.After == nil {
+		found, err := s.search.SearchCustomers(ctx, scope, query, searchCustomerLimit)
+		if err != nil {
+			return nil, domain.PageCursors{}, fmt.Errorf("search customers: %w", err)
+		}
+		for i := range found {
+			found[i].Highlight = highlight(found[i].CustomerName, query.Terms, 0)
+		}
+		customers = append(customers, found...)
+	}
+
+	if messages == nil {
+		messages = []domain.MessageHit{}
+	}
+	return &domain.SearchResults{Customers: customers, Messages: messages}, cursors, nil
+}
+
+// searchTerms splits text into lower-case words of letters and digits, dropping
+// words too short for the full-text index and duplicates
+func searchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if utf8.RuneCountInString(word) < MinSearchTermLength || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// highlight HTML-escapes text and wraps the words starting with a term in <mark>
+// With maxLength > 0 the result is an excerpt of about maxLength characters around
+// the first match (or the start of the text), cut ends are marked with "…"
+// Only case-insensitive exact matches are marked, accent-insensitive ones are not
+func highlight(text string, terms []string, maxLength int) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Matched words as [start, end) rune ranges, in order
+	var matches [][2]int
+	for i := 0; i < len(lower); i++ {
+		if i > 0 && isWordRune(lower[i-1]) {
+			continue
+		}
+		for _, term := range terms {
+			if !hasRunePrefix(lower[i:], []rune(term)) {
+				continue
+			}
+			end := i
+			for end < len(lower) && isWordRune(lower[end]) {
+				end++
+			}
+			matches = append(matches, [2]int{i, end})
+			i = end - 1
+			break
+		}
+	}
+
+	start, end := 0, len(runes)
+	if maxLength > 0 && len(runes) > maxLength {
+		if len(matches) > 0 && matches[0][0] > highlightContext {
+			start = matches[0][0] - highlightContext
+		}
+		end = start + maxLength
+		if end > len(runes) {
+			end = len(runes)
+			start = end - maxLength
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m[1] <= start || m[0] >= end {
+			continue
+		}
+		from, to := max(m[0], start), min(m[1], end)
+		b.WriteString(html.EscapeString(string(runes[pos:from])))
+		b.WriteString("<mark>" + html.EscapeString(string(runes[from:to])) + "</mark>")
+		pos = to
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// isWordRune reports whether r is part of a word (letter or digit)
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// hasRunePrefix reports whether s starts with prefix
+func hasRunePrefix(s, prefix []rune) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
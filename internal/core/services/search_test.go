@@ -0,0 +1,53 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	for name, tc := range map[string]struct {
+		text string
+		want []string
+	}{
+		"lower-cased words":         {"Đơn HÀNG 1234", []string{"đơn", "hàng", "1234"}},
+		"two-letter words are kept": {"áo ăn ở", []string{"áo", "ăn"}},
+		"punctuation splits":        {"giao-hàng, nhanh!", []string{"giao", "hàng", "nhanh"}},
+		"duplicates dropped":        {"ship Ship SHIP", []string{"ship"}},
+		"boolean operators dropped": {`+"abc" -def* (ghi)`, []string{"abc", "def", "ghi"}},
+		"only short words":          {"a b c", nil},
+		"empty":                     {"   ", nil},
+		"at most maxSearchTerms":    {strings.Repeat("aa bb cc dd ee ff gg hh ii jj kk ll ", 2), []string{"aa", "bb", "cc", "dd", "ee", "ff", "gg", "hh", "ii", "jj"}},
+	} {
+		if got := searchTerms(tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: searchTerms(%q) = %q, want %q", name, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("x", 100) + " đơn hàng " + strings.Repeat("y", 100)
+
+	for name, tc := range map[string]struct {
+		text      string
+		terms     []string
+		maxLength int
+		want      string
+	}{
+		"word prefix, case-insensitive": {"Đơn hàng đã giao", []string{"đơn", "gia"}, 0, "<mark>Đơn</mark> hàng đã <mark>giao</mark>"},
+		"not inside a word":             {"hoahong hong", []string{"hong"}, 0, "hoahong <mark>hong</mark>"},
+		"no match":                      {"xin chào", []string{"ship"}, 0, "xin chào"},
+		"text is escaped":               {`<b>"áo"</b> & quần`, []string{"quần"}, 0, "&lt;b&gt;&#34;áo&#34;&lt;/b&gt; &amp; <mark>quần</mark>"},
+		"script inside a match":         {"alert<script>alert(1)</script>", []string{"alert"}, 0, "<mark>alert</mark>&lt;script&gt;<mark>alert</mark>(1)&lt;/script&gt;"},
+		"script tag as a match":         {"<script>alert(1)</script>", []string{"script"}, 0, "&lt;<mark>script</mark>&gt;alert(1)&lt;/<mark>script</mark>&gt;"},
+		"excerpt around the match": {long, []string{"hàng"}, 60,
+			"…" + strings.Repeat("x", 35) + " đơn <mark>hàng</mark> " + strings.Repeat("y", 15) + "…"},
+		"excerpt of a text without match": {long, []string{"zzz"}, 10, strings.Repeat("x", 10) + "…"},
+		"excerpt at the end":              {"abc " + strings.Repeat("y", 60) + " kết", []string{"kết"}, 60, "…" + strings.Repeat("y", 56) + " <mark>kết</mark>"},
+	} {
+		if got := highlight(tc.text, tc.terms, tc.maxLength); got != tc.want {
+			t.Errorf("%s:\n got %q\nwant %q", name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package services contains validation of structured outbound messages
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"immortal-chat/internal/core/domain"
+)
+
+// ErrInvalidStructuredMessage is returned for structured messages Messenger would reject
+// The wrapped message names the offending field
+var ErrInvalidStructuredMessage = errors.New("invalid structured message")
+
+// Messenger Send API limits (characters are counted as runes)
+const (
+	maxMessageTextLength     = 2000 // Text of a message with quick replies
+	maxButtonTemplateText    = 640
+	maxQuickReplies          = 13
+	maxQuickReplyTitle       = 20
+	maxPayloadLength         = 1000 // Quick reply and postback payloads
+	maxButtonTitle           = 20
+	maxTemplateButtons       = 3 // Button template and each generic element
+	maxMediaTemplateButtons  = 1
+	maxGenericElements       = 10
+	maxElementTitleLength    = 80
+	maxElementSubtitleLength = 80
+)
+
+// ValidateStructuredMessage checks a structured message against the Messenger limits
+// Fields that do not belong to the message type are rejected instead of silently dropped
+func ValidateStructuredMessage(msg domain.StructuredMessage) error {
+	if err := validateStructuredBody(msg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStructuredMessage, err)
+	}
+	if err := validateQuickReplies(msg.QuickReplies, msg.Type == domain.StructuredTypeQuickReplies); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStructuredMessage, err)
+	}
+	return nil
+}
+
+// StructuredPreview is the text stored as message content / conversation last message
+func StructuredPreview(msg domain.StructuredMessage) string {
+	switch msg.Type {
+	case domain.StructuredTypeGeneric:
+		if len(msg.Elements) == 0 {
+			return "🛍"
+		}
+		if len(msg.Elements) > 1 {
+			return fmt.Sprintf("🛍 %s (+%d)", msg.Elements[0].Title, len(msg.Elements)-1)
+		}
+		return "🛍 " + msg.Elements[0].Title
+	case domain.StructuredTypeMedia:
+		if msg.Media != nil && msg.Media.MediaType == domain.AttachmentTypeVideo {
+			return "🎬 Video"
+		}
+		return "🖼 Ảnh"
+	default:
+		return msg.Text
+	}
+}
+
+// validateStructuredBody checks the fields selected by msg.Type
+func validateStructuredBody(msg domain.StructuredMessage) error {
+	switch msg.Type {
+	case domain.StructuredTypeQuickReplies:
+		if len(msg.Buttons) > 0 || len(msg.Elements) > 0 || msg.Media != nil {
+			return errors.New("quick_replies only takes text and quick_replies")
+		}
+		return validateText("text", msg.Text, maxMessageTextLength)
+
+	case domain.StructuredTypeButton:
+		if len(msg.Elements) > 0 || msg.Media != nil {
+			return errors.New("button template only takes text and buttons")
+		}
+		if err := validateText("text", msg.Text, maxButtonTemplateText); err != nil {
+			return err
+		}
+		return validateButtons("buttons", msg.Buttons, 1, maxTemplateButtons)
+
+	case domain.StructuredTypeGeneric:
+		if msg.Text != "" || len(msg.Buttons) > 0 || msg.Media != nil {
+			return errors.New("generic template only takes elements")
+		}
+		if len(msg.Elements) == 0 || len(msg.Elements) > maxGenericElements {
+			return fmt.Errorf("elements: 1 to %d required", maxGenericElements)
+		}
+		for i, element := range msg.Elements {
+			if err := validateElement(element); err != nil {
+				return fmt.Errorf("elements[%d].%v", i, err)
+			}
+		}
+		return nil
+
+	case domain.StructuredTypeMedia:
+		if msg.Text != "" || len(msg.Buttons) > 0 || len(msg.Elements) > 0 {
+			return errors.New("media template only takes media")
+		}
+		if msg.Media == nil {
+			return errors.New("media: required")
+		}
+		return validateMedia(*msg.Media)
+
+	default:
+		return fmt.Errorf("type: must be %s, %s, %s or %s", domain.StructuredTypeQuickReplies,
+			domain.StructuredTypeButton, domain.StructuredTypeGeneric, domain.StructuredTypeMedia)
+	}
+}
+
+// validateQuickReplies checks the chips; required is set for quick_replies messages
+func validateQuickReplies(replies []domain.QuickReply, required bool) error {
+	if required && len(replies) == 0 {
+		return errors.New("quick_replies: at least 1 required")
+	}
+	if len(replies) > maxQuickReplies {
+		return fmt.Errorf("quick_replies: at most %d", maxQuickReplies)
+	}
+	for i, reply := range replies {
+		switch reply.ContentType {
+		case domain.QuickReplyText:
+			if err := validateText("title", reply.Title, maxQuickReplyTitle); err != nil {
+				return fmt.Errorf("quick_replies[%d].%v", i, err)
+			}
+			if err := validateText("payload", reply.Payload, maxPayloadLength); err != nil {
+				return fmt.Errorf("quick_replies[%d].%v", i, err)
+			}
+			if reply.ImageURL != "" && !isWebURL(reply.ImageURL) {
+				return fmt.Errorf("quick_replies[%d].image_url: must be an http(s) URL", i)
+			}
+		case domain.QuickReplyPhone, domain.QuickReplyEmail:
+			if reply.Title != "" || reply.Payload != "" || reply.ImageURL != "" {
+				return fmt.Errorf("quick_replies[%d]: %s takes no title, payload or image_url", i, reply.ContentType)
+			}
+		default:
+			return fmt.Errorf("quick_replies[%d].content_type: must be %s, %s or %s", i,
+				domain.QuickReplyText, domain.QuickReplyPhone, domain.QuickReplyEmail)
+		}
+	}
+	return nil
+}
+
+// validateButtons checks between least and most template buttons
+func validateButtons(field string, buttons []domain.MessageButton, least, most int) error {
+	if len(buttons) < least || len(buttons) > most {
+		return fmt.Errorf("%s: %d to %d required", field, least, most)
+	}
+	for i, button := range buttons {
+		if err := validateText("title", button.Title, maxButtonTitle); err != nil {
+			return fmt.Errorf("%s[%d].%v", field, i, err)
+		}
+		switch button.Type {
+		case domain.ButtonWebURL:
+			if !isWebURL(button.URL) || button.Payload != "" {
+				return fmt.Errorf("%s[%d]: web_url takes an http(s) url and no payload", field, i)
+			}
+		case domain.ButtonPostback:
+			if button.URL != "" {
+				return fmt.Errorf("%s[%d]: postback takes no url", field, i)
+			}
+			if err := validateText("payload", button.Payload, maxPayloadLength); err != nil {
+				return fmt.Errorf("%s[%d].%v", field, i, err)
+			}
+		case domain.ButtonPhoneNumber:
+			if button.URL != "" || !isPhoneNumber(button.Payload) {
+				return fmt.Errorf("%s[%d]: phone_number takes the number as payload (+84...)", field, i)
+			}
+		default:
+			return fmt.Errorf("%s[%d].type: must be %s, %s or %s", field, i,
+				domain.ButtonWebURL, domain.ButtonPostback, domain.ButtonPhoneNumber)
+		}
+	}
+	return nil
+}
+
+// validateElement checks one generic template card: a title plus at least one other field
+func validateElement(element domain.TemplateElement) error {
+	if err := validateText("title", element.Title, maxElementTitleLength); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(element.Subtitle) > maxElementSubtitleLength {
+		return fmt.Errorf("subtitle: at most %d characters", maxElementSubtitleLength)
+	}
+	if element.ImageURL != "" && !isWebURL(element.ImageURL) {
+		return errors.New("image_url: must be an http(s) URL")
+	}
+	if element.DefaultURL != "" && !isWebURL(element.DefaultURL) {
+		return errors.New("default_url: must be an http(s) URL")
+	}
+	if element.Subtitle == "" && element.ImageURL == "" && element.DefaultURL == "" && len(element.Buttons) == 0 {
+		return errors.New("subtitle, image_url, default_url or buttons required next to title")
+	}
+	return validateButtons("buttons", element.Buttons, 0, maxTemplateButtons)
+}
+
+// validateMedia checks the media of a media template
+func validateMedia(media domain.MediaElement) error {
+	if media.MediaType != domain.AttachmentTypeImage && media.MediaType != domain.AttachmentTypeVideo {
+		return errors.New("media.media_type: must be image or video")
+	}
+	if (media.AttachmentID == "") == (media.URL == "") {
+		return errors.New("media: exactly one of attachment_id or url required")
+	}
+	if media.URL != "" && !isWebURL(media.URL) {
+		return errors.New("media.url: must be an http(s) URL")
+	}
+	return validateButtons("media.buttons", media.Buttons, 0, maxMediaTemplateButtons)
+}
+
+// validateText requires a non-blank value of at most maxLength characters
+func validateText(field, value string, maxLength int) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("%s: required", field)
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		return fmt.Errorf("%s: at most %d characters", field, maxLength)
+	}
+	return nil
+}
+
+// isWebURL reports whether s is an absolute http(s) URL
+func isWebURL(s string) bool {
+	return (strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")) && !strings.ContainsAny(s, " \t\r\n")
+}
+
+// isPhoneNumber reports whether s is "+" followed by 6 to 15 digits (E.164)
+func isPhoneNumber(s string) bool {
+	digits, ok := strings.CutPrefix(s, "+")
+	if !ok || len(digits) < 6 || len(digits) > 15 {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
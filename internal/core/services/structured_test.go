@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"immortal-chat/internal/core/domain"
+)
+
+func TestValidateStructuredMessageLimits(t *testing.T) {
+	postback := domain.MessageButton{Type: domain.ButtonPostback, Title: "Mua ngay", Payload: "BUY"}
+	chip := domain.QuickReply{ContentType: domain.QuickReplyText, Title: "Có", Payload: "YES"}
+	card := domain.TemplateElement{Title: "Áo thun", Subtitle: "199.000đ"}
+	buttons := func(n int) []domain.MessageButton {
+		list := make([]domain.MessageButton, n)
+		for i := range list {
+			list[i] = postback
+		}
+		return list
+	}
+	chips := func(n int) []domain.QuickReply {
+		list := make([]domain.QuickReply, n)
+		for i := range list {
+			list[i] = chip
+		}
+		return list
+	}
+	cards := func(n int) []domain.TemplateElement {
+		list := make([]domain.TemplateElement, n)
+		for i := range list {
+			list[i] = card
+		}
+		return list
+	}
+
+	// field is part of the error message; "" means the message is valid
+	for name, tc := range map[string]struct {
+		msg   domain.StructuredMessage
+		field string
+	}{
+		"13 quick replies": {domain.StructuredMessage{Type: domain.StructuredTypeQuickReplies, Text: "Chọn", QuickReplies: chips(13)}, ""},
+		"14 quick replies": {domain.StructuredMessage{Type: domain.StructuredTypeQuickReplies, Text: "Chọn", QuickReplies: chips(14)}, "quick_replies: at most 13"},
+		"no quick replies": {domain.StructuredMessage{Type: domain.StructuredTypeQuickReplies, Text: "Chọn"}, "quick_replies: at least 1"},
+		"chip title counted in characters": {domain.StructuredMessage{Type: domain.StructuredTypeQuickReplies, Text: "Chọn",
+			QuickReplies: []domain.QuickReply{{ContentType: domain.QuickReplyText, Title: strings.Repeat("ạ", 20), Payload: "P"}}}, ""},
+		"chip title too long": {domain.StructuredMessage{Type: domain.StructuredTypeQuickReplies, Text: "Chọn",
+			QuickReplies: []domain.QuickReply{{ContentType: domain.QuickReplyText, Title: strings.Repeat("ạ", 21), Payload: "P"}}}, "quick_replies[0].title"},
+		"phone chip with title": {domain.StructuredMessage{Type: domain.StructuredTypeQuickReplies, Text: "Chọn",
+			QuickReplies: []domain.QuickReply{{ContentType: domain.QuickReplyPhone, Title: "SĐT"}}}, "quick_replies[0]"},
+		"quick reply text too long": {domain.StructuredMessage{Type: domain.StructuredTypeQuickReplies, Text: strings.Repeat("a", 2001), QuickReplies: chips(1)}, "text: at most 2000"},
+
+		"3 buttons":             {domain.StructuredMessage{Type: domain.StructuredTypeButton, Text: "Xin chào", Buttons: buttons(3)}, ""},
+		"4 buttons":             {domain.StructuredMessage{Type: domain.StructuredTypeButton, Text: "Xin chào", Buttons: buttons(4)}, "buttons: 1 to 3"},
+		"no buttons":            {domain.StructuredMessage{Type: domain.StructuredTypeButton, Text: "Xin chào"}, "buttons: 1 to 3"},
+		"button text too long":  {domain.StructuredMessage{Type: domain.StructuredTypeButton, Text: strings.Repeat("a", 641), Buttons: buttons(1)}, "text: at most 640"},
+		"button title too long": {domain.StructuredMessage{Type: domain.StructuredTypeButton, Text: "Xin chào", Buttons: []domain.MessageButton{{Type: domain.ButtonPostback, Title: strings.Repeat("a", 21), Payload: "P"}}}, "buttons[0].title"},
+		"payload too long":      {domain.StructuredMessage{Type: domain.StructuredTypeButton, Text: "Xin chào", Buttons: []domain.MessageButton{{Type: domain.ButtonPostback, Title: "OK", Payload: strings.Repeat("a", 1001)}}}, "buttons[0].payload"},
+		"web_url without http":  {domain.StructuredMessage{Type: domain.StructuredTypeButton, Text: "Xin chào", Buttons: []domain.MessageButton{{Type: domain.ButtonWebURL, Title: "Xem", URL: "shop.vn"}}}, "buttons[0]: web_url"},
+		"phone without +":       {domain.StructuredMessage{Type: domain.StructuredTypeButton, Text: "Xin chào", Buttons: []domain.MessageButton{{Type: domain.ButtonPhoneNumber, Title: "Gọi", Payload: "0901234567"}}}, "buttons[0]: phone_number"},
+		"button with elements":  {domain.StructuredMessage{Type: domain.StructuredTypeButton, Text: "Xin chào", Buttons: buttons(1), Elements: cards(1)}, "button template only takes"},
+
+		"10 cards":               {domain.StructuredMessage{Type: domain.StructuredTypeGeneric, Elements: cards(10)}, ""},
+		"11 cards":               {domain.StructuredMessage{Type: domain.StructuredTypeGeneric, Elements: cards(11)}, "elements: 1 to 10"},
+		"card title too long":    {domain.StructuredMessage{Type: domain.StructuredTypeGeneric, Elements: []domain.TemplateElement{{Title: strings.Repeat("a", 81), Subtitle: "s"}}}, "elements[0].title"},
+		"card subtitle too long": {domain.StructuredMessage{Type: domain.StructuredTypeGeneric, Elements: []domain.TemplateElement{{Title: "Áo", Subtitle: strings.Repeat("a", 81)}}}, "elements[0].subtitle"},
+		"card with only a title": {domain.StructuredMessage{Type: domain.StructuredTypeGeneric, Elements: []domain.TemplateElement{{Title: "Áo"}}}, "elements[0].subtitle, image_url"},
+		"card with 4 buttons":    {domain.StructuredMessage{Type: domain.StructuredTypeGeneric, Elements: []domain.TemplateElement{{Title: "Áo", Buttons: buttons(4)}}}, "elements[0].buttons: 0 to 3"},
+
+		"media by url":         {domain.StructuredMessage{Type: domain.StructuredTypeMedia, Media: &domain.MediaElement{MediaType: "image", URL: "https://www.facebook.com/photo/1", Buttons: buttons(1)}}, ""},
+		"media with 2 buttons": {domain.StructuredMessage{Type: domain.StructuredTypeMedia, Media: &domain.MediaElement{MediaType: "image", AttachmentID: "1", Buttons: buttons(2)}}, "media.buttons: 0 to 1"},
+		"media url and id":     {domain.StructuredMessage{Type: domain.StructuredTypeMedia, Media: &domain.MediaElement{MediaType: "video", AttachmentID: "1", URL: "https://www.facebook.com/v/1"}}, "exactly one of"},
+		"media audio":          {domain.StructuredMessage{Type: domain.StructuredTypeMedia, Media: &domain.MediaElement{MediaType: "audio", AttachmentID: "1"}}, "media.media_type"},
+		"media missing":        {domain.StructuredMessage{Type: domain.StructuredTypeMedia}, "media: required"},
+
+		"chips under a template": {domain.StructuredMessage{Type: domain.StructuredTypeGeneric, Elements: cards(1), QuickReplies: chips(14)}, "quick_replies: at most 13"},
+		"unknown type":           {domain.StructuredMessage{Type: "list", Text: "Xin chào"}, "type: must be"},
+	} {
+		err := ValidateStructuredMessage(tc.msg)
+		if tc.field == "" {
+			if err != nil {
+				t.Errorf("%s: err = %v", name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidStructuredMessage) || !strings.Contains(err.Error(), tc.field) {
+			t.Errorf("%s: err = %v, want ErrInvalidStructuredMessage naming %q", name, err, tc.field)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package services contains conversation tagging
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+var (
+	// ErrInvalidTag is returned for an empty / too long tag name or a malformed color
+	ErrInvalidTag = errors.New("invalid tag")
+
+	// ErrTagExists is returned when the tenant already has a tag with that name
+	ErrTagExists = errors.New("tag already exists")
+
+	// ErrTagNotFound is returned for a tag that does not exist in the tenant
+	ErrTagNotFound = errors.New("tag not found")
+)
+
+const (
+	// MaxTagNameLength is the longest tag name (characters), see tags.name
+	MaxTagNameLength = 50
+
+	// DefaultTagColor is used when a tag is created without a color
+	DefaultTagColor = "#6B7280"
+)
+
+// tagColorPattern accepts "#RRGGBB"
+var tagColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// TagService manages tenant tag definitions and the tags of conversations
+type TagService struct {
+	tags      ports.TagRepository
+	publisher ports.EventPublisher // Optional: nil disables live dashboard updates
+	now       func() time.Time
+}
+
+// NewTagService creates a tag service
+func NewTagService(tags ports.TagRepository, publisher ports.EventPublisher) *TagService {
+	return &TagService{
+		tags:      tags,
+		publisher: publisher,
+		now:       time.Now,
+	}
+}
+
+// ListTags returns the tenant's tags with conversation counts within the staff's data scope
+func (s *TagService) ListTags(ctx context.Context, scope domain.ConversationScope) ([]*domain.Tag, error) {
+	tags, err := s.tags.ListTags(ctx, scope)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	return tags, nil
+}
+
+// CreateTag adds a tag definition to the tenant (ErrTagExists if the name is taken)
+func (s *TagService) CreateTag(ctx context.Context, tenantID int, name, color string) (*domain.Tag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxTagNameLength {
+		return nil, ErrInvalidTag
+	}
+	if color == "" {
+		color = DefaultTagColor
+	}
+	if !tagColorPattern.MatchString(color) {
+		return nil, ErrInvalidTag
+	}
+
+	existing, err := s.tags.ListTags(ctx, domain.ConversationScope{TenantID: tenantID})
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	for _, tag := range existing {
+		if strings.EqualFold(tag.Name, name) {
+			return nil, ErrTagExists
+		}
+	}
+
+	tag := &domain.Tag{
+		TenantID:  tenantID,
+		Name:      name,
+		Color:     strings.ToUpper(color),
+		CreatedAt: s.now(),
+	}
+	if err := s.tags.CreateTag(ctx, tag); err != nil {
+		return nil, fmt.Errorf("create tag: %w", err)
+	}
+	return tag, nil
+}
+
+// DeleteTag removes a tag definition; conversations carrying it lose the tag
+func (s *TagService) DeleteTag(ctx context.Context, tenantID, tagID int) error {
+	tag, err := s.tags.GetTag(ctx, tenantID, tagID)
+	if err != nil {
+		return fmt.Errorf("get tag: %w", err)
+	}
+	if tag == nil {
+		return ErrTagNotFound
+	}
+	if err := s.tags.DeleteTag(ctx, tag); err != nil {
+		return fmt.Errorf("delete tag: %w", err)
+	}
+	return nil
+}
+
+// TagConversation adds (present = true) or removes a tag on a conversation within the staff's data scope
+// Returns ErrTagNotFound for an unknown tag, ErrConversationNotFound outside the scope
+func (s *TagService) TagConversation(ctx context.Context, scope domain.ConversationScope, conversationID int64, tagID int, present bool) (*domain.ConversationTags, error) {
+	tag, err := s.tags.GetTag(ctx, scope.TenantID, tagID)
+	if err != nil {
+		return nil, fmt.Errorf("get tag: %w", err)
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+
+	names, err := s.tags.SetConversationTag(ctx, scope, conversationID, tag.Name, present)
+	if err != nil {
+		return nil, fmt.Errorf("set conversation tag: %w", err)
+	}
+	if names == nil {
+		return nil, ErrConversationNotFound
+	}
+
+	result := &domain.ConversationTags{ConversationID: conversationID, Tags: names}
+	if s.publisher != nil {
+		s.publisher.Publish(ctx, domain.DashboardEvent{
+			Type:           domain.DashboardEventConversationTagged,
+			ConversationID: conversationID,
+			Data:           result,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// memTags keeps tags in memory; conversations 1 and 2 belong to tenant 1,
+// conversation 2 is assigned to staff 5
+type memTags struct {
+	ports.TagRepository
+	tags          []*domain.Tag
+	conversations map[int64][]string
+}
+
+func (m *memTags) ListTags(ctx context.Context, scope domain.ConversationScope) ([]*domain.Tag, error) {
+	var list []*domain.Tag
+	for _, tag := range m.tags {
+		if tag.TenantID == scope.TenantID {
+			list = append(list, tag)
+		}
+	}
+	return list, nil
+}
+
+func (m *memTags) GetTag(ctx context.Context, tenantID, tagID int) (*domain.Tag, error) {
+	for _, tag := range m.tags {
+		if tag.TenantID == tenantID && tag.ID == tagID {
+			return tag, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memTags) CreateTag(ctx context.Context, tag *domain.Tag) error {
+	tag.ID = len(m.tags) + 1
+	m.tags = append(m.tags, tag)
+	return nil
+}
+
+func (m *memTags) DeleteTag(ctx context.Context, tag *domain.Tag) error {
+	for i, existing := range m.tags {
+		if existing == tag {
+			m.tags = append(m.tags[:i], m.tags[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func (m *memTags) SetConversationTag(ctx context.Context, scope domain.ConversationScope, conversationID int64, name string, present bool) ([]string, error) {
+	conversation := &domain.ConversationAssignment{ConversationID: conversationID, TenantID: 1}
+	if conversationID == 2 {
+		conversation.AssigneeID = intPtr(5)
+	}
+	if conversationID > 2 || !scope.Includes(conversation) {
+		return nil, nil
+	}
+
+	names := []string{}
+	for _, existing := range m.conversations[conversationID] {
+		if existing != name {
+			names = append(names, existing)
+		}
+	}
+	if present {
+		names = append(names, name)
+	}
+	m.conversations[conversationID] = names
+	return names, nil
+}
+
+func TestCreateTagValidation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		name, color string
+		wantColor   string
+		wantErr     error
+	}{
+		"default color":                   {"VIP", "", DefaultTagColor, nil},
+		"color is uppercased":             {"Hot lead", "#ff00aa", "#FF00AA", nil},
+		"name is trimmed":                 {"  Khách sỉ  ", "#00FF00", "#00FF00", nil},
+		"empty name":                      {"   ", "", "", ErrInvalidTag},
+		"name too long":                   {strings.Repeat("ă", MaxTagNameLength+1), "", "", ErrInvalidTag},
+		"short color":                     {"VIP", "#FFF", "", ErrInvalidTag},
+		"named color":                     {"VIP", "red", "", ErrInvalidTag},
+		"duplicate, any case":             {"spam", "", "", ErrTagExists},
+		"same name, other tenant is fine": {"Refund", "", DefaultTagColor, nil},
+	} {
+		repo := &memTags{tags: []*domain.Tag{
+			{ID: 1, TenantID: 1, Name: "Spam"},
+			{ID: 2, TenantID: 2, Name: "Refund"},
+		}}
+		tag, err := NewTagService(repo, nil).CreateTag(context.Background(), 1, tc.name, tc.color)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if tag.ID == 0 || tag.TenantID != 1 || tag.Name != strings.TrimSpace(tc.name) || tag.Color != tc.wantColor {
+			t.Errorf("%s: created %+v", name, tag)
+		}
+	}
+}
+
+func TestTagConversationWithinScope(t *testing.T) {
+	repo := &memTags{
+		tags:          []*domain.Tag{{ID: 1, TenantID: 1, Name: "VIP"}, {ID: 2, TenantID: 2, Name: "Other"}},
+		conversations: map[int64][]string{},
+	}
+	publisher := &recordingPublisher{}
+	service := NewTagService(repo, publisher)
+	ctx := context.Background()
+
+	owner := domain.ConversationScope{TenantID: 1, StaffID: 1}
+	agent := domain.ConversationScope{TenantID: 1, StaffID: 5, AssignedOnly: true}
+
+	for name, tc := range map[string]struct {
+		scope          domain.ConversationScope
+		conversationID int64
+		tagID          int
+		want           error
+	}{
+		"agent, other agent's conversation": {agent, 1, 1, ErrConversationNotFound},
+		"unknown conversation":              {owner, 9, 1, ErrConversationNotFound},
+		"other tenant's tag":                {owner, 1, 2, ErrTagNotFound},
+		"agent, assigned conversation":      {agent, 2, 1, nil},
+		"owner, any conversation":           {owner, 1, 1, nil},
+	} {
+		if _, err := service.TagConversation(ctx, tc.scope, tc.conversationID, tc.tagID, true); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+	if len(publisher.events) != 2 {
+		t.Errorf("%d events published, want 2 (successful changes only)", len(publisher.events))
+	}
+
+	// Removing is idempotent and returns the remaining tags
+	for i := 0; i < 2; i++ {
+		result, err := service.TagConversation(ctx, owner, 1, 1, false)
+		if err != nil || len(result.Tags) != 0 {
+			t.Errorf("remove #%d: %+v, %v", i+1, result, err)
+		}
+	}
+
+	if err := service.DeleteTag(ctx, 1, 2); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("delete other tenant's tag: err = %v", err)
+	}
+	if err := service.DeleteTag(ctx, 1, 1); err != nil || len(repo.tags) != 1 {
+		t.Errorf("delete: err = %v, %d tags left", err, len(repo.tags))
+	}
+}
@@ -0,0 +1,101 @@
+// Package services contains the page -> tenant resolution
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"immortal-chat/internal/core/ports"
+)
+
+var (
+	// ErrUnknownPage is returned for events addressed to a page that is not connected
+	ErrUnknownPage = errors.New("unknown page")
+
+	// ErrInactivePage is returned for events addressed to a deactivated page
+	ErrInactivePage = errors.New("inactive page")
+)
+
+const (
+	// pageTenantTTL is how long a resolved page -> tenant mapping is cached
+	// Changes made outside Invalidate take effect for inbound routing after at most this delay
+	pageTenantTTL = 5 * time.Minute
+
+	// unroutablePageTTL caches unknown / inactive pages briefly so a flood of
+	// events for an unconnected page does not hit the database each time
+	unroutablePageTTL = 30 * time.Second
+
+	// Cached tenant IDs of unroutable pages
+	cachedUnknownPage  = 0
+	cachedInactivePage = -1
+)
+
+// TenantResolver finds the tenant owning the page that received an event
+// Lookups go through the Redis cache first, then the pages table
+type TenantResolver struct {
+	pages ports.PageRepository
+	cache ports.PageCache
+}
+
+// NewTenantResolver creates a resolver backed by the pages table and a cache
+func NewTenantResolver(pages ports.PageRepository, cache ports.PageCache) *TenantResolver {
+	return &TenantResolver{
+		pages: pages,
+		cache: cache,
+	}
+}
+
+// Resolve returns the tenant ID of an active page
+// Returns ErrUnknownPage / ErrInactivePage when the event must not be stored
+func (r *TenantResolver) Resolve(ctx context.Context, platform, pageID string) (int, error) {
+	tenantID, found, err := r.cache.GetPageTenant(ctx, platform, pageID)
+	if err != nil {
+		// Cache is an optimization only: fall back to the database
+		slog.Warn("Page tenant cache lookup failed", "error", err, "platform", platform, "page_id", pageID)
+	} else if found {
+		switch tenantID {
+		case cachedUnknownPage:
+			return 0, fmt.Errorf("%w: %s/%s (cached)", ErrUnknownPage, platform, pageID)
+		case cachedInactivePage:
+			return 0, fmt.Errorf("%w: %s/%s (cached)", ErrInactivePage, platform, pageID)
+		}
+		return tenantID, nil
+	}
+
+	page, err := r.pages.GetPage(ctx, platform, pageID)
+	if err != nil {
+		return 0, fmt.Errorf("resolve tenant of page %s/%s: %w", platform, pageID, err)
+	}
+
+	var resolveErr error
+	cached, ttl := cachedUnknownPage, unroutablePageTTL
+	switch {
+	case page == nil:
+		resolveErr = fmt.Errorf("%w: %s/%s", ErrUnknownPage, platform, pageID)
+	case !page.IsActive:
+		resolveErr = fmt.Errorf("%w: %s/%s", ErrInactivePage, platform, pageID)
+		cached = cachedInactivePage
+	default:
+		cached, ttl = page.TenantID, pageTenantTTL
+	}
+
+	if err := r.cache.SetPageTenant(ctx, platform, pageID, cached, ttl); err != nil {
+		slog.Warn("Failed to cache page tenant", "error", err, "platform", platform, "page_id", pageID)
+	}
+
+	if resolveErr != nil {
+		return 0, resolveErr
+	}
+	return page.TenantID, nil
+}
+
+// Invalidate drops the cached mapping of a page so the next event reads the pages table
+// (call after deactivating or reconnecting a page); failures only delay the change
+func (r *TenantResolver) Invalidate(ctx context.Context, platform, pageID string) {
+	if err := r.cache.DeletePageTenant(ctx, platform, pageID); err != nil {
+		slog.Warn("Failed to invalidate page tenant cache", "error", err, "platform", platform, "page_id", pageID)
+	}
+}
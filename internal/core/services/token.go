@@ -0,0 +1,104 @@
+// Package services contains dashboard token signing (JWT, HS256)
+//
+// The tokens are only issued and read by this server, so the format is fixed: one
+// header, one algorithm, one key. That subset is a few lines over crypto/hmac and
+// avoids the "alg" confusion (none / RS256 with the HMAC key) general JWT parsers
+// have to guard against; token_test.go covers signature, header pinning and expiry
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+// ErrInvalidToken is returned for malformed, tampered or expired tokens
+var ErrInvalidToken = errors.New("invalid token")
+
+// jwtHeader is the fixed header of every token we issue (base64url of {"alg":"HS256","typ":"JWT"})
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// jwtClaims is the JSON payload of a token
+type jwtClaims struct {
+	Subject   int    `json:"sub"`
+	TenantID  int    `json:"tid"`
+	Role      string `json:"role,omitempty"`
+	TeamID    *int   `json:"team_id,omitempty"`
+	TokenType string `json:"typ"`
+	TokenID   string `json:"jti,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// signToken encodes and signs claims as a compact HS256 JWT
+func signToken(claims domain.AuthClaims, secret []byte) (string, error) {
+	payload, err := json.Marshal(jwtClaims{
+		Subject:   claims.StaffID,
+		TenantID:  claims.TenantID,
+		Role:      claims.Role,
+		TeamID:    claims.TeamID,
+		TokenType: claims.TokenType,
+		TokenID:   claims.TokenID,
+		IssuedAt:  claims.IssuedAt.Unix(),
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode token claims: %w", err)
+	}
+
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + tokenSignature(signingInput, secret), nil
+}
+
+// parseToken verifies the signature and expiry of a token and returns its claims
+// Only tokens with our exact header are accepted (no "alg" negotiation)
+func parseToken(token string, secret []byte, now time.Time) (*domain.AuthClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+
+	expected := tokenSignature(parts[0]+"."+parts[1], secret)
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if claims.Subject <= 0 || claims.TenantID <= 0 || !now.Before(expiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	return &domain.AuthClaims{
+		StaffID:   claims.Subject,
+		TenantID:  claims.TenantID,
+		Role:      claims.Role,
+		TeamID:    claims.TeamID,
+		TokenType: claims.TokenType,
+		TokenID:   claims.TokenID,
+		IssuedAt:  time.Unix(claims.IssuedAt, 0),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// tokenSignature returns the base64url HMAC-SHA256 of the signing input
+func tokenSignature(signingInput string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
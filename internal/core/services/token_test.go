@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+var testTokenSecret = []byte("test-secret")
+
+func testClaims(now time.Time) domain.AuthClaims {
+	team := 7
+	return domain.AuthClaims{
+		StaffID:   42,
+		TenantID:  3,
+		Role:      domain.RoleAgent,
+		TeamID:    &team,
+		TokenType: domain.TokenTypeAccess,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(15 * time.Minute),
+	}
+}
+
+func TestParseTokenRoundTrip(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	token, err := signToken(testClaims(now), testTokenSecret)
+	if err != nil {
+		t.Fatalf("signToken: %v", err)
+	}
+
+	claims, err := parseToken(token, testTokenSecret, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("parseToken: %v", err)
+	}
+	if claims.StaffID != 42 || claims.TenantID != 3 || claims.Role != domain.RoleAgent ||
+		claims.TeamID == nil || *claims.TeamID != 7 || claims.TokenType != domain.TokenTypeAccess {
+		t.Errorf("claims = %+v", claims)
+	}
+	if !claims.ExpiresAt.Equal(now.Add(15 * time.Minute)) {
+		t.Errorf("ExpiresAt = %v", claims.ExpiresAt)
+	}
+}
+
+func TestParseTokenRejectsBadSignature(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	token, _ := signToken(testClaims(now), testTokenSecret)
+
+	if _, err := parseToken(token, []byte("other-secret"), now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("wrong secret: err = %v", err)
+	}
+
+	// Payload swapped for another tenant, signature kept
+	forged, _ := signToken(domain.AuthClaims{StaffID: 42, TenantID: 4, ExpiresAt: now.Add(time.Hour)}, []byte("x"))
+	parts, forgedParts := strings.Split(token, "."), strings.Split(forged, ".")
+	tampered := parts[0] + "." + forgedParts[1] + "." + parts[2]
+	if _, err := parseToken(tampered, testTokenSecret, now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tampered payload: err = %v", err)
+	}
+}
+
+func TestParseTokenPinsAlgorithm(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	token, _ := signToken(testClaims(now), testTokenSecret)
+	parts := strings.Split(token, ".")
+
+	for _, header := range []string{
+		`{"alg":"none","typ":"JWT"}`,
+		`{"alg":"HS512","typ":"JWT"}`,
+		`{"typ":"JWT","alg":"HS256"}`, // Same algorithm, different encoding
+	} {
+		encoded := base64.RawURLEncoding.EncodeToString([]byte(header))
+		signingInput := encoded + "." + parts[1]
+		for _, signature := range []string{"", tokenSignature(signingInput, testTokenSecret)} {
+			if _, err := parseToken(signingInput+"."+signature, testTokenSecret, now); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("header %s, signature %q: err = %v", header, signature, err)
+			}
+		}
+	}
+}
+
+func TestParseTokenExpiry(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	claims := testClaims(now)
+	token, _ := signToken(claims, testTokenSecret)
+
+	if _, err := parseToken(token, testTokenSecret, claims.ExpiresAt.Add(-time.Second)); err != nil {
+		t.Errorf("just before expiry: err = %v", err)
+	}
+	if _, err := parseToken(token, testTokenSecret, claims.ExpiresAt); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("at expiry: err = %v", err)
+	}
+}
+
+func TestParseTokenMalformed(t *testing.T) {
+	for _, token := range []string{"", "a.b", "a.b.c.d", jwtHeader + ".!!!." + "sig"} {
+		if _, err := parseToken(token, testTokenSecret, time.Now()); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%q: err = %v", token, err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package services contains the webhook queue worker pool
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+	"immortal-chat/internal/core/ports"
+)
+
+// WebhookWorkerConfig tunes the worker pool
+type WebhookWorkerConfig struct {
+	Workers           int           // Bounded number of concurrent processors
+	VisibilityTimeout time.Duration // Un-Acked jobs older than this are re-claimed
+	MaxDeliveries     int           // After this many deliveries a job is dead-lettered
+	BlockTimeout      time.Duration // How long a Dequeue call waits for new jobs
+}
+
+// WebhookWorkerPool drains the durable webhook queue with a bounded set of workers
+// Guarantees at-least-once processing: a job is Acked only after ProcessWebhook
+// succeeds; crashed/failed jobs are re-claimed after the visibility timeout
+type WebhookWorkerPool struct {
+	dispatcher *Dispatcher
+	queue      ports.WebhookQueue
+	cfg        WebhookWorkerConfig
+	consumer   string // Unique per process so Redis can track who holds which job
+	wg         sync.WaitGroup
+}
+
+// NewWebhookWorkerPool creates a worker pool (call Start to run it)
+func NewWebhookWorkerPool(dispatcher *Dispatcher, queue ports.WebhookQueue, cfg WebhookWorkerConfig) *WebhookWorkerPool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = 60 * time.Second
+	}
+	if cfg.MaxDeliveries <= 0 {
+		cfg.MaxDeliveries = 5
+	}
+	if cfg.BlockTimeout <= 0 {
+		cfg.BlockTimeout = 5 * time.Second
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &WebhookWorkerPool{
+		dispatcher: dispatcher,
+		queue:      queue,
+		cfg:        cfg,
+		consumer:   fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Start launches the workers and the stale-job reclaimer
+// Workers stop when ctx is cancelled; use Wait to block until they are done
+func (p *WebhookWorkerPool) Start(ctx context.Context) {
+	jobs := make(chan *domain.WebhookJob) // Unbuffered: never hold more than Workers jobs in RAM
+
+	for i := 0; i < p.cfg.Workers; i++ {
+		p.wg.Add(1)
+		go p.work(ctx, jobs)
+	}
+
+	p.wg.Add(2)
+	go p.fetchNew(ctx, jobs)
+	go p.reclaimStale(ctx, jobs)
+
+	slog.Info("Webhook worker pool started",
+		"workers", p.cfg.Workers,
+		"visibility_timeout", p.cfg.VisibilityTimeout,
+		"max_deliveries", p.cfg.MaxDeliveries,
+		"consumer", p.consumer,
+	)
+}
+
+// Wait blocks until all workers have exited (after ctx cancellation)
+func (p *WebhookWorkerPool) Wait() {
+	p.wg.Wait()
+}
+
+// fetchNew pulls never-delivered jobs from the queue
+func (p *WebhookWorkerPool) fetchNew(ctx context.Context, jobs chan<- *domain.WebhookJob) {
+	defer p.wg.Done()
+
+	for ctx.Err() == nil {
+		batch, err := p.queue.Dequeue(ctx, p.consumer, p.cfg.Workers, p.cfg.BlockTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("Failed to dequeue webhooks", "error", err)
+			sleepCtx(ctx, time.Second)
+			continue
+		}
+
+		if !p.dispatch(ctx, jobs, batch) {
+			return
+		}
+	}
+}
+
+// reclaimStale periodically takes over jobs abandoned by crashed/failed workers
+func (p *WebhookWorkerPool) reclaimStale(ctx context.Context, jobs chan<- *domain.WebhookJob) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.VisibilityTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		batch, err := p.queue.ClaimStale(ctx, p.consumer, p.cfg.VisibilityTimeout, p.cfg.Workers*10)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Failed to claim stale webhooks", "error", err)
+			}
+			continue
+		}
+
+		if !p.dispatch(ctx, jobs, batch) {
+			return
+		}
+	}
+}
+
+// dispatch hands a batch to the workers; returns false when shutting down
+// Jobs not handed over stay pending in the queue and are re-claimed later
+func (p *WebhookWorkerPool) dispatch(ctx context.Context, jobs chan<- *domain.WebhookJob, batch []*domain.WebhookJob) bool {
+	for _, job := range batch {
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// work processes jobs one at a time
+func (p *WebhookWorkerPool) work(ctx context.Context, jobs <-chan *domain.WebhookJob) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-jobs:
+			p.handle(ctx, job)
+		}
+	}
+}
+
+// handle runs one job and decides between Ack, retry (no Ack) and dead-letter
+func (p *WebhookWorkerPool) handle(ctx context.Context, job *domain.WebhookJob) {
+	// Poison job protection: stop redelivering after MaxDeliveries
+	if job.Deliveries > p.cfg.MaxDeliveries {
+		reason := fmt.Sprintf("exceeded %d deliveries", p.cfg.MaxDeliveries)
+		p.dispatcher.updateWebhookStatus(job.LogID, domain.WebhookStatusFailed, errors.New(reason))
+		if err := p.queue.DeadLetter(ctx, job, reason); err != nil {
+			slog.Error("Failed to dead-letter webhook job", "error", err, "job_id", job.ID)
+		}
+		return
+	}
+
+	// ProcessJob also moves the webhook_logs row to processed/failed
+	err := p.dispatcher.ProcessJob(ctx, job)
+
+	switch {
+	case err == nil:
+		// Done
+	case errors.Is(err, ErrInvalidPayload):
+		// Retrying cannot fix malformed JSON
+		if dlErr := p.queue.DeadLetter(ctx, job, err.Error()); dlErr != nil {
+			slog.Error("Failed to dead-letter webhook job", "error", dlErr, "job_id", job.ID)
+		}
+		return
+	default:
+		// Leave un-Acked: the reclaimer will redeliver it after the visibility timeout
+		slog.Warn("Webhook job failed, will be redelivered",
+			"error", err,
+			"job_id", job.ID,
+			"deliveries", job.Deliveries,
+		)
+		return
+	}
+
+	// Use a fresh context so a shutdown right after processing still records the Ack
+	ackCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := p.queue.Ack(ackCtx, job.ID); err != nil {
+		// Job will be redelivered; dedup cache keeps it idempotent
+		slog.Error("Failed to ack webhook job", "error", err, "job_id", job.ID)
+	}
+}
+
+// sleepCtx sleeps for d or until ctx is cancelled
+func sleepCtx(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
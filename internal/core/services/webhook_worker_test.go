@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"immortal-chat/internal/core/domain"
+)
+
+// memQueue is an in-memory WebhookQueue with consumer-group semantics: a delivered
+// job stays pending until Acked, and ClaimStale redelivers it after minIdle
+type memQueue struct {
+	mu      sync.Mutex
+	nextID  int
+	fresh   []*domain.WebhookJob
+	pending map[string]*pendingJob
+	acked   map[string]bool
+	dead    map[string]deadJob
+}
+
+type deadJob struct {
+	reason     string
+	deliveries int
+}
+
+type pendingJob struct {
+	job         domain.WebhookJob
+	deliveredAt time.Time
+}
+
+func newMemQueue() *memQueue {
+	return &memQueue{pending: map[string]*pendingJob{}, acked: map[string]bool{}, dead: map[string]deadJob{}}
+}
+
+func (q *memQueue) Enqueue(ctx context.Context, job *domain.WebhookJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	job.ID = fmt.Sprintf("%d-0", q.nextID)
+	q.fresh = append(q.fresh, job)
+	return nil
+}
+
+func (q *memQueue) Dequeue(ctx context.Context, consumer string, count int, block time.Duration) ([]*domain.WebhookJob, error) {
+	q.mu.Lock()
+	var jobs []*domain.WebhookJob
+	for len(q.fresh) > 0 && len(jobs) < count {
+		job := *q.fresh[0]
+		q.fresh = q.fresh[1:]
+		job.Deliveries = 1
+		q.pending[job.ID] = &pendingJob{job: job, deliveredAt: time.Now()}
+		jobs = append(jobs, &job)
+	}
+	q.mu.Unlock()
+
+	if len(jobs) == 0 {
+		sleepCtx(ctx, block)
+	}
+	return jobs, nil
+}
+
+func (q *memQueue) ClaimStale(ctx context.Context, consumer string, minIdle time.Duration, count int) ([]*domain.WebhookJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var jobs []*domain.WebhookJob
+	for _, p := range q.pending {
+		if time.Since(p.deliveredAt) < minIdle || len(jobs) >= count {
+			continue
+		}
+		p.job.Deliveries++
+		p.deliveredAt = time.Now()
+		job := p.job
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+func (q *memQueue) Ack(ctx context.Context, jobID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, jobID)
+	q.acked[jobID] = true
+	return nil
+}
+
+func (q *memQueue) DeadLetter(ctx context.Context, job *domain.WebhookJob, reason string) error {
+	q.mu.Lock()
+	q.dead[job.ID] = deadJob{reason: reason, deliveries: job.Deliveries}
+	q.mu.Unlock()
+	return q.Ack(ctx, job.ID)
+}
+
+func (q *memQueue) PendingLogIDs(ctx context.Context) (map[int64]bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ids := map[int64]bool{}
+	for _, p := range q.pending {
+		ids[p.job.LogID] = true
+	}
+	return ids, nil
+}
+
+// brokenMessages fails to store "mid.broken" (e.g. the database rejects it)
+type brokenMessages struct{ *fakeMessages }
+
+func (m brokenMessages) SaveMessage(ctx context.Context, msg *domain.Message) error {
+	if *msg.ExternalMsgID == "mid.broken" {
+		return errors.New("database unavailable")
+	}
+	return m.fakeMessages.SaveMessage(ctx, msg)
+}
+
+func TestWebhookWorkerPoolAcksReclaimsAndDeadLetters(t *testing.T) {
+	queue := newMemQueue()
+	webhooks := &fakeWebhookLogs{statuses: map[int64]string{}}
+	messages := &fakeMessages{saved: map[string]bool{}, racing: map[string]bool{}}
+	dispatcher := NewDispatcher(webhooks, brokenMessages{messages}, fakeConversations{}, fakeDedup{}, queue,
+		NewPlatformRegistry(fakeChannel{}), NewTenantResolver(fakePages{}, fakePages{}), nil, nil, nil, nil)
+
+	jobs := map[string]*domain.WebhookJob{}
+	for logID, payload := range map[int64]string{1: `"mid.ok"`, 2: `"mid.broken"`, 3: `not json`} {
+		job := &domain.WebhookJob{LogID: logID, Platform: "fake", Payload: json.RawMessage(payload)}
+		if err := queue.Enqueue(context.Background(), job); err != nil {
+			t.Fatal(err)
+		}
+		jobs[payload] = job
+	}
+
+	pool := NewWebhookWorkerPool(dispatcher, queue, WebhookWorkerConfig{
+		Workers:           2,
+		VisibilityTimeout: 20 * time.Millisecond,
+		MaxDeliveries:     3,
+		BlockTimeout:      5 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		queue.mu.Lock()
+		done := len(queue.pending) == 0 && len(queue.fresh) == 0
+		queue.mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("jobs still pending")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	pool.Wait()
+
+	ok, broken, invalid := jobs[`"mid.ok"`], jobs[`"mid.broken"`], jobs[`not json`]
+	if _, dead := queue.dead[ok.ID]; !queue.acked[ok.ID] || dead || !messages.saved["mid.ok"] {
+		t.Errorf("valid job: acked %v, dead %v", queue.acked[ok.ID], dead)
+	}
+	if webhooks.statuses[1] != domain.WebhookStatusProcessed {
+		t.Errorf("valid job status = %q", webhooks.statuses[1])
+	}
+
+	// Failing job: redelivered by ClaimStale until MaxDeliveries, then dead-lettered
+	if dead := queue.dead[broken.ID]; dead.reason != "exceeded 3 deliveries" || dead.deliveries != 4 {
+		t.Errorf("failing job dead-lettered = %+v", dead)
+	}
+	if webhooks.statuses[2] != domain.WebhookStatusFailed {
+		t.Errorf("failing job status = %q", webhooks.statuses[2])
+	}
+
+	// Malformed payload: dead-lettered on the first delivery
+	if dead := queue.dead[invalid.ID]; !strings.Contains(dead.reason, ErrInvalidPayload.Error()) || dead.deliveries != 1 {
+		t.Errorf("invalid job dead-lettered = %+v", dead)
+	}
+}
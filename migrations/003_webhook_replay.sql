@@ -0,0 +1,29 @@
+-- Webhook Replay Engine
+-- Run this AFTER 002_phase3_sample_data.sql
+
+-- 1. Track when a webhook was last attempted (drives exponential backoff)
+ALTER TABLE webhook_logs
+    ADD COLUMN last_attempt_at TIMESTAMP NULL DEFAULT NULL AFTER error_log,
+    ADD INDEX idx_status_created (status, created_at),
+    ADD INDEX idx_platform_created (platform, created_at);
+
+-- 2. DB-level idempotency for replays older than the Redis dedup TTL
+ALTER TABLE messages
+    ADD INDEX idx_external_msg (external_msg_id);
+
+-- 3. Atomic message deduplication
+-- The dedup cache and the Exists check are check-then-act: a replay racing a queue
+-- redelivery of the same webhook could store the message twice. A conversation
+-- belongs to one tenant and page, so the key is per conversation
+-- (idx_external_msg stays: Exists looks up external_msg_id alone)
+
+-- Remove duplicates stored before the key existed (keep the first copy)
+DELETE m FROM messages m
+JOIN messages first
+    ON first.conversation_id = m.conversation_id
+   AND first.external_msg_id = m.external_msg_id
+   AND first.id < m.id;
+
+-- One row per platform message; NULL (queued replies) is not a duplicate
+ALTER TABLE messages
+    ADD UNIQUE KEY uniq_conversation_external_msg (conversation_id, external_msg_id);
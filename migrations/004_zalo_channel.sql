@@ -0,0 +1,17 @@
+-- Zalo Official Account channel
+-- Run this AFTER 003_webhook_replay.sql
+
+-- 1. Remember which platform a conversation belongs to (outbound routing,
+--    per-platform stats). Page IDs are only unique per platform.
+ALTER TABLE conversations
+    ADD COLUMN platform VARCHAR(20) NOT NULL DEFAULT 'facebook' AFTER tenant_id,
+    ADD INDEX idx_platform_page (platform, page_id);
+
+-- 2. Backfill from pages for existing rows (pages.platform is authoritative)
+UPDATE conversations c
+JOIN pages p ON p.page_id = c.page_id
+SET c.platform = p.platform;
+
+-- 3. Zalo OA access tokens go into pages like Facebook page tokens:
+-- INSERT INTO pages (tenant_id, platform, page_id, page_name, access_token)
+-- VALUES (1, 'zalo', '<OA_ID>', '<OA name>', '<OA access token>');
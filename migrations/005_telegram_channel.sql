@@ -0,0 +1,10 @@
+-- Telegram Bot channel
+-- Run this AFTER 004_zalo_channel.sql
+
+-- 1. Allow Telegram bots in pages (page_id = bot ID, access_token = bot token)
+ALTER TABLE pages
+    MODIFY COLUMN platform ENUM('facebook', 'zalo', 'telegram') NOT NULL;
+
+-- 2. Register the bot:
+-- INSERT INTO pages (tenant_id, platform, page_id, page_name, access_token)
+-- VALUES (1, 'telegram', '<BOT_ID>', '<bot username>', '<BOT_TOKEN>');
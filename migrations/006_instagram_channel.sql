@@ -0,0 +1,17 @@
+-- Instagram Direct channel (Messenger API for Instagram)
+-- Run this AFTER 005_telegram_channel.sql
+
+-- 1. Allow Instagram business accounts in pages
+--    page_id = IG business account ID, access_token = token of the linked Facebook page
+ALTER TABLE pages
+    MODIFY COLUMN platform ENUM('facebook', 'zalo', 'telegram', 'instagram') NOT NULL;
+
+-- 2. Message types sent by Messenger / Instagram that the original enum rejected
+--    share = post/reel shared in DM, story_reply = text reply to a story,
+--    story_mention = customer mentioned the account in a story
+ALTER TABLE messages
+    MODIFY COLUMN type ENUM('text', 'image', 'file', 'sticker', 'voice', 'video', 'audio', 'share', 'story_reply', 'story_mention');
+
+-- 3. Register an IG account:
+-- INSERT INTO pages (tenant_id, platform, page_id, page_name, access_token)
+-- VALUES (1, 'instagram', '<IG_ACCOUNT_ID>', '<ig username>', '<LINKED_PAGE_TOKEN>');
@@ -0,0 +1,15 @@
+-- Messenger postbacks, quick replies, referrals and opt-ins
+-- Run this AFTER 006_instagram_channel.sql
+
+-- 1. Postback / quick reply / opt-in payload for bot flows
+ALTER TABLE messages
+    ADD COLUMN payload VARCHAR(1000) NULL AFTER external_msg_id,
+    MODIFY COLUMN type ENUM('text', 'image', 'file', 'sticker', 'voice', 'video', 'audio', 'share', 'story_reply', 'story_mention', 'postback', 'optin');
+
+-- 2. Last entry point of the customer (m.me ref, Click-to-Messenger ad, plugin)
+ALTER TABLE conversations
+    ADD COLUMN referral_source VARCHAR(50) NULL AFTER status,
+    ADD COLUMN referral_ref VARCHAR(255) NULL AFTER referral_source,
+    ADD COLUMN referral_ad_id VARCHAR(50) NULL AFTER referral_ref,
+    ADD COLUMN referred_at TIMESTAMP NULL DEFAULT NULL AFTER referral_ad_id,
+    ADD INDEX idx_referral_ad (referral_ad_id);
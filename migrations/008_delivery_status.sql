@@ -0,0 +1,10 @@
+-- Per-message delivery state for outbound messages
+-- Run this AFTER 007_messenger_events.sql
+
+-- NULL for inbound messages; outbound: sent -> delivered -> read, or failed
+ALTER TABLE messages
+    ADD COLUMN delivery_status ENUM('sent', 'delivered', 'read', 'failed') NULL DEFAULT NULL AFTER payload,
+    ADD COLUMN delivery_updated_at TIMESTAMP NULL DEFAULT NULL AFTER delivery_status;
+
+-- Existing agent replies were accepted by the platform
+UPDATE messages SET delivery_status = 'sent' WHERE sender_type = 'agent' AND delivery_status IS NULL;
@@ -0,0 +1,6 @@
+-- Page echoes of messages sent outside Immortal Chat (Page inbox, Pages app, ...)
+-- Run this AFTER 008_delivery_status.sql
+
+-- TRUE for agent messages that were not sent through the dashboard
+ALTER TABLE messages
+    ADD COLUMN is_external BOOLEAN NOT NULL DEFAULT FALSE AFTER delivery_status;
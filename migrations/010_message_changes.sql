@@ -0,0 +1,15 @@
+-- Message edits, unsends and reactions (Messenger / Instagram)
+-- Run this AFTER 009_external_echoes.sql
+
+-- edit_history: previous versions [{"content", "edited_at"}]
+-- is_deleted: unsent by the customer, content is kept for audit
+-- reactions: current reactions [{"user_id", "reaction", "emoji", "reacted_at"}]
+-- edited_at / reacted_at: webhook time of the last applied edit / reaction, in ms so
+-- that redelivered or replayed changes (not newer) are ignored
+ALTER TABLE messages
+    ADD COLUMN edit_history JSON NULL DEFAULT NULL AFTER is_external,
+    ADD COLUMN edited_at TIMESTAMP(3) NULL DEFAULT NULL AFTER edit_history,
+    ADD COLUMN is_deleted BOOLEAN NOT NULL DEFAULT FALSE AFTER edited_at,
+    ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL AFTER is_deleted,
+    ADD COLUMN reactions JSON NULL DEFAULT NULL AFTER deleted_at,
+    ADD COLUMN reacted_at TIMESTAMP(3) NULL DEFAULT NULL AFTER reactions;
@@ -0,0 +1,21 @@
+-- Tenant resolution from the receiving page
+-- Run this AFTER 010_message_changes.sql
+
+-- 1. Events for pages that are not connected (or deactivated) are kept here
+--    instead of being stored under a default tenant; once the page is fixed, replay
+--    them with POST /api/admin/quarantine/replay (replayed rows are deleted)
+CREATE TABLE IF NOT EXISTS quarantined_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    platform VARCHAR(20) NOT NULL,
+    page_id VARCHAR(50) NOT NULL,
+    reason ENUM('unknown_page', 'inactive_page') NOT NULL,
+    event_json JSON NOT NULL,
+    event_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_event_hash (event_hash),
+    INDEX idx_page_created (platform, page_id, created_at)
+);
+
+-- 2. Tenant-scoped conversation lookups
+ALTER TABLE conversations
+    ADD INDEX idx_tenant_page (tenant_id, platform, page_id);
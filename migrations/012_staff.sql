@@ -0,0 +1,19 @@
+-- Staff accounts for the dashboard (password login + JWT)
+-- Run this AFTER 011_tenant_routing.sql
+
+-- Email is the login and is unique across tenants
+-- password_hash: bcrypt ("$2a$12$...", cost included)
+CREATE TABLE IF NOT EXISTS staff (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    email VARCHAR(191) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_login_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_email (email),
+    INDEX idx_tenant (tenant_id)
+);
+
+-- The first owner is created at startup from BOOTSTRAP_ADMIN_EMAIL / BOOTSTRAP_ADMIN_PASSWORD
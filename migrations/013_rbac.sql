@@ -0,0 +1,29 @@
+-- Roles, teams and conversation data scopes for the dashboard
+-- Run this AFTER 012_staff.sql
+
+-- 1. Teams group agents; a conversation can be assigned to a team
+CREATE TABLE IF NOT EXISTS teams (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_tenant_name (tenant_id, name)
+);
+
+-- 2. Staff role and team
+--    owner / admin: whole tenant, manage staff
+--    agent: only conversations assigned to them or their team
+--    viewer: whole tenant, read-only
+ALTER TABLE staff
+    ADD COLUMN role ENUM('owner', 'admin', 'agent', 'viewer') NOT NULL DEFAULT 'agent' AFTER name,
+    ADD COLUMN team_id INT NULL AFTER role;
+
+-- The first account of every tenant (the bootstrap account) becomes its owner
+UPDATE staff s
+JOIN (SELECT tenant_id, MIN(id) AS id FROM staff GROUP BY tenant_id) first_staff ON first_staff.id = s.id
+SET s.role = 'owner';
+
+-- 3. Team assignment of conversations (assignee_id already holds the staff)
+ALTER TABLE conversations
+    ADD COLUMN assigned_team_id INT NULL AFTER assignee_id,
+    ADD INDEX idx_tenant_team (tenant_id, assigned_team_id);
@@ -0,0 +1,15 @@
+-- Tenant-defined conversation tags
+-- Run this AFTER 013_rbac.sql
+
+-- 1. Tag definitions; conversations.tags holds the names of the tags set on a conversation
+CREATE TABLE IF NOT EXISTS tags (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    color CHAR(7) NOT NULL DEFAULT '#6B7280',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_tenant_name (tenant_id, name)
+);
+
+-- 2. Conversations created before tags were used may have NULL instead of an empty list
+UPDATE conversations SET tags = JSON_ARRAY() WHERE tags IS NULL;
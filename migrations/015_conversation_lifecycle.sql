@@ -0,0 +1,9 @@
+-- Conversation lifecycle: snooze and resolve next to archive
+-- Run this AFTER 014_tags.sql
+
+-- 1. unread / read are open; snoozed, resolved and archived reopen when the customer writes again
+ALTER TABLE conversations
+    MODIFY COLUMN status ENUM('unread', 'read', 'snoozed', 'resolved', 'archived') DEFAULT 'unread',
+    ADD COLUMN snoozed_until DATETIME NULL AFTER status,
+    ADD INDEX idx_tenant_status (tenant_id, status),
+    ADD INDEX idx_snoozed_until (status, snoozed_until);
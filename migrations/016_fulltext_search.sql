@@ -0,0 +1,14 @@
+-- Full-text search over message content and customer names
+-- Run this AFTER 015_conversation_lifecycle.sql
+
+-- 1. Used by GET /api/search (MATCH ... AGAINST ... IN BOOLEAN MODE)
+-- Words shorter than innodb_ft_min_token_size are not indexed. The default (3) drops
+-- two-letter Vietnamese words ("áo", "ăn"): start MariaDB with
+-- --innodb-ft-min-token-size=2 (see docker-compose.yml) BEFORE running this file.
+-- On a server where the indexes already exist, change the setting, restart, then
+-- drop and re-create the two indexes below
+ALTER TABLE messages
+    ADD FULLTEXT INDEX ft_messages_content (content);
+
+ALTER TABLE conversations
+    ADD FULLTEXT INDEX ft_conversations_customer_name (customer_name);
@@ -0,0 +1,24 @@
+-- Media uploaded from the dashboard (stored on local disk, see MEDIA_DIR)
+-- Run this AFTER 016_fulltext_search.sql
+
+-- 1. One row per stored file; id is random and also the file name on disk
+CREATE TABLE IF NOT EXISTS media (
+    id CHAR(32) PRIMARY KEY,
+    tenant_id INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    type ENUM('image', 'video', 'audio', 'file') NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_tenant_created (tenant_id, created_at)
+);
+
+-- 2. Reusable platform attachment IDs (Messenger attachment_id is scoped to the page)
+CREATE TABLE IF NOT EXISTS media_attachments (
+    media_id CHAR(32) NOT NULL,
+    platform VARCHAR(20) NOT NULL,
+    page_id VARCHAR(50) NOT NULL,
+    attachment_id VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (media_id, platform, page_id)
+);
@@ -0,0 +1,15 @@
+-- Local copies of inbound attachments (platform CDN URLs expire within days)
+-- Run this AFTER 017_media.sql
+
+-- 1. One row per message with remote attachments; files are stored under MEDIA_DIR
+--    named by the SHA-256 of their content and served under /media/{hash}
+CREATE TABLE IF NOT EXISTS media_mirrors (
+    message_id BIGINT PRIMARY KEY,
+    status ENUM('pending', 'done', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_error TEXT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_status_next (status, next_attempt_at)
+);
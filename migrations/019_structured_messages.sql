@@ -0,0 +1,6 @@
+-- Structured outbound messages: quick replies, button / generic / media templates
+-- Run this AFTER 018_media_mirror.sql
+
+-- 1. Templates are stored with type 'template' (content = preview text); their echoes too
+ALTER TABLE messages
+    MODIFY COLUMN type ENUM('text', 'image', 'file', 'sticker', 'voice', 'video', 'audio', 'share', 'story_reply', 'story_mention', 'postback', 'optin', 'template');
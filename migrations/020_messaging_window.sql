@@ -0,0 +1,10 @@
+-- Messenger 24-hour messaging window and message tags
+-- Run this AFTER 019_structured_messages.sql
+
+-- 1. Tag an outbound message was sent with outside the window (NULL = standard reply)
+ALTER TABLE messages
+    ADD COLUMN message_tag VARCHAR(32) NULL AFTER delivery_status;
+
+-- 2. The window starts at the customer's last message
+ALTER TABLE messages
+    ADD INDEX idx_conv_sender_time (conversation_id, sender_type, created_at);
@@ -0,0 +1,28 @@
+-- Outbound message queue: replies are stored as queued and sent by background workers
+-- Run this AFTER 020_messaging_window.sql
+
+-- 1. Queued replies, and why a failed one failed
+ALTER TABLE messages
+    MODIFY COLUMN delivery_status ENUM('queued', 'sent', 'delivered', 'read', 'failed') NULL DEFAULT NULL,
+    ADD COLUMN delivery_error VARCHAR(32) NULL AFTER delivery_status;
+
+-- 2. One send job per queued message, deleted once the message is sent or failed
+-- next_attempt_at is also the lease of a job being sent (a crashed worker's job runs again)
+CREATE TABLE IF NOT EXISTS outbound_jobs (
+    message_id BIGINT PRIMARY KEY,
+    conversation_id BIGINT NOT NULL,
+    tenant_id INT NOT NULL,
+    platform VARCHAR(20) NOT NULL,
+    page_id VARCHAR(50) NOT NULL,
+    recipient_id VARCHAR(100) NOT NULL,
+    message_tag VARCHAR(32) NULL,
+    text TEXT NULL,
+    media_id CHAR(32) NULL,
+    structured JSON NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME(3) NOT NULL,
+    last_error VARCHAR(500) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_next_attempt (next_attempt_at),
+    INDEX idx_conversation (conversation_id, message_id)
+);
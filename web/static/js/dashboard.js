@@ -1,26 +1,112 @@
 const API_BASE = "/api";
 let currentConversationId = null;
 let refreshTimer = null;
+let currentMessages = [];
+let earlyMessageUpdates = {}; // message_updated for messages not in currentMessages yet (id -> message)
+let currentRole = null; // owner | admin | agent | viewer (from /api/status)
+let currentStaffId = null;
+let assignedOnlyFilter = false; // "Của tôi": only conversations assigned to me
+let currentConversations = [];
+let currentTags = []; // Tag definitions with conversation counts (from /api/tags)
+let tagFilter = null; // Only conversations carrying this tag name
+let statusFilter = "open"; // open | snoozed | resolved | archived
+let conversationsNextCursor = null; // paging.next: older conversations (?before=)
+let messagesPrevCursor = null; // paging.prev: older messages (?before=)
+let searchNextCursor = null; // paging.next: older search results (?before=)
 
 // Lấy Secret Key từ URL (Ví dụ: ?secret_key=abc...)
 const urlParams = new URLSearchParams(window.location.search);
 const MESH_SECRET = urlParams.get('secret_key');
 
-// Hàm tạo Header chuẩn (kèm Secret Key)
+// Token đăng nhập (lưu bởi /login)
+const ACCESS_TOKEN_KEY = "auth_access_token";
+const REFRESH_TOKEN_KEY = "auth_refresh_token";
+
+// Hàm tạo Header chuẩn (kèm Bearer token và Secret Key)
 function getAuthHeaders() {
   const headers = { "Content-Type": "application/json" };
+  const token = localStorage.getItem(ACCESS_TOKEN_KEY);
+  if (token) {
+    headers["Authorization"] = `Bearer ${token}`;
+  }
   if (MESH_SECRET) {
     headers["X-Mesh-Secret"] = MESH_SECRET; // Chìa khóa vạn năng cho Admin
   }
   return headers;
 }
 
+// fetch kèm token; hết hạn (401) thì gia hạn 1 lần rồi gọi lại, thất bại thì về trang đăng nhập
+async function apiFetch(path, options = {}) {
+  const send = () => {
+    const headers = { ...getAuthHeaders(), ...(options.headers || {}) };
+    // FormData: trình duyệt tự đặt multipart/form-data kèm boundary
+    if (options.body instanceof FormData) delete headers["Content-Type"];
+    return fetch(`${API_BASE}${path}`, { ...options, headers });
+  };
+
+  let res = await send();
+  if (res.status === 401 && (await refreshTokens())) {
+    res = await send();
+  }
+  if (res.status === 401) {
+    redirectToLogin();
+  }
+  return res;
+}
+
+// Đổi refresh token lấy cặp token mới (refresh token chỉ dùng được 1 lần)
+let refreshPromise = null;
+function refreshTokens() {
+  const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY);
+  if (!refreshToken) return Promise.resolve(false);
+
+  // Nhiều request cùng hết hạn chỉ gia hạn 1 lần
+  if (!refreshPromise) {
+    refreshPromise = fetch(`${API_BASE}/auth/refresh`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    })
+      .then(async (res) => {
+        if (!res.ok) return false;
+        const result = await res.json();
+        localStorage.setItem(ACCESS_TOKEN_KEY, result.data.access_token);
+        localStorage.setItem(REFRESH_TOKEN_KEY, result.data.refresh_token);
+        return true;
+      })
+      .catch(() => false)
+      .finally(() => {
+        refreshPromise = null;
+      });
+  }
+  return refreshPromise;
+}
+
+function redirectToLogin() {
+  localStorage.removeItem(ACCESS_TOKEN_KEY);
+  localStorage.removeItem(REFRESH_TOKEN_KEY);
+  window.location.href = "/login";
+}
+
+async function logout() {
+  const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY);
+  try {
+    await fetch(`${API_BASE}/auth/logout`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ refresh_token: refreshToken || "" }),
+    });
+  } finally {
+    redirectToLogin();
+  }
+}
+
 document.addEventListener("DOMContentLoaded", () => {
 
-  // Kiểm tra bảo mật
-  if (!MESH_SECRET) {
-    console.warn("⚠️ Không tìm thấy secret_key trên URL. API có thể bị lỗi 403.");
-    alert("Vui lòng truy cập kèm ?secret_key=YOUR_KEY để có quyền Admin");
+  // Chưa đăng nhập thì chuyển sang trang đăng nhập
+  if (!localStorage.getItem(ACCESS_TOKEN_KEY)) {
+    redirectToLogin();
+    return;
   }
 
   // 1. Init UI
@@ -32,8 +118,106 @@ document.addEventListener("DOMContentLoaded", () => {
 
   // 3. Event Listeners
   setupEventListeners();
+
+  // 4. Live updates (edits, unsends, reactions, assignments)
+  connectEvents();
+
+  // 5. Presence: online agents receive new conversations automatically
+  sendHeartbeat(true);
+  setInterval(() => sendHeartbeat(true), 30000); // 30s, server TTL is 120s
+  window.addEventListener("pagehide", () => sendHeartbeat(false));
 });
 
+function sendHeartbeat(online) {
+  apiFetch("/presence", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ online }),
+    keepalive: !online, // Still delivered while the page unloads
+  }).catch((e) => console.error("Heartbeat error:", e));
+}
+
+// --- LIVE EVENTS (WebSocket /ws/events) ---
+
+// Token đăng nhập đi kèm dưới dạng subprotocol "bearer.<token>" (WebSocket không gửi được header)
+// Server chỉ gửi sự kiện của các hội thoại nhân viên được xem
+let eventsConnectedOnce = false;
+function connectEvents() {
+  const token = localStorage.getItem(ACCESS_TOKEN_KEY);
+  if (!token) return;
+  const protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
+  const ws = new WebSocket(`${protocol}//${window.location.host}/ws/events`, [
+    "events",
+    `bearer.${token}`,
+  ]);
+  ws.onopen = () => {
+    // Kết nối lại: tải lại hội thoại đang mở để lấy trạng thái gửi bị lỡ
+    if (eventsConnectedOnce && currentConversationId) {
+      selectConversation(currentConversationId, document.getElementById("header-name").innerText);
+    }
+    eventsConnectedOnce = true;
+  };
+  // Several events can arrive in one frame, one JSON object per line
+  ws.onmessage = (e) => {
+    e.data.split("\n").forEach((line) => {
+      try {
+        handleLiveEvent(JSON.parse(line));
+      } catch (err) {
+        console.error("Invalid live event:", err);
+      }
+    });
+  };
+  // Auto-reconnect; token hết hạn (server đóng kết nối hoặc từ chối) thì gia hạn trước
+  ws.onclose = async () => {
+    if (accessTokenExpired()) await refreshTokens();
+    setTimeout(connectEvents, 5000);
+  };
+}
+
+// Đọc hạn (exp) của access token, không kiểm tra chữ ký
+function accessTokenExpired() {
+  const token = localStorage.getItem(ACCESS_TOKEN_KEY) || "";
+  try {
+    const payload = JSON.parse(atob(token.split(".")[1].replace(/-/g, "+").replace(/_/g, "/")));
+    return payload.exp * 1000 <= Date.now();
+  } catch (e) {
+    return true;
+  }
+}
+
+function handleLiveEvent(event) {
+  if (
+    event.type === "conversation_assigned" ||
+    event.type === "conversation_tagged" ||
+    event.type === "conversation_status"
+  ) {
+    if (!document.getElementById("view-facebook").classList.contains("hidden"))
+      loadConversations();
+    return;
+  }
+  if (event.type !== "message_updated") return;
+  if (String(event.conversation_id) !== String(currentConversationId)) return;
+  const idx = currentMessages.findIndex((m) => m.id === event.data.id);
+  if (idx === -1) {
+    // Kết quả gửi có thể về trước phản hồi của POST /messages/reply
+    earlyMessageUpdates[event.data.id] = event.data;
+    return;
+  }
+  currentMessages[idx] = event.data;
+  renderMessages(currentMessages);
+}
+
+// Tin nhắn vừa xếp hàng gửi (status "queued"); trạng thái cuối về qua message_updated
+function addQueuedMessages(conversationId, messages) {
+  if (String(conversationId) !== String(currentConversationId)) return;
+  (messages || []).forEach((m) => {
+    const latest = earlyMessageUpdates[m.id] || m;
+    delete earlyMessageUpdates[m.id];
+    if (!currentMessages.some((c) => c.id === m.id)) currentMessages.push(latest);
+  });
+  renderMessages(currentMessages);
+}
+
 // --- CORE SYSTEM LOGIC (FROM OLD DASHBOARD.JS) ---
 
 async function loadAllSystemData() {
@@ -51,7 +235,7 @@ async function loadAllSystemData() {
 
 async function loadSystemMetrics() {
   try {
-    const res = await fetch(`${API_BASE}/system/metrics`);
+    const res = await apiFetch("/system/metrics");
     if (!res.ok) return;
     const data = await res.json();
 
@@ -86,7 +270,7 @@ async function loadSystemMetrics() {
 
 async function loadSystemStatus() {
   try {
-    const res = await fetch(`${API_BASE}/status`);
+    const res = await apiFetch("/status");
     if (!res.ok) return;
     const data = await res.json();
 
@@ -96,7 +280,9 @@ async function loadSystemStatus() {
     document.getElementById("stat-version").innerText = data.version;
     document.getElementById(
       "stat-tenant"
-    ).innerText = `Tenant: ${data.tenant_id}`;
+    ).innerText = `Tenant: ${data.tenant_id} · ${data.staff_role}`;
+    currentRole = data.staff_role;
+    currentStaffId = data.staff_id;
   } catch (e) {
     console.warn("Status error");
   }
@@ -104,7 +290,7 @@ async function loadSystemStatus() {
 
 async function loadPlatforms() {
   try {
-    const res = await fetch(`${API_BASE}/platforms`);
+    const res = await apiFetch("/platforms");
     if (!res.ok) return;
     const data = await res.json(); // Array
 
@@ -126,7 +312,7 @@ async function loadPlatforms() {
 
 async function loadSyncStatus() {
   try {
-    const res = await fetch(`${API_BASE}/sync/status`);
+    const res = await apiFetch("/sync/status");
     if (!res.ok) return;
     const data = await res.json();
 
@@ -151,9 +337,8 @@ async function handlePanicMode() {
     return;
 
   try {
-    const res = await fetch(`${API_BASE}/system/panic`, {
+    const res = await apiFetch("/system/panic", {
       method: "POST",
-      headers: { "Content-Type": "application/json" },
       body: JSON.stringify({ action: "enable", reason: "Admin Trigger" }),
     });
     if (res.ok) alert("🚨 ĐÃ KÍCH HOẠT PANIC MODE!");
@@ -213,25 +398,43 @@ function switchView(viewName) {
 
 // --- CHAT LOGIC (PHASE 3) ---
 
-async function loadConversations() {
+// more = true appends the next (older) page instead of reloading the list
+async function loadConversations(more = false) {
   const container = document.getElementById("conversation-list");
   if (!container) return;
 
   try {
-    const res = await fetch(`${API_BASE}/conversations`);
+    const params = new URLSearchParams();
+    if (assignedOnlyFilter) params.set("assigned", "me");
+    if (tagFilter) params.set("tag", tagFilter);
+    if (statusFilter) params.set("status", statusFilter);
+    if (more && conversationsNextCursor) params.set("before", conversationsNextCursor);
+    const query = params.toString();
+    const res = await apiFetch("/conversations" + (query ? `?${query}` : ""));
+    if (!more) loadTags();
     if (!res.headers.get("content-type")?.includes("application/json"))
       throw new Error("API Error");
 
     const result = await res.json();
-    container.innerHTML = "";
+    const page = result.data || [];
+    conversationsNextCursor = (result.paging && result.paging.next) || null;
+    document.getElementById("load-more-conversations")?.remove();
+    if (!more) {
+      container.innerHTML = "";
+      currentConversations = [];
+    }
+    currentConversations = currentConversations.concat(page);
+    updateClaimButton();
+    renderConversationTags();
+    updateStatusButtons();
 
-    if (!result.data || result.data.length === 0) {
+    if (currentConversations.length === 0) {
       container.innerHTML =
         '<div class="p-4 text-center text-xs text-gray-400">Trống</div>';
       return;
     }
 
-    result.data.forEach((conv) => {
+    page.forEach((conv) => {
       const div = document.createElement("div");
       div.className =
         "p-3 mx-2 my-1 flex items-center cursor-pointer hover:bg-gray-100 rounded-lg transition conversation-item";
@@ -247,17 +450,304 @@ async function loadConversations() {
                     <p class="text-xs text-gray-500 truncate">${
                       conv.last_message_content || "..."
                     }</p>
+                    <div class="conv-tags flex flex-wrap gap-1 mt-1"></div>
                 </div>
             `;
+      const tagBox = div.querySelector(".conv-tags");
+      (conv.tags || []).forEach((name) => tagBox.appendChild(tagChip(name)));
       container.appendChild(div);
     });
+
+    if (conversationsNextCursor) {
+      const btn = document.createElement("button");
+      btn.id = "load-more-conversations";
+      btn.className = "w-full p-3 text-xs text-blue-600 hover:bg-gray-50";
+      btn.textContent = "Tải thêm";
+      btn.onclick = () => loadConversations(true);
+      container.appendChild(btn);
+    }
+  } catch (e) {
+    container.innerHTML = `<div class="p-4 text-center text-red-500 text-xs">Lỗi kết nối</div>`;
+  }
+}
+
+// --- SEARCH ---
+
+// Kết quả tìm kiếm thay cho danh sách hội thoại; ô tìm kiếm trống thì quay lại danh sách
+// highlight do server trả về đã được escape HTML, chỉ có thẻ <mark>
+async function searchMessages(more = false) {
+  const container = document.getElementById("conversation-list");
+  const q = document.getElementById("search-input").value.trim();
+  if (!q) {
+    searchNextCursor = null;
+    loadConversations();
+    return;
+  }
+
+  try {
+    const params = new URLSearchParams({ q });
+    if (more && searchNextCursor) params.set("before", searchNextCursor);
+    const res = await apiFetch(`/search?${params.toString()}`);
+    const result = await res.json();
+    document.getElementById("load-more-search")?.remove();
+    if (!more) container.innerHTML = "";
+    if (result.code !== 200) {
+      container.innerHTML = `<div class="p-4 text-center text-xs text-gray-400">${
+        result.message || "Không tìm được"
+      }</div>`;
+      return;
+    }
+    searchNextCursor = (result.paging && result.paging.next) || null;
+    const { customers = [], messages = [] } = result.data || {};
+
+    if (!more && customers.length === 0 && messages.length === 0) {
+      container.innerHTML =
+        '<div class="p-4 text-center text-xs text-gray-400">Không có kết quả</div>';
+      return;
+    }
+    if (customers.length > 0) {
+      container.appendChild(searchSection("Khách hàng"));
+      customers.forEach((c) =>
+        container.appendChild(
+          searchItem(c.conversation_id, c.customer_name, c.highlight, c.platform)
+        )
+      );
+    }
+    if (!more && messages.length > 0) container.appendChild(searchSection("Tin nhắn"));
+    messages.forEach((m) =>
+      container.appendChild(
+        searchItem(
+          m.conversation_id,
+          m.customer_name,
+          m.highlight,
+          new Date(m.created_at).toLocaleString("vi-VN")
+        )
+      )
+    );
+
+    if (searchNextCursor) {
+      const btn = document.createElement("button");
+      btn.id = "load-more-search";
+      btn.className = "w-full p-3 text-xs text-blue-600 hover:bg-gray-50";
+      btn.textContent = "Tải thêm";
+      btn.onclick = () => searchMessages(true);
+      container.appendChild(btn);
+    }
   } catch (e) {
     container.innerHTML = `<div class="p-4 text-center text-red-500 text-xs">Lỗi kết nối</div>`;
   }
 }
 
+function searchSection(title) {
+  const h = document.createElement("div");
+  h.className = "px-4 pt-3 pb-1 text-[10px] font-bold uppercase text-gray-400";
+  h.textContent = title;
+  return h;
+}
+
+function searchItem(conversationId, customerName, highlightHtml, meta) {
+  const div = document.createElement("div");
+  div.className = "p-3 mx-2 my-1 cursor-pointer hover:bg-gray-100 rounded-lg transition";
+  div.onclick = () => selectConversation(conversationId, customerName);
+  div.innerHTML = `
+                <div class="flex justify-between gap-2">
+                    <h3 class="text-sm font-bold text-gray-800 truncate"></h3>
+                    <span class="text-[10px] text-gray-400 shrink-0"></span>
+                </div>
+                <p class="text-xs text-gray-500 line-clamp-2 [&_mark]:bg-yellow-200">${highlightHtml}</p>
+            `;
+  div.querySelector("h3").textContent = customerName;
+  div.querySelector("span").textContent = meta || "";
+  return div;
+}
+
+// --- TAGS ---
+
+async function loadTags() {
+  const container = document.getElementById("tag-list");
+  if (!container) return;
+  try {
+    const res = await apiFetch("/tags");
+    const result = await res.json();
+    if (result.code !== 200) return;
+    currentTags = result.data || [];
+  } catch (e) {
+    console.warn("Tags error");
+    return;
+  }
+
+  container.innerHTML = "";
+  currentTags.forEach((tag) => {
+    const chip = tagChip(tag.name, ` ${tag.conversation_count}`);
+    chip.classList.add("cursor-pointer");
+    if (tagFilter === tag.name) chip.classList.add("ring-2", "ring-blue-400");
+    chip.onclick = () => {
+      tagFilter = tagFilter === tag.name ? null : tag.name;
+      loadConversations();
+    };
+    container.appendChild(chip);
+  });
+  renderConversationTags();
+}
+
+// Nhãn có màu theo định nghĩa của tenant (màu xám nếu nhãn đã bị xóa)
+function tagChip(name, suffix = "") {
+  const tag = currentTags.find((t) => t.name === name);
+  const chip = document.createElement("span");
+  chip.className = "px-2 py-0.5 rounded-full text-[10px] text-white whitespace-nowrap";
+  chip.style.backgroundColor = tag ? tag.color : "#6B7280";
+  chip.textContent = name + suffix;
+  return chip;
+}
+
+// Nhãn của hội thoại đang mở: bấm × để gỡ, chọn trong danh sách để gắn thêm
+function renderConversationTags() {
+  const box = document.getElementById("conversation-tags");
+  const select = document.getElementById("tag-select");
+  if (!box || !select) return;
+  const conv = currentConversations.find((c) => c.id === currentConversationId);
+  const tags = (conv && conv.tags) || [];
+  const canTag = currentRole !== "viewer";
+
+  box.innerHTML = "";
+  tags.forEach((name) => {
+    const chip = tagChip(name);
+    const tag = currentTags.find((t) => t.name === name);
+    if (canTag && tag) {
+      chip.textContent = `${name} ×`;
+      chip.classList.add("cursor-pointer");
+      chip.onclick = () => setConversationTag(tag.id, false);
+    }
+    box.appendChild(chip);
+  });
+
+  select.innerHTML = '<option value="">+ Nhãn</option>';
+  currentTags
+    .filter((t) => !tags.includes(t.name))
+    .forEach((t) => {
+      const option = document.createElement("option");
+      option.value = t.id;
+      option.textContent = t.name;
+      select.appendChild(option);
+    });
+  select.classList.toggle("hidden", !canTag || !conv);
+}
+
+async function setConversationTag(tagId, present) {
+  if (!currentConversationId || !tagId) return;
+  try {
+    const path = present
+      ? `/conversations/${currentConversationId}/tags`
+      : `/conversations/${currentConversationId}/tags/${tagId}`;
+    const res = await apiFetch(path, {
+      method: present ? "POST" : "DELETE",
+      headers: { "Content-Type": "application/json" },
+      body: present ? JSON.stringify({ tag_id: Number(tagId) }) : undefined,
+    });
+    const result = await res.json();
+    if (result.code !== 200) throw new Error(result.message);
+    loadConversations();
+  } catch (e) {
+    alert("Không cập nhật được nhãn: " + (e.message || "Mất kết nối Server"));
+  }
+}
+
+// --- LIFECYCLE (archive / resolve / snooze / reopen) ---
+
+function setStatusFilter(status) {
+  statusFilter = status;
+  document.querySelectorAll(".status-tab").forEach((tab) => {
+    const active = tab.dataset.status === status;
+    tab.classList.toggle("text-blue-600", active);
+    tab.classList.toggle("border-blue-600", active);
+    tab.classList.toggle("text-gray-400", !active);
+    tab.classList.toggle("border-transparent", !active);
+  });
+  loadConversations();
+}
+
+// Hội thoại đang mở: "Mở lại" khi đã đóng, ngược lại "Xong / Tạm ẩn / Lưu trữ"
+function updateStatusButtons() {
+  const box = document.getElementById("status-actions");
+  if (!box) return;
+  const conv = currentConversations.find((c) => c.id === currentConversationId);
+  const canChange = currentRole !== "viewer" && !!conv;
+  const closed = conv && !["unread", "read"].includes(conv.status);
+  box.classList.toggle("hidden", !canChange);
+  document.getElementById("reopen-btn").classList.toggle("hidden", !closed);
+  document
+    .querySelectorAll(".close-action")
+    .forEach((btn) => btn.classList.toggle("hidden", !!closed));
+}
+
+async function changeConversationStatus(action) {
+  if (!currentConversationId) return;
+  let body;
+  if (action === "snooze") {
+    const hours = Number(prompt("Tạm ẩn trong bao nhiêu giờ?", "4"));
+    if (!hours || hours <= 0) return;
+    body = JSON.stringify({
+      until: new Date(Date.now() + hours * 3600 * 1000).toISOString(),
+    });
+  }
+  try {
+    const res = await apiFetch(`/conversations/${currentConversationId}/${action}`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body,
+    });
+    const result = await res.json();
+    if (result.code !== 200) throw new Error(result.message);
+    loadConversations();
+  } catch (e) {
+    alert("Không cập nhật được trạng thái: " + (e.message || "Mất kết nối Server"));
+  }
+}
+
+function toggleAssignedFilter() {
+  assignedOnlyFilter = !assignedOnlyFilter;
+  const btn = document.getElementById("assigned-filter-btn");
+  btn.classList.toggle("text-blue-600", assignedOnlyFilter);
+  btn.classList.toggle("text-gray-400", !assignedOnlyFilter);
+  loadConversations();
+}
+
+// Nút "Nhận hội thoại": chỉ hiện khi hội thoại đang mở chưa được giao cho mình
+function updateClaimButton() {
+  const btn = document.getElementById("claim-btn");
+  if (!btn) return;
+  const conv = currentConversations.find((c) => c.id === currentConversationId);
+  const canClaim =
+    currentConversationId !== null &&
+    currentRole !== "viewer" &&
+    (!conv || conv.assignee_id !== currentStaffId);
+  btn.classList.toggle("hidden", !canClaim);
+}
+
+async function claimConversation() {
+  if (!currentConversationId) return;
+  try {
+    const res = await apiFetch(`/conversations/${currentConversationId}/assign`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ staff_id: currentStaffId }),
+    });
+    const result = await res.json();
+    if (result.code !== 200) throw new Error(result.message);
+    loadConversations();
+  } catch (e) {
+    alert("Không nhận được hội thoại: " + (e.message || "Mất kết nối Server"));
+  }
+}
+
 async function selectConversation(id, name) {
   currentConversationId = id;
+  messagesPrevCursor = null;
+  updateClaimButton();
+  renderConversationTags();
+  updateStatusButtons();
+  renderMessagingWindow(null);
+  loadMessagingWindow(id);
   document.getElementById("header-name").innerText = name;
   document.getElementById("header-avatar").innerText = name
     .charAt(0)
@@ -266,60 +756,348 @@ async function selectConversation(id, name) {
   document.getElementById("welcome-screen").classList.add("hidden");
   document.getElementById("chat-header").classList.remove("hidden");
   document.getElementById("chat-messages").classList.remove("hidden");
-  document.getElementById("input-area").classList.remove("hidden");
+  // Viewer chỉ được xem, không được trả lời
+  document.getElementById("input-area").classList.toggle("hidden", currentRole === "viewer");
 
   const chatBox = document.getElementById("chat-messages");
   chatBox.innerHTML =
     '<div class="text-center mt-4 text-xs text-gray-400">Đang tải...</div>';
 
   try {
-    const res = await fetch(`${API_BASE}/conversations/${id}/messages`);
+    const res = await apiFetch(`/conversations/${id}/messages`);
     const result = await res.json();
-    if (result.code === 200) renderMessages(result.data);
+    if (result.code === 200) {
+      messagesPrevCursor = (result.paging && result.paging.prev) || null;
+      renderMessages(result.data);
+    }
   } catch (e) {
     chatBox.innerHTML =
       '<div class="text-center text-red-500 text-xs mt-4">Lỗi tải tin nhắn</div>';
   }
 }
 
+// Tin nhắn cũ hơn (?before=paging.prev), giữ nguyên vị trí cuộn
+async function loadOlderMessages() {
+  if (!currentConversationId || !messagesPrevCursor) return;
+  const conversationId = currentConversationId;
+  try {
+    const res = await apiFetch(
+      `/conversations/${conversationId}/messages?before=${encodeURIComponent(messagesPrevCursor)}`
+    );
+    const result = await res.json();
+    if (result.code !== 200 || conversationId !== currentConversationId) return;
+    messagesPrevCursor = (result.paging && result.paging.prev) || null;
+
+    const chatBox = document.getElementById("chat-messages");
+    const fromBottom = chatBox.scrollHeight - chatBox.scrollTop;
+    renderMessages((result.data || []).concat(currentMessages));
+    chatBox.scrollTop = chatBox.scrollHeight - fromBottom;
+  } catch (e) {
+    console.error("Load older messages error:", e);
+  }
+}
+
 function renderMessages(msgs) {
+  currentMessages = msgs || [];
   const chatBox = document.getElementById("chat-messages");
   chatBox.innerHTML = '<div class="h-2"></div>';
-  msgs.forEach((msg) => {
+  if (messagesPrevCursor) {
+    const btn = document.createElement("button");
+    btn.className = "block mx-auto mb-3 text-xs text-blue-600 hover:underline";
+    btn.textContent = "Tải tin nhắn cũ hơn";
+    btn.onclick = loadOlderMessages;
+    chatBox.appendChild(btn);
+  }
+  currentMessages.forEach((msg) => {
     const isMe = msg.sender_type === "agent";
     const div = document.createElement("div");
     div.className = `flex ${isMe ? "justify-end" : "justify-start"} mb-3`;
+    const content = msg.is_deleted
+      ? '<span class="italic opacity-60">Tin nhắn đã bị thu hồi</span>'
+      : `${renderMessageContent(msg)}${msg.edited_at ? ' <span class="text-[10px] opacity-60">(đã chỉnh sửa)</span>' : ""}`;
     div.innerHTML = `<div class="max-w-[75%] px-4 py-2 rounded-2xl text-sm ${
       isMe ? "bg-blue-600 text-white" : "bg-white border text-gray-800"
-    }">${msg.content}</div>`;
+    }">${content}</div>`;
     chatBox.appendChild(div);
+    if (msg.reactions && msg.reactions.length > 0) {
+      const reactions = document.createElement("div");
+      reactions.className = `flex ${isMe ? "justify-end" : "justify-start"} -mt-2 mb-3 text-xs`;
+      reactions.textContent = msg.reactions.map((r) => r.emoji || r.reaction).join(" ");
+      chatBox.appendChild(reactions);
+    }
+    if (isMe && msg.delivery_status) {
+      const status = document.createElement("div");
+      status.className = `flex justify-end -mt-2 mb-3 text-[10px] ${
+        msg.delivery_status === "failed" ? "text-red-500" : "text-gray-400"
+      }`;
+      status.textContent = DELIVERY_LABELS[msg.delivery_status] || "";
+      if (msg.delivery_status === "failed" && msg.delivery_error) {
+        status.textContent += `: ${DELIVERY_ERROR_LABELS[msg.delivery_error] || msg.delivery_error}`;
+      }
+      if (msg.is_external) {
+        status.textContent = `Gửi ngoài hệ thống · ${status.textContent}`;
+      }
+      if (msg.message_tag) {
+        status.textContent = `${MESSAGE_TAG_LABELS[msg.message_tag] || msg.message_tag} · ${status.textContent}`;
+      }
+      chatBox.appendChild(status);
+    }
   });
   chatBox.scrollTop = chatBox.scrollHeight;
 }
 
+// Tin nhắn ảnh / video / âm thanh / tệp: content là URL của tệp
+function renderMessageContent(msg) {
+  const structured = (msg.attachments || []).find((a) => a.type === "structured");
+  if (structured && structured.payload) return renderStructured(structured.payload);
+  const url = msg.content || "";
+  const safeUrl = url.replace(/"/g, "&quot;");
+  switch (msg.type) {
+    case "image":
+      return `<a href="${safeUrl}" target="_blank" rel="noopener"><img src="${safeUrl}" class="max-w-full max-h-64 rounded-lg" alt="Ảnh" /></a>`;
+    case "video":
+      return `<video src="${safeUrl}" controls class="max-w-full max-h-64 rounded-lg"></video>`;
+    case "audio":
+    case "voice":
+      return `<audio src="${safeUrl}" controls></audio>`;
+    case "file": {
+      const attachment = (msg.attachments || [])[0];
+      const name = (attachment && attachment.payload && attachment.payload.file_name) || "Tệp đính kèm";
+      const link = document.createElement("a");
+      link.href = url;
+      link.target = "_blank";
+      link.rel = "noopener";
+      link.className = "underline";
+      link.textContent = `📎 ${name}`;
+      return link.outerHTML;
+    }
+    default:
+      return url;
+  }
+}
+
+// Nút trả lời nhanh / mẫu tin (POST /api/messages/structured)
+function renderStructured(m) {
+  const pill = (title) =>
+    `<span class="inline-block mt-1 mr-1 px-2 py-0.5 rounded-full border border-current text-xs">${escapeHtml(title)}</span>`;
+  let html = "";
+  if (m.text) html += `<div>${escapeHtml(m.text)}</div>`;
+  (m.elements || []).forEach((el) => {
+    html += `<div class="mt-1 p-2 rounded-lg bg-black/5">`;
+    if (el.image_url) html += `<img src="${escapeHtml(el.image_url)}" class="max-h-32 rounded mb-1" alt="" />`;
+    html += `<div class="font-semibold">${escapeHtml(el.title)}</div>`;
+    if (el.subtitle) html += `<div class="text-xs opacity-75">${escapeHtml(el.subtitle)}</div>`;
+    html += (el.buttons || []).map((b) => pill(b.title)).join("");
+    html += "</div>";
+  });
+  if (m.media) html += `<div>${m.media.media_type === "video" ? "🎬 Video" : "🖼 Ảnh"}</div>`;
+  const buttons = (m.buttons || []).concat((m.media && m.media.buttons) || []);
+  html += buttons.map((b) => pill(b.title)).join("");
+  if (m.quick_replies && m.quick_replies.length) {
+    html += `<div class="mt-1">${m.quick_replies
+      .map((q) => pill(q.title || (q.content_type === "user_email" ? "Email" : "Số điện thoại")))
+      .join("")}</div>`;
+  }
+  return html;
+}
+
+function escapeHtml(s) {
+  const div = document.createElement("div");
+  div.textContent = s || "";
+  return div.innerHTML.replace(/"/g, "&quot;");
+}
+
+const DELIVERY_LABELS = {
+  queued: "Đang gửi…",
+  sent: "Đã gửi",
+  delivered: "Đã nhận",
+  read: "Đã xem",
+  failed: "Gửi lỗi",
+};
+
+const DELIVERY_ERROR_LABELS = {
+  token_expired: "Fanpage đã mất kết nối, vui lòng kết nối lại trong phần Cài đặt",
+  page_inactive: "Fanpage đã bị tắt",
+  permission_denied: "Fanpage không có quyền gửi tin nhắn",
+  outside_window: "Nền tảng từ chối tin nhắn ngoài khung 24 giờ",
+  rejected: "Nền tảng từ chối tin nhắn",
+  send_failed: "Đã thử lại nhiều lần không thành công",
+};
+
+// Tin nhắn kèm nút trả lời nhanh: bấm nút gửi lại tiêu đề nút, payload = tiêu đề
+async function sendQuickReplies(text, titles) {
+  const chatBox = document.getElementById("chat-messages");
+  const temp = document.createElement("div");
+  temp.className = "flex justify-end mb-3 opacity-50";
+  temp.innerHTML = `<div class="max-w-[75%] px-4 py-2 rounded-2xl bg-blue-600 text-white text-sm"></div>`;
+  temp.firstElementChild.textContent = `${text} ⚡ ${titles.join(" · ")}`;
+  chatBox.appendChild(temp);
+  chatBox.scrollTop = chatBox.scrollHeight;
+
+  try {
+    const res = await apiFetch("/messages/structured", {
+      method: "POST",
+      body: JSON.stringify({
+        conversation_id: parseInt(currentConversationId),
+        message: {
+          type: "quick_replies",
+          text,
+          quick_replies: titles.map((title) => ({ content_type: "text", title, payload: title })),
+        },
+        message_tag: selectedMessageTag(),
+      }),
+    });
+    const result = await res.json();
+    if (result.code === 409 && result.data) renderMessagingWindow(result.data);
+    if (result.code !== 200) {
+      temp.innerHTML = `<span class="text-red-500 text-xs"></span>`;
+      temp.firstElementChild.textContent = result.message || "Lỗi gửi";
+      return;
+    }
+    temp.remove();
+    addQueuedMessages(result.data.conversation_id, result.data.messages);
+  } catch (e) {
+    temp.innerHTML = '<span class="text-red-500 text-xs">Lỗi gửi</span>';
+  }
+}
+
+// --- 24-HOUR WINDOW ---
+// Messenger / Instagram: quá 24 giờ kể từ tin nhắn cuối của khách thì phải gửi kèm thẻ tin nhắn
+
+const MESSAGE_TAG_LABELS = {
+  HUMAN_AGENT: "Nhân viên hỗ trợ (trong 7 ngày)",
+  POST_PURCHASE_UPDATE: "Cập nhật đơn hàng",
+  CONFIRMED_EVENT_UPDATE: "Nhắc lịch / sự kiện",
+  ACCOUNT_UPDATE: "Cập nhật tài khoản",
+};
+
+async function loadMessagingWindow(id) {
+  try {
+    const res = await apiFetch(`/conversations/${id}/window`);
+    const result = await res.json();
+    if (result.code === 200 && id === currentConversationId) renderMessagingWindow(result.data);
+  } catch (e) {
+    console.error("Load messaging window error:", e);
+  }
+}
+
+function renderMessagingWindow(info) {
+  const bar = document.getElementById("window-bar");
+  const select = document.getElementById("message-tag-select");
+  select.innerHTML = "";
+  if (!info || info.open) {
+    bar.classList.add("hidden");
+    return;
+  }
+  const tags = info.allowed_tags || [];
+  document.getElementById("window-text").textContent = tags.length
+    ? "Đã quá 24 giờ kể từ tin nhắn cuối của khách, chỉ gửi được kèm thẻ tin nhắn:"
+    : "Đã hết thời hạn nhắn tin với khách, vui lòng chờ khách nhắn lại";
+  tags.forEach((tag) => {
+    const option = document.createElement("option");
+    option.value = tag;
+    option.textContent = MESSAGE_TAG_LABELS[tag] || tag;
+    select.appendChild(option);
+  });
+  select.classList.toggle("hidden", tags.length === 0);
+  bar.classList.remove("hidden");
+}
+
+// Thẻ đang chọn, chỉ khi khung 24 giờ đã đóng
+function selectedMessageTag() {
+  if (document.getElementById("window-bar").classList.contains("hidden")) return undefined;
+  return document.getElementById("message-tag-select").value || undefined;
+}
+
+// --- ATTACHMENTS ---
+
+let pendingAttachment = null; // { id, file_name, type } từ POST /api/media
+
+async function uploadAttachment(fileInput) {
+  const file = fileInput.files[0];
+  fileInput.value = "";
+  if (!file) return;
+
+  const label = document.getElementById("pending-attachment-name");
+  document.getElementById("pending-attachment").classList.remove("hidden");
+  label.textContent = `Đang tải lên ${file.name}...`;
+  pendingAttachment = null;
+
+  try {
+    const form = new FormData();
+    form.append("file", file);
+    const res = await apiFetch("/media", { method: "POST", body: form });
+    const result = await res.json();
+    if (result.code !== 201) {
+      label.textContent = result.message || "Tải tệp thất bại";
+      return;
+    }
+    pendingAttachment = result.data;
+    label.textContent = result.data.file_name;
+  } catch (e) {
+    label.textContent = "Tải tệp thất bại";
+  }
+}
+
+function clearPendingAttachment() {
+  pendingAttachment = null;
+  document.getElementById("pending-attachment").classList.add("hidden");
+}
+
 async function sendMessage() {
   const input = document.getElementById("message-input");
   const text = input.value.trim();
-  if (!text || !currentConversationId) return;
+  const attachment = pendingAttachment;
+  if ((!text && !attachment) || !currentConversationId) return;
+
+  const quickInput = document.getElementById("quick-replies-input");
+  const chips = quickInput.value
+    .split(",")
+    .map((t) => t.trim())
+    .filter(Boolean);
+  if (chips.length) {
+    if (attachment) {
+      alert("Không gửi được nút trả lời nhanh kèm tệp đính kèm");
+      return;
+    }
+    if (!text) return;
+    await sendQuickReplies(text, chips);
+    input.value = "";
+    quickInput.value = "";
+    return;
+  }
 
   const chatBox = document.getElementById("chat-messages");
   const temp = document.createElement("div");
   temp.className = "flex justify-end mb-3 opacity-50";
-  temp.innerHTML = `<div class="max-w-[75%] px-4 py-2 rounded-2xl bg-blue-600 text-white text-sm">${text}</div>`;
+  temp.innerHTML = `<div class="max-w-[75%] px-4 py-2 rounded-2xl bg-blue-600 text-white text-sm"></div>`;
+  temp.firstElementChild.textContent = attachment
+    ? `📎 ${attachment.file_name}${text ? ` · ${text}` : ""}`
+    : text;
   chatBox.appendChild(temp);
   chatBox.scrollTop = chatBox.scrollHeight;
   input.value = "";
+  if (attachment) clearPendingAttachment();
 
   try {
-    await fetch(`${API_BASE}/messages/reply`, {
+    const res = await apiFetch("/messages/reply", {
       method: "POST",
-      headers: { "Content-Type": "application/json" },
       body: JSON.stringify({
         conversation_id: parseInt(currentConversationId),
         text,
+        media_id: attachment ? attachment.id : undefined,
+        message_tag: selectedMessageTag(),
       }),
     });
-    temp.classList.remove("opacity-50");
+    const result = await res.json();
+    if (result.code === 409 && result.data) renderMessagingWindow(result.data);
+    if (result.code !== 200) {
+      temp.innerHTML = `<span class="text-red-500 text-xs"></span>`;
+      temp.firstElementChild.textContent = result.message || "Lỗi gửi";
+      return;
+    }
+    temp.remove();
+    addQueuedMessages(result.data.conversation_id, result.data.messages);
   } catch (e) {
     temp.innerHTML = '<span class="text-red-500 text-xs">Lỗi gửi</span>';
   }